@@ -26,11 +26,17 @@ import (
 	"github.com/aler9/gortsplib/pkg/base"
 	"github.com/aler9/gortsplib/pkg/headers"
 	"github.com/aler9/gortsplib/pkg/liberrors"
+	"github.com/aler9/gortsplib/pkg/mikey"
+	"github.com/aler9/gortsplib/pkg/parameters"
 	"github.com/aler9/gortsplib/pkg/ringbuffer"
 	"github.com/aler9/gortsplib/pkg/rtcpreceiver"
 	"github.com/aler9/gortsplib/pkg/rtcpsender"
 	"github.com/aler9/gortsplib/pkg/rtpcleaner"
+	"github.com/aler9/gortsplib/pkg/rtpfec"
+	"github.com/aler9/gortsplib/pkg/rtpretransmitter"
+	"github.com/aler9/gortsplib/pkg/rtprtx"
 	"github.com/aler9/gortsplib/pkg/sdp"
+	"github.com/aler9/gortsplib/pkg/srtp"
 	"github.com/aler9/gortsplib/pkg/url"
 )
 
@@ -88,15 +94,40 @@ type clientTrack struct {
 	track           Track
 	tcpChannel      int
 	udpRTPListener  *clientUDPListener
-	udpRTCPListener *clientUDPListener
+	udpRTCPListener *clientUDPListener // nil when RTP and RTCP are multiplexed
+	srtpContext     *srtp.Context
+	rtpPort         int // chosen by the user, zero when chosen automatically
+	rtcpPort        int // chosen by the user, zero when chosen automatically
 
 	// play
 	udpRTPPacketBuffer *rtpPacketMultiBuffer
-	udpRTCPReceiver    *rtcpreceiver.RTCPReceiver
+	rtcpReceiver       *rtcpreceiver.RTCPReceiver
+	udpRTXReceiver     *rtxReceiver
+	udpFECDecoder      *rtpfec.Decoder
 	cleaner            *rtpcleaner.Cleaner
+	localSSRC          uint32
+	remoteSSRC         uint32 // read atomically
+	firSequenceNumber  uint32 // read atomically
 
 	// record
-	udpRTCPSender *rtcpsender.RTCPSender
+	rtcpSender      *rtcpsender.RTCPSender
+	receiverReports *receiverReportStats
+	udpRTXHistory   *rtpretransmitter.Retransmitter
+	udpRTXEncoder   *rtprtx.Encoder
+}
+
+func newClientFECDecoder(track Track) *rtpfec.Decoder {
+	fecPayloadType, fecScheme, ok := track.FEC()
+	if !ok {
+		return nil
+	}
+
+	d := &rtpfec.Decoder{
+		Scheme:      fecScheme,
+		PayloadType: fecPayloadType,
+	}
+	d.Init()
+	return d
 }
 
 func (s clientState) String() string {
@@ -116,22 +147,26 @@ func (s clientState) String() string {
 }
 
 type optionsReq struct {
+	ctx context.Context
 	url *url.URL
 	res chan clientRes
 }
 
 type describeReq struct {
+	ctx context.Context
 	url *url.URL
 	res chan clientRes
 }
 
 type announceReq struct {
+	ctx    context.Context
 	url    *url.URL
 	tracks Tracks
 	res    chan clientRes
 }
 
 type setupReq struct {
+	ctx      context.Context
 	forPlay  bool
 	track    Track
 	baseURL  *url.URL
@@ -141,21 +176,47 @@ type setupReq struct {
 }
 
 type playReq struct {
-	ra  *headers.Range
-	res chan clientRes
+	ctx       context.Context
+	ra        *headers.Range
+	scale     *headers.Scale
+	speed     *headers.Speed
+	keepRates bool
+	res       chan clientRes
 }
 
 type recordReq struct {
+	ctx context.Context
 	res chan clientRes
 }
 
 type pauseReq struct {
+	ctx context.Context
 	res chan clientRes
 }
 
+type getParameterReq struct {
+	ctx   context.Context
+	url   *url.URL
+	names []string
+	res   chan clientRes
+}
+
+type setParameterReq struct {
+	ctx    context.Context
+	url    *url.URL
+	params parameters.Parameters
+	res    chan clientRes
+}
+
+type redirectReq struct {
+	url *url.URL
+	ra  *headers.Range
+}
+
 type clientRes struct {
 	tracks  Tracks
 	baseURL *url.URL
+	params  parameters.Parameters
 	res     *base.Response
 	err     error
 }
@@ -167,6 +228,11 @@ type ClientOnPacketRTPCtx struct {
 	PTSEqualsDTS bool
 	H264NALUs    [][]byte
 	H264PTS      time.Duration
+
+	// absolute time of the packet, computed with the latest RTCP sender report.
+	NTP time.Time
+	// whether NTP is filled, that happens after the first RTCP sender report.
+	NTPSynchronized bool
 }
 
 // ClientOnPacketRTCPCtx is the context of a RTCP packet.
@@ -188,6 +254,14 @@ type Client struct {
 	OnPacketRTP func(*ClientOnPacketRTPCtx)
 	// called when a RTCP packet arrives.
 	OnPacketRTCP func(*ClientOnPacketRTCPCtx)
+	// called when the server sends a request to the client (SET_PARAMETER, ANNOUNCE, REDIRECT, PLAY_NOTIFY).
+	// The returned response is sent back to the server.
+	// It defaults to a function that replies with 200 OK to PLAY_NOTIFY requests
+	// and with 501 Not Implemented to other requests.
+	OnServerRequest func(*base.Request) *base.Response
+	// called when a redirect requested by the server fails
+	// and the previous session is resumed.
+	OnRedirectError func(error)
 
 	//
 	// RTSP parameters
@@ -201,7 +275,11 @@ type Client struct {
 	// a TLS configuration to connect to TLS (RTSPS) servers.
 	// It defaults to nil.
 	TLSConfig *tls.Config
-	// disable being redirected to other servers, that can happen during Describe().
+	// try RTSP 2.0 first, then, if it is not supported by the server, fallback to RTSP 1.0.
+	// It defaults to false.
+	RTSP2Enable bool
+	// disable being redirected to other servers, that can happen during Describe()
+	// or when the server sends a REDIRECT request while reading.
 	// It defaults to false.
 	RedirectDisable bool
 	// enable communication with servers which don't provide server ports or use
@@ -226,9 +304,20 @@ type Client struct {
 	// It allows to queue packets before sending them.
 	// It defaults to 256.
 	WriteBufferCount int
+	// number of sent RTP packets that are kept in order to be retransmitted
+	// when they are requested by the server with a NACK.
+	// It is used only when publishing with UDP tracks that have a RTX payload type.
+	// It defaults to 512.
+	RTXBufferCount int
 	// user agent header
 	// It defaults to "gortsplib"
 	UserAgent string
+	// function that provides a Bearer token used to authenticate requests,
+	// instead of the credentials in the URL.
+	// It is called before the first request and, in order to refresh the token,
+	// when a request is rejected with code 401.
+	// It defaults to nil.
+	BearerTokenProvider func() (string, error)
 
 	//
 	// system functions
@@ -244,10 +333,10 @@ type Client struct {
 	// private
 	//
 
-	udpSenderReportPeriod   time.Duration
-	udpReceiverReportPeriod time.Duration
-	checkStreamPeriod       time.Duration
-	keepalivePeriod         time.Duration
+	senderReportPeriod   time.Duration
+	receiverReportPeriod time.Duration
+	checkStreamPeriod    time.Duration
+	keepalivePeriod      time.Duration
 
 	scheme             string
 	host               string
@@ -258,7 +347,9 @@ type Client struct {
 	br                 *bufio.Reader
 	session            string
 	sender             *auth.Sender
+	bearerToken        string
 	cseq               int
+	version            base.Version
 	optionsSent        bool
 	useGetParameter    bool
 	lastDescribeURL    *url.URL
@@ -267,8 +358,12 @@ type Client struct {
 	tracks             []*clientTrack
 	tcpTracksByChannel map[int]*clientTrack
 	lastRange          *headers.Range
-	writeMutex         sync.RWMutex // publish
-	writeFrameAllowed  bool         // publish
+	requestCtx         context.Context
+	requestCancelled   bool
+	lastScale          *headers.Scale
+	lastSpeed          *headers.Speed
+	writeMutex         sync.RWMutex
+	writeFrameAllowed  bool
 	checkStreamTimer   *time.Timer
 	checkStreamInitial bool
 	tcpLastFrameTime   *int64
@@ -276,25 +371,32 @@ type Client struct {
 	closeError         error
 	writerRunning      bool
 	writeBuffer        *ringbuffer.RingBuffer
+	noRTSPSession      bool // the stream is read from a SDP, without RTSP
 
 	// connCloser channels
 	connCloserTerminate chan struct{}
 	connCloserDone      chan struct{}
 
 	// reader channels
-	readerErr chan error
+	readerErr       chan error
+	readerTerminate chan struct{}
+	readerResponse  chan *base.Response
+	readerRedirect  chan *redirectReq
 
 	// writer channels
 	writerDone chan struct{}
 
 	// in
-	options  chan optionsReq
-	describe chan describeReq
-	announce chan announceReq
-	setup    chan setupReq
-	play     chan playReq
-	record   chan recordReq
-	pause    chan pauseReq
+	options      chan optionsReq
+	describe     chan describeReq
+	announce     chan announceReq
+	setup        chan setupReq
+	play         chan playReq
+	record       chan recordReq
+	pause        chan pauseReq
+	getParameter chan getParameterReq
+	setParameter chan setParameterReq
+	readSDP      chan readSDPReq
 
 	// out
 	done chan struct{}
@@ -311,6 +413,18 @@ func (c *Client) Start(scheme string, host string) error {
 		c.OnPacketRTCP = func(ctx *ClientOnPacketRTCPCtx) {
 		}
 	}
+	if c.OnServerRequest == nil {
+		c.OnServerRequest = func(req *base.Request) *base.Response {
+			if req.Method == base.PlayNotify {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}
+			}
+			return &base.Response{
+				StatusCode: base.StatusNotImplemented,
+			}
+		}
+	}
 
 	// RTSP parameters
 	if c.ReadTimeout == 0 {
@@ -328,6 +442,9 @@ func (c *Client) Start(scheme string, host string) error {
 	if c.WriteBufferCount == 0 {
 		c.WriteBufferCount = 256
 	}
+	if c.RTXBufferCount == 0 {
+		c.RTXBufferCount = 512
+	}
 	if c.UserAgent == "" {
 		c.UserAgent = "gortsplib"
 	}
@@ -341,11 +458,11 @@ func (c *Client) Start(scheme string, host string) error {
 	}
 
 	// private
-	if c.udpSenderReportPeriod == 0 {
-		c.udpSenderReportPeriod = 10 * time.Second
+	if c.senderReportPeriod == 0 {
+		c.senderReportPeriod = 10 * time.Second
 	}
-	if c.udpReceiverReportPeriod == 0 {
-		c.udpReceiverReportPeriod = 10 * time.Second
+	if c.receiverReportPeriod == 0 {
+		c.receiverReportPeriod = 10 * time.Second
 	}
 	if c.checkStreamPeriod == 0 {
 		c.checkStreamPeriod = 1 * time.Second
@@ -358,6 +475,7 @@ func (c *Client) Start(scheme string, host string) error {
 
 	c.scheme = scheme
 	c.host = host
+	c.version = c.initialVersion()
 	c.ctx = ctx
 	c.ctxCancel = ctxCancel
 	c.checkStreamTimer = emptyTimer()
@@ -369,6 +487,10 @@ func (c *Client) Start(scheme string, host string) error {
 	c.play = make(chan playReq)
 	c.record = make(chan recordReq)
 	c.pause = make(chan pauseReq)
+	c.getParameter = make(chan getParameterReq)
+	c.setParameter = make(chan setParameterReq)
+	c.readSDP = make(chan readSDPReq)
+	c.readerResponse = make(chan *base.Response)
 	c.done = make(chan struct{})
 
 	go c.run()
@@ -378,6 +500,12 @@ func (c *Client) Start(scheme string, host string) error {
 
 // StartReading connects to the address and starts reading all tracks.
 func (c *Client) StartReading(address string) error {
+	return c.StartReadingContext(context.Background(), address)
+}
+
+// StartReadingContext is like StartReading, but requests are cancelled when ctx is done.
+// ctx is used only during the initialization of the stream.
+func (c *Client) StartReadingContext(ctx context.Context, address string) error {
 	u, err := url.Parse(address)
 	if err != nil {
 		return err
@@ -388,13 +516,19 @@ func (c *Client) StartReading(address string) error {
 		return err
 	}
 
-	tracks, baseURL, _, err := c.Describe(u)
+	tracks, baseURL, _, err := c.DescribeContext(ctx, u)
+	if err != nil {
+		c.Close()
+		return err
+	}
+
+	err = c.SetupAndPlayContext(ctx, tracks, baseURL)
 	if err != nil {
 		c.Close()
 		return err
 	}
 
-	return c.SetupAndPlay(tracks, baseURL)
+	return nil
 }
 
 // StartReadingAndWait connects to the address, starts reading all tracks and waits
@@ -408,8 +542,45 @@ func (c *Client) StartReadingAndWait(address string) error {
 	return c.Wait()
 }
 
+// StartReadingSDP starts reading the tracks described by a SDP, without RTSP.
+// Multicast groups in the SDP are joined, while unicast ports in the SDP
+// are bound in order to receive packets pushed by a sender.
+func (c *Client) StartReadingSDP(byts []byte) error {
+	err := c.Start("", "")
+	if err != nil {
+		return err
+	}
+
+	cres := make(chan clientRes)
+	select {
+	case c.readSDP <- readSDPReq{sdp: byts, res: cres}:
+		err = (<-cres).err
+	case <-c.ctx.Done():
+		err = liberrors.ErrClientTerminated{}
+	}
+
+	if err != nil {
+		c.Close()
+		return err
+	}
+
+	return nil
+}
+
+// StartReadingSAP starts reading a session announced with SAP,
+// by joining its multicast groups, without RTSP.
+func (c *Client) StartReadingSAP(session *SAPSession) error {
+	return c.StartReadingSDP(session.SDP)
+}
+
 // StartPublishing connects to the address and starts publishing the tracks.
 func (c *Client) StartPublishing(address string, tracks Tracks) error {
+	return c.StartPublishingContext(context.Background(), address, tracks)
+}
+
+// StartPublishingContext is like StartPublishing, but requests are cancelled when ctx is done.
+// ctx is used only during the initialization of the stream.
+func (c *Client) StartPublishingContext(ctx context.Context, address string, tracks Tracks) error {
 	u, err := url.Parse(address)
 	if err != nil {
 		return err
@@ -424,21 +595,21 @@ func (c *Client) StartPublishing(address string, tracks Tracks) error {
 	// use a copy in order not to mess the client-read-republish example.
 	tracks = tracks.clone()
 
-	_, err = c.Announce(u, tracks)
+	_, err = c.AnnounceContext(ctx, u, tracks)
 	if err != nil {
 		c.Close()
 		return err
 	}
 
 	for _, track := range tracks {
-		_, err := c.Setup(false, track, u, 0, 0)
+		_, err := c.SetupContext(ctx, false, track, u, 0, 0)
 		if err != nil {
 			c.Close()
 			return err
 		}
 	}
 
-	_, err = c.Record()
+	_, err = c.RecordContext(ctx)
 	if err != nil {
 		c.Close()
 		return err
@@ -484,33 +655,68 @@ func (c *Client) runInner() error {
 	for {
 		select {
 		case req := <-c.options:
+			c.requestStart(req.ctx)
 			res, err := c.doOptions(req.url)
+			c.requestEnd()
 			req.res <- clientRes{res: res, err: err}
 
 		case req := <-c.describe:
+			c.requestStart(req.ctx)
 			tracks, baseURL, res, err := c.doDescribe(req.url)
+			c.requestEnd()
 			req.res <- clientRes{tracks: tracks, baseURL: baseURL, res: res, err: err}
 
 		case req := <-c.announce:
+			c.requestStart(req.ctx)
 			res, err := c.doAnnounce(req.url, req.tracks)
+			c.requestEnd()
 			req.res <- clientRes{res: res, err: err}
 
 		case req := <-c.setup:
+			c.requestStart(req.ctx)
 			res, err := c.doSetup(req.forPlay, req.track, req.baseURL, req.rtpPort, req.rtcpPort)
+			c.requestEnd()
 			req.res <- clientRes{res: res, err: err}
 
 		case req := <-c.play:
-			res, err := c.doPlay(req.ra, false)
+			scale, speed := req.scale, req.speed
+			if req.keepRates {
+				scale, speed = c.lastScale, c.lastSpeed
+			}
+
+			c.requestStart(req.ctx)
+			res, err := c.doPlay(req.ra, scale, speed)
+			c.requestEnd()
 			req.res <- clientRes{res: res, err: err}
 
 		case req := <-c.record:
+			c.requestStart(req.ctx)
 			res, err := c.doRecord()
+			c.requestEnd()
 			req.res <- clientRes{res: res, err: err}
 
 		case req := <-c.pause:
+			c.requestStart(req.ctx)
 			res, err := c.doPause()
+			c.requestEnd()
+			req.res <- clientRes{res: res, err: err}
+
+		case req := <-c.getParameter:
+			c.requestStart(req.ctx)
+			params, res, err := c.doGetParameter(req.url, req.names)
+			c.requestEnd()
+			req.res <- clientRes{params: params, res: res, err: err}
+
+		case req := <-c.setParameter:
+			c.requestStart(req.ctx)
+			res, err := c.doSetParameter(req.url, req.params)
+			c.requestEnd()
 			req.res <- clientRes{res: res, err: err}
 
+		case req := <-c.readSDP:
+			err := c.doReadSDP(req.sdp)
+			req.res <- clientRes{err: err}
+
 		case <-c.checkStreamTimer.C:
 			if *c.effectiveTransport == TransportUDP ||
 				*c.effectiveTransport == TransportUDPMulticast {
@@ -525,14 +731,21 @@ func (c *Client) runInner() error {
 								return false
 							}
 
-							lft = atomic.LoadInt64(ct.udpRTCPListener.lastPacketTime)
-							if lft != 0 {
-								return false
+							if ct.udpRTCPListener != nil {
+								lft = atomic.LoadInt64(ct.udpRTCPListener.lastPacketTime)
+								if lft != 0 {
+									return false
+								}
 							}
 						}
 						return true
 					}()
 					if inTimeout {
+						// without RTSP, there's no other protocol to switch to
+						if c.noRTSPSession {
+							return liberrors.ErrClientUDPTimeout{}
+						}
+
 						err := c.trySwitchingProtocol()
 						if err != nil {
 							return err
@@ -547,9 +760,11 @@ func (c *Client) runInner() error {
 								return false
 							}
 
-							lft = time.Unix(atomic.LoadInt64(ct.udpRTCPListener.lastPacketTime), 0)
-							if now.Sub(lft) < c.ReadTimeout {
-								return false
+							if ct.udpRTCPListener != nil {
+								lft = time.Unix(atomic.LoadInt64(ct.udpRTCPListener.lastPacketTime), 0)
+								if now.Sub(lft) < c.ReadTimeout {
+									return false
+								}
 							}
 						}
 						return true
@@ -589,6 +804,21 @@ func (c *Client) runInner() error {
 
 			c.keepaliveTimer = time.NewTimer(c.keepalivePeriod)
 
+		case <-c.readerResponse:
+			// response to a request whose response is skipped (keepalive)
+
+		case req := <-c.readerRedirect:
+			err := c.doRedirect(req)
+			if err != nil {
+				if _, ok := err.(liberrors.ErrClientRedirectFailed); !ok {
+					return err
+				}
+
+				if c.OnRedirectError != nil {
+					c.OnRedirectError(err)
+				}
+			}
+
 		case err := <-c.readerErr:
 			c.readerErr = nil
 			return err
@@ -599,8 +829,30 @@ func (c *Client) runInner() error {
 	}
 }
 
+// requestStart sets the context of the request that is being processed.
+func (c *Client) requestStart(ctx context.Context) {
+	c.requestCtx = ctx
+}
+
+// requestEnd unsets the context of the request that has been processed.
+// If the request has been cancelled while waiting for a response,
+// the connection is in an unknown state, therefore it is closed
+// and the client returns to the initial state.
+func (c *Client) requestEnd() {
+	c.requestCtx = nil
+
+	if c.requestCancelled {
+		c.requestCancelled = false
+		c.reset()
+	}
+}
+
 func (c *Client) doClose() {
-	if c.state == clientStatePlay || c.state == clientStateRecord {
+	if c.noRTSPSession {
+		if c.state == clientStatePlay {
+			c.playRecordStop(true)
+		}
+	} else if c.state == clientStatePlay || c.state == clientStateRecord {
 		c.playRecordStop(true)
 
 		c.do(&base.Request{
@@ -619,6 +871,8 @@ func (c *Client) doClose() {
 	for _, track := range c.tracks {
 		if track.udpRTPListener != nil {
 			track.udpRTPListener.close()
+		}
+		if track.udpRTCPListener != nil {
 			track.udpRTCPListener.close()
 		}
 	}
@@ -630,13 +884,23 @@ func (c *Client) reset() {
 	c.state = clientStateInitial
 	c.session = ""
 	c.sender = nil
+	c.bearerToken = ""
 	c.cseq = 0
+	c.version = c.initialVersion()
 	c.optionsSent = false
 	c.useGetParameter = false
 	c.baseURL = nil
 	c.effectiveTransport = nil
 	c.tracks = nil
 	c.tcpTracksByChannel = nil
+	c.noRTSPSession = false
+}
+
+func (c *Client) initialVersion() base.Version {
+	if c.RTSP2Enable {
+		return base.Version20
+	}
+	return base.Version10
 }
 
 func (c *Client) checkState(allowed map[clientState]struct{}) error {
@@ -682,7 +946,7 @@ func (c *Client) trySwitchingProtocol() error {
 		}
 	}
 
-	_, err = c.doPlay(c.lastRange, true)
+	_, err = c.doPlay(c.lastRange, c.lastScale, c.lastSpeed)
 	if err != nil {
 		return err
 	}
@@ -692,7 +956,9 @@ func (c *Client) trySwitchingProtocol() error {
 
 func (c *Client) playRecordStart() {
 	// stop connCloser
-	c.connCloserStop()
+	if !c.noRTSPSession {
+		c.connCloserStop()
+	}
 
 	// start writer
 	if c.state == clientStatePlay {
@@ -707,6 +973,28 @@ func (c *Client) playRecordStart() {
 	c.writerDone = make(chan struct{})
 	go c.runWriter()
 
+	for trackID, ct := range c.tracks {
+		ctrackID := trackID
+
+		// statistics are collected with any transport,
+		// but reports are sent with UDP only.
+		var writeReport func(rtcp.Packet)
+		if *c.effectiveTransport != TransportTCP {
+			writeReport = func(pkt rtcp.Packet) {
+				c.WritePacketRTCP(ctrackID, pkt)
+			}
+		}
+
+		if c.state == clientStatePlay {
+			ct.rtcpReceiver = rtcpreceiver.New(c.receiverReportPeriod, &ct.localSSRC,
+				ct.track.ClockRate(), writeReport)
+		} else {
+			ct.rtcpSender = rtcpsender.New(c.senderReportPeriod,
+				ct.track.ClockRate(), writeReport)
+			ct.receiverReports = newReceiverReportStats(ct.track.ClockRate())
+		}
+	}
+
 	// allow writing
 	c.writeMutex.Lock()
 	c.writeFrameAllowed = true
@@ -718,17 +1006,25 @@ func (c *Client) playRecordStart() {
 			ct.cleaner = rtpcleaner.NewCleaner(isH264, *c.effectiveTransport == TransportTCP)
 		}
 
-		c.keepaliveTimer = time.NewTimer(c.keepalivePeriod)
+		if !c.noRTSPSession {
+			c.keepaliveTimer = time.NewTimer(c.keepalivePeriod)
+		}
 
 		switch *c.effectiveTransport {
 		case TransportUDP:
 			for trackID, ct := range c.tracks {
 				ctrackID := trackID
 				ct.udpRTPPacketBuffer = newRTPPacketMultiBuffer(uint64(c.ReadBufferCount))
-				ct.udpRTCPReceiver = rtcpreceiver.New(c.udpReceiverReportPeriod, nil,
-					ct.track.ClockRate(), func(pkt rtcp.Packet) {
-						c.WritePacketRTCP(ctrackID, pkt)
-					})
+
+				if rtxPayloadType, ok := ct.track.RTXPayloadType(); ok {
+					originalPayloadType, _ := trackRTXOriginalPayloadType(ct.track)
+					ct.udpRTXReceiver = newRTXReceiver(ct.localSSRC, rtxPayloadType, originalPayloadType,
+						func(pkt rtcp.Packet) {
+							c.WritePacketRTCP(ctrackID, pkt)
+						})
+				}
+
+				ct.udpFECDecoder = newClientFECDecoder(ct.track)
 			}
 
 			c.checkStreamTimer = time.NewTimer(c.InitialUDPReadTimeout)
@@ -736,24 +1032,30 @@ func (c *Client) playRecordStart() {
 
 			for _, ct := range c.tracks {
 				ct.udpRTPListener.start(true)
-				ct.udpRTCPListener.start(true)
+				if ct.udpRTCPListener != nil {
+					ct.udpRTCPListener.start(true)
+				}
 			}
 
 		case TransportUDPMulticast:
-			for trackID, ct := range c.tracks {
-				ctrackID := trackID
+			for _, ct := range c.tracks {
 				ct.udpRTPPacketBuffer = newRTPPacketMultiBuffer(uint64(c.ReadBufferCount))
-				ct.udpRTCPReceiver = rtcpreceiver.New(c.udpReceiverReportPeriod, nil,
-					ct.track.ClockRate(), func(pkt rtcp.Packet) {
-						c.WritePacketRTCP(ctrackID, pkt)
-					})
+				ct.udpFECDecoder = newClientFECDecoder(ct.track)
 			}
 
-			c.checkStreamTimer = time.NewTimer(c.checkStreamPeriod)
+			if c.noRTSPSession {
+				// the sender may start sending after the groups are joined
+				c.checkStreamTimer = time.NewTimer(c.InitialUDPReadTimeout)
+				c.checkStreamInitial = true
+			} else {
+				c.checkStreamTimer = time.NewTimer(c.checkStreamPeriod)
+			}
 
 			for _, ct := range c.tracks {
 				ct.udpRTPListener.start(true)
-				ct.udpRTCPListener.start(true)
+				if ct.udpRTCPListener != nil {
+					ct.udpRTCPListener.start(true)
+				}
 			}
 
 		default: // TCP
@@ -762,21 +1064,28 @@ func (c *Client) playRecordStart() {
 			c.tcpLastFrameTime = &v
 		}
 	} else if *c.effectiveTransport == TransportUDP {
-		for trackID, ct := range c.tracks {
-			ctrackID := trackID
-
-			ct.udpRTCPSender = rtcpsender.New(c.udpSenderReportPeriod,
-				ct.track.ClockRate(), func(pkt rtcp.Packet) {
-					c.WritePacketRTCP(ctrackID, pkt)
-				})
+		for _, ct := range c.tracks {
+			if rtxPayloadType, ok := ct.track.RTXPayloadType(); ok {
+				ct.udpRTXHistory = rtpretransmitter.New(c.RTXBufferCount)
+				ct.udpRTXEncoder = &rtprtx.Encoder{
+					PayloadType: rtxPayloadType,
+				}
+				ct.udpRTXEncoder.Init()
+			}
 		}
 
 		for _, ct := range c.tracks {
 			ct.udpRTPListener.start(false)
-			ct.udpRTCPListener.start(false)
+			if ct.udpRTCPListener != nil {
+				ct.udpRTCPListener.start(false)
+			}
 		}
 	}
 
+	if c.noRTSPSession {
+		return
+	}
+
 	// for some reason, SetReadDeadline() must always be called in the same
 	// goroutine, otherwise Read() freezes.
 	// therefore, we disable the deadline and perform a check with a ticker.
@@ -784,22 +1093,16 @@ func (c *Client) playRecordStart() {
 
 	// start reader
 	c.readerErr = make(chan error)
+	c.readerTerminate = make(chan struct{})
+	c.readerRedirect = make(chan *redirectReq, 1)
 	go c.runReader()
 }
 
 func (c *Client) runReader() {
 	c.readerErr <- func() error {
-		if *c.effectiveTransport == TransportUDP || *c.effectiveTransport == TransportUDPMulticast {
-			for {
-				var res base.Response
-				err := res.Read(c.br)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			var processFunc func(*clientTrack, bool, []byte) error
+		var processFunc func(*clientTrack, bool, []byte) error
 
+		if *c.effectiveTransport == TransportTCP {
 			if c.state == clientStatePlay {
 				tcpRTPPacketBuffer := newRTPPacketMultiBuffer(uint64(c.ReadBufferCount))
 
@@ -814,18 +1117,27 @@ func (c *Client) runReader() {
 							return err
 						}
 
+						atomic.StoreUint32(&track.remoteSSRC, pkt.SSRC)
+
 						out, err := track.cleaner.Clear(pkt)
 						if err != nil {
 							return err
 						}
 
+						track.rtcpReceiver.ProcessPacketRTP(now, pkt,
+							len(out) != 0 && out[0].PTSEqualsDTS)
+
+						ntp, ntpSynchronized := track.rtcpReceiver.PacketNTP(pkt.Timestamp)
+
 						for _, entry := range out {
 							c.OnPacketRTP(&ClientOnPacketRTPCtx{
-								TrackID:      track.id,
-								Packet:       entry.Packet,
-								PTSEqualsDTS: entry.PTSEqualsDTS,
-								H264NALUs:    entry.H264NALUs,
-								H264PTS:      entry.H264PTS,
+								TrackID:         track.id,
+								Packet:          entry.Packet,
+								PTSEqualsDTS:    entry.PTSEqualsDTS,
+								H264NALUs:       entry.H264NALUs,
+								H264PTS:         entry.H264PTS,
+								NTP:             ntp,
+								NTPSynchronized: ntpSynchronized,
 							})
 						}
 					} else {
@@ -840,6 +1152,7 @@ func (c *Client) runReader() {
 						}
 
 						for _, pkt := range packets {
+							track.rtcpReceiver.ProcessPacketRTCP(now, pkt)
 							c.OnPacketRTCP(&ClientOnPacketRTCPCtx{
 								TrackID: track.id,
 								Packet:  pkt,
@@ -862,7 +1175,10 @@ func (c *Client) runReader() {
 							return err
 						}
 
+						now := time.Now()
+
 						for _, pkt := range packets {
+							track.receiverReports.processPacketRTCP(now, pkt)
 							c.OnPacketRTCP(&ClientOnPacketRTCPCtx{
 								TrackID: track.id,
 								Packet:  pkt,
@@ -874,32 +1190,88 @@ func (c *Client) runReader() {
 				}
 			}
 
-			var frame base.InterleavedFrame
-			var res base.Response
+		}
 
-			for {
-				what, err := base.ReadInterleavedFrameOrResponse(&frame, tcpMaxFramePayloadSize, &res, c.br)
-				if err != nil {
-					return err
+		var frame base.InterleavedFrame
+		var req base.Request
+		var res base.Response
+
+		for {
+			what, err := base.ReadInterleavedFrameOrRequestOrResponse(&frame, tcpMaxFramePayloadSize,
+				&req, &res, c.br)
+			if err != nil {
+				return err
+			}
+
+			switch what.(type) {
+			case *base.InterleavedFrame:
+				if processFunc == nil {
+					continue
+				}
+
+				channel := frame.Channel
+				isRTP := true
+				if (channel % 2) != 0 {
+					channel--
+					isRTP = false
 				}
 
-				if _, ok := what.(*base.InterleavedFrame); ok {
-					channel := frame.Channel
-					isRTP := true
-					if (channel % 2) != 0 {
-						channel--
-						isRTP = false
+				track, ok := c.tcpTracksByChannel[channel]
+				if !ok {
+					continue
+				}
+
+				payload := frame.Payload
+				if track.srtpContext != nil {
+					payload, err = srtpDecrypt(track.srtpContext, isRTP, payload)
+					if err != nil {
+						continue
 					}
+				}
+
+				err := processFunc(track, isRTP, payload)
+				if err != nil {
+					return err
+				}
 
-					track, ok := c.tcpTracksByChannel[channel]
-					if !ok {
+			case *base.Request:
+				if req.Method == base.Redirect && c.state == clientStatePlay && !c.RedirectDisable {
+					rr, err := readRedirectReq(&req)
+					if err != nil {
+						err = c.writeServerResponse(&req, &base.Response{
+							StatusCode: base.StatusBadRequest,
+						})
+						if err != nil {
+							return err
+						}
 						continue
 					}
 
-					err := processFunc(track, isRTP, frame.Payload)
+					err = c.writeServerResponse(&req, &base.Response{
+						StatusCode: base.StatusOK,
+					})
 					if err != nil {
 						return err
 					}
+
+					// the session is going to be torn down, stop reading
+					c.readerRedirect <- rr
+					<-c.readerTerminate
+					return fmt.Errorf("terminated")
+				}
+
+				err := c.handleServerRequest(&req)
+				if err != nil {
+					return err
+				}
+
+			case *base.Response:
+				// route the response to the routine that sent the request
+				cres := res
+				select {
+				case c.readerResponse <- &cres:
+				case <-c.readerTerminate:
+					return fmt.Errorf("terminated")
 				}
 			}
 		}
@@ -909,8 +1281,10 @@ func (c *Client) runReader() {
 func (c *Client) playRecordStop(isClosing bool) {
 	// stop reader
 	if c.readerErr != nil {
+		close(c.readerTerminate)
 		c.conn.SetReadDeadline(time.Now())
 		<-c.readerErr
+		c.readerErr = nil
 	}
 
 	// forbid writing
@@ -922,23 +1296,38 @@ func (c *Client) playRecordStop(isClosing bool) {
 		*c.effectiveTransport == TransportUDPMulticast {
 		for _, ct := range c.tracks {
 			ct.udpRTPListener.stop()
-			ct.udpRTCPListener.stop()
+			if ct.udpRTCPListener != nil {
+				ct.udpRTCPListener.stop()
+			}
 		}
 
 		if c.state == clientStatePlay {
 			for _, ct := range c.tracks {
 				ct.udpRTPPacketBuffer = nil
-				ct.udpRTCPReceiver.Close()
-				ct.udpRTCPReceiver = nil
+				ct.udpRTXReceiver = nil
+				ct.udpFECDecoder = nil
 			}
 		} else {
 			for _, ct := range c.tracks {
-				ct.udpRTCPSender.Close()
-				ct.udpRTCPSender = nil
+				ct.udpRTXHistory = nil
+				ct.udpRTXEncoder = nil
 			}
 		}
 	}
 
+	if c.state == clientStatePlay {
+		for _, ct := range c.tracks {
+			ct.rtcpReceiver.Close()
+			ct.rtcpReceiver = nil
+		}
+	} else {
+		for _, ct := range c.tracks {
+			ct.rtcpSender.Close()
+			ct.rtcpSender = nil
+			ct.receiverReports = nil
+		}
+	}
+
 	for _, ct := range c.tracks {
 		ct.cleaner = nil
 	}
@@ -954,7 +1343,7 @@ func (c *Client) playRecordStop(isClosing bool) {
 	c.writeBuffer = nil
 
 	// start connCloser
-	if !isClosing {
+	if !isClosing && !c.noRTSPSession {
 		c.connCloserStart()
 	}
 }
@@ -975,8 +1364,25 @@ func (c *Client) connOpen() error {
 	ctx, cancel := context.WithTimeout(c.ctx, c.ReadTimeout)
 	defer cancel()
 
+	if c.requestCtx != nil {
+		requestCtx := c.requestCtx
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			select {
+			case <-requestCtx.Done():
+				cancel()
+			case <-done:
+			}
+		}()
+	}
+
 	nconn, err := c.DialContext(ctx, "tcp", c.host)
 	if err != nil {
+		if c.requestCtx != nil && c.requestCtx.Err() != nil {
+			return c.requestCtx.Err()
+		}
 		return err
 	}
 
@@ -1005,14 +1411,19 @@ func (c *Client) connCloserStart() {
 	c.connCloserTerminate = make(chan struct{})
 	c.connCloserDone = make(chan struct{})
 
+	// fields are copied since they are replaced during redirects
+	conn := c.conn
+	terminate := c.connCloserTerminate
+	done := c.connCloserDone
+
 	go func() {
-		defer close(c.connCloserDone)
+		defer close(done)
 
 		select {
 		case <-c.ctx.Done():
-			c.conn.Close()
+			conn.Close()
 
-		case <-c.connCloserTerminate:
+		case <-terminate:
 		}
 	}()
 }
@@ -1024,6 +1435,17 @@ func (c *Client) connCloserStop() {
 }
 
 func (c *Client) do(req *base.Request, skipResponse bool, allowFrames bool) (*base.Response, error) {
+	return c.doWithRetry(req, skipResponse, allowFrames, true)
+}
+
+// doWithRetry sends a request and reads a response.
+// If canRetry is true, the request can be sent again once with new credentials.
+func (c *Client) doWithRetry(
+	req *base.Request,
+	skipResponse bool,
+	allowFrames bool,
+	canRetry bool,
+) (*base.Response, error) {
 	if c.conn == nil {
 		err := c.connOpen()
 		if err != nil {
@@ -1046,11 +1468,21 @@ func (c *Client) do(req *base.Request, skipResponse bool, allowFrames bool) (*ba
 		req.Header["Session"] = base.HeaderValue{c.session}
 	}
 
+	req.Version = c.version
+
 	c.cseq++
-	req.Header["CSeq"] = base.HeaderValue{strconv.FormatInt(int64(c.cseq), 10)}
+	cseq := strconv.FormatInt(int64(c.cseq), 10)
+	req.Header["CSeq"] = base.HeaderValue{cseq}
 
 	req.Header["User-Agent"] = base.HeaderValue{c.UserAgent}
 
+	if c.BearerTokenProvider != nil && c.sender == nil {
+		err := c.refreshBearerToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if c.sender != nil {
 		c.sender.AddAuthorization(req)
 	}
@@ -1061,93 +1493,472 @@ func (c *Client) do(req *base.Request, skipResponse bool, allowFrames bool) (*ba
 
 	byts, _ := req.Write()
 
+	// when the connection is not being read by the reader,
+	// interrupt writing and reading when the request context is done.
+	if c.requestCtx != nil && c.readerErr == nil {
+		stop := c.watchRequestContext()
+		defer stop()
+	}
+
 	c.conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout))
 	_, err := c.conn.Write(byts)
 	if err != nil {
-		return nil, err
+		return nil, c.requestError(err)
 	}
 
-	var res base.Response
+	if skipResponse {
+		return &base.Response{}, nil
+	}
+
+	var res *base.Response
 
-	if !skipResponse {
+	if c.readerErr != nil {
+		// the connection is being read by the reader, that routes responses to us
+		res, err = c.waitResponse(cseq)
+	} else {
 		c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+		res, err = c.readResponse(allowFrames)
+		if err != nil {
+			err = c.requestError(err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		if allowFrames {
-			// read the response and ignore interleaved frames in between;
-			// interleaved frames are sent in two cases:
-			// * when the server is v4lrtspserver, before the PLAY response
-			// * when the stream is already playing
-			err = res.ReadIgnoreFrames(tcpMaxFramePayloadSize, c.br)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			err = res.Read(c.br)
-			if err != nil {
-				return nil, err
-			}
+	if c.OnResponse != nil {
+		c.OnResponse(res)
+	}
+
+	// get session from response
+	if v, ok := res.Header["Session"]; ok {
+		var sx headers.Session
+		err := sx.Read(v)
+		if err != nil {
+			return nil, liberrors.ErrClientSessionHeaderInvalid{Err: err}
 		}
+		c.session = sx.Session
 
-		if c.OnResponse != nil {
-			c.OnResponse(&res)
+		if sx.Timeout != nil && *sx.Timeout > 0 {
+			c.keepalivePeriod = time.Duration(float64(*sx.Timeout)*0.8) * time.Second
 		}
+	}
 
-		// get session from response
-		if v, ok := res.Header["Session"]; ok {
-			var sx headers.Session
-			err := sx.Read(v)
-			if err != nil {
-				return nil, liberrors.ErrClientSessionHeaderInvalid{Err: err}
-			}
-			c.session = sx.Session
+	// if the token has been rejected, send request again with a new token.
+	if res.StatusCode == base.StatusUnauthorized && c.BearerTokenProvider != nil {
+		if !canRetry {
+			return res, nil
+		}
 
-			if sx.Timeout != nil && *sx.Timeout > 0 {
-				c.keepalivePeriod = time.Duration(float64(*sx.Timeout)*0.8) * time.Second
-			}
+		prevToken := c.bearerToken
+
+		err := c.refreshBearerToken()
+		if err != nil {
+			return nil, err
 		}
 
-		// if required, send request again with authentication
-		if res.StatusCode == base.StatusUnauthorized && req.URL.User != nil && c.sender == nil {
-			pass, _ := req.URL.User.Password()
-			user := req.URL.User.Username()
+		if c.bearerToken != prevToken {
+			return c.doWithRetry(req, skipResponse, allowFrames, false)
+		}
 
-			sender, err := auth.NewSender(res.Header["WWW-Authenticate"], user, pass)
-			if err != nil {
-				return nil, fmt.Errorf("unable to setup authentication: %s", err)
-			}
-			c.sender = sender
+		return res, nil
+	}
 
-			return c.do(req, skipResponse, allowFrames)
+	// if required, send request again with authentication.
+	// if the nonce has expired, authenticate again with the new one.
+	if res.StatusCode == base.StatusUnauthorized && req.URL.User != nil && canRetry &&
+		(c.sender == nil || c.sender.IsStale(res.Header["WWW-Authenticate"])) {
+		pass, _ := req.URL.User.Password()
+		user := req.URL.User.Username()
+
+		sender, err := auth.NewSender(res.Header["WWW-Authenticate"], user, pass)
+		if err != nil {
+			return nil, fmt.Errorf("unable to setup authentication: %s", err)
 		}
+		c.sender = sender
+
+		return c.doWithRetry(req, skipResponse, allowFrames, false)
 	}
 
-	return &res, nil
+	return res, nil
 }
 
-func (c *Client) doOptions(u *url.URL) (*base.Response, error) {
-	err := c.checkState(map[clientState]struct{}{
-		clientStateInitial:   {},
-		clientStatePrePlay:   {},
-		clientStatePreRecord: {},
-	})
+func (c *Client) refreshBearerToken() error {
+	token, err := c.BearerTokenProvider()
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("unable to get bearer token: %s", err)
 	}
 
-	res, err := c.do(&base.Request{
-		Method: base.Options,
-		URL:    u,
-	}, false, false)
-	if err != nil {
-		return nil, err
-	}
+	c.bearerToken = token
+	c.sender = auth.NewSenderBearer(token)
+	return nil
+}
 
-	if res.StatusCode != base.StatusOK {
-		// since this method is not implemented by every RTSP server,
-		// return only if status code is not 404
-		if res.StatusCode == base.StatusNotFound {
-			return res, nil
-		}
+// watchRequestContext sets the deadlines of the connection to now
+// when the request context is done, in order to interrupt pending reads and writes.
+func (c *Client) watchRequestContext() func() {
+	ctx := c.requestCtx
+	conn := c.conn
+	terminate := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+
+		case <-terminate:
+		}
+	}()
+
+	return func() {
+		close(terminate)
+		<-done
+	}
+}
+
+// requestError replaces a read or write error with the error of the request context,
+// if the error has been caused by the cancellation of the request.
+func (c *Client) requestError(err error) error {
+	if c.requestCtx != nil && c.requestCtx.Err() != nil {
+		c.requestCancelled = true
+		return c.requestCtx.Err()
+	}
+	return err
+}
+
+func (c *Client) readResponse(allowFrames bool) (*base.Response, error) {
+	var frame base.InterleavedFrame
+	var req base.Request
+	var res base.Response
+
+	for {
+		what, err := base.ReadInterleavedFrameOrRequestOrResponse(&frame, tcpMaxFramePayloadSize,
+			&req, &res, c.br)
+		if err != nil {
+			return nil, err
+		}
+
+		switch what.(type) {
+		case *base.InterleavedFrame:
+			// interleaved frames are sent in two cases:
+			// * when the server is v4lrtspserver, before the PLAY response
+			// * when the stream is already playing
+			if !allowFrames {
+				return nil, liberrors.ErrClientUnexpectedFrame{}
+			}
+
+		case *base.Request:
+			err := c.handleServerRequest(&req)
+			if err != nil {
+				return nil, err
+			}
+
+		case *base.Response:
+			return &res, nil
+		}
+	}
+}
+
+func (c *Client) waitResponse(cseq string) (*base.Response, error) {
+	t := time.NewTimer(c.ReadTimeout)
+	defer t.Stop()
+
+	var requestDone <-chan struct{}
+	if c.requestCtx != nil {
+		requestDone = c.requestCtx.Done()
+	}
+
+	for {
+		select {
+		case res := <-c.readerResponse:
+			// discard responses to requests whose response is skipped (keepalive)
+			if v, ok := res.Header["CSeq"]; ok && (len(v) != 1 || v[0] != cseq) {
+				continue
+			}
+			return res, nil
+
+		case <-t.C:
+			return nil, liberrors.ErrClientResponseTimeout{}
+
+		case <-requestDone:
+			// the response is discarded when it arrives, since its CSeq doesn't match
+			return nil, c.requestCtx.Err()
+
+		case <-c.ctx.Done():
+			return nil, liberrors.ErrClientTerminated{}
+		}
+	}
+}
+
+// handleServerRequest handles a request sent by the server to the client.
+// It can be called by the client routine or by the reader.
+func (c *Client) handleServerRequest(req *base.Request) error {
+	return c.writeServerResponse(req, c.OnServerRequest(req))
+}
+
+func (c *Client) writeServerResponse(req *base.Request, res *base.Response) error {
+	if res.Header == nil {
+		res.Header = make(base.Header)
+	}
+
+	if cseq, ok := req.Header["CSeq"]; ok {
+		res.Header["CSeq"] = cseq
+	}
+
+	res.Header["User-Agent"] = base.HeaderValue{c.UserAgent}
+
+	res.Version = req.Version
+
+	byts, _ := res.Write()
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout))
+	_, err := c.conn.Write(byts)
+	return err
+}
+
+func readRedirectReq(req *base.Request) (*redirectReq, error) {
+	v, ok := req.Header["Location"]
+	if !ok || len(v) != 1 {
+		return nil, fmt.Errorf("Location header is missing")
+	}
+
+	u, err := url.Parse(v[0])
+	if err != nil {
+		return nil, err
+	}
+
+	rr := &redirectReq{
+		url: u,
+	}
+
+	if v, ok := req.Header["Range"]; ok {
+		rr.ra = &headers.Range{}
+		err := rr.ra.Read(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rr, nil
+}
+
+// clientSessionState is the state of a connection and of its session.
+// It is saved during redirects, in order to keep the previous session
+// until the new one has been set up.
+type clientSessionState struct {
+	scheme              string
+	host                string
+	state               clientState
+	conn                net.Conn
+	br                  *bufio.Reader
+	session             string
+	sender              *auth.Sender
+	bearerToken         string
+	cseq                int
+	version             base.Version
+	optionsSent         bool
+	useGetParameter     bool
+	lastDescribeURL     *url.URL
+	baseURL             *url.URL
+	effectiveTransport  *Transport
+	tracks              []*clientTrack
+	tcpTracksByChannel  map[int]*clientTrack
+	connCloserTerminate chan struct{}
+	connCloserDone      chan struct{}
+}
+
+// swapSessionState replaces the state of the connection and of the session,
+// and returns the replaced one.
+func (c *Client) swapSessionState(s *clientSessionState) *clientSessionState {
+	prev := &clientSessionState{
+		scheme:              c.scheme,
+		host:                c.host,
+		state:               c.state,
+		conn:                c.conn,
+		br:                  c.br,
+		session:             c.session,
+		sender:              c.sender,
+		bearerToken:         c.bearerToken,
+		cseq:                c.cseq,
+		version:             c.version,
+		optionsSent:         c.optionsSent,
+		useGetParameter:     c.useGetParameter,
+		lastDescribeURL:     c.lastDescribeURL,
+		baseURL:             c.baseURL,
+		effectiveTransport:  c.effectiveTransport,
+		tracks:              c.tracks,
+		tcpTracksByChannel:  c.tcpTracksByChannel,
+		connCloserTerminate: c.connCloserTerminate,
+		connCloserDone:      c.connCloserDone,
+	}
+
+	c.scheme = s.scheme
+	c.host = s.host
+	c.state = s.state
+	c.conn = s.conn
+	c.br = s.br
+	c.session = s.session
+	c.sender = s.sender
+	c.bearerToken = s.bearerToken
+	c.cseq = s.cseq
+	c.version = s.version
+	c.optionsSent = s.optionsSent
+	c.useGetParameter = s.useGetParameter
+	c.lastDescribeURL = s.lastDescribeURL
+	c.baseURL = s.baseURL
+	c.effectiveTransport = s.effectiveTransport
+	c.tracks = s.tracks
+	c.tcpTracksByChannel = s.tcpTracksByChannel
+	c.connCloserTerminate = s.connCloserTerminate
+	c.connCloserDone = s.connCloserDone
+
+	return prev
+}
+
+// doRedirect establishes the session again on the URL contained in a REDIRECT request,
+// by setupping the same tracks with the same ports and transport.
+// The previous session is torn down after the new one has been set up,
+// and it is resumed if the new one can't be set up.
+func (c *Client) doRedirect(rr *redirectReq) error {
+	ra := rr.ra
+	if ra == nil {
+		ra = c.lastRange
+	}
+
+	if rr.url.User == nil && c.lastDescribeURL != nil {
+		rr.url.User = c.lastDescribeURL.User
+	}
+
+	c.playRecordStop(false)
+	c.state = clientStatePrePlay
+
+	prev := c.swapSessionState(&clientSessionState{
+		scheme:             rr.url.Scheme,
+		host:               rr.url.Host,
+		state:              clientStateInitial,
+		version:            c.initialVersion(),
+		effectiveTransport: c.effectiveTransport,
+	})
+
+	// ports chosen by the user are moved to the new session
+	for _, ct := range prev.tracks {
+		if ct.rtpPort != 0 && ct.udpRTPListener != nil {
+			ct.udpRTPListener.close()
+			if ct.udpRTCPListener != nil {
+				ct.udpRTCPListener.close()
+			}
+		}
+	}
+
+	err := c.redirectSetup(rr.url, prev.tracks)
+	if err != nil {
+		c.reset()
+		c.swapSessionState(prev)
+
+		rerr := c.redirectResume()
+		if rerr != nil {
+			return err
+		}
+		return liberrors.ErrClientRedirectFailed{Err: err}
+	}
+
+	// tear down the previous session
+	cur := c.swapSessionState(prev)
+	c.connCloserStop()
+	c.do(&base.Request{
+		Method: base.Teardown,
+		URL:    c.baseURL,
+	}, true, false)
+	c.conn.Close()
+	for _, ct := range c.tracks {
+		if ct.rtpPort == 0 && ct.udpRTPListener != nil {
+			ct.udpRTPListener.close()
+			if ct.udpRTCPListener != nil {
+				ct.udpRTCPListener.close()
+			}
+		}
+	}
+	c.swapSessionState(cur)
+
+	_, err = c.doPlay(ra, c.lastScale, c.lastSpeed)
+	return err
+}
+
+func (c *Client) redirectSetup(u *url.URL, prevTracks []*clientTrack) error {
+	_, baseURL, _, err := c.doDescribe(u)
+	if err != nil {
+		return err
+	}
+
+	for _, ct := range prevTracks {
+		_, err := c.doSetup(true, ct.track, baseURL, ct.rtpPort, ct.rtcpPort)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// redirectResume resumes the previous session after a failed redirect.
+func (c *Client) redirectResume() error {
+	for _, ct := range c.tracks {
+		if ct.rtpPort != 0 && ct.udpRTPListener != nil {
+			err := ct.udpRTPListener.reopen(ct.rtpPort)
+			if err != nil {
+				return err
+			}
+
+			if ct.udpRTCPListener != nil {
+				err := ct.udpRTCPListener.reopen(ct.rtcpPort)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	_, err := c.doPlay(c.lastRange, c.lastScale, c.lastSpeed)
+	return err
+}
+
+func (c *Client) doOptions(u *url.URL) (*base.Response, error) {
+	err := c.checkState(map[clientState]struct{}{
+		clientStateInitial:   {},
+		clientStatePrePlay:   {},
+		clientStatePreRecord: {},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.do(&base.Request{
+		Method: base.Options,
+		URL:    u,
+	}, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// fallback to RTSP 1.0 if RTSP 2.0 is not supported by the server
+	if c.version == base.Version20 &&
+		(res.Version != base.Version20 || res.StatusCode == base.StatusRTSPVersionNotSupported) {
+		c.version = base.Version10
+
+		if res.StatusCode != base.StatusOK {
+			return c.doOptions(u)
+		}
+	}
+
+	if res.StatusCode != base.StatusOK {
+		// since this method is not implemented by every RTSP server,
+		// return only if status code is not 404
+		if res.StatusCode == base.StatusNotFound {
+			return res, nil
+		}
 		return nil, liberrors.ErrClientBadStatusCode{Code: res.StatusCode, Message: res.StatusMessage}
 	}
 
@@ -1172,12 +1983,20 @@ func (c *Client) doOptions(u *url.URL) (*base.Response, error) {
 
 // Options writes an OPTIONS request and reads a response.
 func (c *Client) Options(u *url.URL) (*base.Response, error) {
+	return c.OptionsContext(context.Background(), u)
+}
+
+// OptionsContext is like Options, but the request is cancelled when ctx is done.
+func (c *Client) OptionsContext(ctx context.Context, u *url.URL) (*base.Response, error) {
 	cres := make(chan clientRes)
 	select {
-	case c.options <- optionsReq{url: u, res: cres}:
+	case c.options <- optionsReq{ctx: ctx, url: u, res: cres}:
 		res := <-cres
 		return res.res, res.err
 
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
 	case <-c.ctx.Done():
 		return nil, liberrors.ErrClientTerminated{}
 	}
@@ -1259,12 +2078,20 @@ func (c *Client) doDescribe(u *url.URL) (Tracks, *url.URL, *base.Response, error
 
 // Describe writes a DESCRIBE request and reads a Response.
 func (c *Client) Describe(u *url.URL) (Tracks, *url.URL, *base.Response, error) {
+	return c.DescribeContext(context.Background(), u)
+}
+
+// DescribeContext is like Describe, but the request is cancelled when ctx is done.
+func (c *Client) DescribeContext(ctx context.Context, u *url.URL) (Tracks, *url.URL, *base.Response, error) {
 	cres := make(chan clientRes)
 	select {
-	case c.describe <- describeReq{url: u, res: cres}:
+	case c.describe <- describeReq{ctx: ctx, url: u, res: cres}:
 		res := <-cres
 		return res.tracks, res.baseURL, res.res, res.err
 
+	case <-ctx.Done():
+		return nil, nil, nil, ctx.Err()
+
 	case <-c.ctx.Done():
 		return nil, nil, nil, liberrors.ErrClientTerminated{}
 	}
@@ -1306,12 +2133,20 @@ func (c *Client) doAnnounce(u *url.URL, tracks Tracks) (*base.Response, error) {
 
 // Announce writes an ANNOUNCE request and reads a Response.
 func (c *Client) Announce(u *url.URL, tracks Tracks) (*base.Response, error) {
+	return c.AnnounceContext(context.Background(), u, tracks)
+}
+
+// AnnounceContext is like Announce, but the request is cancelled when ctx is done.
+func (c *Client) AnnounceContext(ctx context.Context, u *url.URL, tracks Tracks) (*base.Response, error) {
 	cres := make(chan clientRes)
 	select {
-	case c.announce <- announceReq{url: u, tracks: tracks, res: cres}:
+	case c.announce <- announceReq{ctx: ctx, url: u, tracks: tracks, res: cres}:
 		res := <-cres
 		return res.res, res.err
 
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
 	case <-c.ctx.Done():
 		return nil, liberrors.ErrClientTerminated{}
 	}
@@ -1373,10 +2208,23 @@ func (c *Client) doSetup(
 		Mode: &mode,
 	}
 
+	srtpContext, err := newSRTPContext(track)
+	if err != nil {
+		return nil, err
+	}
+
+	if srtpContext != nil {
+		th.Profile = headers.TransportProfileSAVP
+	}
+
 	trackID := len(c.tracks)
 
 	ct := &clientTrack{
-		track: track,
+		track:       track,
+		localSSRC:   randUint32(),
+		srtpContext: srtpContext,
+		rtpPort:     rtpPort,
+		rtcpPort:    rtcpPort,
 	}
 
 	switch transport {
@@ -1394,7 +2242,6 @@ func (c *Client) doSetup(
 			var err error
 			ct.udpRTPListener, err = newClientUDPListener(
 				c,
-				false,
 				":"+strconv.FormatInt(int64(rtpPort), 10),
 				ct,
 				true)
@@ -1404,7 +2251,6 @@ func (c *Client) doSetup(
 
 			ct.udpRTCPListener, err = newClientUDPListener(
 				c,
-				false,
 				":"+strconv.FormatInt(int64(rtcpPort), 10),
 				ct,
 				true)
@@ -1419,9 +2265,21 @@ func (c *Client) doSetup(
 		v1 := headers.TransportDeliveryUnicast
 		th.Delivery = &v1
 		th.Protocol = headers.TransportProtocolUDP
-		th.ClientPorts = &[2]int{
-			ct.udpRTPListener.port(),
-			ct.udpRTCPListener.port(),
+
+		// request RTP/RTCP multiplexing when the track supports it.
+		// both ports are provided anyway, in case the server doesn't support it.
+		th.RTCPMux = track.RTCPMux()
+
+		if c.version == base.Version20 {
+			th.DestAddrs = &[2]*net.UDPAddr{
+				{Port: ct.udpRTPListener.port()},
+				{Port: ct.udpRTCPListener.port()},
+			}
+		} else {
+			th.ClientPorts = &[2]int{
+				ct.udpRTPListener.port(),
+				ct.udpRTCPListener.port(),
+			}
 		}
 
 	case TransportUDPMulticast:
@@ -1489,6 +2347,16 @@ func (c *Client) doSetup(
 		return nil, liberrors.ErrClientTransportHeaderInvalid{Err: err}
 	}
 
+	// RTSP 2.0: ports are provided with the source and destination addresses
+	if thRes.ServerPorts == nil && thRes.SrcAddrs != nil {
+		thRes.ServerPorts = &[2]int{thRes.SrcAddrs[0].Port, thRes.SrcAddrs[1].Port}
+	}
+	if transport == TransportUDPMulticast && thRes.Destination == nil && thRes.Ports == nil &&
+		thRes.DestAddrs != nil && thRes.DestAddrs[0].IP != nil {
+		thRes.Destination = &thRes.DestAddrs[0].IP
+		thRes.Ports = &[2]int{thRes.DestAddrs[0].Port, thRes.DestAddrs[1].Port}
+	}
+
 	switch transport {
 	case TransportUDP:
 		if thRes.Delivery != nil && *thRes.Delivery != headers.TransportDeliveryUnicast {
@@ -1533,6 +2401,13 @@ func (c *Client) doSetup(
 			}
 		}
 
+		// the server accepted RTP/RTCP multiplexing: the RTCP port is not needed anymore.
+		if th.RTCPMux && thRes.RTCPMux {
+			ct.udpRTCPListener.close()
+			ct.udpRTCPListener = nil
+			ct.udpRTPListener.rtcpMux = true
+		}
+
 	case TransportUDPMulticast:
 		if thRes.Delivery == nil || *thRes.Delivery != headers.TransportDeliveryMulticast {
 			return nil, liberrors.ErrClientTransportHeaderInvalidDelivery{}
@@ -1546,20 +2421,38 @@ func (c *Client) doSetup(
 			return nil, liberrors.ErrClientTransportHeaderNoDestination{}
 		}
 
-		ct.udpRTPListener, err = newClientUDPListener(
+		// with source-specific multicast, join the group with a source filter
+		// and accept packets from the advertised source only.
+		var source net.IP
+		readIP := c.conn.RemoteAddr().(*net.TCPAddr).IP
+		if thRes.Source != nil {
+			source = *thRes.Source
+			readIP = source
+		}
+
+		ttl := multicastTTL
+		if thRes.TTL != nil {
+			ttl = int(*thRes.TTL)
+		}
+
+		ct.udpRTPListener, err = newClientUDPListenerMulticast(
 			c,
-			true,
-			thRes.Destination.String()+":"+strconv.FormatInt(int64(thRes.Ports[0]), 10),
+			*thRes.Destination,
+			thRes.Ports[0],
+			source,
+			ttl,
 			ct,
 			true)
 		if err != nil {
 			return nil, err
 		}
 
-		ct.udpRTCPListener, err = newClientUDPListener(
+		ct.udpRTCPListener, err = newClientUDPListenerMulticast(
 			c,
-			true,
-			thRes.Destination.String()+":"+strconv.FormatInt(int64(thRes.Ports[1]), 10),
+			*thRes.Destination,
+			thRes.Ports[1],
+			source,
+			ttl,
 			ct,
 			false)
 		if err != nil {
@@ -1567,14 +2460,14 @@ func (c *Client) doSetup(
 			return nil, err
 		}
 
-		ct.udpRTPListener.readIP = c.conn.RemoteAddr().(*net.TCPAddr).IP
+		ct.udpRTPListener.readIP = readIP
 		ct.udpRTPListener.readPort = thRes.Ports[0]
 		ct.udpRTPListener.writeAddr = &net.UDPAddr{
 			IP:   *thRes.Destination,
 			Port: thRes.Ports[0],
 		}
 
-		ct.udpRTCPListener.readIP = c.conn.RemoteAddr().(*net.TCPAddr).IP
+		ct.udpRTCPListener.readIP = readIP
 		ct.udpRTCPListener.readPort = thRes.Ports[1]
 		ct.udpRTCPListener.writeAddr = &net.UDPAddr{
 			IP:   *thRes.Destination,
@@ -1609,6 +2502,10 @@ func (c *Client) doSetup(
 		ct.tcpChannel = thRes.InterleavedIDs[0]
 	}
 
+	if thRes.SSRC != nil {
+		ct.remoteSSRC = *thRes.SSRC
+	}
+
 	c.tracks = append(c.tracks, ct)
 	ct.id = trackID
 
@@ -1633,10 +2530,23 @@ func (c *Client) Setup(
 	baseURL *url.URL,
 	rtpPort int,
 	rtcpPort int,
+) (*base.Response, error) {
+	return c.SetupContext(context.Background(), forPlay, track, baseURL, rtpPort, rtcpPort)
+}
+
+// SetupContext is like Setup, but the request is cancelled when ctx is done.
+func (c *Client) SetupContext(
+	ctx context.Context,
+	forPlay bool,
+	track Track,
+	baseURL *url.URL,
+	rtpPort int,
+	rtcpPort int,
 ) (*base.Response, error) {
 	cres := make(chan clientRes)
 	select {
 	case c.setup <- setupReq{
+		ctx:      ctx,
 		forPlay:  forPlay,
 		track:    track,
 		baseURL:  baseURL,
@@ -1647,12 +2557,15 @@ func (c *Client) Setup(
 		res := <-cres
 		return res.res, res.err
 
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
 	case <-c.ctx.Done():
 		return nil, liberrors.ErrClientTerminated{}
 	}
 }
 
-func (c *Client) doPlay(ra *headers.Range, isSwitchingProtocol bool) (*base.Response, error) {
+func (c *Client) doPlay(ra *headers.Range, scale *headers.Scale, speed *headers.Speed) (*base.Response, error) {
 	err := c.checkState(map[clientState]struct{}{
 		clientStatePrePlay: {},
 	})
@@ -1667,8 +2580,10 @@ func (c *Client) doPlay(ra *headers.Range, isSwitchingProtocol bool) (*base.Resp
 			byts, _ := (&rtp.Packet{Header: rtp.Header{Version: 2}}).Marshal()
 			ct.udpRTPListener.write(byts)
 
-			byts, _ = (&rtcp.ReceiverReport{}).Marshal()
-			ct.udpRTCPListener.write(byts)
+			if ct.udpRTCPListener != nil {
+				byts, _ = (&rtcp.ReceiverReport{}).Marshal()
+				ct.udpRTCPListener.write(byts)
+			}
 		}
 	}
 
@@ -1681,12 +2596,22 @@ func (c *Client) doPlay(ra *headers.Range, isSwitchingProtocol bool) (*base.Resp
 		}
 	}
 
+	header := base.Header{
+		"Range": ra.Write(),
+	}
+
+	if scale != nil {
+		header["Scale"] = scale.Write()
+	}
+
+	if speed != nil {
+		header["Speed"] = speed.Write()
+	}
+
 	res, err := c.do(&base.Request{
 		Method: base.Play,
 		URL:    c.baseURL,
-		Header: base.Header{
-			"Range": ra.Write(),
-		},
+		Header: header,
 	}, false, *c.effectiveTransport == TransportTCP)
 	if err != nil {
 		return nil, err
@@ -1698,22 +2623,110 @@ func (c *Client) doPlay(ra *headers.Range, isSwitchingProtocol bool) (*base.Resp
 		}
 	}
 
+	// the server may grant rates that are different from the requested ones.
+	// invalid granted rates are ignored, since the server is already playing.
+	if v, ok := res.Header["Scale"]; ok {
+		var granted headers.Scale
+		err := granted.Read(v)
+		if err == nil {
+			scale = &granted
+		}
+	}
+
+	if v, ok := res.Header["Speed"]; ok {
+		var granted headers.Speed
+		err := granted.Read(v)
+		if err == nil {
+			speed = &granted
+		}
+	}
+
+	// the server may send the rollover counters of tracks protected with SRTP,
+	// that are needed to decrypt a stream in progress.
+	if v, ok := res.Header["KeyMgmt"]; ok {
+		var km headers.KeyMgmt
+		err := km.Read(v)
+		if err == nil {
+			c.applyKeyMgmt(km)
+		}
+	}
+
 	c.lastRange = ra
+	c.lastScale = scale
+	c.lastSpeed = speed
 	c.state = clientStatePlay
 	c.playRecordStart()
 
 	return res, nil
 }
 
+// applyKeyMgmt sets the rollover counters contained in a KeyMgmt header.
+func (c *Client) applyKeyMgmt(km headers.KeyMgmt) {
+	for _, e := range km {
+		if e.Protocol != "mikey" {
+			continue
+		}
+
+		u, err := url.Parse(e.URL)
+		if err != nil {
+			continue
+		}
+
+		var msg mikey.Message
+		err = msg.Unmarshal(e.Data)
+		if err != nil {
+			continue
+		}
+
+		for _, ct := range c.tracks {
+			if ct.srtpContext == nil {
+				continue
+			}
+
+			trackURL, err := ct.track.url(c.baseURL)
+			if err != nil ||
+				trackURL.CloneWithoutCredentials().String() != u.CloneWithoutCredentials().String() {
+				continue
+			}
+
+			for _, cs := range msg.CryptoSessions {
+				ct.srtpContext.SetROC(cs.SSRC, cs.ROC)
+			}
+		}
+	}
+}
+
 // Play writes a PLAY request and reads a Response.
 // This can be called only after Setup().
 func (c *Client) Play(ra *headers.Range) (*base.Response, error) {
+	return c.PlayContext(context.Background(), ra)
+}
+
+// PlayContext is like Play, but the request is cancelled when ctx is done.
+func (c *Client) PlayContext(ctx context.Context, ra *headers.Range) (*base.Response, error) {
+	return c.PlayWithOptionsContext(ctx, ra, nil, nil)
+}
+
+// PlayWithOptions writes a PLAY request with the given range, playback rate (scale)
+// and delivery rate (speed), and reads a Response.
+// scale and speed are optional; the rates granted by the server can be read
+// from the Scale and Speed headers of the response, and are kept by Seek().
+// This can be called only after Setup().
+func (c *Client) PlayWithOptions(ra *headers.Range, scale *headers.Scale, speed *headers.Speed) (*base.Response, error) {
+	return c.PlayWithOptionsContext(context.Background(), ra, scale, speed)
+}
+
+// PlayWithOptionsContext is like PlayWithOptions, but the request is cancelled when ctx is done.
+func (c *Client) PlayWithOptionsContext(ctx context.Context, ra *headers.Range, scale *headers.Scale, speed *headers.Speed) (*base.Response, error) {
 	cres := make(chan clientRes)
 	select {
-	case c.play <- playReq{ra: ra, res: cres}:
+	case c.play <- playReq{ctx: ctx, ra: ra, scale: scale, speed: speed, res: cres}:
 		res := <-cres
 		return res.res, res.err
 
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
 	case <-c.ctx.Done():
 		return nil, liberrors.ErrClientTerminated{}
 	}
@@ -1721,14 +2734,19 @@ func (c *Client) Play(ra *headers.Range) (*base.Response, error) {
 
 // SetupAndPlay setups and play the given tracks.
 func (c *Client) SetupAndPlay(tracks Tracks, baseURL *url.URL) error {
+	return c.SetupAndPlayContext(context.Background(), tracks, baseURL)
+}
+
+// SetupAndPlayContext is like SetupAndPlay, but requests are cancelled when ctx is done.
+func (c *Client) SetupAndPlayContext(ctx context.Context, tracks Tracks, baseURL *url.URL) error {
 	for _, t := range tracks {
-		_, err := c.Setup(true, t, baseURL, 0, 0)
+		_, err := c.SetupContext(ctx, true, t, baseURL, 0, 0)
 		if err != nil {
 			return err
 		}
 	}
 
-	_, err := c.Play(nil)
+	_, err := c.PlayContext(ctx, nil)
 	return err
 }
 
@@ -1763,12 +2781,20 @@ func (c *Client) doRecord() (*base.Response, error) {
 // Record writes a RECORD request and reads a Response.
 // This can be called only after Announce() and Setup().
 func (c *Client) Record() (*base.Response, error) {
+	return c.RecordContext(context.Background())
+}
+
+// RecordContext is like Record, but the request is cancelled when ctx is done.
+func (c *Client) RecordContext(ctx context.Context) (*base.Response, error) {
 	cres := make(chan clientRes)
 	select {
-	case c.record <- recordReq{res: cres}:
+	case c.record <- recordReq{ctx: ctx, res: cres}:
 		res := <-cres
 		return res.res, res.err
 
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
 	case <-c.ctx.Done():
 		return nil, liberrors.ErrClientTerminated{}
 	}
@@ -1783,6 +2809,10 @@ func (c *Client) doPause() (*base.Response, error) {
 		return nil, err
 	}
 
+	if c.noRTSPSession {
+		return nil, liberrors.ErrClientNoRTSPSession{}
+	}
+
 	c.playRecordStop(false)
 
 	// change state regardless of the response
@@ -1813,25 +2843,50 @@ func (c *Client) doPause() (*base.Response, error) {
 // Pause writes a PAUSE request and reads a Response.
 // This can be called only after Play() or Record().
 func (c *Client) Pause() (*base.Response, error) {
+	return c.PauseContext(context.Background())
+}
+
+// PauseContext is like Pause, but the request is cancelled when ctx is done.
+func (c *Client) PauseContext(ctx context.Context) (*base.Response, error) {
 	cres := make(chan clientRes)
 	select {
-	case c.pause <- pauseReq{res: cres}:
+	case c.pause <- pauseReq{ctx: ctx, res: cres}:
 		res := <-cres
 		return res.res, res.err
 
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
 	case <-c.ctx.Done():
 		return nil, liberrors.ErrClientTerminated{}
 	}
 }
 
 // Seek asks the server to re-start the stream from a specific timestamp.
+// The playback and delivery rates granted by the previous PLAY request are kept.
 func (c *Client) Seek(ra *headers.Range) (*base.Response, error) {
-	_, err := c.Pause()
+	return c.SeekContext(context.Background(), ra)
+}
+
+// SeekContext is like Seek, but requests are cancelled when ctx is done.
+func (c *Client) SeekContext(ctx context.Context, ra *headers.Range) (*base.Response, error) {
+	_, err := c.PauseContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return c.Play(ra)
+	cres := make(chan clientRes)
+	select {
+	case c.play <- playReq{ctx: ctx, ra: ra, keepRates: true, res: cres}:
+		res := <-cres
+		return res.res, res.err
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
+	case <-c.ctx.Done():
+		return nil, liberrors.ErrClientTerminated{}
+	}
 }
 
 func (c *Client) runWriter() {
@@ -1842,7 +2897,7 @@ func (c *Client) runWriter() {
 	switch *c.effectiveTransport {
 	case TransportUDP, TransportUDPMulticast:
 		writeFunc = func(trackID int, isRTP bool, payload []byte) {
-			if isRTP {
+			if isRTP || c.tracks[trackID].udpRTCPListener == nil {
 				c.tracks[trackID].udpRTPListener.write(payload)
 			} else {
 				c.tracks[trackID].udpRTCPListener.write(payload)
@@ -1886,10 +2941,116 @@ func (c *Client) runWriter() {
 		}
 		data := tmp.(trackTypePayload)
 
+		if ctx := c.tracks[data.trackID].srtpContext; ctx != nil {
+			var err error
+			data.payload, err = srtpEncrypt(ctx, data.isRTP, data.payload)
+			if err != nil {
+				continue
+			}
+		}
+
 		writeFunc(data.trackID, data.isRTP, data.payload)
 	}
 }
 
+func (c *Client) doGetParameter(u *url.URL, names []string) (parameters.Parameters, *base.Response, error) {
+	req := &base.Request{
+		Method: base.GetParameter,
+		URL:    u,
+	}
+
+	if len(names) != 0 {
+		req.Header = base.Header{
+			"Content-Type": base.HeaderValue{parameters.ContentType},
+		}
+		req.Body = parameters.WriteNames(names)
+	}
+
+	res, err := c.do(req, false, c.effectiveTransport != nil && *c.effectiveTransport == TransportTCP)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res.StatusCode != base.StatusOK {
+		return nil, nil, liberrors.ErrClientBadStatusCode{Code: res.StatusCode, Message: res.StatusMessage}
+	}
+
+	var params parameters.Parameters
+	err = params.Read(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return params, res, nil
+}
+
+// GetParameter writes a GET_PARAMETER request with the given parameter names
+// and reads a response, whose text/parameters body is decoded.
+// When names is empty, the request has no body and can be used as a keepalive.
+// This can be called in any state, even while reading or publishing.
+func (c *Client) GetParameter(u *url.URL, names []string) (parameters.Parameters, *base.Response, error) {
+	return c.GetParameterContext(context.Background(), u, names)
+}
+
+// GetParameterContext is like GetParameter, but the request is cancelled when ctx is done.
+func (c *Client) GetParameterContext(ctx context.Context, u *url.URL, names []string) (parameters.Parameters, *base.Response, error) {
+	cres := make(chan clientRes)
+	select {
+	case c.getParameter <- getParameterReq{ctx: ctx, url: u, names: names, res: cres}:
+		res := <-cres
+		return res.params, res.res, res.err
+
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+
+	case <-c.ctx.Done():
+		return nil, nil, liberrors.ErrClientTerminated{}
+	}
+}
+
+func (c *Client) doSetParameter(u *url.URL, params parameters.Parameters) (*base.Response, error) {
+	res, err := c.do(&base.Request{
+		Method: base.SetParameter,
+		URL:    u,
+		Header: base.Header{
+			"Content-Type": base.HeaderValue{parameters.ContentType},
+		},
+		Body: params.Write(),
+	}, false, c.effectiveTransport != nil && *c.effectiveTransport == TransportTCP)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != base.StatusOK {
+		return nil, liberrors.ErrClientBadStatusCode{Code: res.StatusCode, Message: res.StatusMessage}
+	}
+
+	return res, nil
+}
+
+// SetParameter writes a SET_PARAMETER request with the given parameters,
+// encoded into a text/parameters body, and reads a response.
+// This can be called in any state, even while reading or publishing.
+func (c *Client) SetParameter(u *url.URL, params parameters.Parameters) (*base.Response, error) {
+	return c.SetParameterContext(context.Background(), u, params)
+}
+
+// SetParameterContext is like SetParameter, but the request is cancelled when ctx is done.
+func (c *Client) SetParameterContext(ctx context.Context, u *url.URL, params parameters.Parameters) (*base.Response, error) {
+	cres := make(chan clientRes)
+	select {
+	case c.setParameter <- setParameterReq{ctx: ctx, url: u, params: params, res: cres}:
+		res := <-cres
+		return res.res, res.err
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
+	case <-c.ctx.Done():
+		return nil, liberrors.ErrClientTerminated{}
+	}
+}
+
 // WritePacketRTP writes a RTP packet.
 func (c *Client) WritePacketRTP(trackID int, pkt *rtp.Packet, ptsEqualsDTS bool) error {
 	c.writeMutex.RLock()
@@ -1911,8 +3072,12 @@ func (c *Client) WritePacketRTP(trackID int, pkt *rtp.Packet, ptsEqualsDTS bool)
 	}
 	byts = byts[:n]
 
-	if c.tracks[trackID].udpRTCPSender != nil {
-		c.tracks[trackID].udpRTCPSender.ProcessPacketRTP(time.Now(), pkt, ptsEqualsDTS)
+	if c.tracks[trackID].rtcpSender != nil {
+		c.tracks[trackID].rtcpSender.ProcessPacketRTP(time.Now(), pkt, ptsEqualsDTS)
+	}
+
+	if c.tracks[trackID].udpRTXHistory != nil {
+		c.tracks[trackID].udpRTXHistory.ProcessPacketRTP(pkt)
 	}
 
 	c.writeBuffer.Push(trackTypePayload{
@@ -1923,8 +3088,43 @@ func (c *Client) WritePacketRTP(trackID int, pkt *rtp.Packet, ptsEqualsDTS bool)
 	return nil
 }
 
+// retransmit sends the RTP packets requested by a RTCP NACK
+// wrapped into retransmission packets.
+func (c *Client) retransmit(trackID int, pkt rtcp.Packet) {
+	c.writeMutex.RLock()
+	defer c.writeMutex.RUnlock()
+
+	ct := c.tracks[trackID]
+
+	if !c.writeFrameAllowed || ct.udpRTXHistory == nil {
+		return
+	}
+
+	for _, rpkt := range ct.udpRTXHistory.ProcessPacketRTCP(pkt) {
+		byts, err := ct.udpRTXEncoder.Encode(rpkt).Marshal()
+		if err != nil {
+			continue
+		}
+
+		c.writeBuffer.Push(trackTypePayload{
+			trackID: trackID,
+			isRTP:   true,
+			payload: byts,
+		})
+	}
+}
+
 // WritePacketRTCP writes a RTCP packet.
+// It can be used both when reading and when publishing.
 func (c *Client) WritePacketRTCP(trackID int, pkt rtcp.Packet) error {
+	return c.writePacketRTCP(trackID, func(*clientTrack) rtcp.Packet {
+		return pkt
+	})
+}
+
+// writePacketRTCP writes a RTCP packet that is built
+// from the state of the track, that is read under writeMutex.
+func (c *Client) writePacketRTCP(trackID int, buildPacket func(*clientTrack) rtcp.Packet) error {
 	c.writeMutex.RLock()
 	defer c.writeMutex.RUnlock()
 
@@ -1937,7 +3137,7 @@ func (c *Client) WritePacketRTCP(trackID int, pkt rtcp.Packet) error {
 		}
 	}
 
-	byts, err := pkt.Marshal()
+	byts, err := buildPacket(c.tracks[trackID]).Marshal()
 	if err != nil {
 		return err
 	}
@@ -1949,3 +3149,74 @@ func (c *Client) WritePacketRTCP(trackID int, pkt rtcp.Packet) error {
 	})
 	return nil
 }
+
+// WritePacketRTCPPLI writes a RTCP Picture Loss Indication, that asks the server
+// to send a key frame of the given track.
+// This can be called only after Play().
+func (c *Client) WritePacketRTCPPLI(trackID int) error {
+	return c.writePacketRTCP(trackID, func(ct *clientTrack) rtcp.Packet {
+		return &rtcp.PictureLossIndication{
+			SenderSSRC: ct.localSSRC,
+			MediaSSRC:  atomic.LoadUint32(&ct.remoteSSRC),
+		}
+	})
+}
+
+// WritePacketRTCPFIR writes a RTCP Full Intra Request, that asks the server
+// to send a key frame of the given track.
+// This can be called only after Play().
+func (c *Client) WritePacketRTCPFIR(trackID int) error {
+	return c.writePacketRTCP(trackID, func(ct *clientTrack) rtcp.Packet {
+		mediaSSRC := atomic.LoadUint32(&ct.remoteSSRC)
+
+		return &rtcp.FullIntraRequest{
+			SenderSSRC: ct.localSSRC,
+			MediaSSRC:  mediaSSRC,
+			FIR: []rtcp.FIREntry{{
+				SSRC:           mediaSSRC,
+				SequenceNumber: uint8(atomic.AddUint32(&ct.firSequenceNumber, 1)),
+			}},
+		}
+	})
+}
+
+// WritePacketRTCPNACK writes a RTCP generic NACK, that asks the server
+// to retransmit the RTP packets of the given track with the given sequence numbers.
+// This can be called only after Play().
+func (c *Client) WritePacketRTCPNACK(trackID int, sequenceNumbers []uint16) error {
+	return c.writePacketRTCP(trackID, func(ct *clientTrack) rtcp.Packet {
+		return &rtcp.TransportLayerNack{
+			SenderSSRC: ct.localSSRC,
+			MediaSSRC:  atomic.LoadUint32(&ct.remoteSSRC),
+			Nacks:      rtcp.NackPairsFromSequenceNumbers(sequenceNumbers),
+		}
+	})
+}
+
+// Stats returns statistics about the setupped tracks, indexed by track ID.
+// It returns nil when the client is neither reading nor publishing.
+func (c *Client) Stats() map[int]TrackStats {
+	c.writeMutex.RLock()
+	defer c.writeMutex.RUnlock()
+
+	if !c.writeFrameAllowed {
+		return nil
+	}
+
+	ret := make(map[int]TrackStats, len(c.tracks))
+
+	for trackID, ct := range c.tracks {
+		var s TrackStats
+
+		if ct.rtcpReceiver != nil {
+			s.fillFromReceiver(ct.rtcpReceiver.Stats(), ct.track.ClockRate())
+		} else {
+			s.fillFromSender(ct.rtcpSender.Stats())
+			ct.receiverReports.fill(&s)
+		}
+
+		ret[trackID] = s
+	}
+
+	return ret
+}
@@ -851,6 +851,7 @@ func TestClientPublishAutomaticProtocol(t *testing.T) {
 
 func TestClientPublishRTCPReport(t *testing.T) {
 	reportReceived := make(chan struct{})
+	rrReceived := make(chan struct{})
 
 	l, err := net.Listen("tcp", "localhost:8554")
 	require.NoError(t, err)
@@ -958,6 +959,23 @@ func TestClientPublishRTCPReport(t *testing.T) {
 			OctetCount:  4,
 		}, sr)
 
+		rr := &rtcp.ReceiverReport{
+			SSRC: 1234,
+			Reports: []rtcp.ReceptionReport{{
+				SSRC:             753621,
+				TotalLost:        3,
+				Jitter:           9000,
+				LastSenderReport: uint32(sr.NTPTime >> 16),
+			}},
+		}
+		byts, _ = rr.Marshal()
+		_, err = l2.WriteTo(byts, &net.UDPAddr{
+			IP:   net.ParseIP("127.0.0.1"),
+			Port: inTH.ClientPorts[1],
+		})
+		require.NoError(t, err)
+
+		<-rrReceived
 		close(reportReceived)
 
 		req, err = readRequest(br)
@@ -972,7 +990,12 @@ func TestClientPublishRTCPReport(t *testing.T) {
 	}()
 
 	c := &Client{
-		udpSenderReportPeriod: 1 * time.Second,
+		senderReportPeriod: 1 * time.Second,
+		OnPacketRTCP: func(ctx *ClientOnPacketRTCPCtx) {
+			if _, ok := ctx.Packet.(*rtcp.ReceiverReport); ok {
+				close(rrReceived)
+			}
+		},
 	}
 
 	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
@@ -997,6 +1020,180 @@ func TestClientPublishRTCPReport(t *testing.T) {
 	require.NoError(t, err)
 
 	<-reportReceived
+
+	stats := c.Stats()
+	require.Equal(t, uint64(1), stats[0].PacketsSent)
+	require.Equal(t, uint64(16), stats[0].BytesSent)
+	require.Equal(t, uint16(946), stats[0].LastSequenceNumber)
+	require.Equal(t, uint64(3), stats[0].PacketsLost)
+	require.Equal(t, 100*time.Millisecond, stats[0].Jitter)
+	require.Less(t, stats[0].RTT, 1*time.Second)
+	require.NotEqual(t, time.Time{}, stats[0].LastSenderReport)
+	require.NotEqual(t, time.Time{}, stats[0].LastReceiverReport)
+}
+
+func TestClientPublishRTX(t *testing.T) {
+	rtxReceived := make(chan struct{})
+
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Announce),
+					string(base.Setup),
+					string(base.Record),
+				}, ", ")},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Announce, req.Method)
+
+		tracks, _, err := ReadTracks(req.Body, false)
+		require.NoError(t, err)
+		rtxPayloadType, ok := tracks[0].RTXPayloadType()
+		require.True(t, ok)
+		require.Equal(t, uint8(97), rtxPayloadType)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var inTH headers.Transport
+		err = inTH.Read(req.Header["Transport"])
+		require.NoError(t, err)
+
+		l1, err := net.ListenPacket("udp", "localhost:34556")
+		require.NoError(t, err)
+		defer l1.Close()
+
+		l2, err := net.ListenPacket("udp", "localhost:34557")
+		require.NoError(t, err)
+		defer l2.Close()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Delivery: func() *headers.TransportDelivery {
+						v := headers.TransportDeliveryUnicast
+						return &v
+					}(),
+					Protocol:    headers.TransportProtocolUDP,
+					ClientPorts: inTH.ClientPorts,
+					ServerPorts: &[2]int{34556, 34557},
+				}.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Record, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			buf := make([]byte, 2048)
+			_, _, err = l1.ReadFrom(buf)
+			require.NoError(t, err)
+		}
+
+		byts, _ = (&rtcp.TransportLayerNack{
+			SenderSSRC: 0x12345678,
+			MediaSSRC:  753621,
+			Nacks:      rtcp.NackPairsFromSequenceNumbers([]uint16{947}),
+		}).Marshal()
+		_, err = l2.WriteTo(byts, &net.UDPAddr{
+			IP:   net.ParseIP("127.0.0.1"),
+			Port: inTH.ClientPorts[1],
+		})
+		require.NoError(t, err)
+
+		buf := make([]byte, 2048)
+		n, _, err := l1.ReadFrom(buf)
+		require.NoError(t, err)
+		var pkt rtp.Packet
+		err = pkt.Unmarshal(buf[:n])
+		require.NoError(t, err)
+		require.Equal(t, uint8(97), pkt.PayloadType)
+		require.Equal(t, []byte{0x03, 0xb3, 0x01}, pkt.Payload)
+
+		close(rtxReceived)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}()
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportUDP
+			return &v
+		}(),
+	}
+
+	track := NewTrackPCMU()
+	track.SetRTXPayloadType(97)
+
+	err = c.StartPublishing("rtsp://localhost:8554/teststream",
+		Tracks{track})
+	require.NoError(t, err)
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		err = c.WritePacketRTP(0, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    0,
+				SequenceNumber: uint16(946 + i),
+				Timestamp:      54352,
+				SSRC:           753621,
+			},
+			Payload: []byte{byte(i)},
+		}, true)
+		require.NoError(t, err)
+	}
+
+	<-rtxReceived
 }
 
 func TestClientPublishIgnoreTCPRTPPackets(t *testing.T) {
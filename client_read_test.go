@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"testing"
@@ -18,6 +19,10 @@ import (
 	"github.com/aler9/gortsplib/pkg/auth"
 	"github.com/aler9/gortsplib/pkg/base"
 	"github.com/aler9/gortsplib/pkg/headers"
+	"github.com/aler9/gortsplib/pkg/liberrors"
+	"github.com/aler9/gortsplib/pkg/parameters"
+	"github.com/aler9/gortsplib/pkg/rtpfec"
+	"github.com/aler9/gortsplib/pkg/sap"
 	"github.com/aler9/gortsplib/pkg/url"
 )
 
@@ -426,6 +431,387 @@ func TestClientRead(t *testing.T) {
 	}
 }
 
+func TestClientReadMulticastSSM(t *testing.T) {
+	packetRecv := make(chan struct{})
+
+	listenIP := multicastCapableIP(t)
+	l, err := net.Listen("tcp", listenIP+":8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackGeneric("application", []string{"97"}, "97 private/90000", "")
+		require.NoError(t, err)
+
+		tracks := Tracks{track}
+		tracks.setControls()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+				"Content-Base": base.HeaderValue{"rtsp://" + listenIP + ":8554/teststream/"},
+			},
+			Body: tracks.Write(false),
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var inTH headers.Transport
+		err = inTH.Read(req.Header["Transport"])
+		require.NoError(t, err)
+		require.Equal(t, headers.TransportDeliveryMulticast, *inTH.Delivery)
+
+		// send from the multicast port, as a server would do
+		l1, err := net.ListenPacket("udp", "224.0.0.0:25000")
+		require.NoError(t, err)
+		defer l1.Close()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Protocol: headers.TransportProtocolUDP,
+					Delivery: func() *headers.TransportDelivery {
+						v := headers.TransportDeliveryMulticast
+						return &v
+					}(),
+					Destination: func() *net.IP {
+						v := net.ParseIP("232.1.0.1")
+						return &v
+					}(),
+					Source: func() *net.IP {
+						v := net.ParseIP(listenIP)
+						return &v
+					}(),
+					TTL: func() *uint {
+						v := uint(8)
+						return &v
+					}(),
+					Ports: &[2]int{25000, 25001},
+				}.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		time.Sleep(1 * time.Second)
+		l1.WriteTo(testRTPPacketMarshaled, &net.UDPAddr{
+			IP:   net.ParseIP("232.1.0.1"),
+			Port: 25000,
+		})
+
+		<-packetRecv
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}()
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportUDPMulticast
+			return &v
+		}(),
+	}
+
+	c.OnPacketRTP = func(ctx *ClientOnPacketRTPCtx) {
+		// skip multicast loopback
+		if len(ctx.Packet.Payload) == 0 {
+			return
+		}
+
+		require.Equal(t, 0, ctx.TrackID)
+		require.Equal(t, &testRTPPacket, ctx.Packet)
+		close(packetRecv)
+	}
+
+	err = c.StartReading("rtsp://" + listenIP + ":8554/teststream")
+	require.NoError(t, err)
+	defer c.Close()
+
+	<-packetRecv
+}
+
+func TestClientReadSAP(t *testing.T) {
+	track, err := NewTrackGeneric("application", []string{"97"}, "97 private/90000", "")
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	listenIP := multicastCapableIP(t)
+
+	s := &Server{
+		Handler:           &testServerHandler{},
+		RTSPAddress:       listenIP + ":8554",
+		MulticastIPRange:  "224.1.0.0/16",
+		MulticastRTPPort:  8002,
+		MulticastRTCPPort: 8003,
+		SAPAnnouncePeriod: 100 * time.Millisecond,
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	sessionAnnounced := make(chan *SAPSession, 1)
+	sessionDeleted := make(chan *SAPSession, 1)
+
+	l := &SAPListener{
+		OnSessionAnnounce: func(sx *SAPSession) {
+			sessionAnnounced <- sx
+		},
+		OnSessionDelete: func(sx *SAPSession) {
+			sessionDeleted <- sx
+		},
+	}
+	err = l.Start()
+	require.NoError(t, err)
+	defer l.Close()
+
+	err = stream.AnnounceSAP(s, "test session")
+	require.NoError(t, err)
+
+	sx := <-sessionAnnounced
+	require.Equal(t, "test session", sx.Name)
+	require.Equal(t, listenIP, sx.Origin.String())
+	require.Equal(t, 1, len(sx.Tracks))
+	require.Equal(t, 1, len(l.Sessions()))
+
+	packetRecv := make(chan struct{})
+
+	c := &Client{
+		OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
+			// skip multicast loopback
+			if len(ctx.Packet.Payload) == 0 {
+				return
+			}
+
+			require.Equal(t, 0, ctx.TrackID)
+			require.Equal(t, testRTPPacket.Payload, ctx.Packet.Payload)
+			close(packetRecv)
+		},
+	}
+
+	err = c.StartReadingSAP(sx)
+	require.NoError(t, err)
+	defer c.Close()
+
+	time.Sleep(500 * time.Millisecond)
+
+	stream.WritePacketRTP(0, &testRTPPacket, true)
+
+	<-packetRecv
+
+	_, err = c.Pause()
+	require.Equal(t, liberrors.ErrClientNoRTSPSession{}, err)
+
+	stream.Close()
+
+	sx2 := <-sessionDeleted
+	require.Equal(t, sx, sx2)
+	require.Equal(t, 0, len(l.Sessions()))
+}
+
+func TestClientReadSAPMaxSessions(t *testing.T) {
+	var deleted []string
+
+	l := &SAPListener{
+		OnSessionAnnounce: func(sx *SAPSession) {},
+		OnSessionDelete: func(sx *SAPSession) {
+			deleted = append(deleted, sx.Name)
+		},
+		MaxSessions: 2,
+		sessions:    make(map[string]*SAPSession),
+	}
+
+	announce := func(sessionID int) {
+		l.handlePacket(&sap.Packet{
+			MessageIDHash:     uint16(sessionID),
+			OriginatingSource: net.ParseIP("192.168.1.1"),
+			PayloadType:       sap.PayloadTypeSDP,
+			Payload: []byte("v=0\r\n" +
+				"o=- " + strconv.Itoa(sessionID) + " 0 IN IP4 192.168.1.1\r\n" +
+				"s=session" + strconv.Itoa(sessionID) + "\r\n" +
+				"c=IN IP4 224.1.0.1/16\r\n" +
+				"t=0 0\r\n" +
+				"m=application 25000 RTP/AVP 97\r\n" +
+				"a=rtpmap:97 private/90000\r\n"),
+		})
+	}
+
+	announce(1)
+	time.Sleep(10 * time.Millisecond)
+	announce(2)
+	time.Sleep(10 * time.Millisecond)
+
+	// the first session is announced again
+	announce(1)
+
+	announce(3)
+	require.Equal(t, []string{"session2"}, deleted)
+	require.Equal(t, 2, len(l.Sessions()))
+}
+
+func TestClientReadSDP(t *testing.T) {
+	sdp := []byte("v=0\r\n" +
+		"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+		"s=Stream\r\n" +
+		"c=IN IP4 127.0.0.1\r\n" +
+		"t=0 0\r\n" +
+		"m=application 25000 RTP/AVP 97\r\n" +
+		"a=rtpmap:97 private/90000\r\n")
+
+	l1, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l1.Close()
+
+	l2, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l2.Close()
+
+	packetRecv := make(chan struct{})
+
+	c := &Client{
+		OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
+			require.Equal(t, 0, ctx.TrackID)
+			require.Equal(t, &testRTPPacket, ctx.Packet)
+			close(packetRecv)
+		},
+		receiverReportPeriod: 500 * time.Millisecond,
+	}
+
+	err = c.StartReadingSDP(sdp)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.Equal(t, 1, len(c.Tracks()))
+
+	l1.WriteTo(testRTPPacketMarshaled, &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: 25000,
+	})
+
+	<-packetRecv
+
+	// receiver reports are sent to the address of the sender reports
+	byts, _ := (&rtcp.SenderReport{
+		SSRC:        testRTPPacket.SSRC,
+		NTPTime:     0xe1ebd4f5a0000000,
+		RTPTime:     testRTPPacket.Timestamp,
+		PacketCount: 1,
+		OctetCount:  4,
+	}).Marshal()
+	l2.WriteTo(byts, &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: 25001,
+	})
+
+	l2.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := l2.ReadFrom(buf)
+	require.NoError(t, err)
+
+	packets, err := rtcp.Unmarshal(buf[:n])
+	require.NoError(t, err)
+	rr, ok := packets[0].(*rtcp.ReceiverReport)
+	require.Equal(t, true, ok)
+	require.Equal(t, testRTPPacket.SSRC, rr.Reports[0].SSRC)
+
+	_, err = c.Pause()
+	require.Equal(t, liberrors.ErrClientNoRTSPSession{}, err)
+}
+
+func TestClientReadSDPErrors(t *testing.T) {
+	for _, ca := range []string{
+		"mixed transports",
+		"timeout",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			sdp := "v=0\r\n" +
+				"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+				"s=Stream\r\n" +
+				"t=0 0\r\n" +
+				"m=application 25000 RTP/AVP 97\r\n" +
+				"c=IN IP4 127.0.0.1\r\n" +
+				"a=rtpmap:97 private/90000\r\n"
+
+			if ca == "mixed transports" {
+				sdp += "m=application 25002 RTP/AVP 97\r\n" +
+					"c=IN IP4 224.1.0.1/16\r\n" +
+					"a=rtpmap:97 private/90000\r\n"
+			}
+
+			c := &Client{
+				InitialUDPReadTimeout: 500 * time.Millisecond,
+			}
+
+			err := c.StartReadingSDP([]byte(sdp))
+
+			switch ca {
+			case "mixed transports":
+				require.Equal(t, liberrors.ErrClientSDPMixedTransports{}, err)
+
+			case "timeout":
+				require.NoError(t, err)
+				err = c.Wait()
+				require.Equal(t, liberrors.ErrClientUDPTimeout{}, err)
+			}
+		})
+	}
+}
+
 func TestClientReadPartial(t *testing.T) {
 	listenIP := multicastCapableIP(t)
 	l, err := net.Listen("tcp", listenIP+":8554")
@@ -1970,7 +2356,7 @@ func TestClientReadRTCPReport(t *testing.T) {
 	}()
 
 	c := &Client{
-		udpReceiverReportPeriod: 1 * time.Second,
+		receiverReportPeriod: 1 * time.Second,
 	}
 
 	err = c.StartReading("rtsp://localhost:8554/teststream")
@@ -1978,27 +2364,1689 @@ func TestClientReadRTCPReport(t *testing.T) {
 	defer c.Close()
 
 	<-reportReceived
+
+	stats := c.Stats()
+	require.Equal(t, uint64(1), stats[0].PacketsReceived)
+	require.Equal(t, uint64(16), stats[0].BytesReceived)
+	require.Equal(t, uint16(946), stats[0].LastSequenceNumber)
+	require.NotEqual(t, time.Time{}, stats[0].LastSenderReport)
+	require.NotEqual(t, time.Time{}, stats[0].LastReceiverReport)
 }
 
-func TestClientReadErrorTimeout(t *testing.T) {
-	for _, transport := range []string{
-		"udp",
-		"tcp",
-		"auto",
-	} {
-		t.Run(transport, func(t *testing.T) {
-			l, err := net.Listen("tcp", "localhost:8554")
-			require.NoError(t, err)
-			defer l.Close()
+func TestClientReadRTCPFeedback(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
 
-			serverDone := make(chan struct{})
-			defer func() { <-serverDone }()
-			go func() {
-				defer close(serverDone)
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
 
-				conn, err := l.Accept()
-				require.NoError(t, err)
-				defer conn.Close()
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+		require.NoError(t, err)
+
+		tracks := Tracks{track}
+		tracks.setControls()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+			},
+			Body: tracks.Write(false),
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		th := headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Protocol:       headers.TransportProtocolTCP,
+			InterleavedIDs: &[2]int{0, 1},
+			SSRC: func() *uint32 {
+				v := uint32(0x38F27A2F)
+				return &v
+			}(),
+		}
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": th.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		var f base.InterleavedFrame
+
+		err = f.Read(2048, br)
+		require.NoError(t, err)
+		require.Equal(t, 1, f.Channel)
+		packets, err := rtcp.Unmarshal(f.Payload)
+		require.NoError(t, err)
+		pli, ok := packets[0].(*rtcp.PictureLossIndication)
+		require.True(t, ok)
+		require.Equal(t, uint32(0x38F27A2F), pli.MediaSSRC)
+
+		err = f.Read(2048, br)
+		require.NoError(t, err)
+		require.Equal(t, 1, f.Channel)
+		packets, err = rtcp.Unmarshal(f.Payload)
+		require.NoError(t, err)
+		fir, ok := packets[0].(*rtcp.FullIntraRequest)
+		require.True(t, ok)
+		require.Equal(t, []rtcp.FIREntry{{
+			SSRC:           0x38F27A2F,
+			SequenceNumber: 1,
+		}}, fir.FIR)
+
+		err = f.Read(2048, br)
+		require.NoError(t, err)
+		require.Equal(t, 1, f.Channel)
+		packets, err = rtcp.Unmarshal(f.Payload)
+		require.NoError(t, err)
+		nack, ok := packets[0].(*rtcp.TransportLayerNack)
+		require.True(t, ok)
+		require.Equal(t, uint32(0x38F27A2F), nack.MediaSSRC)
+		require.Equal(t, []rtcp.NackPair{{PacketID: 10, LostPackets: 0b101}}, nack.Nacks)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}()
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportTCP
+			return &v
+		}(),
+	}
+
+	err = c.StartReading("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	err = c.WritePacketRTCPPLI(0)
+	require.NoError(t, err)
+
+	err = c.WritePacketRTCPFIR(0)
+	require.NoError(t, err)
+
+	err = c.WritePacketRTCPNACK(0, []uint16{10, 11, 13})
+	require.NoError(t, err)
+
+	c.Close()
+}
+
+func TestClientReadNTP(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		tracks := Tracks{NewTrackPCMU()}
+		tracks.setControls()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+			},
+			Body: tracks.Write(false),
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		th := headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Protocol:       headers.TransportProtocolTCP,
+			InterleavedIDs: &[2]int{0, 1},
+			SSRC: func() *uint32 {
+				v := uint32(0x38F27A2F)
+				return &v
+			}(),
+		}
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": th.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		for _, pkt := range []interface {
+			Marshal() ([]byte, error)
+		}{
+			&rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    0,
+					SequenceNumber: 946,
+					Timestamp:      54352,
+					SSRC:           0x38F27A2F,
+				},
+				Payload: []byte{0x01, 0x02, 0x03, 0x04},
+			},
+			&rtcp.SenderReport{
+				SSRC:    0x38F27A2F,
+				NTPTime: 0xe398e48080000000, // 2021-01-01 00:00:00.5 UTC
+				RTPTime: 54352,
+			},
+			&rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    0,
+					SequenceNumber: 947,
+					Timestamp:      54352 + 4000,
+					SSRC:           0x38F27A2F,
+				},
+				Payload: []byte{0x01, 0x02, 0x03, 0x04},
+			},
+		} {
+			channel := 0
+			if _, ok := pkt.(*rtcp.SenderReport); ok {
+				channel = 1
+			}
+
+			byts, _ := pkt.Marshal()
+			byts, _ = base.InterleavedFrame{
+				Channel: channel,
+				Payload: byts,
+			}.Write()
+			_, err = conn.Write(byts)
+			require.NoError(t, err)
+		}
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}()
+
+	packetRecv := make(chan *ClientOnPacketRTPCtx, 2)
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportTCP
+			return &v
+		}(),
+		OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
+			packetRecv <- ctx
+		},
+	}
+
+	err = c.StartReading("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	ctx := <-packetRecv
+	require.Equal(t, false, ctx.NTPSynchronized)
+
+	ctx = <-packetRecv
+	require.Equal(t, true, ctx.NTPSynchronized)
+	require.Equal(t, time.Date(2021, 1, 1, 0, 0, 1, 0, time.UTC), ctx.NTP.UTC())
+
+	c.Close()
+}
+
+func TestClientReadRTX(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track := NewTrackPCMU()
+		track.SetRTXPayloadType(97)
+
+		tracks := Tracks{track}
+		tracks.setControls()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+			},
+			Body: tracks.Write(false),
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var inTH headers.Transport
+		err = inTH.Read(req.Header["Transport"])
+		require.NoError(t, err)
+
+		l1, err := net.ListenPacket("udp", "localhost:34556")
+		require.NoError(t, err)
+		defer l1.Close()
+
+		l2, err := net.ListenPacket("udp", "localhost:34557")
+		require.NoError(t, err)
+		defer l2.Close()
+
+		th := headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Protocol:    headers.TransportProtocolUDP,
+			ClientPorts: inTH.ClientPorts,
+			ServerPorts: &[2]int{34556, 34557},
+		}
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": th.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		// skip firewall opening
+		buf := make([]byte, 2048)
+		_, _, err = l2.ReadFrom(buf)
+		require.NoError(t, err)
+
+		for _, seq := range []uint16{10, 13} {
+			byts, _ = (&rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    0,
+					SequenceNumber: seq,
+					Timestamp:      54352,
+					SSRC:           753621,
+				},
+				Payload: []byte{0x01, 0x02, 0x03, 0x04},
+			}).Marshal()
+			_, err = l1.WriteTo(byts, &net.UDPAddr{
+				IP:   net.ParseIP("127.0.0.1"),
+				Port: th.ClientPorts[0],
+			})
+			require.NoError(t, err)
+		}
+
+		buf = make([]byte, 2048)
+		n, _, err := l2.ReadFrom(buf)
+		require.NoError(t, err)
+		packets, err := rtcp.Unmarshal(buf[:n])
+		require.NoError(t, err)
+		nack, ok := packets[0].(*rtcp.TransportLayerNack)
+		require.True(t, ok)
+		require.Equal(t, uint32(753621), nack.MediaSSRC)
+		require.Equal(t, []rtcp.NackPair{{PacketID: 11, LostPackets: 0b1}}, nack.Nacks)
+
+		byts, _ = (&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    97,
+				SequenceNumber: 500,
+				Timestamp:      54352,
+				SSRC:           1234,
+			},
+			Payload: []byte{0x00, 0x0b, 0x05, 0x06},
+		}).Marshal()
+		_, err = l1.WriteTo(byts, &net.UDPAddr{
+			IP:   net.ParseIP("127.0.0.1"),
+			Port: th.ClientPorts[0],
+		})
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}()
+
+	packetRecv := make(chan *rtp.Packet, 10)
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportUDP
+			return &v
+		}(),
+		OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
+			packetRecv <- ctx.Packet.Clone()
+		},
+	}
+
+	err = c.StartReading("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	require.Equal(t, uint16(10), (<-packetRecv).SequenceNumber)
+	require.Equal(t, uint16(13), (<-packetRecv).SequenceNumber)
+	require.Equal(t, &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    0,
+			SequenceNumber: 11,
+			Timestamp:      54352,
+			SSRC:           753621,
+		},
+		Payload: []byte{0x05, 0x06},
+	}, <-packetRecv)
+
+	c.Close()
+}
+
+func TestClientReadFEC(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track := NewTrackPCMU()
+		track.SetFEC(98, rtpfec.SchemeFlexFEC)
+
+		tracks := Tracks{track}
+		tracks.setControls()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+			},
+			Body: tracks.Write(false),
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var inTH headers.Transport
+		err = inTH.Read(req.Header["Transport"])
+		require.NoError(t, err)
+
+		l1, err := net.ListenPacket("udp", "localhost:34556")
+		require.NoError(t, err)
+		defer l1.Close()
+
+		l2, err := net.ListenPacket("udp", "localhost:34557")
+		require.NoError(t, err)
+		defer l2.Close()
+
+		th := headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Protocol:    headers.TransportProtocolUDP,
+			ClientPorts: inTH.ClientPorts,
+			ServerPorts: &[2]int{34556, 34557},
+		}
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": th.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		// skip firewall opening
+		buf := make([]byte, 2048)
+		_, _, err = l2.ReadFrom(buf)
+		require.NoError(t, err)
+
+		e := &rtpfec.Encoder{
+			Scheme:      rtpfec.SchemeFlexFEC,
+			PayloadType: 98,
+			GroupSize:   3,
+		}
+		e.Init()
+
+		for i := 0; i < 3; i++ {
+			pkt := &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    0,
+					SequenceNumber: uint16(10 + i),
+					Timestamp:      54352,
+					SSRC:           753621,
+				},
+				Payload: []byte{byte(i), 0x01, 0x02},
+			}
+
+			fec, err := e.Encode(pkt)
+			require.NoError(t, err)
+
+			// simulate the loss of the second packet
+			if i != 1 {
+				byts, _ = pkt.Marshal()
+				_, err = l1.WriteTo(byts, &net.UDPAddr{
+					IP:   net.ParseIP("127.0.0.1"),
+					Port: th.ClientPorts[0],
+				})
+				require.NoError(t, err)
+			}
+
+			if fec != nil {
+				byts, _ = fec.Marshal()
+				_, err = l1.WriteTo(byts, &net.UDPAddr{
+					IP:   net.ParseIP("127.0.0.1"),
+					Port: th.ClientPorts[0],
+				})
+				require.NoError(t, err)
+			}
+		}
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}()
+
+	packetRecv := make(chan *rtp.Packet, 10)
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportUDP
+			return &v
+		}(),
+		OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
+			packetRecv <- ctx.Packet.Clone()
+		},
+	}
+
+	err = c.StartReading("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	require.Equal(t, uint16(10), (<-packetRecv).SequenceNumber)
+	require.Equal(t, uint16(12), (<-packetRecv).SequenceNumber)
+	require.Equal(t, &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    0,
+			SequenceNumber: 11,
+			Timestamp:      54352,
+			SSRC:           753621,
+			CSRC:           []uint32{},
+		},
+		Payload: []byte{0x01, 0x01, 0x02},
+	}, <-packetRecv)
+
+	c.Close()
+}
+
+func TestClientReadErrorTimeout(t *testing.T) {
+	for _, transport := range []string{
+		"udp",
+		"tcp",
+		"auto",
+	} {
+		t.Run(transport, func(t *testing.T) {
+			l, err := net.Listen("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer l.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := l.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+
+				req, err := readRequest(br)
+				require.NoError(t, err)
+				require.Equal(t, base.Options, req.Method)
+
+				byts, _ := base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Public": base.HeaderValue{strings.Join([]string{
+							string(base.Describe),
+							string(base.Setup),
+							string(base.Play),
+						}, ", ")},
+					},
+				}.Write()
+				_, err = conn.Write(byts)
+				require.NoError(t, err)
+
+				req, err = readRequest(br)
+				require.NoError(t, err)
+				require.Equal(t, base.Describe, req.Method)
+
+				track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+				require.NoError(t, err)
+
+				tracks := Tracks{track}
+				tracks.setControls()
+
+				byts, _ = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Content-Type": base.HeaderValue{"application/sdp"},
+						"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+					},
+					Body: tracks.Write(false),
+				}.Write()
+				_, err = conn.Write(byts)
+				require.NoError(t, err)
+
+				req, err = readRequest(br)
+				require.NoError(t, err)
+				require.Equal(t, base.Setup, req.Method)
+
+				var inTH headers.Transport
+				err = inTH.Read(req.Header["Transport"])
+				require.NoError(t, err)
+
+				th := headers.Transport{
+					Delivery: func() *headers.TransportDelivery {
+						v := headers.TransportDeliveryUnicast
+						return &v
+					}(),
+				}
+
+				var l1 net.PacketConn
+				if transport == "udp" || transport == "auto" {
+					var err error
+					l1, err = net.ListenPacket("udp", "localhost:34556")
+					require.NoError(t, err)
+					defer l1.Close()
+
+					th.Protocol = headers.TransportProtocolUDP
+					th.ServerPorts = &[2]int{34556, 34557}
+					th.ClientPorts = inTH.ClientPorts
+				} else {
+					th.Protocol = headers.TransportProtocolTCP
+					th.InterleavedIDs = inTH.InterleavedIDs
+				}
+
+				byts, _ = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Transport": th.Write(),
+					},
+				}.Write()
+				_, err = conn.Write(byts)
+				require.NoError(t, err)
+
+				req, err = readRequest(br)
+				require.NoError(t, err)
+				require.Equal(t, base.Play, req.Method)
+
+				byts, _ = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write()
+				_, err = conn.Write(byts)
+				require.NoError(t, err)
+
+				if transport == "udp" || transport == "auto" {
+					// write a packet to skip the protocol autodetection feature
+					l1.WriteTo(testRTPPacketMarshaled, &net.UDPAddr{
+						IP:   net.ParseIP("127.0.0.1"),
+						Port: th.ClientPorts[0],
+					})
+				}
+
+				req, err = readRequest(br)
+				require.NoError(t, err)
+				require.Equal(t, base.Teardown, req.Method)
+
+				byts, _ = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write()
+				_, err = conn.Write(byts)
+				require.NoError(t, err)
+			}()
+
+			c := &Client{
+				Transport: func() *Transport {
+					switch transport {
+					case "udp":
+						v := TransportUDP
+						return &v
+
+					case "tcp":
+						v := TransportTCP
+						return &v
+					}
+					return nil
+				}(),
+				InitialUDPReadTimeout: 1 * time.Second,
+				ReadTimeout:           1 * time.Second,
+			}
+
+			err = c.StartReading("rtsp://localhost:8554/teststream")
+			require.NoError(t, err)
+
+			err = c.Wait()
+
+			switch transport {
+			case "udp", "auto":
+				require.EqualError(t, err, "UDP timeout")
+
+			case "tcp":
+				require.EqualError(t, err, "TCP timeout")
+			}
+		})
+	}
+}
+
+func TestClientReadIgnoreTCPInvalidTrack(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+		require.NoError(t, err)
+
+		tracks := Tracks{track}
+		tracks.setControls()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+			},
+			Body: tracks.Write(false),
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var inTH headers.Transport
+		err = inTH.Read(req.Header["Transport"])
+		require.NoError(t, err)
+
+		th := headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+		}
+		th.Protocol = headers.TransportProtocolTCP
+		th.InterleavedIDs = inTH.InterleavedIDs
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": th.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		byts, _ = base.InterleavedFrame{
+			Channel: 6,
+			Payload: testRTPPacketMarshaled,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		byts, _ = base.InterleavedFrame{
+			Channel: 0,
+			Payload: testRTPPacketMarshaled,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}()
+
+	recv := make(chan struct{})
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportTCP
+			return &v
+		}(),
+		OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
+			close(recv)
+		},
+	}
+
+	err = c.StartReading("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer c.Close()
+
+	<-recv
+}
+
+func TestClientReadSeek(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+		require.NoError(t, err)
+
+		tracks := Tracks{track}
+		tracks.setControls()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+			},
+			Body: tracks.Write(false),
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var inTH headers.Transport
+		err = inTH.Read(req.Header["Transport"])
+		require.NoError(t, err)
+
+		th := headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Protocol:       headers.TransportProtocolTCP,
+			InterleavedIDs: inTH.InterleavedIDs,
+		}
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": th.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		var ra headers.Range
+		err = ra.Read(req.Header["Range"])
+		require.NoError(t, err)
+		require.Equal(t, headers.Range{
+			Value: &headers.RangeNPT{
+				Start: headers.RangeNPTTime(5500 * time.Millisecond),
+			},
+		}, ra)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Pause, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		err = ra.Read(req.Header["Range"])
+		require.NoError(t, err)
+		require.Equal(t, headers.Range{
+			Value: &headers.RangeNPT{
+				Start: headers.RangeNPTTime(6400 * time.Millisecond),
+			},
+		}, ra)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}()
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportTCP
+			return &v
+		}(),
+	}
+
+	u, err := url.Parse("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	err = c.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer c.Close()
+
+	tracks, baseURL, _, err := c.Describe(u)
+	require.NoError(t, err)
+
+	for _, track := range tracks {
+		_, err := c.Setup(true, track, baseURL, 0, 0)
+		require.NoError(t, err)
+	}
+
+	_, err = c.Play(&headers.Range{
+		Value: &headers.RangeNPT{
+			Start: headers.RangeNPTTime(5500 * time.Millisecond),
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = c.Seek(&headers.Range{
+		Value: &headers.RangeNPT{
+			Start: headers.RangeNPTTime(6400 * time.Millisecond),
+		},
+	})
+	require.NoError(t, err)
+}
+
+func TestClientReadPlayScaleSpeed(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+		require.NoError(t, err)
+
+		tracks := Tracks{track}
+		tracks.setControls()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+			},
+			Body: tracks.Write(false),
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var inTH headers.Transport
+		err = inTH.Read(req.Header["Transport"])
+		require.NoError(t, err)
+
+		th := headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Protocol:       headers.TransportProtocolTCP,
+			InterleavedIDs: inTH.InterleavedIDs,
+		}
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": th.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		require.Equal(t, base.HeaderValue{"-2"}, req.Header["Scale"])
+		require.Equal(t, base.HeaderValue{"1.5"}, req.Header["Speed"])
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Scale": base.HeaderValue{"-2"},
+				// invalid granted rates are ignored
+				"Speed": base.HeaderValue{"invalid"},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequestIgnoreFrames(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Pause, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		// rates granted by the server are kept when seeking
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+		require.Equal(t, base.HeaderValue{"npt=5-"}, req.Header["Range"])
+		require.Equal(t, base.HeaderValue{"-2"}, req.Header["Scale"])
+		require.Equal(t, base.HeaderValue{"1.5"}, req.Header["Speed"])
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}()
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportTCP
+			return &v
+		}(),
+	}
+
+	u, err := url.Parse("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	err = c.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer c.Close()
+
+	tracks, baseURL, _, err := c.Describe(u)
+	require.NoError(t, err)
+
+	for _, track := range tracks {
+		_, err := c.Setup(true, track, baseURL, 0, 0)
+		require.NoError(t, err)
+	}
+
+	scale := headers.Scale(-2)
+	speed := headers.Speed(1.5)
+
+	res, err := c.PlayWithOptions(nil, &scale, &speed)
+	require.NoError(t, err)
+
+	var grantedScale headers.Scale
+	err = grantedScale.Read(res.Header["Scale"])
+	require.NoError(t, err)
+	require.Equal(t, headers.Scale(-2), grantedScale)
+
+	_, err = c.Seek(&headers.Range{
+		Value: &headers.RangeNPT{
+			Start: headers.RangeNPTTime(5 * time.Second),
+		},
+	})
+	require.NoError(t, err)
+}
+
+func TestClientReadRTSP2(t *testing.T) {
+	packetRecv := make(chan struct{})
+	notifyDone := make(chan struct{})
+
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+		require.Equal(t, base.Version20, req.Version)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"CSeq": req.Header["CSeq"],
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+			Version: base.Version20,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+		require.Equal(t, base.Version20, req.Version)
+
+		track, err := NewTrackGeneric("application", []string{"97"}, "97 private/90000", "")
+		require.NoError(t, err)
+
+		tracks := Tracks{track}
+		tracks.setControls()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"CSeq":         req.Header["CSeq"],
+				"Content-Type": base.HeaderValue{"application/sdp"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+			},
+			Body:    tracks.Write(false),
+			Version: base.Version20,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+		require.Equal(t, base.Version20, req.Version)
+
+		var inTH headers.Transport
+		err = inTH.Read(req.Header["Transport"])
+		require.NoError(t, err)
+		require.Nil(t, inTH.ClientPorts)
+		require.NotNil(t, inTH.DestAddrs)
+
+		l1, err := net.ListenPacket("udp", "localhost:34556")
+		require.NoError(t, err)
+		defer l1.Close()
+
+		l2, err := net.ListenPacket("udp", "localhost:34557")
+		require.NoError(t, err)
+		defer l2.Close()
+
+		v := headers.TransportDeliveryUnicast
+		th := headers.Transport{
+			Protocol: headers.TransportProtocolUDP,
+			Delivery: &v,
+			DestAddrs: &[2]*net.UDPAddr{
+				{IP: net.ParseIP("127.0.0.1"), Port: inTH.DestAddrs[0].Port},
+				{IP: net.ParseIP("127.0.0.1"), Port: inTH.DestAddrs[1].Port},
+			},
+			SrcAddrs: &[2]*net.UDPAddr{
+				{IP: net.ParseIP("127.0.0.1"), Port: 34556},
+				{IP: net.ParseIP("127.0.0.1"), Port: 34557},
+			},
+		}
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"CSeq":      req.Header["CSeq"],
+				"Transport": th.Write(),
+				"Session":   base.HeaderValue{"ABCDEF"},
+			},
+			Version: base.Version20,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+		require.Equal(t, base.Version20, req.Version)
+		require.Equal(t, base.HeaderValue{"ABCDEF"}, req.Header["Session"])
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"CSeq": req.Header["CSeq"],
+			},
+			Version: base.Version20,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		time.Sleep(500 * time.Millisecond)
+
+		l1.WriteTo(testRTPPacketMarshaled, &net.UDPAddr{
+			IP:   net.ParseIP("127.0.0.1"),
+			Port: th.DestAddrs[0].Port,
+		})
+
+		<-packetRecv
+
+		byts, _ = base.Request{
+			Method: base.PlayNotify,
+			URL:    mustParseURL("rtsp://localhost:8554/teststream/"),
+			Header: base.Header{
+				"CSeq":          base.HeaderValue{"1"},
+				"Session":       base.HeaderValue{"ABCDEF"},
+				"Notify-Reason": headers.NotifyReasonEndOfStream.Write(),
+			},
+			Version: base.Version20,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		var res base.Response
+		err = res.Read(br)
+		require.NoError(t, err)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+		require.Equal(t, base.Version20, res.Version)
+		require.Equal(t, base.HeaderValue{"1"}, res.Header["CSeq"])
+		close(notifyDone)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+		require.Equal(t, base.Version20, req.Version)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"CSeq": req.Header["CSeq"],
+			},
+			Version: base.Version20,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}()
+
+	c := &Client{
+		RTSP2Enable: true,
+		Transport: func() *Transport {
+			v := TransportUDP
+			return &v
+		}(),
+	}
+
+	c.OnPacketRTP = func(ctx *ClientOnPacketRTPCtx) {
+		close(packetRecv)
+	}
+
+	err = c.StartReading("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer c.Close()
+
+	<-notifyDone
+}
+
+func TestClientReadKeepaliveFromSession(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	keepaliveOk := make(chan struct{})
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		req, err := readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		byts, _ := base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+		require.NoError(t, err)
+
+		tracks := Tracks{track}
+		tracks.setControls()
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+			},
+			Body: tracks.Write(false),
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var inTH headers.Transport
+		err = inTH.Read(req.Header["Transport"])
+		require.NoError(t, err)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Protocol: headers.TransportProtocolUDP,
+					Delivery: func() *headers.TransportDelivery {
+						v := headers.TransportDeliveryUnicast
+						return &v
+					}(),
+					ClientPorts: inTH.ClientPorts,
+					ServerPorts: &[2]int{34556, 34557},
+				}.Write(),
+				"Session": headers.Session{
+					Session: "ABCDE",
+					Timeout: func() *uint {
+						v := uint(1)
+						return &v
+					}(),
+				}.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+
+		recv := make(chan struct{})
+		go func() {
+			defer close(recv)
+			req, err = readRequest(br)
+			require.NoError(t, err)
+			require.Equal(t, base.Options, req.Method)
+
+			byts, _ := base.Response{
+				StatusCode: base.StatusOK,
+			}.Write()
+			_, err = conn.Write(byts)
+			require.NoError(t, err)
+		}()
+
+		select {
+		case <-recv:
+		case <-time.After(3 * time.Second):
+			t.Errorf("should not happen")
+		}
+
+		close(keepaliveOk)
+	}()
+
+	c := &Client{}
+
+	err = c.StartReading("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer c.Close()
+
+	<-keepaliveOk
+}
+
+func TestClientReadParameters(t *testing.T) {
+	for _, transport := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(transport, func(t *testing.T) {
+			l, err := net.Listen("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer l.Close()
+
+			serverRequestHandled := make(chan struct{})
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := l.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
 				br := bufio.NewReader(conn)
 
 				req, err := readRequest(br)
@@ -2012,6 +4060,7 @@ func TestClientReadErrorTimeout(t *testing.T) {
 							string(base.Describe),
 							string(base.Setup),
 							string(base.Play),
+							string(base.GetParameter),
 						}, ", ")},
 					},
 				}.Write()
@@ -2054,16 +4103,10 @@ func TestClientReadErrorTimeout(t *testing.T) {
 					}(),
 				}
 
-				var l1 net.PacketConn
-				if transport == "udp" || transport == "auto" {
-					var err error
-					l1, err = net.ListenPacket("udp", "localhost:34556")
-					require.NoError(t, err)
-					defer l1.Close()
-
+				if transport == "udp" {
 					th.Protocol = headers.TransportProtocolUDP
-					th.ServerPorts = &[2]int{34556, 34557}
 					th.ClientPorts = inTH.ClientPorts
+					th.ServerPorts = &[2]int{34556, 34557}
 				} else {
 					th.Protocol = headers.TransportProtocolTCP
 					th.InterleavedIDs = inTH.InterleavedIDs
@@ -2088,14 +4131,51 @@ func TestClientReadErrorTimeout(t *testing.T) {
 				_, err = conn.Write(byts)
 				require.NoError(t, err)
 
-				if transport == "udp" || transport == "auto" {
-					// write a packet to skip the protocol autodetection feature
-					l1.WriteTo(testRTPPacketMarshaled, &net.UDPAddr{
-						IP:   net.ParseIP("127.0.0.1"),
-						Port: th.ClientPorts[0],
-					})
+				byts, _ = base.Request{
+					Method: base.SetParameter,
+					URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+					Header: base.Header{
+						"CSeq":         base.HeaderValue{"1"},
+						"Content-Type": base.HeaderValue{"text/parameters"},
+					},
+					Body: []byte("bitrate: 1000\r\n"),
+				}.Write()
+				_, err = conn.Write(byts)
+				require.NoError(t, err)
+
+				var res base.Response
+				err = res.Read(br)
+				require.NoError(t, err)
+				require.Equal(t, base.StatusOK, res.StatusCode)
+				require.Equal(t, base.HeaderValue{"1"}, res.Header["CSeq"])
+
+				close(serverRequestHandled)
+
+				req, err = readRequest(br)
+				require.NoError(t, err)
+				require.Equal(t, base.GetParameter, req.Method)
+				require.Equal(t, []byte("packets_received\r\n"), req.Body)
+
+				if transport == "tcp" {
+					byts, _ = base.InterleavedFrame{
+						Channel: 0,
+						Payload: testRTPPacketMarshaled,
+					}.Write()
+					_, err = conn.Write(byts)
+					require.NoError(t, err)
 				}
 
+				byts, _ = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"CSeq":         req.Header["CSeq"],
+						"Content-Type": base.HeaderValue{"text/parameters"},
+					},
+					Body: []byte("packets_received: 10\r\n"),
+				}.Write()
+				_, err = conn.Write(byts)
+				require.NoError(t, err)
+
 				req, err = readRequest(br)
 				require.NoError(t, err)
 				require.Equal(t, base.Teardown, req.Method)
@@ -2109,47 +4189,52 @@ func TestClientReadErrorTimeout(t *testing.T) {
 
 			c := &Client{
 				Transport: func() *Transport {
-					switch transport {
-					case "udp":
+					if transport == "udp" {
 						v := TransportUDP
 						return &v
-
-					case "tcp":
-						v := TransportTCP
-						return &v
 					}
-					return nil
+					v := TransportTCP
+					return &v
 				}(),
-				InitialUDPReadTimeout: 1 * time.Second,
-				ReadTimeout:           1 * time.Second,
+				OnServerRequest: func(req *base.Request) *base.Response {
+					require.Equal(t, base.SetParameter, req.Method)
+					require.Equal(t, []byte("bitrate: 1000\r\n"), req.Body)
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}
+				},
 			}
 
 			err = c.StartReading("rtsp://localhost:8554/teststream")
 			require.NoError(t, err)
+			defer c.Close()
 
-			err = c.Wait()
-
-			switch transport {
-			case "udp", "auto":
-				require.EqualError(t, err, "UDP timeout")
+			<-serverRequestHandled
 
-			case "tcp":
-				require.EqualError(t, err, "TCP timeout")
-			}
+			params, _, err := c.GetParameter(mustParseURL("rtsp://localhost:8554/teststream/"),
+				[]string{"packets_received"})
+			require.NoError(t, err)
+			require.Equal(t, parameters.Parameters{"packets_received": "10"}, params)
 		})
 	}
 }
 
-func TestClientReadIgnoreTCPInvalidTrack(t *testing.T) {
-	l, err := net.Listen("tcp", "localhost:8554")
+func TestClientReadRedirectRequest(t *testing.T) {
+	l1, err := net.Listen("tcp", "localhost:8554")
 	require.NoError(t, err)
-	defer l.Close()
+	defer l1.Close()
 
-	serverDone := make(chan struct{})
-	defer func() { <-serverDone }()
-	go func() {
-		defer close(serverDone)
+	l2, err := net.Listen("tcp", "localhost:8555")
+	require.NoError(t, err)
+	defer l2.Close()
+
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	tracks := Tracks{track}
+	tracks.setControls()
 
+	serve := func(l net.Listener, host string, path string, redirect bool) {
 		conn, err := l.Accept()
 		require.NoError(t, err)
 		defer conn.Close()
@@ -2175,18 +4260,13 @@ func TestClientReadIgnoreTCPInvalidTrack(t *testing.T) {
 		req, err = readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Describe, req.Method)
-
-		track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
-		require.NoError(t, err)
-
-		tracks := Tracks{track}
-		tracks.setControls()
+		require.Equal(t, mustParseURL("rtsp://"+host+path), req.URL)
 
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
 			Header: base.Header{
 				"Content-Type": base.HeaderValue{"application/sdp"},
-				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+				"Content-Base": base.HeaderValue{"rtsp://" + host + path + "/"},
 			},
 			Body: tracks.Write(false),
 		}.Write()
@@ -2196,24 +4276,19 @@ func TestClientReadIgnoreTCPInvalidTrack(t *testing.T) {
 		req, err = readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Setup, req.Method)
-
-		var inTH headers.Transport
-		err = inTH.Read(req.Header["Transport"])
-		require.NoError(t, err)
-
-		th := headers.Transport{
-			Delivery: func() *headers.TransportDelivery {
-				v := headers.TransportDeliveryUnicast
-				return &v
-			}(),
-		}
-		th.Protocol = headers.TransportProtocolTCP
-		th.InterleavedIDs = inTH.InterleavedIDs
+		require.Equal(t, mustParseURL("rtsp://"+host+path+"/trackID=0"), req.URL)
 
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
 			Header: base.Header{
-				"Transport": th.Write(),
+				"Transport": headers.Transport{
+					Protocol: headers.TransportProtocolTCP,
+					Delivery: func() *headers.TransportDelivery {
+						v := headers.TransportDeliveryUnicast
+						return &v
+					}(),
+					InterleavedIDs: &[2]int{0, 1},
+				}.Write(),
 			},
 		}.Write()
 		_, err = conn.Write(byts)
@@ -2222,6 +4297,11 @@ func TestClientReadIgnoreTCPInvalidTrack(t *testing.T) {
 		req, err = readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Play, req.Method)
+		if redirect {
+			require.Equal(t, base.HeaderValue{"npt=0-"}, req.Header["Range"])
+		} else {
+			require.Equal(t, base.HeaderValue{"npt=5-"}, req.Header["Range"])
+		}
 
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
@@ -2229,32 +4309,62 @@ func TestClientReadIgnoreTCPInvalidTrack(t *testing.T) {
 		_, err = conn.Write(byts)
 		require.NoError(t, err)
 
-		byts, _ = base.InterleavedFrame{
-			Channel: 6,
-			Payload: testRTPPacketMarshaled,
-		}.Write()
-		_, err = conn.Write(byts)
-		require.NoError(t, err)
+		if redirect {
+			byts, _ = base.Request{
+				Method: base.Redirect,
+				URL:    mustParseURL("rtsp://" + host + path),
+				Header: base.Header{
+					"CSeq":     base.HeaderValue{"1"},
+					"Location": base.HeaderValue{"rtsp://localhost:8555/teststream2"},
+					"Range":    base.HeaderValue{"npt=5-"},
+				},
+			}.Write()
+			_, err = conn.Write(byts)
+			require.NoError(t, err)
 
-		byts, _ = base.InterleavedFrame{
-			Channel: 0,
-			Payload: testRTPPacketMarshaled,
-		}.Write()
-		_, err = conn.Write(byts)
-		require.NoError(t, err)
+			var res base.Response
+			err = res.Read(br)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+			require.Equal(t, base.HeaderValue{"1"}, res.Header["CSeq"])
+		} else {
+			byts, _ = base.InterleavedFrame{
+				Channel: 0,
+				Payload: testRTPPacketMarshaled,
+			}.Write()
+			_, err = conn.Write(byts)
+			require.NoError(t, err)
+		}
 
-		req, err = readRequest(br)
+		req, err = readRequestIgnoreFrames(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Teardown, req.Method)
+		require.Equal(t, mustParseURL("rtsp://"+host+path+"/"), req.URL)
 
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
 		}.Write()
 		_, err = conn.Write(byts)
 		require.NoError(t, err)
+	}
+
+	// the previous session is torn down after the new one has been set up,
+	// therefore servers must run in parallel.
+	server1Done := make(chan struct{})
+	defer func() { <-server1Done }()
+	go func() {
+		defer close(server1Done)
+		serve(l1, "localhost:8554", "/teststream", true)
 	}()
 
-	recv := make(chan struct{})
+	server2Done := make(chan struct{})
+	defer func() { <-server2Done }()
+	go func() {
+		defer close(server2Done)
+		serve(l2, "localhost:8555", "/teststream2", false)
+	}()
+
+	packetRecv := make(chan struct{})
 
 	c := &Client{
 		Transport: func() *Transport {
@@ -2262,7 +4372,9 @@ func TestClientReadIgnoreTCPInvalidTrack(t *testing.T) {
 			return &v
 		}(),
 		OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
-			close(recv)
+			require.Equal(t, 0, ctx.TrackID)
+			require.Equal(t, &testRTPPacket, ctx.Packet)
+			close(packetRecv)
 		},
 	}
 
@@ -2270,20 +4382,30 @@ func TestClientReadIgnoreTCPInvalidTrack(t *testing.T) {
 	require.NoError(t, err)
 	defer c.Close()
 
-	<-recv
+	<-packetRecv
 }
 
-func TestClientReadSeek(t *testing.T) {
-	l, err := net.Listen("tcp", "localhost:8554")
+func TestClientReadRedirectRequestFailed(t *testing.T) {
+	l1, err := net.Listen("tcp", "localhost:8554")
 	require.NoError(t, err)
-	defer l.Close()
+	defer l1.Close()
 
-	serverDone := make(chan struct{})
-	defer func() { <-serverDone }()
+	l2, err := net.Listen("tcp", "localhost:8555")
+	require.NoError(t, err)
+	defer l2.Close()
+
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	tracks := Tracks{track}
+	tracks.setControls()
+
+	server1Done := make(chan struct{})
+	defer func() { <-server1Done }()
 	go func() {
-		defer close(serverDone)
+		defer close(server1Done)
 
-		conn, err := l.Accept()
+		conn, err := l1.Accept()
 		require.NoError(t, err)
 		defer conn.Close()
 		br := bufio.NewReader(conn)
@@ -2309,12 +4431,6 @@ func TestClientReadSeek(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, base.Describe, req.Method)
 
-		track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
-		require.NoError(t, err)
-
-		tracks := Tracks{track}
-		tracks.setControls()
-
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
 			Header: base.Header{
@@ -2330,40 +4446,53 @@ func TestClientReadSeek(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, base.Setup, req.Method)
 
-		var inTH headers.Transport
-		err = inTH.Read(req.Header["Transport"])
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Protocol: headers.TransportProtocolTCP,
+					Delivery: func() *headers.TransportDelivery {
+						v := headers.TransportDeliveryUnicast
+						return &v
+					}(),
+					InterleavedIDs: &[2]int{0, 1},
+				}.Write(),
+			},
+		}.Write()
+		_, err = conn.Write(byts)
 		require.NoError(t, err)
 
-		th := headers.Transport{
-			Delivery: func() *headers.TransportDelivery {
-				v := headers.TransportDeliveryUnicast
-				return &v
-			}(),
-			Protocol:       headers.TransportProtocolTCP,
-			InterleavedIDs: inTH.InterleavedIDs,
-		}
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
 
-		byts, _ = base.Response{
-			StatusCode: base.StatusOK,
+		byts, _ = base.Request{
+			Method: base.Redirect,
+			URL:    mustParseURL("rtsp://localhost:8554/teststream"),
 			Header: base.Header{
-				"Transport": th.Write(),
+				"CSeq":     base.HeaderValue{"1"},
+				"Location": base.HeaderValue{"rtsp://localhost:8555/teststream2"},
 			},
 		}.Write()
 		_, err = conn.Write(byts)
 		require.NoError(t, err)
 
-		req, err = readRequest(br)
+		var res base.Response
+		err = res.Read(br)
 		require.NoError(t, err)
-		require.Equal(t, base.Play, req.Method)
+		require.Equal(t, base.StatusOK, res.StatusCode)
 
-		var ra headers.Range
-		err = ra.Read(req.Header["Range"])
+		// the new session can't be set up: the previous one is resumed
+		req, err = readRequest(br)
 		require.NoError(t, err)
-		require.Equal(t, headers.Range{
-			Value: &headers.RangeNPT{
-				Start: headers.RangeNPTTime(5500 * time.Millisecond),
-			},
-		}, ra)
+		require.Equal(t, base.Play, req.Method)
+		require.Equal(t, mustParseURL("rtsp://localhost:8554/teststream/"), req.URL)
 
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
@@ -2371,29 +4500,39 @@ func TestClientReadSeek(t *testing.T) {
 		_, err = conn.Write(byts)
 		require.NoError(t, err)
 
-		req, err = readRequest(br)
+		byts, _ = base.InterleavedFrame{
+			Channel: 0,
+			Payload: testRTPPacketMarshaled,
+		}.Write()
+		_, err = conn.Write(byts)
 		require.NoError(t, err)
-		require.Equal(t, base.Pause, req.Method)
+
+		req, err = readRequestIgnoreFrames(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
 
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
 		}.Write()
 		_, err = conn.Write(byts)
 		require.NoError(t, err)
+	}()
 
-		req, err = readRequest(br)
+	server2Done := make(chan struct{})
+	defer func() { <-server2Done }()
+	go func() {
+		defer close(server2Done)
+
+		conn, err := l2.Accept()
 		require.NoError(t, err)
-		require.Equal(t, base.Play, req.Method)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
 
-		err = ra.Read(req.Header["Range"])
+		req, err := readRequest(br)
 		require.NoError(t, err)
-		require.Equal(t, headers.Range{
-			Value: &headers.RangeNPT{
-				Start: headers.RangeNPTTime(6400 * time.Millisecond),
-			},
-		}, ra)
+		require.Equal(t, base.Options, req.Method)
 
-		byts, _ = base.Response{
+		byts, _ := base.Response{
 			StatusCode: base.StatusOK,
 		}.Write()
 		_, err = conn.Write(byts)
@@ -2401,59 +4540,51 @@ func TestClientReadSeek(t *testing.T) {
 
 		req, err = readRequest(br)
 		require.NoError(t, err)
-		require.Equal(t, base.Teardown, req.Method)
+		require.Equal(t, base.Describe, req.Method)
 
 		byts, _ = base.Response{
-			StatusCode: base.StatusOK,
+			StatusCode: base.StatusNotFound,
 		}.Write()
 		_, err = conn.Write(byts)
 		require.NoError(t, err)
+
+		_, err = readRequest(br)
+		require.Error(t, err)
 	}()
 
+	packetRecv := make(chan struct{})
+	redirectErr := make(chan error, 1)
+
 	c := &Client{
 		Transport: func() *Transport {
 			v := TransportTCP
 			return &v
 		}(),
+		OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
+			close(packetRecv)
+		},
+		OnRedirectError: func(err error) {
+			redirectErr <- err
+		},
 	}
 
-	u, err := url.Parse("rtsp://localhost:8554/teststream")
-	require.NoError(t, err)
-
-	err = c.Start(u.Scheme, u.Host)
+	err = c.StartReading("rtsp://localhost:8554/teststream")
 	require.NoError(t, err)
 	defer c.Close()
 
-	tracks, baseURL, _, err := c.Describe(u)
-	require.NoError(t, err)
-
-	for _, track := range tracks {
-		_, err := c.Setup(true, track, baseURL, 0, 0)
-		require.NoError(t, err)
-	}
-
-	_, err = c.Play(&headers.Range{
-		Value: &headers.RangeNPT{
-			Start: headers.RangeNPTTime(5500 * time.Millisecond),
-		},
-	})
-	require.NoError(t, err)
+	err = <-redirectErr
+	require.IsType(t, liberrors.ErrClientRedirectFailed{}, err)
 
-	_, err = c.Seek(&headers.Range{
-		Value: &headers.RangeNPT{
-			Start: headers.RangeNPTTime(6400 * time.Millisecond),
-		},
-	})
-	require.NoError(t, err)
+	<-packetRecv
 }
 
-func TestClientReadKeepaliveFromSession(t *testing.T) {
+func TestClientReadDifferentSource(t *testing.T) {
+	packetRecv := make(chan struct{})
+
 	l, err := net.Listen("tcp", "localhost:8554")
 	require.NoError(t, err)
 	defer l.Close()
 
-	keepaliveOk := make(chan struct{})
-
 	serverDone := make(chan struct{})
 	defer func() { <-serverDone }()
 	go func() {
@@ -2467,6 +4598,7 @@ func TestClientReadKeepaliveFromSession(t *testing.T) {
 		req, err := readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Options, req.Method)
+		require.Equal(t, mustParseURL("rtsp://localhost:8554/test/stream?param=value"), req.URL)
 
 		byts, _ := base.Response{
 			StatusCode: base.StatusOK,
@@ -2484,6 +4616,7 @@ func TestClientReadKeepaliveFromSession(t *testing.T) {
 		req, err = readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Describe, req.Method)
+		require.Equal(t, mustParseURL("rtsp://localhost:8554/test/stream?param=value"), req.URL)
 
 		track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
 		require.NoError(t, err)
@@ -2495,7 +4628,7 @@ func TestClientReadKeepaliveFromSession(t *testing.T) {
 			StatusCode: base.StatusOK,
 			Header: base.Header{
 				"Content-Type": base.HeaderValue{"application/sdp"},
-				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/test/stream?param=value/"},
 			},
 			Body: tracks.Write(false),
 		}.Write()
@@ -2505,30 +4638,38 @@ func TestClientReadKeepaliveFromSession(t *testing.T) {
 		req, err = readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Setup, req.Method)
+		require.Equal(t, mustParseURL("rtsp://localhost:8554/test/stream?param=value/trackID=0"), req.URL)
 
 		var inTH headers.Transport
 		err = inTH.Read(req.Header["Transport"])
 		require.NoError(t, err)
 
+		th := headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Protocol:    headers.TransportProtocolUDP,
+			ClientPorts: inTH.ClientPorts,
+			ServerPorts: &[2]int{34556, 34557},
+			Source: func() *net.IP {
+				i := net.ParseIP("127.0.1.1")
+				return &i
+			}(),
+		}
+
+		l1, err := net.ListenPacket("udp", "127.0.1.1:34556")
+		require.NoError(t, err)
+		defer l1.Close()
+
+		l2, err := net.ListenPacket("udp", "127.0.1.1:34557")
+		require.NoError(t, err)
+		defer l2.Close()
+
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
 			Header: base.Header{
-				"Transport": headers.Transport{
-					Protocol: headers.TransportProtocolUDP,
-					Delivery: func() *headers.TransportDelivery {
-						v := headers.TransportDeliveryUnicast
-						return &v
-					}(),
-					ClientPorts: inTH.ClientPorts,
-					ServerPorts: &[2]int{34556, 34557},
-				}.Write(),
-				"Session": headers.Session{
-					Session: "ABCDE",
-					Timeout: func() *uint {
-						v := uint(1)
-						return &v
-					}(),
-				}.Write(),
+				"Transport": th.Write(),
 			},
 		}.Write()
 		_, err = conn.Write(byts)
@@ -2537,6 +4678,8 @@ func TestClientReadKeepaliveFromSession(t *testing.T) {
 		req, err = readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Play, req.Method)
+		require.Equal(t, mustParseURL("rtsp://localhost:8554/test/stream?param=value/"), req.URL)
+		require.Equal(t, base.HeaderValue{"npt=0-"}, req.Header["Range"])
 
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
@@ -2544,41 +4687,49 @@ func TestClientReadKeepaliveFromSession(t *testing.T) {
 		_, err = conn.Write(byts)
 		require.NoError(t, err)
 
-		recv := make(chan struct{})
-		go func() {
-			defer close(recv)
-			req, err = readRequest(br)
-			require.NoError(t, err)
-			require.Equal(t, base.Options, req.Method)
-
-			byts, _ := base.Response{
-				StatusCode: base.StatusOK,
-			}.Write()
-			_, err = conn.Write(byts)
-			require.NoError(t, err)
-		}()
+		// server -> client (RTP)
+		time.Sleep(1 * time.Second)
+		l1.WriteTo(testRTPPacketMarshaled, &net.UDPAddr{
+			IP:   net.ParseIP("127.0.0.1"),
+			Port: th.ClientPorts[0],
+		})
 
-		select {
-		case <-recv:
-		case <-time.After(3 * time.Second):
-			t.Errorf("should not happen")
-		}
+		req, err = readRequest(br)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+		require.Equal(t, mustParseURL("rtsp://localhost:8554/test/stream?param=value/"), req.URL)
 
-		close(keepaliveOk)
+		byts, _ = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
 	}()
 
-	c := &Client{}
+	c := &Client{
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+		Transport: func() *Transport {
+			v := TransportUDP
+			return &v
+		}(),
+	}
 
-	err = c.StartReading("rtsp://localhost:8554/teststream")
+	c.OnPacketRTP = func(ctx *ClientOnPacketRTPCtx) {
+		require.Equal(t, 0, ctx.TrackID)
+		require.Equal(t, &testRTPPacket, ctx.Packet)
+		close(packetRecv)
+	}
+
+	err = c.StartReading("rtsp://localhost:8554/test/stream?param=value")
 	require.NoError(t, err)
 	defer c.Close()
 
-	<-keepaliveOk
+	<-packetRecv
 }
 
-func TestClientReadDifferentSource(t *testing.T) {
-	packetRecv := make(chan struct{})
-
+func TestClientReadRTCPMux(t *testing.T) {
 	l, err := net.Listen("tcp", "localhost:8554")
 	require.NoError(t, err)
 	defer l.Close()
@@ -2596,7 +4747,6 @@ func TestClientReadDifferentSource(t *testing.T) {
 		req, err := readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Options, req.Method)
-		require.Equal(t, mustParseURL("rtsp://localhost:8554/test/stream?param=value"), req.URL)
 
 		byts, _ := base.Response{
 			StatusCode: base.StatusOK,
@@ -2614,10 +4764,9 @@ func TestClientReadDifferentSource(t *testing.T) {
 		req, err = readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Describe, req.Method)
-		require.Equal(t, mustParseURL("rtsp://localhost:8554/test/stream?param=value"), req.URL)
 
-		track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
-		require.NoError(t, err)
+		track := NewTrackPCMU()
+		track.SetRTCPMux(true)
 
 		tracks := Tracks{track}
 		tracks.setControls()
@@ -2626,7 +4775,7 @@ func TestClientReadDifferentSource(t *testing.T) {
 			StatusCode: base.StatusOK,
 			Header: base.Header{
 				"Content-Type": base.HeaderValue{"application/sdp"},
-				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/test/stream?param=value/"},
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/"},
 			},
 			Body: tracks.Write(false),
 		}.Write()
@@ -2636,11 +4785,15 @@ func TestClientReadDifferentSource(t *testing.T) {
 		req, err = readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Setup, req.Method)
-		require.Equal(t, mustParseURL("rtsp://localhost:8554/test/stream?param=value/trackID=0"), req.URL)
 
 		var inTH headers.Transport
 		err = inTH.Read(req.Header["Transport"])
 		require.NoError(t, err)
+		require.Equal(t, true, inTH.RTCPMux)
+
+		l1, err := net.ListenPacket("udp", "localhost:34556")
+		require.NoError(t, err)
+		defer l1.Close()
 
 		th := headers.Transport{
 			Delivery: func() *headers.TransportDelivery {
@@ -2648,22 +4801,11 @@ func TestClientReadDifferentSource(t *testing.T) {
 				return &v
 			}(),
 			Protocol:    headers.TransportProtocolUDP,
-			ClientPorts: inTH.ClientPorts,
-			ServerPorts: &[2]int{34556, 34557},
-			Source: func() *net.IP {
-				i := net.ParseIP("127.0.1.1")
-				return &i
-			}(),
+			ClientPorts: &[2]int{inTH.ClientPorts[0], inTH.ClientPorts[0]},
+			ServerPorts: &[2]int{34556, 34556},
+			RTCPMux:     true,
 		}
 
-		l1, err := net.ListenPacket("udp", "127.0.1.1:34556")
-		require.NoError(t, err)
-		defer l1.Close()
-
-		l2, err := net.ListenPacket("udp", "127.0.1.1:34557")
-		require.NoError(t, err)
-		defer l2.Close()
-
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
 			Header: base.Header{
@@ -2676,8 +4818,6 @@ func TestClientReadDifferentSource(t *testing.T) {
 		req, err = readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Play, req.Method)
-		require.Equal(t, mustParseURL("rtsp://localhost:8554/test/stream?param=value/"), req.URL)
-		require.Equal(t, base.HeaderValue{"npt=0-"}, req.Header["Range"])
 
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
@@ -2685,17 +4825,44 @@ func TestClientReadDifferentSource(t *testing.T) {
 		_, err = conn.Write(byts)
 		require.NoError(t, err)
 
-		// server -> client (RTP)
-		time.Sleep(1 * time.Second)
-		l1.WriteTo(testRTPPacketMarshaled, &net.UDPAddr{
+		// skip firewall opening, that is performed on the RTP port only
+		buf := make([]byte, 2048)
+		_, _, err = l1.ReadFrom(buf)
+		require.NoError(t, err)
+
+		// RTP and RTCP packets are sent to the same port
+		byts, _ = (&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    0,
+				SequenceNumber: 10,
+				Timestamp:      54352,
+				SSRC:           753621,
+			},
+			Payload: []byte{0x01, 0x02, 0x03, 0x04},
+		}).Marshal()
+		_, err = l1.WriteTo(byts, &net.UDPAddr{
+			IP:   net.ParseIP("127.0.0.1"),
+			Port: th.ClientPorts[0],
+		})
+		require.NoError(t, err)
+
+		byts, _ = (&rtcp.SenderReport{
+			SSRC:        753621,
+			NTPTime:     0xe44ac5cc00000000,
+			RTPTime:     54352,
+			PacketCount: 1,
+			OctetCount:  4,
+		}).Marshal()
+		_, err = l1.WriteTo(byts, &net.UDPAddr{
 			IP:   net.ParseIP("127.0.0.1"),
 			Port: th.ClientPorts[0],
 		})
+		require.NoError(t, err)
 
 		req, err = readRequest(br)
 		require.NoError(t, err)
 		require.Equal(t, base.Teardown, req.Method)
-		require.Equal(t, mustParseURL("rtsp://localhost:8554/test/stream?param=value/"), req.URL)
 
 		byts, _ = base.Response{
 			StatusCode: base.StatusOK,
@@ -2704,25 +4871,30 @@ func TestClientReadDifferentSource(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
+	packetRecv := make(chan struct{})
+	rtcpRecv := make(chan struct{})
+
 	c := &Client{
-		TLSConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
 		Transport: func() *Transport {
 			v := TransportUDP
 			return &v
 		}(),
+		OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
+			require.Equal(t, uint16(10), ctx.Packet.SequenceNumber)
+			close(packetRecv)
+		},
+		OnPacketRTCP: func(ctx *ClientOnPacketRTCPCtx) {
+			_, ok := ctx.Packet.(*rtcp.SenderReport)
+			require.True(t, ok)
+			close(rtcpRecv)
+		},
 	}
 
-	c.OnPacketRTP = func(ctx *ClientOnPacketRTPCtx) {
-		require.Equal(t, 0, ctx.TrackID)
-		require.Equal(t, &testRTPPacket, ctx.Packet)
-		close(packetRecv)
-	}
-
-	err = c.StartReading("rtsp://localhost:8554/test/stream?param=value")
+	err = c.StartReading("rtsp://localhost:8554/teststream")
 	require.NoError(t, err)
-	defer c.Close()
 
 	<-packetRecv
+	<-rtcpRecv
+
+	c.Close()
 }
@@ -0,0 +1,255 @@
+package gortsplib
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	psdp "github.com/pion/sdp/v3"
+
+	"github.com/aler9/gortsplib/pkg/liberrors"
+	"github.com/aler9/gortsplib/pkg/sdp"
+)
+
+type readSDPReq struct {
+	sdp []byte
+	res chan clientRes
+}
+
+// sdpConnectionAddress returns the address and TTL of a media description.
+// The address is nil when connection information is missing.
+func sdpConnectionAddress(sd *sdp.SessionDescription, md *psdp.MediaDescription) (net.IP, int, error) {
+	ci := md.ConnectionInformation
+	if ci == nil {
+		ci = sd.ConnectionInformation
+	}
+	if ci == nil || ci.Address == nil {
+		return nil, 0, nil
+	}
+
+	// the address can be followed by the TTL and by the number of addresses
+	parts := strings.Split(ci.Address.Address, "/")
+
+	ip := net.ParseIP(parts[0])
+	if ip == nil {
+		return nil, 0, fmt.Errorf("invalid address '%s'", ci.Address.Address)
+	}
+
+	ttl := 0
+	if ci.Address.TTL != nil {
+		ttl = *ci.Address.TTL
+	} else if len(parts) >= 2 && ip.To4() != nil {
+		v, err := strconv.ParseUint(parts[1], 10, 8)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid TTL '%s'", parts[1])
+		}
+		ttl = int(v)
+	}
+
+	return ip, ttl, nil
+}
+
+// sdpSourceFilter returns the source of a source-specific multicast group (RFC 4570), if any.
+func sdpSourceFilter(sd *sdp.SessionDescription, md *psdp.MediaDescription, group net.IP) net.IP {
+	for _, attrs := range [][]psdp.Attribute{md.Attributes, sd.Attributes} {
+		for _, attr := range attrs {
+			if attr.Key != "source-filter" {
+				continue
+			}
+
+			// incl <nettype> <addrtype> <dest-address> <src-list>
+			fields := strings.Fields(attr.Value)
+			if len(fields) < 5 || fields[0] != "incl" {
+				continue
+			}
+
+			if fields[3] != "*" && !net.ParseIP(fields[3]).Equal(group) {
+				continue
+			}
+
+			if ip := net.ParseIP(fields[4]); ip != nil {
+				return ip
+			}
+		}
+	}
+
+	return nil
+}
+
+// doReadSDP starts reading the tracks described by a SDP, without RTSP,
+// by joining the multicast groups or by listening on the unicast ports in the SDP.
+func (c *Client) doReadSDP(byts []byte) error {
+	err := c.checkState(map[clientState]struct{}{
+		clientStateInitial: {},
+	})
+	if err != nil {
+		return err
+	}
+
+	tracks, sd, err := ReadTracks(byts, false)
+	if err != nil {
+		return err
+	}
+
+	var transport *Transport
+
+	err = func() error {
+		for trackID, track := range tracks {
+			md := sd.MediaDescriptions[trackID]
+
+			ip, ttl, err := sdpConnectionAddress(sd, md)
+			if err != nil {
+				return err
+			}
+
+			isMulticast := ip != nil && ip.IsMulticast()
+
+			if transport == nil {
+				v := TransportUDP
+				if isMulticast {
+					v = TransportUDPMulticast
+				}
+				transport = &v
+			} else if isMulticast != (*transport == TransportUDPMulticast) {
+				return liberrors.ErrClientSDPMixedTransports{}
+			}
+
+			rtpPort := md.MediaName.Port.Value
+			if rtpPort == 0 {
+				return fmt.Errorf("track %d has no port", trackID+1)
+			}
+
+			srtpContext, err := newSRTPContext(track)
+			if err != nil {
+				return err
+			}
+
+			ct := &clientTrack{
+				id:          trackID,
+				track:       track,
+				localSSRC:   randUint32(),
+				srtpContext: srtpContext,
+			}
+
+			if isMulticast {
+				err = c.readSDPMulticastTrack(sd, md, ct, ip, ttl, rtpPort)
+			} else {
+				err = c.readSDPUnicastTrack(ct, rtpPort)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+	if err != nil {
+		c.reset()
+		return err
+	}
+
+	c.effectiveTransport = transport
+	c.noRTSPSession = true
+	c.state = clientStatePlay
+	c.playRecordStart()
+
+	return nil
+}
+
+func (c *Client) readSDPMulticastTrack(
+	sd *sdp.SessionDescription,
+	md *psdp.MediaDescription,
+	ct *clientTrack,
+	group net.IP,
+	ttl int,
+	rtpPort int,
+) error {
+	if ttl == 0 {
+		ttl = multicastTTL
+	}
+
+	source := sdpSourceFilter(sd, md, group)
+
+	var err error
+	ct.udpRTPListener, err = newClientUDPListenerMulticast(
+		c,
+		group,
+		rtpPort,
+		source,
+		ttl,
+		ct,
+		true)
+	if err != nil {
+		return err
+	}
+
+	// the source address is known only with source-specific multicast,
+	// and the source port is never known.
+	ct.udpRTPListener.readIP = source
+	ct.udpRTPListener.writeAddr = &net.UDPAddr{
+		IP:   group,
+		Port: rtpPort,
+	}
+
+	c.tracks = append(c.tracks, ct)
+
+	if ct.track.RTCPMux() {
+		ct.udpRTPListener.rtcpMux = true
+		return nil
+	}
+
+	ct.udpRTCPListener, err = newClientUDPListenerMulticast(
+		c,
+		group,
+		rtpPort+1,
+		source,
+		ttl,
+		ct,
+		false)
+	if err != nil {
+		return err
+	}
+
+	ct.udpRTCPListener.readIP = source
+	ct.udpRTCPListener.writeAddr = &net.UDPAddr{
+		IP:   group,
+		Port: rtpPort + 1,
+	}
+
+	return nil
+}
+
+func (c *Client) readSDPUnicastTrack(ct *clientTrack, rtpPort int) error {
+	// packets are pushed to the ports in the SDP by a sender whose address is not known.
+	var err error
+	ct.udpRTPListener, err = newClientUDPListener(
+		c,
+		":"+strconv.FormatInt(int64(rtpPort), 10),
+		ct,
+		true)
+	if err != nil {
+		return err
+	}
+
+	c.tracks = append(c.tracks, ct)
+
+	if ct.track.RTCPMux() {
+		ct.udpRTPListener.rtcpMux = true
+		ct.udpRTPListener.learnWriteAddr = true
+		return nil
+	}
+
+	ct.udpRTCPListener, err = newClientUDPListener(
+		c,
+		":"+strconv.FormatInt(int64(rtpPort+1), 10),
+		ct,
+		false)
+	if err != nil {
+		return err
+	}
+
+	ct.udpRTCPListener.learnWriteAddr = true
+
+	return nil
+}
@@ -8,7 +8,7 @@ import (
 	"time"
 
 	"github.com/pion/rtcp"
-	"golang.org/x/net/ipv4"
+	"github.com/pion/rtp"
 )
 
 func randUint32() uint32 {
@@ -30,6 +30,12 @@ type clientUDPListener struct {
 	readIP    net.IP
 	readPort  int
 	writeAddr *net.UDPAddr
+	rtcpMux   bool
+
+	// when reading without RTSP from unicast, the address of the sender is not known
+	// and RTCP packets are sent to the address of the received RTCP packets.
+	learnWriteAddr   bool
+	learnedWriteAddr atomic.Value // *net.UDPAddr
 
 	running        bool
 	lastPacketTime *int64
@@ -44,7 +50,6 @@ func newClientUDPListenerPair(c *Client, ct *clientTrack) (*clientUDPListener, *
 		rtpPort := (randIntn((65535-10000)/2) * 2) + 10000
 		rtpListener, err := newClientUDPListener(
 			c,
-			false,
 			":"+strconv.FormatInt(int64(rtpPort), 10),
 			ct,
 			true)
@@ -55,7 +60,6 @@ func newClientUDPListenerPair(c *Client, ct *clientTrack) (*clientUDPListener, *
 		rtcpPort := rtpPort + 1
 		rtcpListener, err := newClientUDPListener(
 			c,
-			false,
 			":"+strconv.FormatInt(int64(rtcpPort), 10),
 			ct,
 			false)
@@ -70,53 +74,47 @@ func newClientUDPListenerPair(c *Client, ct *clientTrack) (*clientUDPListener, *
 
 func newClientUDPListener(
 	c *Client,
-	multicast bool,
 	address string,
 	ct *clientTrack,
 	isRTP bool,
 ) (*clientUDPListener, error) {
-	var pc *net.UDPConn
-	if multicast {
-		host, port, err := net.SplitHostPort(address)
-		if err != nil {
-			return nil, err
-		}
-
-		tmp, err := c.ListenPacket("udp", "224.0.0.0:"+port)
-		if err != nil {
-			return nil, err
-		}
-
-		p := ipv4.NewPacketConn(tmp)
-
-		err = p.SetMulticastTTL(multicastTTL)
-		if err != nil {
-			return nil, err
-		}
-
-		intfs, err := net.Interfaces()
-		if err != nil {
-			return nil, err
-		}
+	tmp, err := c.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, intf := range intfs {
-			err := p.JoinGroup(&intf, &net.UDPAddr{IP: net.ParseIP(host)})
-			if err != nil {
-				return nil, err
-			}
-		}
+	return newClientUDPListenerFromConn(c, tmp.(*net.UDPConn), ct, isRTP)
+}
 
-		pc = tmp.(*net.UDPConn)
-	} else {
-		tmp, err := c.ListenPacket("udp", address)
-		if err != nil {
-			return nil, err
-		}
-		pc = tmp.(*net.UDPConn)
+// newClientUDPListenerMulticast allocates a listener that receives packets
+// sent to a multicast group. If source is not nil, only packets sent by source
+// are received (source-specific multicast).
+func newClientUDPListenerMulticast(
+	c *Client,
+	group net.IP,
+	port int,
+	source net.IP,
+	ttl int,
+	ct *clientTrack,
+	isRTP bool,
+) (*clientUDPListener, error) {
+	pc, err := listenMulticast(c.ListenPacket, group, port, source, ttl, true)
+	if err != nil {
+		return nil, err
 	}
 
+	return newClientUDPListenerFromConn(c, pc, ct, isRTP)
+}
+
+func newClientUDPListenerFromConn(
+	c *Client,
+	pc *net.UDPConn,
+	ct *clientTrack,
+	isRTP bool,
+) (*clientUDPListener, error) {
 	err := pc.SetReadBuffer(udpKernelReadBufferSize)
 	if err != nil {
+		pc.Close()
 		return nil, err
 	}
 
@@ -139,6 +137,25 @@ func (u *clientUDPListener) close() {
 	u.pc.Close()
 }
 
+// reopen opens again the socket of a closed listener, on the given port.
+func (u *clientUDPListener) reopen(port int) error {
+	tmp, err := u.c.ListenPacket("udp", ":"+strconv.FormatInt(int64(port), 10))
+	if err != nil {
+		return err
+	}
+
+	pc := tmp.(*net.UDPConn)
+
+	err = pc.SetReadBuffer(udpKernelReadBufferSize)
+	if err != nil {
+		pc.Close()
+		return err
+	}
+
+	u.pc = pc
+	return nil
+}
+
 func (u *clientUDPListener) port() int {
 	return u.pc.LocalAddr().(*net.UDPAddr).Port
 }
@@ -158,15 +175,14 @@ func (u *clientUDPListener) stop() {
 func (u *clientUDPListener) runReader(forPlay bool) {
 	defer close(u.readerDone)
 
-	var processFunc func(time.Time, []byte)
+	var processRTP func(time.Time, []byte)
+	var processRTCP func(time.Time, []byte)
 	if forPlay {
-		if u.isRTP {
-			processFunc = u.processPlayRTP
-		} else {
-			processFunc = u.processPlayRTCP
-		}
+		processRTP = u.processPlayRTP
+		processRTCP = u.processPlayRTCP
 	} else {
-		processFunc = u.processRecordRTCP
+		processRTP = u.processRecordRTCP
+		processRTCP = u.processRecordRTCP
 	}
 
 	for {
@@ -178,14 +194,34 @@ func (u *clientUDPListener) runReader(forPlay bool) {
 
 		uaddr := addr.(*net.UDPAddr)
 
-		if !u.readIP.Equal(uaddr.IP) || (!u.c.AnyPortEnable && u.readPort != uaddr.Port) {
+		// readIP and readPort are not known when reading without RTSP
+		if (u.readIP != nil && !u.readIP.Equal(uaddr.IP)) ||
+			(!u.c.AnyPortEnable && u.readPort != 0 && u.readPort != uaddr.Port) {
 			continue
 		}
 
+		payload := buf[:n]
+		isRTP := u.isRTP && !(u.rtcpMux && isRTCPPacket(payload))
+
+		if u.ct.srtpContext != nil {
+			payload, err = srtpDecrypt(u.ct.srtpContext, isRTP, payload)
+			if err != nil {
+				continue
+			}
+		}
+
 		now := time.Now()
 		atomic.StoreInt64(u.lastPacketTime, now.Unix())
 
-		processFunc(now, buf[:n])
+		if u.learnWriteAddr && !isRTP {
+			u.learnedWriteAddr.Store(uaddr)
+		}
+
+		if isRTP {
+			processRTP(now, payload)
+		} else {
+			processRTCP(now, payload)
+		}
 	}
 }
 
@@ -196,20 +232,53 @@ func (u *clientUDPListener) processPlayRTP(now time.Time, payload []byte) {
 		return
 	}
 
+	if u.ct.udpRTXReceiver != nil {
+		pkt = u.ct.udpRTXReceiver.processPacketRTP(pkt)
+		if pkt == nil {
+			return
+		}
+	}
+
+	if u.ct.udpFECDecoder != nil {
+		recovered, err := u.ct.udpFECDecoder.Decode(pkt)
+		if err != nil {
+			return
+		}
+
+		if pkt.PayloadType != u.ct.udpFECDecoder.PayloadType {
+			u.processPlayRTPPacket(pkt)
+		}
+
+		for _, rpkt := range recovered {
+			u.processPlayRTPPacket(rpkt)
+		}
+		return
+	}
+
+	u.processPlayRTPPacket(pkt)
+}
+
+func (u *clientUDPListener) processPlayRTPPacket(pkt *rtp.Packet) {
+	atomic.StoreUint32(&u.ct.remoteSSRC, pkt.SSRC)
+
 	out, err := u.ct.cleaner.Clear(pkt)
 	if err != nil {
 		return
 	}
 	out0 := out[0]
 
-	u.ct.udpRTCPReceiver.ProcessPacketRTP(time.Now(), pkt, out0.PTSEqualsDTS)
+	u.ct.rtcpReceiver.ProcessPacketRTP(time.Now(), pkt, out0.PTSEqualsDTS)
+
+	ntp, ntpSynchronized := u.ct.rtcpReceiver.PacketNTP(pkt.Timestamp)
 
 	u.c.OnPacketRTP(&ClientOnPacketRTPCtx{
-		TrackID:      u.ct.id,
-		Packet:       out0.Packet,
-		PTSEqualsDTS: out0.PTSEqualsDTS,
-		H264NALUs:    out0.H264NALUs,
-		H264PTS:      out0.H264PTS,
+		TrackID:         u.ct.id,
+		Packet:          out0.Packet,
+		PTSEqualsDTS:    out0.PTSEqualsDTS,
+		H264NALUs:       out0.H264NALUs,
+		H264PTS:         out0.H264PTS,
+		NTP:             ntp,
+		NTPSynchronized: ntpSynchronized,
 	})
 }
 
@@ -220,7 +289,7 @@ func (u *clientUDPListener) processPlayRTCP(now time.Time, payload []byte) {
 	}
 
 	for _, pkt := range packets {
-		u.ct.udpRTCPReceiver.ProcessPacketRTCP(now, pkt)
+		u.ct.rtcpReceiver.ProcessPacketRTCP(now, pkt)
 		u.c.OnPacketRTCP(&ClientOnPacketRTCPCtx{
 			TrackID: u.ct.id,
 			Packet:  pkt,
@@ -235,6 +304,8 @@ func (u *clientUDPListener) processRecordRTCP(now time.Time, payload []byte) {
 	}
 
 	for _, pkt := range packets {
+		u.ct.receiverReports.processPacketRTCP(now, pkt)
+		u.c.retransmit(u.ct.id, pkt)
 		u.c.OnPacketRTCP(&ClientOnPacketRTCPCtx{
 			TrackID: u.ct.id,
 			Packet:  pkt,
@@ -246,7 +317,17 @@ func (u *clientUDPListener) write(payload []byte) error {
 	// no mutex is needed here since Write() has an internal lock.
 	// https://github.com/golang/go/issues/27203#issuecomment-534386117
 
+	writeAddr := u.writeAddr
+	if u.learnWriteAddr {
+		var ok bool
+		writeAddr, ok = u.learnedWriteAddr.Load().(*net.UDPAddr)
+		if !ok {
+			// the sender has not sent any RTCP packet yet
+			return nil
+		}
+	}
+
 	u.pc.SetWriteDeadline(time.Now().Add(u.c.WriteTimeout))
-	_, err := u.pc.WriteTo(payload, u.writeAddr)
+	_, err := u.pc.WriteTo(payload, writeAddr)
 	return err
 }
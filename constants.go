@@ -15,4 +15,14 @@ const (
 
 	// same size as GStreamer's rtspsrc
 	multicastTTL = 16
+
+	// connections that exceed MaxConnections and are waiting for a 503 response.
+	// Further connections are closed without a response.
+	serverMaxPendingRejections = 16
+
+	// SAP address for the global IPv4 scope (RFC 2974)
+	sapAddress = "224.2.127.254:9875"
+
+	// seconds between 1st January 1900 and 1st January 1970
+	ntpEpochOffset = 2208988800
 )
@@ -0,0 +1,165 @@
+package gortsplib
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// isSSMAddress checks whether an IP belongs to a source-specific multicast range
+// (232.0.0.0/8 or ff3x::/32).
+func isSSMAddress(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4[0] == 232
+	}
+	return len(ip) == net.IPv6len && ip[0] == 0xff && (ip[1]&0xf0) == 0x30
+}
+
+// multicastNextIP returns the IP that follows ip inside n,
+// wrapping around when the end of n is reached.
+func multicastNextIP(n *net.IPNet, ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+
+	for i := range next {
+		next[i] = (n.IP[i] & n.Mask[i]) | (next[i] & ^n.Mask[i])
+	}
+
+	return next
+}
+
+// multicastOriginIP returns the IP used by the server to send packets to a multicast address.
+func multicastOriginIP(s *Server, dest *net.UDPAddr) (net.IP, error) {
+	host, _, err := net.SplitHostPort(s.RTSPAddress)
+	if err == nil {
+		ip := net.ParseIP(host)
+		if ip != nil && !ip.IsUnspecified() && (ip.To4() != nil) == (dest.IP.To4() != nil) {
+			return ip, nil
+		}
+	}
+
+	// connecting a UDP socket doesn't send any packet,
+	// but fills the local address with the IP of the outgoing interface.
+	conn, err := net.DialUDP("udp", nil, dest)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+type multicastPacketConn interface {
+	SetMulticastTTL(int) error
+	JoinGroup(*net.Interface, net.IP, net.IP) error
+}
+
+type multicastPacketConnIPv4 struct {
+	p *ipv4.PacketConn
+}
+
+func (c multicastPacketConnIPv4) SetMulticastTTL(ttl int) error {
+	return c.p.SetMulticastTTL(ttl)
+}
+
+func (c multicastPacketConnIPv4) JoinGroup(intf *net.Interface, group net.IP, source net.IP) error {
+	if source != nil {
+		return c.p.JoinSourceSpecificGroup(intf, &net.UDPAddr{IP: group}, &net.UDPAddr{IP: source})
+	}
+	return c.p.JoinGroup(intf, &net.UDPAddr{IP: group})
+}
+
+type multicastPacketConnIPv6 struct {
+	p *ipv6.PacketConn
+}
+
+func (c multicastPacketConnIPv6) SetMulticastTTL(ttl int) error {
+	return c.p.SetMulticastHopLimit(ttl)
+}
+
+func (c multicastPacketConnIPv6) JoinGroup(intf *net.Interface, group net.IP, source net.IP) error {
+	if source != nil {
+		return c.p.JoinSourceSpecificGroup(intf, &net.UDPAddr{IP: group}, &net.UDPAddr{IP: source})
+	}
+	return c.p.JoinGroup(intf, &net.UDPAddr{IP: group})
+}
+
+func newMulticastPacketConn(pc net.PacketConn, group net.IP) multicastPacketConn {
+	if group.To4() != nil {
+		return multicastPacketConnIPv4{ipv4.NewPacketConn(pc)}
+	}
+	return multicastPacketConnIPv6{ipv6.NewPacketConn(pc)}
+}
+
+// listenMulticast opens a socket that receives packets sent to a multicast group
+// and joins the group on every interface that supports multicast.
+// If source is not nil, the group is joined with a source filter (IGMPv3 / MLDv2)
+// and only packets sent by source are received.
+// If mustJoin is true, an error is returned when the group can't be joined on any interface.
+func listenMulticast(
+	listenPacket func(network, address string) (net.PacketConn, error),
+	group net.IP,
+	port int,
+	source net.IP,
+	ttl int,
+	mustJoin bool,
+) (*net.UDPConn, error) {
+	// listen on a multicast address in order to enable address reuse,
+	// that allows multiple streams to share the same port.
+	var address string
+	if group.To4() != nil {
+		address = "224.0.0.0:" + strconv.FormatInt(int64(port), 10)
+	} else {
+		address = net.JoinHostPort(group.String(), strconv.FormatInt(int64(port), 10))
+	}
+
+	tmp, err := listenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newMulticastPacketConn(tmp, group)
+
+	err = p.SetMulticastTTL(ttl)
+	if err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	intfs, err := net.Interfaces()
+	if err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	joined := false
+
+	for _, intf := range intfs {
+		if (intf.Flags & net.FlagMulticast) != 0 {
+			// do not stop at the first error.
+			// on macOS, there are interfaces with the multicast flag but
+			// without support for multicast, that makes this function fail.
+			err := p.JoinGroup(&intf, group, source)
+			if err == nil {
+				joined = true
+			}
+		}
+	}
+
+	if mustJoin && !joined {
+		tmp.Close()
+		return nil, fmt.Errorf("unable to join multicast group %v on any interface", group)
+	}
+
+	return tmp.(*net.UDPConn), nil
+}
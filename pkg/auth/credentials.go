@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+// Credentials are the credentials of a user, provided by a CredentialStore.
+// Only one of Pass, HA1 and BcryptHash needs to be filled.
+type Credentials struct {
+	// plain password.
+	// It allows the Basic method and the Digest method with every algorithm.
+	Pass string
+
+	// hashes of "user:realm:pass" (HA1), indexed by Digest algorithm
+	// ("MD5", "SHA-256" or "SHA-512-256").
+	// It allows the Basic method and the Digest method with the provided algorithms.
+	// Since clients pick the first offered algorithm they support,
+	// a hash should be provided for each algorithm offered by the Validator.
+	HA1 map[string]string
+
+	// bcrypt hash of the password.
+	// It allows the Basic method only.
+	BcryptHash []byte
+
+	// base64-encoded SHA-256 hash of the password.
+	passSHA256 string
+}
+
+// CredentialStore provides the credentials of users to a Validator.
+type CredentialStore interface {
+	// Lookup returns the credentials of a user that is performing a request
+	// with the given method on the given path.
+	// It returns an error if the user does not exist.
+	Lookup(user string, realm string, path string, method base.Method) (*Credentials, error)
+}
+
+// staticCredentialStore is a CredentialStore that contains a single user.
+type staticCredentialStore struct {
+	user       string
+	userHashed bool
+	creds      Credentials
+}
+
+func (s *staticCredentialStore) Lookup(user string, realm string, path string, method base.Method) (*Credentials, error) {
+	if s.userHashed {
+		if sha256Base64(user) != s.user {
+			return nil, fmt.Errorf("wrong username")
+		}
+	} else if user != s.user {
+		return nil, fmt.Errorf("wrong username")
+	}
+
+	return &s.creds, nil
+}
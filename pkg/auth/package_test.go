@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/aler9/gortsplib/pkg/base"
 	"github.com/aler9/gortsplib/pkg/headers"
@@ -165,3 +168,256 @@ func TestAuthHashed(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthDigestParams(t *testing.T) {
+	for _, ca := range []struct {
+		name      string
+		algorithm string
+		qop       string
+	}{
+		{
+			"md5 without qop",
+			"MD5",
+			"",
+		},
+		{
+			"sha-256 auth",
+			"SHA-256",
+			"auth",
+		},
+		{
+			"sha-512-256 auth",
+			"SHA-512-256",
+			"auth",
+		},
+		{
+			"sha-256 auth-int",
+			"SHA-256",
+			"auth-int",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			va := NewValidator("testuser", "testpass", []headers.AuthMethod{headers.AuthDigest})
+
+			var challenge headers.Authenticate
+			err := challenge.Read(va.Header()[:1])
+			require.NoError(t, err)
+
+			challenge.Algorithm = &ca.algorithm
+			if ca.qop != "" {
+				challenge.QOP = &ca.qop
+			} else {
+				challenge.QOP = nil
+			}
+
+			se, err := NewSender(challenge.Write(), "testuser", "testpass")
+			require.NoError(t, err)
+
+			req := &base.Request{
+				Method: base.Announce,
+				URL:    mustParseURL("rtsp://myhost/mypath"),
+				Body:   []byte{1, 2, 3, 4},
+			}
+			se.AddAuthorization(req)
+
+			var auth headers.Authorization
+			err = auth.Read(req.Header["Authorization"])
+			require.NoError(t, err)
+			require.Equal(t, ca.algorithm, *auth.DigestValues.Algorithm)
+
+			if ca.qop != "" {
+				require.Equal(t, ca.qop, *auth.DigestValues.QOP)
+				require.Equal(t, "00000001", *auth.DigestValues.NC)
+			} else {
+				require.Nil(t, auth.DigestValues.QOP)
+			}
+
+			err = va.ValidateRequest(req)
+			require.NoError(t, err)
+
+			if ca.qop == "auth-int" {
+				req.Body = []byte{5, 6, 7, 8}
+				se.AddAuthorization(req)
+				req.Body = []byte{1, 2, 3, 4}
+
+				err = va.ValidateRequest(req)
+				require.EqualError(t, err, "wrong response")
+			}
+		})
+	}
+}
+
+func TestAuthDigestPreference(t *testing.T) {
+	va := NewValidator("testuser", "testpass", nil)
+
+	se, err := NewSender(base.HeaderValue{
+		`Digest realm="IPCAM", nonce="abcde", algorithm="SHA-1"`,
+		va.Header()[1],
+		va.Header()[2],
+		va.Header()[3],
+		va.Header()[0],
+	}, "testuser", "testpass")
+	require.NoError(t, err)
+	require.Equal(t, headers.AuthDigest, se.method)
+	require.Equal(t, "SHA-512-256", *se.algorithm)
+	require.Equal(t, "auth", se.qop)
+}
+
+func TestAuthDigestReplay(t *testing.T) {
+	va := NewValidator("testuser", "testpass", []headers.AuthMethod{headers.AuthDigest})
+
+	se, err := NewSender(va.Header(), "testuser", "testpass")
+	require.NoError(t, err)
+
+	req := &base.Request{
+		Method: base.Describe,
+		URL:    mustParseURL("rtsp://myhost/mypath"),
+	}
+	se.AddAuthorization(req)
+
+	err = va.ValidateRequest(req)
+	require.NoError(t, err)
+
+	err = va.ValidateRequest(req)
+	require.EqualError(t, err, "wrong nc")
+
+	se.AddAuthorization(req)
+
+	err = va.ValidateRequest(req)
+	require.NoError(t, err)
+}
+
+func TestAuthDigestStale(t *testing.T) {
+	va := NewValidator("testuser", "testpass", []headers.AuthMethod{headers.AuthDigest})
+
+	// generate a nonce that is already expired
+	timeNow = func() time.Time {
+		return time.Now().Add(-nonceLifetime)
+	}
+	se, err := NewSender(va.Header(), "testuser", "testpass")
+	timeNow = time.Now
+	require.NoError(t, err)
+
+	req := &base.Request{
+		Method: base.Describe,
+		URL:    mustParseURL("rtsp://myhost/mypath"),
+	}
+	se.AddAuthorization(req)
+
+	err = va.ValidateRequest(req)
+	require.Equal(t, ErrStaleNonce{}, err)
+
+	h := va.HeaderForError(err)
+	require.True(t, se.IsStale(h))
+
+	var challenge headers.Authenticate
+	err = challenge.Read(h[:1])
+	require.NoError(t, err)
+	require.Equal(t, "true", *challenge.Stale)
+
+	se, err = NewSender(h, "testuser", "testpass")
+	require.NoError(t, err)
+	require.False(t, se.IsStale(h))
+
+	se.AddAuthorization(req)
+
+	err = va.ValidateRequest(req)
+	require.NoError(t, err)
+}
+
+type testCredentialStore struct {
+	lookup func(user string, realm string, path string, method base.Method) (*Credentials, error)
+}
+
+func (s *testCredentialStore) Lookup(user string, realm string, path string, method base.Method) (*Credentials, error) {
+	return s.lookup(user, realm, path, method)
+}
+
+func TestAuthCredentialStore(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("testpass"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	for _, ca := range []struct {
+		name    string
+		creds   Credentials
+		methods []headers.AuthMethod
+	}{
+		{
+			"pass basic",
+			Credentials{Pass: "testpass"},
+			[]headers.AuthMethod{headers.AuthBasic},
+		},
+		{
+			"pass digest",
+			Credentials{Pass: "testpass"},
+			[]headers.AuthMethod{headers.AuthDigest},
+		},
+		{
+			"ha1 basic",
+			Credentials{HA1: map[string]string{
+				"MD5": "3ea7b9257bf507bd233c31f0b3db2ca9",
+			}},
+			[]headers.AuthMethod{headers.AuthBasic},
+		},
+		{
+			"ha1 digest",
+			Credentials{HA1: map[string]string{
+				"SHA-512-256": "9979968cd102bc0542652145f8ff47c871422e03730afbf7af5470fa711bf9af",
+				"SHA-256":     "81d6aa4f06fffa0d2ee33428a7a19df140f5586944916f95d1e34087aec681a3",
+			}},
+			[]headers.AuthMethod{headers.AuthDigest},
+		},
+		{
+			"bcrypt basic",
+			Credentials{BcryptHash: bcryptHash},
+			[]headers.AuthMethod{headers.AuthBasic},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			store := &testCredentialStore{
+				lookup: func(user string, realm string, path string, method base.Method) (*Credentials, error) {
+					require.Equal(t, "IPCAM", realm)
+					require.Equal(t, "mypath", path)
+					require.Equal(t, base.Setup, method)
+
+					if user != "testuser" {
+						return nil, fmt.Errorf("user not found")
+					}
+					return &ca.creds, nil
+				},
+			}
+
+			va := NewValidatorWithStore(store, ca.methods)
+
+			for _, conf := range []string{"nofail", "wronguser", "wrongpass"} {
+				se, err := NewSender(va.Header(),
+					func() string {
+						if conf == "wronguser" {
+							return "test1user"
+						}
+						return "testuser"
+					}(),
+					func() string {
+						if conf == "wrongpass" {
+							return "test1pass"
+						}
+						return "testpass"
+					}())
+				require.NoError(t, err)
+
+				req := &base.Request{
+					Method: base.Setup,
+					URL:    mustParseURL("rtsp://myhost/mypath?key=val/trackID=0"),
+				}
+				se.AddAuthorization(req)
+
+				err = va.ValidateRequest(req)
+				if conf == "nofail" {
+					require.NoError(t, err)
+				} else {
+					require.Error(t, err)
+				}
+			}
+		})
+	}
+}
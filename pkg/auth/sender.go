@@ -2,6 +2,7 @@ package auth
 
 import (
 	"fmt"
+	"hash"
 	"strings"
 
 	"github.com/aler9/gortsplib/pkg/base"
@@ -10,46 +11,42 @@ import (
 
 // Sender allows to generate credentials for a Validator.
 type Sender struct {
-	user   string
-	pass   string
-	method headers.AuthMethod
-	realm  string
-	nonce  string
+	user      string
+	pass      string
+	method    headers.AuthMethod
+	realm     string
+	nonce     string
+	opaque    *string
+	algorithm *string
+	hf        func() hash.Hash
+	qop       string
+	cnonce    string
+	nc        uint32
+	token     string
 }
 
 // NewSender allocates a Sender with the WWW-Authenticate header provided by
 // a Validator and a set of credentials.
+// Digest challenges are preferred to Basic ones. Among Digest challenges,
+// the first one with a supported algorithm and quality of protection is picked,
+// since servers list them in order of preference.
 func NewSender(v base.HeaderValue, user string, pass string) (*Sender, error) {
 	// prefer digest
-	if v0 := func() string {
-		for _, vi := range v {
-			if strings.HasPrefix(vi, "Digest") {
-				return vi
-			}
-		}
-		return ""
-	}(); v0 != "" {
-		var auth headers.Authenticate
-		err := auth.Read(base.HeaderValue{v0})
-		if err != nil {
-			return nil, err
-		}
-
-		if auth.Realm == nil {
-			return nil, fmt.Errorf("realm is missing")
+	var digestErr error
+	for _, vi := range v {
+		if !strings.HasPrefix(vi, "Digest") {
+			continue
 		}
 
-		if auth.Nonce == nil {
-			return nil, fmt.Errorf("nonce is missing")
+		se, err := newDigestSender(vi, user, pass)
+		if err != nil {
+			if digestErr == nil {
+				digestErr = err
+			}
+			continue
 		}
 
-		return &Sender{
-			user:   user,
-			pass:   pass,
-			method: headers.AuthDigest,
-			realm:  *auth.Realm,
-			nonce:  *auth.Nonce,
-		}, nil
+		return se, nil
 	}
 
 	if v0 := func() string {
@@ -78,9 +75,97 @@ func NewSender(v base.HeaderValue, user string, pass string) (*Sender, error) {
 		}, nil
 	}
 
+	if digestErr != nil {
+		return nil, digestErr
+	}
+
 	return nil, fmt.Errorf("no authentication methods available")
 }
 
+// NewSenderBearer allocates a Sender that authenticates requests with a Bearer token.
+func NewSenderBearer(token string) *Sender {
+	return &Sender{
+		method: headers.AuthBearer,
+		token:  token,
+	}
+}
+
+func newDigestSender(v string, user string, pass string) (*Sender, error) {
+	var auth headers.Authenticate
+	err := auth.Read(base.HeaderValue{v})
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.Realm == nil {
+		return nil, fmt.Errorf("realm is missing")
+	}
+
+	if auth.Nonce == nil {
+		return nil, fmt.Errorf("nonce is missing")
+	}
+
+	_, hf, err := digestAlgorithm(auth.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	qop := ""
+	if auth.QOP != nil {
+		switch {
+		case qopContains(*auth.QOP, qopAuth):
+			qop = qopAuth
+
+		case qopContains(*auth.QOP, qopAuthInt):
+			qop = qopAuthInt
+
+		default:
+			return nil, fmt.Errorf("unsupported qop (%v)", *auth.QOP)
+		}
+	}
+
+	return &Sender{
+		user:      user,
+		pass:      pass,
+		method:    headers.AuthDigest,
+		realm:     *auth.Realm,
+		nonce:     *auth.Nonce,
+		opaque:    auth.Opaque,
+		algorithm: auth.Algorithm,
+		hf:        hf,
+		qop:       qop,
+		cnonce:    randomHex(16),
+	}, nil
+}
+
+// IsStale checks whether a WWW-Authenticate header notifies that the nonce
+// used by the Sender has expired. In this case, a new Sender must be
+// allocated with the header.
+func (se *Sender) IsStale(v base.HeaderValue) bool {
+	if se.method != headers.AuthDigest {
+		return false
+	}
+
+	for _, vi := range v {
+		if !strings.HasPrefix(vi, "Digest") {
+			continue
+		}
+
+		var auth headers.Authenticate
+		err := auth.Read(base.HeaderValue{vi})
+		if err != nil {
+			continue
+		}
+
+		if auth.Stale != nil && strings.ToLower(*auth.Stale) == "true" &&
+			auth.Nonce != nil && *auth.Nonce != se.nonce {
+			return true
+		}
+	}
+
+	return false
+}
+
 // AddAuthorization adds the Authorization header to a Request.
 func (se *Sender) AddAuthorization(req *base.Request) {
 	urStr := req.URL.CloneWithoutCredentials().String()
@@ -94,18 +179,46 @@ func (se *Sender) AddAuthorization(req *base.Request) {
 		h.BasicUser = se.user
 		h.BasicPass = se.pass
 
-	default: // headers.AuthDigest
-		response := md5Hex(md5Hex(se.user+":"+se.realm+":"+se.pass) + ":" +
-			se.nonce + ":" + md5Hex(string(req.Method)+":"+urStr))
+	case headers.AuthBearer:
+		h.BearerToken = se.token
 
+	default: // headers.AuthDigest
 		h.DigestValues = headers.Authenticate{
-			Method:   headers.AuthDigest,
-			Username: &se.user,
-			Realm:    &se.realm,
-			Nonce:    &se.nonce,
-			URI:      &urStr,
-			Response: &response,
+			Method:    headers.AuthDigest,
+			Username:  &se.user,
+			Realm:     &se.realm,
+			Nonce:     &se.nonce,
+			URI:       &urStr,
+			Opaque:    se.opaque,
+			Algorithm: se.algorithm,
+		}
+
+		params := digestParams{
+			hf:     se.hf,
+			ha1:    digestHA1(se.hf, se.user, se.realm, se.pass),
+			nonce:  se.nonce,
+			method: string(req.Method),
+			uri:    urStr,
+			body:   req.Body,
+		}
+
+		if se.qop != "" {
+			se.nc++
+			nc := fmt.Sprintf("%08x", se.nc)
+			qop := se.qop
+			cnonce := se.cnonce
+
+			params.qop = qop
+			params.nc = nc
+			params.cnonce = cnonce
+
+			h.DigestValues.QOP = &qop
+			h.DigestValues.NC = &nc
+			h.DigestValues.CNonce = &cnonce
 		}
+
+		response := digestResponse(params)
+		h.DigestValues.Response = &response
 	}
 
 	if req.Header == nil {
@@ -51,3 +51,18 @@ func TestSenderErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestSenderBearer(t *testing.T) {
+	se := NewSenderBearer("mytoken")
+
+	req := &base.Request{
+		Method: base.Describe,
+		URL:    mustParseURL("rtsp://myhost/mypath"),
+	}
+	se.AddAuthorization(req)
+	require.Equal(t, base.HeaderValue{"Bearer mytoken"}, req.Header["Authorization"])
+
+	va := NewValidator("myuser", "mypass", nil)
+	err := va.ValidateRequest(req)
+	require.EqualError(t, err, "unsupported authentication method")
+}
@@ -2,19 +2,109 @@ package auth
 
 import (
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
 )
 
-func md5Hex(in string) string {
-	h := md5.New()
-	h.Write([]byte(in))
-	return hex.EncodeToString(h.Sum(nil))
-}
+// digest algorithms.
+// https://tools.ietf.org/html/rfc7616#section-6.1
+const (
+	algorithmMD5       = "MD5"
+	algorithmSHA256    = "SHA-256"
+	algorithmSHA512256 = "SHA-512-256"
+)
+
+// digest qualities of protection.
+const (
+	qopAuth    = "auth"
+	qopAuthInt = "auth-int"
+)
 
 func sha256Base64(in string) string {
 	h := sha256.New()
 	h.Write([]byte(in))
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
+
+func randomHex(n int) string {
+	byts := make([]byte, n)
+	rand.Read(byts)
+	return hex.EncodeToString(byts)
+}
+
+// digestAlgorithm returns the normalized name and the hash function of a digest algorithm.
+func digestAlgorithm(algorithm *string) (string, func() hash.Hash, error) {
+	if algorithm == nil {
+		return algorithmMD5, md5.New, nil
+	}
+
+	switch strings.ToUpper(*algorithm) {
+	case algorithmMD5:
+		return algorithmMD5, md5.New, nil
+
+	case algorithmSHA256:
+		return algorithmSHA256, sha256.New, nil
+
+	case algorithmSHA512256:
+		return algorithmSHA512256, sha512.New512_256, nil
+	}
+
+	return "", nil, fmt.Errorf("unsupported algorithm (%v)", *algorithm)
+}
+
+func hashHex(hf func() hash.Hash, in []byte) string {
+	h := hf()
+	h.Write(in)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// digestHA1 computes the hash of the credentials of a user.
+func digestHA1(hf func() hash.Hash, user string, realm string, pass string) string {
+	return hashHex(hf, []byte(user+":"+realm+":"+pass))
+}
+
+// digestParams are the parameters needed to compute a digest response.
+type digestParams struct {
+	hf     func() hash.Hash
+	ha1    string
+	nonce  string
+	method string
+	uri    string
+	body   []byte
+	qop    string
+	nc     string
+	cnonce string
+}
+
+// digestResponse computes a digest response.
+// https://tools.ietf.org/html/rfc7616#section-3.4.1
+func digestResponse(p digestParams) string {
+	var ha2 string
+	if p.qop == qopAuthInt {
+		ha2 = hashHex(p.hf, []byte(p.method+":"+p.uri+":"+hashHex(p.hf, p.body)))
+	} else {
+		ha2 = hashHex(p.hf, []byte(p.method+":"+p.uri))
+	}
+
+	if p.qop == "" {
+		return hashHex(p.hf, []byte(p.ha1+":"+p.nonce+":"+ha2))
+	}
+
+	return hashHex(p.hf, []byte(p.ha1+":"+p.nonce+":"+p.nc+":"+p.cnonce+":"+p.qop+":"+ha2))
+}
+
+// qopContains checks whether a comma-separated qop list contains a value.
+func qopContains(list string, v string) bool {
+	for _, e := range strings.Split(list, ",") {
+		if strings.TrimSpace(e) == v {
+			return true
+		}
+	}
+	return false
+}
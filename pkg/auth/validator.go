@@ -1,16 +1,48 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/aler9/gortsplib/pkg/base"
 	"github.com/aler9/gortsplib/pkg/headers"
 	"github.com/aler9/gortsplib/pkg/url"
 )
 
+const (
+	// nonces are valid for this duration after their generation.
+	nonceLifetime = 5 * time.Minute
+
+	// maximum number of used nonces whose count is remembered.
+	maxUsedNonces = 1024
+
+	// a nonce contains its creation time and random bytes,
+	// followed by their signature.
+	nonceDataLen      = 16
+	nonceSignatureLen = 16
+)
+
+var timeNow = time.Now
+
+// ErrStaleNonce is returned by Validator.ValidateRequest when the credentials
+// are correct but their nonce has expired.
+type ErrStaleNonce struct{}
+
+// Error implements the error interface.
+func (e ErrStaleNonce) Error() string {
+	return "stale nonce"
+}
+
 func stringsReverseIndex(s, substr string) int {
 	for i := len(s) - 1 - len(substr); i >= 0; i-- {
 		if s[i:i+len(substr)] == substr {
@@ -39,59 +71,172 @@ func generateAltURL(req *base.Request) (*url.URL, bool) {
 	return ur, true
 }
 
+// requestPath returns the path of a request, without the query and the control attribute.
+func requestPath(req *base.Request) string {
+	if req.URL == nil {
+		return ""
+	}
+
+	pathAndQuery, ok := req.URL.RTSPPathAndQuery()
+	if !ok {
+		return ""
+	}
+
+	if req.Method == base.Setup {
+		if i := stringsReverseIndex(pathAndQuery, "/trackID="); i >= 0 {
+			pathAndQuery = pathAndQuery[:i]
+		}
+	}
+
+	path, _ := url.PathSplitQuery(pathAndQuery)
+	return path
+}
+
+type validatorNonce struct {
+	created time.Time
+	nc      uint32
+}
+
 // Validator allows to validate credentials generated by a Sender.
 type Validator struct {
-	user       string
-	userHashed bool
-	pass       string
-	passHashed bool
-	methods    []headers.AuthMethod
-	realm      string
-	nonce      string
+	store    CredentialStore
+	methods  []headers.AuthMethod
+	realm    string
+	nonceKey []byte
+
+	mutex      sync.Mutex
+	usedNonces map[string]*validatorNonce
+	// used nonces created up to this time may have been forgotten.
+	forgottenBefore time.Time
 }
 
 // NewValidator allocates a Validator.
 // If methods is nil, the Basic and Digest methods are used.
+// The Digest method is offered with the SHA-512-256, SHA-256 and MD5 algorithms, in this order,
+// and with the auth and auth-int qualities of protection.
 func NewValidator(user string, pass string, methods []headers.AuthMethod) *Validator {
-	if methods == nil {
-		methods = []headers.AuthMethod{headers.AuthBasic, headers.AuthDigest}
-	}
-
 	userHashed := false
 	if strings.HasPrefix(user, "sha256:") {
 		user = strings.TrimPrefix(user, "sha256:")
 		userHashed = true
 	}
 
-	passHashed := false
+	store := &staticCredentialStore{
+		user:       user,
+		userHashed: userHashed,
+	}
+
 	if strings.HasPrefix(pass, "sha256:") {
-		pass = strings.TrimPrefix(pass, "sha256:")
-		passHashed = true
+		store.creds.passSHA256 = strings.TrimPrefix(pass, "sha256:")
+	} else {
+		store.creds.Pass = pass
 	}
 
 	// if credentials are hashed, only basic auth is supported
-	if userHashed || passHashed {
+	if userHashed || store.creds.passSHA256 != "" {
 		methods = []headers.AuthMethod{headers.AuthBasic}
 	}
 
-	nonceByts := make([]byte, 16)
-	rand.Read(nonceByts)
-	nonce := hex.EncodeToString(nonceByts)
+	return NewValidatorWithStore(store, methods)
+}
+
+// NewValidatorWithStore allocates a Validator that obtains credentials from a CredentialStore.
+// If methods is nil, the Basic and Digest methods are used.
+func NewValidatorWithStore(store CredentialStore, methods []headers.AuthMethod) *Validator {
+	if methods == nil {
+		methods = []headers.AuthMethod{headers.AuthBasic, headers.AuthDigest}
+	}
+
+	nonceKey := make([]byte, 32)
+	rand.Read(nonceKey)
 
 	return &Validator{
-		user:       user,
-		userHashed: userHashed,
-		pass:       pass,
-		passHashed: passHashed,
+		store:      store,
 		methods:    methods,
 		realm:      "IPCAM",
-		nonce:      nonce,
+		nonceKey:   nonceKey,
+		usedNonces: make(map[string]*validatorNonce),
 	}
 }
 
-// Header generates the WWW-Authenticate header needed by a client to
-// authenticate.
-func (va *Validator) Header() base.HeaderValue {
+func (va *Validator) nonceSignature(data []byte) []byte {
+	h := hmac.New(sha256.New, va.nonceKey)
+	h.Write(data)
+	return h.Sum(nil)[:nonceSignatureLen]
+}
+
+// generateNonce generates a new nonce.
+// Nonces are signed and contain their creation time,
+// therefore they don't have to be stored until they are used.
+func (va *Validator) generateNonce() string {
+	data := make([]byte, nonceDataLen)
+	binary.BigEndian.PutUint64(data, uint64(timeNow().UnixNano()))
+	rand.Read(data[8:])
+
+	return hex.EncodeToString(append(data, va.nonceSignature(data)...))
+}
+
+// readNonce checks the signature of a nonce and returns its creation time.
+func (va *Validator) readNonce(nonce string) (time.Time, bool) {
+	byts, err := hex.DecodeString(nonce)
+	if err != nil || len(byts) != (nonceDataLen+nonceSignatureLen) {
+		return time.Time{}, false
+	}
+
+	if !hmac.Equal(va.nonceSignature(byts[:nonceDataLen]), byts[nonceDataLen:]) {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, int64(binary.BigEndian.Uint64(byts))), true
+}
+
+// usedNonce returns the state of a nonce that has been used with a nonce count.
+// When the table is full, expired nonces are removed and, if it is still full,
+// the oldest nonce is forgotten.
+// It must be called with the mutex locked.
+func (va *Validator) usedNonce(nonce string, created time.Time) (*validatorNonce, bool) {
+	if n, ok := va.usedNonces[nonce]; ok {
+		return n, true
+	}
+
+	// the count of the nonce may have been forgotten
+	if !created.After(va.forgottenBefore) {
+		return nil, false
+	}
+
+	if len(va.usedNonces) >= maxUsedNonces {
+		now := timeNow()
+		var oldestKey string
+		var oldest *validatorNonce
+
+		for k, n := range va.usedNonces {
+			if now.Sub(n.created) >= nonceLifetime {
+				delete(va.usedNonces, k)
+				continue
+			}
+
+			if oldest == nil || n.created.Before(oldest.created) {
+				oldestKey = k
+				oldest = n
+			}
+		}
+
+		if len(va.usedNonces) >= maxUsedNonces {
+			delete(va.usedNonces, oldestKey)
+			if oldest.created.After(va.forgottenBefore) {
+				va.forgottenBefore = oldest.created
+			}
+		}
+	}
+
+	n := &validatorNonce{
+		created: created,
+	}
+	va.usedNonces[nonce] = n
+	return n, true
+}
+
+func (va *Validator) header(stale bool) base.HeaderValue {
 	var ret base.HeaderValue
 	for _, m := range va.methods {
 		switch m {
@@ -102,18 +247,55 @@ func (va *Validator) Header() base.HeaderValue {
 			}).Write()...)
 
 		case headers.AuthDigest:
-			ret = append(ret, headers.Authenticate{
-				Method: headers.AuthDigest,
-				Realm:  &va.realm,
-				Nonce:  &va.nonce,
-			}.Write()...)
+			nonce := va.generateNonce()
+			qop := qopAuth + ", " + qopAuthInt
+
+			var staleStr *string
+			if stale {
+				v := "true"
+				staleStr = &v
+			}
+
+			for _, algorithm := range []string{algorithmSHA512256, algorithmSHA256, algorithmMD5} {
+				algorithm := algorithm
+				ret = append(ret, headers.Authenticate{
+					Method:    headers.AuthDigest,
+					Realm:     &va.realm,
+					Nonce:     &nonce,
+					Stale:     staleStr,
+					Algorithm: &algorithm,
+					QOP:       &qop,
+				}.Write()...)
+			}
 		}
 	}
 	return ret
 }
 
+// Header generates the WWW-Authenticate header needed by a client to
+// authenticate. Each call generates a new nonce.
+func (va *Validator) Header() base.HeaderValue {
+	return va.header(false)
+}
+
+// HeaderForError generates the WWW-Authenticate header needed by a client to
+// authenticate after ValidateRequest returned an error.
+// If the error is ErrStaleNonce, the client is notified with stale=true
+// that it can retry with the same credentials.
+func (va *Validator) HeaderForError(err error) base.HeaderValue {
+	_, stale := err.(ErrStaleNonce)
+	return va.header(stale)
+}
+
 // ValidateRequest validates a request sent by a client.
+// The path passed to the CredentialStore is extracted from the request URL.
 func (va *Validator) ValidateRequest(req *base.Request) error {
+	return va.ValidateRequestPath(req, requestPath(req))
+}
+
+// ValidateRequestPath validates a request sent by a client
+// that is accessing the given path.
+func (va *Validator) ValidateRequestPath(req *base.Request, path string) error {
 	var auth headers.Authorization
 	err := auth.Read(req.Header["Authorization"])
 	if err != nil {
@@ -122,81 +304,179 @@ func (va *Validator) ValidateRequest(req *base.Request) error {
 
 	switch auth.Method {
 	case headers.AuthBasic:
-		if !va.userHashed {
-			if auth.BasicUser != va.user {
-				return fmt.Errorf("wrong response")
-			}
-		} else {
-			if sha256Base64(auth.BasicUser) != va.user {
-				return fmt.Errorf("wrong response")
-			}
+		creds, err := va.store.Lookup(auth.BasicUser, va.realm, path, req.Method)
+		if err != nil {
+			return fmt.Errorf("wrong response")
 		}
 
-		if !va.passHashed {
-			if auth.BasicPass != va.pass {
-				return fmt.Errorf("wrong response")
-			}
-		} else {
-			if sha256Base64(auth.BasicPass) != va.pass {
-				return fmt.Errorf("wrong response")
-			}
+		if !va.checkPass(creds, auth.BasicUser, auth.BasicPass) {
+			return fmt.Errorf("wrong response")
 		}
 
+	case headers.AuthBearer:
+		return fmt.Errorf("unsupported authentication method")
+
 	default: // headers.AuthDigest
-		if auth.DigestValues.Realm == nil {
-			return fmt.Errorf("realm is missing")
-		}
+		return va.validateDigest(req, path, &auth.DigestValues)
+	}
+
+	return nil
+}
+
+func (va *Validator) checkPass(creds *Credentials, user string, pass string) bool {
+	switch {
+	case creds.BcryptHash != nil:
+		return bcrypt.CompareHashAndPassword(creds.BcryptHash, []byte(pass)) == nil
 
-		if auth.DigestValues.Nonce == nil {
-			return fmt.Errorf("nonce is missing")
+	case creds.passSHA256 != "":
+		return sha256Base64(pass) == creds.passSHA256
+
+	case creds.HA1 != nil:
+		for algorithm, ha1 := range creds.HA1 {
+			_, hf, err := digestAlgorithm(&algorithm)
+			if err != nil {
+				continue
+			}
+
+			return digestHA1(hf, user, va.realm, pass) == ha1
 		}
+		return false
+
+	default:
+		return pass == creds.Pass
+	}
+}
+
+func (va *Validator) validateDigest(req *base.Request, path string, vals *headers.Authenticate) error {
+	if vals.Realm == nil {
+		return fmt.Errorf("realm is missing")
+	}
+
+	if vals.Nonce == nil {
+		return fmt.Errorf("nonce is missing")
+	}
+
+	if vals.Username == nil {
+		return fmt.Errorf("username is missing")
+	}
+
+	if vals.URI == nil {
+		return fmt.Errorf("uri is missing")
+	}
+
+	if vals.Response == nil {
+		return fmt.Errorf("response is missing")
+	}
+
+	nonceCreated, ok := va.readNonce(*vals.Nonce)
+	if !ok {
+		return fmt.Errorf("wrong nonce")
+	}
+
+	if *vals.Realm != va.realm {
+		return fmt.Errorf("wrong realm")
+	}
 
-		if auth.DigestValues.Username == nil {
-			return fmt.Errorf("username is missing")
+	creds, err := va.store.Lookup(*vals.Username, va.realm, path, req.Method)
+	if err != nil {
+		return err
+	}
+
+	ur := req.URL
+
+	if *vals.URI != ur.String() {
+		// in SETUP requests, VLC strips the control attribute.
+		// try again with an alternative URL without the control attribute.
+		if altURL, ok := generateAltURL(req); ok {
+			ur = altURL
+
+			if *vals.URI != ur.String() {
+				return fmt.Errorf("wrong URL")
+			}
+		} else {
+			return fmt.Errorf("wrong URL")
 		}
+	}
 
-		if auth.DigestValues.URI == nil {
-			return fmt.Errorf("uri is missing")
+	algorithm, hf, err := digestAlgorithm(vals.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	var ha1 string
+	switch {
+	case creds.HA1 != nil:
+		var ok bool
+		ha1, ok = creds.HA1[algorithm]
+		if !ok {
+			return fmt.Errorf("credentials do not support the %s algorithm", algorithm)
 		}
 
-		if auth.DigestValues.Response == nil {
-			return fmt.Errorf("response is missing")
+	case creds.BcryptHash != nil || creds.passSHA256 != "":
+		return fmt.Errorf("credentials do not support the Digest method")
+
+	default:
+		ha1 = digestHA1(hf, *vals.Username, va.realm, creds.Pass)
+	}
+
+	params := digestParams{
+		hf:     hf,
+		ha1:    ha1,
+		nonce:  *vals.Nonce,
+		method: string(req.Method),
+		uri:    ur.String(),
+		body:   req.Body,
+	}
+
+	var nc uint32
+
+	// clients that don't support qop (RFC 2069) are accepted too.
+	if vals.QOP != nil {
+		if *vals.QOP != qopAuth && *vals.QOP != qopAuthInt {
+			return fmt.Errorf("unsupported qop (%v)", *vals.QOP)
 		}
 
-		if *auth.DigestValues.Nonce != va.nonce {
-			return fmt.Errorf("wrong nonce")
+		if vals.NC == nil {
+			return fmt.Errorf("nc is missing")
 		}
 
-		if *auth.DigestValues.Realm != va.realm {
-			return fmt.Errorf("wrong realm")
+		if vals.CNonce == nil {
+			return fmt.Errorf("cnonce is missing")
 		}
 
-		if *auth.DigestValues.Username != va.user {
-			return fmt.Errorf("wrong username")
+		tmp, err := strconv.ParseUint(*vals.NC, 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid nc (%v)", *vals.NC)
 		}
+		nc = uint32(tmp)
 
-		ur := req.URL
+		params.qop = *vals.QOP
+		params.nc = *vals.NC
+		params.cnonce = *vals.CNonce
+	}
 
-		if *auth.DigestValues.URI != ur.String() {
-			// in SETUP requests, VLC strips the control attribute.
-			// try again with an alternative URL without the control attribute.
-			if altURL, ok := generateAltURL(req); ok {
-				ur = altURL
+	if *vals.Response != digestResponse(params) {
+		return fmt.Errorf("wrong response")
+	}
 
-				if *auth.DigestValues.URI != ur.String() {
-					return fmt.Errorf("wrong URL")
-				}
-			} else {
-				return fmt.Errorf("wrong URL")
-			}
-		}
+	if timeNow().Sub(nonceCreated) >= nonceLifetime {
+		return ErrStaleNonce{}
+	}
 
-		response := md5Hex(md5Hex(va.user+":"+va.realm+":"+va.pass) +
-			":" + va.nonce + ":" + md5Hex(string(req.Method)+":"+ur.String()))
+	if vals.QOP != nil {
+		va.mutex.Lock()
+		defer va.mutex.Unlock()
 
-		if *auth.DigestValues.Response != response {
-			return fmt.Errorf("wrong response")
+		nonce, ok := va.usedNonce(*vals.Nonce, nonceCreated)
+		if !ok {
+			return ErrStaleNonce{}
+		}
+
+		// nonce counts must increase, in order to prevent replay attacks.
+		if nc <= nonce.nc {
+			return fmt.Errorf("wrong nc")
 		}
+		nonce.nc = nc
 	}
 
 	return nil
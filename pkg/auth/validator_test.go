@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -54,18 +56,77 @@ func TestValidatorErrors(t *testing.T) {
 			base.HeaderValue{`Digest realm=123,nonce=abcde,username=123,uri=123,response=123`},
 			"wrong realm",
 		},
+		{
+			"digest unsupported algorithm",
+			base.HeaderValue{`Digest realm=IPCAM,nonce=abcde,username=myuser,uri="rtsp://myhost/mypath",response=123,algorithm=SHA-1`},
+			"unsupported algorithm (SHA-1)",
+		},
+		{
+			"digest unsupported qop",
+			base.HeaderValue{`Digest realm=IPCAM,nonce=abcde,username=myuser,uri="rtsp://myhost/mypath",response=123,qop=other`},
+			"unsupported qop (other)",
+		},
+		{
+			"digest missing nc",
+			base.HeaderValue{`Digest realm=IPCAM,nonce=abcde,username=myuser,uri="rtsp://myhost/mypath",response=123,qop=auth`},
+			"nc is missing",
+		},
+		{
+			"digest missing cnonce",
+			base.HeaderValue{`Digest realm=IPCAM,nonce=abcde,username=myuser,uri="rtsp://myhost/mypath",response=123,qop=auth,nc=00000001`},
+			"cnonce is missing",
+		},
 	} {
 		t.Run(ca.name, func(t *testing.T) {
 			va := NewValidator("myuser", "mypass", nil)
-			va.nonce = "abcde"
+
+			// abcde is replaced with a valid nonce
+			nonce := va.generateNonce()
+			hv := make(base.HeaderValue, len(ca.hv))
+			for i, v := range ca.hv {
+				hv[i] = strings.ReplaceAll(v, "nonce=abcde", "nonce="+nonce)
+			}
+
 			err := va.ValidateRequest(&base.Request{
 				Method: base.Describe,
-				URL:    nil,
+				URL:    mustParseURL("rtsp://myhost/mypath"),
 				Header: base.Header{
-					"Authorization": ca.hv,
+					"Authorization": hv,
 				},
 			})
 			require.EqualError(t, err, ca.err)
 		})
 	}
 }
+
+func TestValidatorUsedNonces(t *testing.T) {
+	va := NewValidator("myuser", "mypass", nil)
+
+	// nonces that have not been used are not stored
+	for i := 0; i < 10; i++ {
+		va.Header()
+	}
+	require.Equal(t, 0, len(va.usedNonces))
+
+	nonces := make([]string, maxUsedNonces+1)
+	for i := range nonces {
+		nonces[i] = va.generateNonce()
+		created, ok := va.readNonce(nonces[i])
+		require.True(t, ok)
+		time.Sleep(time.Microsecond)
+
+		va.mutex.Lock()
+		_, ok = va.usedNonce(nonces[i], created)
+		va.mutex.Unlock()
+		require.True(t, ok)
+	}
+
+	// the table is bounded
+	require.Equal(t, maxUsedNonces, len(va.usedNonces))
+
+	// counts of forgotten nonces can't be checked anymore
+	va.mutex.Lock()
+	_, ok := va.usedNonce("forgotten", va.forgottenBefore)
+	va.mutex.Unlock()
+	require.False(t, ok)
+}
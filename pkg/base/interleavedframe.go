@@ -67,6 +67,49 @@ func ReadInterleavedFrameOrResponse(
 	return res, nil
 }
 
+// ReadInterleavedFrameOrRequestOrResponse reads an InterleavedFrame, a Request or a Response.
+func ReadInterleavedFrameOrRequestOrResponse(
+	frame *InterleavedFrame,
+	maxPayloadSize int,
+	req *Request,
+	res *Response,
+	br *bufio.Reader,
+) (interface{}, error) {
+	b, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	br.UnreadByte()
+
+	if b == interleavedFrameMagicByte {
+		err := frame.Read(maxPayloadSize, br)
+		if err != nil {
+			return nil, err
+		}
+		return frame, err
+	}
+
+	// responses start with the protocol, while requests start with the method
+	byts, err := br.Peek(len(rtspProtocolPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	if string(byts) == rtspProtocolPrefix {
+		err = res.Read(br)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	err = req.Read(br)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
 // InterleavedFrame is an interleaved frame, and allows to transfer binary data
 // within RTSP/TCP connections. It is used to send and receive RTP and RTCP packets with TCP.
 type InterleavedFrame struct {
@@ -191,3 +191,36 @@ func TestReadInterleavedFrameOrResponseErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestReadInterleavedFrameOrRequestOrResponse(t *testing.T) {
+	byts := []byte{0x24, 0x6, 0x0, 0x4, 0x1, 0x2, 0x3, 0x4}
+	byts = append(byts, []byte("SET_PARAMETER rtsp://example.com/media.mp4 RTSP/1.0\r\n"+
+		"CSeq: 1\r\n"+
+		"\r\n")...)
+	byts = append(byts, []byte("RTSP/1.0 200 OK\r\n"+
+		"CSeq: 2\r\n"+
+		"\r\n")...)
+	br := bufio.NewReader(bytes.NewBuffer(byts))
+
+	var f InterleavedFrame
+	var req Request
+	var res Response
+
+	out, err := ReadInterleavedFrameOrRequestOrResponse(&f, 1024, &req, &res, br)
+	require.NoError(t, err)
+	require.Equal(t, &f, out)
+	require.Equal(t, InterleavedFrame{
+		Channel: 6,
+		Payload: []byte{0x01, 0x02, 0x03, 0x04},
+	}, f)
+
+	out, err = ReadInterleavedFrameOrRequestOrResponse(&f, 1024, &req, &res, br)
+	require.NoError(t, err)
+	require.Equal(t, &req, out)
+	require.Equal(t, SetParameter, req.Method)
+
+	out, err = ReadInterleavedFrameOrRequestOrResponse(&f, 1024, &req, &res, br)
+	require.NoError(t, err)
+	require.Equal(t, &res, out)
+	require.Equal(t, StatusOK, res.StatusCode)
+}
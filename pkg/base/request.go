@@ -10,7 +10,6 @@ import (
 )
 
 const (
-	rtspProtocol10           = "RTSP/1.0"
 	requestMaxMethodLength   = 64
 	requestMaxURLLength      = 2048
 	requestMaxProtocolLength = 64
@@ -27,7 +26,9 @@ const (
 	Options      Method = "OPTIONS"
 	Pause        Method = "PAUSE"
 	Play         Method = "PLAY"
+	PlayNotify   Method = "PLAY_NOTIFY"
 	Record       Method = "RECORD"
+	Redirect     Method = "REDIRECT"
 	Setup        Method = "SETUP"
 	SetParameter Method = "SET_PARAMETER"
 	Teardown     Method = "TEARDOWN"
@@ -46,6 +47,10 @@ type Request struct {
 
 	// optional body
 	Body []byte
+
+	// protocol version.
+	// It defaults to RTSP/1.0.
+	Version Version
 }
 
 // Read reads a request.
@@ -78,8 +83,9 @@ func (req *Request) Read(rb *bufio.Reader) error {
 	}
 	proto := byts[:len(byts)-1]
 
-	if string(proto) != rtspProtocol10 {
-		return fmt.Errorf("expected '%s', got %v", rtspProtocol10, proto)
+	err = req.Version.read(proto)
+	if err != nil {
+		return err
 	}
 
 	err = readByteEqual(rb, '\n')
@@ -117,12 +123,35 @@ func (req *Request) ReadIgnoreFrames(maxPayloadSize int, rb *bufio.Reader) error
 	}
 }
 
+// ReadIgnoreResponses reads a request and ignores any response sent before the request.
+// Responses are sent by clients after receiving requests from the server.
+func (req *Request) ReadIgnoreResponses(rb *bufio.Reader) error {
+	var res Response
+
+	for {
+		// responses start with the protocol, while requests start with the method
+		byts, err := rb.Peek(len(rtspProtocolPrefix))
+		if err != nil {
+			return err
+		}
+
+		if string(byts) != rtspProtocolPrefix {
+			return req.Read(rb)
+		}
+
+		err = res.Read(rb)
+		if err != nil {
+			return err
+		}
+	}
+}
+
 // WriteSize returns the size of a Request.
 func (req Request) WriteSize() int {
 	n := 0
 
 	urStr := req.URL.CloneWithoutCredentials().String()
-	n += len([]byte(string(req.Method) + " " + urStr + " " + rtspProtocol10 + "\r\n"))
+	n += len([]byte(string(req.Method) + " " + urStr + " " + req.Version.String() + "\r\n"))
 
 	if len(req.Body) != 0 {
 		req.Header["Content-Length"] = HeaderValue{strconv.FormatInt(int64(len(req.Body)), 10)}
@@ -140,7 +169,7 @@ func (req Request) WriteTo(buf []byte) (int, error) {
 	pos := 0
 
 	urStr := req.URL.CloneWithoutCredentials().String()
-	pos += copy(buf[pos:], []byte(string(req.Method)+" "+urStr+" "+rtspProtocol10+"\r\n"))
+	pos += copy(buf[pos:], []byte(string(req.Method)+" "+urStr+" "+req.Version.String()+"\r\n"))
 
 	if len(req.Body) != 0 {
 		req.Header["Content-Length"] = HeaderValue{strconv.FormatInt(int64(len(req.Body)), 10)}
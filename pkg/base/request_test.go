@@ -40,6 +40,24 @@ var casesRequest = []struct {
 			},
 		},
 	},
+	{
+		"play notify",
+		[]byte("PLAY_NOTIFY rtsp://example.com/media.mp4 RTSP/2.0\r\n" +
+			"CSeq: 854\r\n" +
+			"Notify-Reason: end-of-stream\r\n" +
+			"Session: uZ3ci0K+Ld\r\n" +
+			"\r\n"),
+		Request{
+			Method: "PLAY_NOTIFY",
+			URL:    mustParseURL("rtsp://example.com/media.mp4"),
+			Header: Header{
+				"CSeq":          HeaderValue{"854"},
+				"Notify-Reason": HeaderValue{"end-of-stream"},
+				"Session":       HeaderValue{"uZ3ci0K+Ld"},
+			},
+			Version: Version20,
+		},
+	},
 	{
 		"describe",
 		[]byte("DESCRIBE rtsp://example.com/media.mp4 RTSP/1.0\r\n" +
@@ -197,7 +215,7 @@ func TestRequestReadErrors(t *testing.T) {
 		{
 			"empty protocol",
 			[]byte("GET rtsp://testing123 \r\n"),
-			"expected 'RTSP/1.0', got []",
+			"expected 'RTSP/1.0' or 'RTSP/2.0', got []",
 		},
 		{
 			"invalid URL",
@@ -206,8 +224,8 @@ func TestRequestReadErrors(t *testing.T) {
 		},
 		{
 			"invalid protocol",
-			[]byte("GET rtsp://testing123 RTSP/2.0\r\n"),
-			"expected 'RTSP/1.0', got [82 84 83 80 47 50 46 48]",
+			[]byte("GET rtsp://testing123 RTSP/3.0\r\n"),
+			"expected 'RTSP/1.0' or 'RTSP/2.0', got [82 84 83 80 47 51 46 48]",
 		},
 		{
 			"invalid header",
@@ -261,6 +279,22 @@ func TestRequestReadIgnoreFramesErrors(t *testing.T) {
 	require.EqualError(t, err, "EOF")
 }
 
+func TestRequestReadIgnoreResponses(t *testing.T) {
+	byts := []byte("RTSP/1.0 200 OK\r\n" +
+		"CSeq: 1\r\n" +
+		"\r\n" +
+		"OPTIONS rtsp://example.com/media.mp4 RTSP/1.0\r\n" +
+		"CSeq: 2\r\n" +
+		"\r\n")
+
+	rb := bufio.NewReader(bytes.NewBuffer(byts))
+	var req Request
+	err := req.ReadIgnoreResponses(rb)
+	require.NoError(t, err)
+	require.Equal(t, Options, req.Method)
+	require.Equal(t, HeaderValue{"2"}, req.Header["CSeq"])
+}
+
 func TestRequestString(t *testing.T) {
 	byts := []byte("OPTIONS rtsp://example.com/media.mp4 RTSP/1.0\r\n" +
 		"CSeq: 1\r\n" +
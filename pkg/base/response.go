@@ -130,6 +130,10 @@ type Response struct {
 
 	// optional body
 	Body []byte
+
+	// protocol version.
+	// It defaults to RTSP/1.0.
+	Version Version
 }
 
 // Read reads a response.
@@ -140,8 +144,9 @@ func (res *Response) Read(rb *bufio.Reader) error {
 	}
 	proto := byts[:len(byts)-1]
 
-	if string(proto) != rtspProtocol10 {
-		return fmt.Errorf("expected '%s', got %v", rtspProtocol10, proto)
+	err = res.Version.read(proto)
+	if err != nil {
+		return err
 	}
 
 	byts, err = readBytesLimited(rb, ' ', 4)
@@ -211,7 +216,7 @@ func (res Response) WriteSize() int {
 		}
 	}
 
-	n += len([]byte(rtspProtocol10 + " " +
+	n += len([]byte(res.Version.String() + " " +
 		strconv.FormatInt(int64(res.StatusCode), 10) + " " +
 		res.StatusMessage + "\r\n"))
 
@@ -236,7 +241,7 @@ func (res Response) WriteTo(buf []byte) (int, error) {
 
 	pos := 0
 
-	pos += copy(buf[pos:], []byte(rtspProtocol10+" "+
+	pos += copy(buf[pos:], []byte(res.Version.String()+" "+
 		strconv.FormatInt(int64(res.StatusCode), 10)+" "+
 		res.StatusMessage+"\r\n"))
 
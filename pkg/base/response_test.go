@@ -37,6 +37,23 @@ var casesResponse = []struct {
 			},
 		},
 	},
+	{
+		"ok rtsp 2.0",
+		[]byte("RTSP/2.0 200 OK\r\n" +
+			"CSeq: 1\r\n" +
+			"Pipelined-Requests: 7c9\r\n" +
+			"\r\n",
+		),
+		Response{
+			StatusCode:    StatusOK,
+			StatusMessage: "OK",
+			Header: Header{
+				"CSeq":               HeaderValue{"1"},
+				"Pipelined-Requests": HeaderValue{"7c9"},
+			},
+			Version: Version20,
+		},
+	},
 	{
 		"ok with payload",
 		[]byte("RTSP/1.0 200 OK\r\n" +
@@ -138,8 +155,8 @@ func TestResponseReadErrors(t *testing.T) {
 		},
 		{
 			"invalid protocol",
-			[]byte("RTSP/2.0 200 OK\r\n"),
-			"expected 'RTSP/1.0', got [82 84 83 80 47 50 46 48]",
+			[]byte("RTSP/3.0 200 OK\r\n"),
+			"expected 'RTSP/1.0' or 'RTSP/2.0', got [82 84 83 80 47 51 46 48]",
 		},
 		{
 			"code too long",
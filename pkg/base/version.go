@@ -0,0 +1,43 @@
+package base
+
+import (
+	"fmt"
+)
+
+const (
+	rtspProtocol10     = "RTSP/1.0"
+	rtspProtocol20     = "RTSP/2.0"
+	rtspProtocolPrefix = "RTSP/"
+)
+
+// Version is a RTSP protocol version.
+type Version int
+
+// versions.
+const (
+	Version10 Version = iota
+	Version20
+)
+
+// String implements fmt.Stringer.
+func (v Version) String() string {
+	if v == Version20 {
+		return rtspProtocol20
+	}
+	return rtspProtocol10
+}
+
+func (v *Version) read(proto []byte) error {
+	switch string(proto) {
+	case rtspProtocol10:
+		*v = Version10
+
+	case rtspProtocol20:
+		*v = Version20
+
+	default:
+		return fmt.Errorf("expected '%s' or '%s', got %v", rtspProtocol10, rtspProtocol20, proto)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package headers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+// AcceptRanges is an Accept-Ranges header (RTSP 2.0).
+// It contains the range formats supported by a server (npt, clock, smpte).
+type AcceptRanges []string
+
+// Read decodes an Accept-Ranges header.
+func (h *AcceptRanges) Read(v base.HeaderValue) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value not provided")
+	}
+
+	if len(v) > 1 {
+		return fmt.Errorf("value provided multiple times (%v)", v)
+	}
+
+	*h = nil
+
+	for _, part := range strings.Split(v[0], ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return fmt.Errorf("invalid value (%v)", v[0])
+		}
+		*h = append(*h, part)
+	}
+
+	return nil
+}
+
+// Write encodes an Accept-Ranges header.
+func (h AcceptRanges) Write() base.HeaderValue {
+	return base.HeaderValue{strings.Join(h, ", ")}
+}
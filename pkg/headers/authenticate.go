@@ -17,6 +17,9 @@ const (
 
 	// AuthDigest is the Digest authentication method
 	AuthDigest
+
+	// AuthBearer is the Bearer authentication method (RFC 6750)
+	AuthBearer
 )
 
 // Authenticate is an Authenticate or a WWW-Authenticate header.
@@ -47,6 +50,22 @@ type Authenticate struct {
 
 	// (optional) algorithm
 	Algorithm *string
+
+	// (optional) quality of protection.
+	// In challenges, it can contain multiple comma-separated values.
+	QOP *string
+
+	// (optional) nonce count
+	NC *string
+
+	// (optional) client nonce
+	CNonce *string
+
+	// (optional) scope (Bearer)
+	Scope *string
+
+	// (optional) error (Bearer)
+	Error *string
 }
 
 // Read decodes an Authenticate or a WWW-Authenticate header.
@@ -74,6 +93,9 @@ func (h *Authenticate) Read(v base.HeaderValue) error {
 	case "Digest":
 		h.Method = AuthDigest
 
+	case "Bearer":
+		h.Method = AuthBearer
+
 	default:
 		return fmt.Errorf("invalid method (%s)", method)
 	}
@@ -110,6 +132,21 @@ func (h *Authenticate) Read(v base.HeaderValue) error {
 
 		case "algorithm":
 			h.Algorithm = &v
+
+		case "qop":
+			h.QOP = &v
+
+		case "nc":
+			h.NC = &v
+
+		case "cnonce":
+			h.CNonce = &v
+
+		case "scope":
+			h.Scope = &v
+
+		case "error":
+			h.Error = &v
 		}
 	}
 
@@ -126,6 +163,9 @@ func (h Authenticate) Write() base.HeaderValue {
 
 	case AuthDigest:
 		ret += "Digest"
+
+	case AuthBearer:
+		ret += "Bearer"
 	}
 
 	ret += " "
@@ -164,6 +204,31 @@ func (h Authenticate) Write() base.HeaderValue {
 		rets = append(rets, "algorithm=\""+*h.Algorithm+"\"")
 	}
 
+	if h.QOP != nil {
+		// in responses, qop is a token and must not be quoted.
+		if h.Response != nil {
+			rets = append(rets, "qop="+*h.QOP)
+		} else {
+			rets = append(rets, "qop=\""+*h.QOP+"\"")
+		}
+	}
+
+	if h.NC != nil {
+		rets = append(rets, "nc="+*h.NC)
+	}
+
+	if h.CNonce != nil {
+		rets = append(rets, "cnonce=\""+*h.CNonce+"\"")
+	}
+
+	if h.Scope != nil {
+		rets = append(rets, "scope=\""+*h.Scope+"\"")
+	}
+
+	if h.Error != nil {
+		rets = append(rets, "error=\""+*h.Error+"\"")
+	}
+
 	ret += strings.Join(rets, ", ")
 
 	return base.HeaderValue{ret}
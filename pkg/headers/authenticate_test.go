@@ -176,6 +176,90 @@ var casesAuthenticate = []struct {
 			}(),
 		},
 	},
+	{
+		"digest request sha-256 with qop",
+		base.HeaderValue{`Digest realm="IPCAM", nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", ` +
+			`algorithm=SHA-256, qop="auth, auth-int"`},
+		base.HeaderValue{`Digest realm="IPCAM", nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", ` +
+			`algorithm="SHA-256", qop="auth, auth-int"`},
+		Authenticate{
+			Method: AuthDigest,
+			Realm: func() *string {
+				v := "IPCAM"
+				return &v
+			}(),
+			Nonce: func() *string {
+				v := "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v"
+				return &v
+			}(),
+			Algorithm: func() *string {
+				v := "SHA-256"
+				return &v
+			}(),
+			QOP: func() *string {
+				v := "auth, auth-int"
+				return &v
+			}(),
+		},
+	},
+	{
+		"digest response with qop",
+		base.HeaderValue{`Digest username="aa", realm="bb", nonce="cc", uri="dd", response="ee", ` +
+			`qop=auth, nc=00000001, cnonce="ff"`},
+		base.HeaderValue{`Digest username="aa", realm="bb", nonce="cc", uri="dd", response="ee", ` +
+			`qop=auth, nc=00000001, cnonce="ff"`},
+		Authenticate{
+			Method: AuthDigest,
+			Username: func() *string {
+				v := "aa"
+				return &v
+			}(),
+			Realm: func() *string {
+				v := "bb"
+				return &v
+			}(),
+			Nonce: func() *string {
+				v := "cc"
+				return &v
+			}(),
+			URI: func() *string {
+				v := "dd"
+				return &v
+			}(),
+			Response: func() *string {
+				v := "ee"
+				return &v
+			}(),
+			QOP: func() *string {
+				v := "auth"
+				return &v
+			}(),
+			NC: func() *string {
+				v := "00000001"
+				return &v
+			}(),
+			CNonce: func() *string {
+				v := "ff"
+				return &v
+			}(),
+		},
+	},
+	{
+		"bearer",
+		base.HeaderValue{`Bearer realm="IPCAM", error="invalid_token"`},
+		base.HeaderValue{`Bearer realm="IPCAM", error="invalid_token"`},
+		Authenticate{
+			Method: AuthBearer,
+			Realm: func() *string {
+				v := "IPCAM"
+				return &v
+			}(),
+			Error: func() *string {
+				v := "invalid_token"
+				return &v
+			}(),
+		},
+	},
 }
 
 func TestAuthenticateRead(t *testing.T) {
@@ -21,6 +21,9 @@ type Authorization struct {
 
 	// digest values
 	DigestValues Authenticate
+
+	// bearer token
+	BearerToken string
 }
 
 // Read decodes an Authorization header.
@@ -64,6 +67,10 @@ func (h *Authorization) Read(v base.HeaderValue) error {
 
 		h.DigestValues = vals
 
+	case strings.HasPrefix(v0, "Bearer "):
+		h.Method = AuthBearer
+		h.BearerToken = strings.TrimSpace(v0[len("Bearer "):])
+
 	default:
 		return fmt.Errorf("invalid authorization header")
 	}
@@ -79,6 +86,9 @@ func (h Authorization) Write() base.HeaderValue {
 
 		return base.HeaderValue{"Basic " + response}
 
+	case AuthBearer:
+		return base.HeaderValue{"Bearer " + h.BearerToken}
+
 	default: // AuthDigest
 		return h.DigestValues.Write()
 	}
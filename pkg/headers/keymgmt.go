@@ -0,0 +1,99 @@
+package headers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+// KeyMgmtEntry is an entry of a KeyMgmt header.
+type KeyMgmtEntry struct {
+	// key management protocol.
+	Protocol string
+
+	// (optional) URL of the stream the entry refers to.
+	URL string
+
+	// key management data.
+	Data []byte
+}
+
+// KeyMgmt is a KeyMgmt header (RFC 4567).
+type KeyMgmt []*KeyMgmtEntry
+
+// Read decodes a KeyMgmt header.
+func (h *KeyMgmt) Read(v base.HeaderValue) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value not provided")
+	}
+
+	if len(v) > 1 {
+		return fmt.Errorf("value provided multiple times (%v)", v)
+	}
+
+	for _, part := range strings.Split(v[0], ",") {
+		e := &KeyMgmtEntry{}
+
+		// remove leading spaces
+		part = strings.TrimLeft(part, " ")
+
+		kvs, err := keyValParse(part, ';')
+		if err != nil {
+			return err
+		}
+
+		for k, v := range kvs {
+			switch k {
+			case "prot":
+				e.Protocol = v
+
+			case "uri":
+				e.URL = v
+
+			case "data":
+				byts, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					return fmt.Errorf("invalid data (%v)", v)
+				}
+				e.Data = byts
+
+			default:
+				// ignore non-standard keys
+			}
+		}
+
+		if e.Protocol == "" {
+			return fmt.Errorf("protocol is missing")
+		}
+
+		if e.Data == nil {
+			return fmt.Errorf("data is missing")
+		}
+
+		*h = append(*h, e)
+	}
+
+	return nil
+}
+
+// Write encodes a KeyMgmt header.
+func (h KeyMgmt) Write() base.HeaderValue {
+	rets := make([]string, len(h))
+
+	for i, e := range h {
+		var tmp []string
+		tmp = append(tmp, "prot="+e.Protocol)
+
+		if e.URL != "" {
+			tmp = append(tmp, "uri=\""+e.URL+"\"")
+		}
+
+		tmp = append(tmp, "data=\""+base64.StdEncoding.EncodeToString(e.Data)+"\"")
+
+		rets[i] = strings.Join(tmp, ";")
+	}
+
+	return base.HeaderValue{strings.Join(rets, ",")}
+}
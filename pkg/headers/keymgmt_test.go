@@ -0,0 +1,124 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+var casesKeyMgmt = []struct {
+	name string
+	vin  base.HeaderValue
+	vout base.HeaderValue
+	h    KeyMgmt
+}{
+	{
+		"single value",
+		base.HeaderValue{`prot=mikey;uri="rtsp://127.0.0.1/test/trackID=0";data="AQIDBA=="`},
+		base.HeaderValue{`prot=mikey;uri="rtsp://127.0.0.1/test/trackID=0";data="AQIDBA=="`},
+		KeyMgmt{
+			{
+				Protocol: "mikey",
+				URL:      "rtsp://127.0.0.1/test/trackID=0",
+				Data:     []byte{1, 2, 3, 4},
+			},
+		},
+	},
+	{
+		"multiple values",
+		base.HeaderValue{`prot=mikey; uri="rtsp://127.0.0.1/test/trackID=0"; data="AQIDBA==", ` +
+			`prot=mikey; uri="rtsp://127.0.0.1/test/trackID=1"; data="BQYHCA=="`},
+		base.HeaderValue{`prot=mikey;uri="rtsp://127.0.0.1/test/trackID=0";data="AQIDBA==",` +
+			`prot=mikey;uri="rtsp://127.0.0.1/test/trackID=1";data="BQYHCA=="`},
+		KeyMgmt{
+			{
+				Protocol: "mikey",
+				URL:      "rtsp://127.0.0.1/test/trackID=0",
+				Data:     []byte{1, 2, 3, 4},
+			},
+			{
+				Protocol: "mikey",
+				URL:      "rtsp://127.0.0.1/test/trackID=1",
+				Data:     []byte{5, 6, 7, 8},
+			},
+		},
+	},
+	{
+		"missing url",
+		base.HeaderValue{`prot=mikey;data="AQIDBA=="`},
+		base.HeaderValue{`prot=mikey;data="AQIDBA=="`},
+		KeyMgmt{
+			{
+				Protocol: "mikey",
+				Data:     []byte{1, 2, 3, 4},
+			},
+		},
+	},
+}
+
+func TestKeyMgmtRead(t *testing.T) {
+	for _, ca := range casesKeyMgmt {
+		t.Run(ca.name, func(t *testing.T) {
+			var h KeyMgmt
+			err := h.Read(ca.vin)
+			require.NoError(t, err)
+			require.Equal(t, ca.h, h)
+		})
+	}
+}
+
+func TestKeyMgmtReadErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		hv   base.HeaderValue
+		err  string
+	}{
+		{
+			"empty",
+			base.HeaderValue{},
+			"value not provided",
+		},
+		{
+			"2 values",
+			base.HeaderValue{"a", "b"},
+			"value provided multiple times ([a b])",
+		},
+		{
+			"invalid key-value",
+			base.HeaderValue{"test=\"a"},
+			"apexes not closed (test=\"a)",
+		},
+		{
+			"invalid data",
+			base.HeaderValue{`prot=mikey;data="!!"`},
+			"invalid data (!!)",
+		},
+		{
+			"missing protocol",
+			base.HeaderValue{`data="AQIDBA=="`},
+			"protocol is missing",
+		},
+		{
+			"missing data",
+			base.HeaderValue{`prot=mikey`},
+			"data is missing",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var h KeyMgmt
+			err := h.Read(ca.hv)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestKeyMgmtWrite(t *testing.T) {
+	for _, ca := range casesKeyMgmt {
+		t.Run(ca.name, func(t *testing.T) {
+			req := ca.h.Write()
+			require.Equal(t, ca.vout, req)
+		})
+	}
+}
@@ -16,6 +16,29 @@ func readKey(origstr string, str string, separator byte) (string, string) {
 	return str[:i], str[i:]
 }
 
+func readMultipartValue(origstr string, str string, separator byte) (string, string, error) {
+	inQuotes := false
+	i := 0
+	for {
+		if i >= len(str) {
+			if inQuotes {
+				return "", "", fmt.Errorf("apexes not closed (%v)", origstr)
+			}
+			return str, "", nil
+		}
+
+		switch {
+		case str[i] == '"':
+			inQuotes = !inQuotes
+
+		case str[i] == separator && !inQuotes:
+			return str[:i], str[i:], nil
+		}
+
+		i++
+	}
+}
+
 func readValue(origstr string, str string, separator byte) (string, string, error) {
 	if len(str) > 0 && str[0] == '"' {
 		i := 1
@@ -25,6 +48,12 @@ func readValue(origstr string, str string, separator byte) (string, string, erro
 			}
 
 			if str[i] == '"' {
+				// values made of multiple quoted parts separated by slashes
+				// (i.e. RTSP 2.0 addresses) are returned as they are.
+				if i+1 < len(str) && str[i+1] == '/' {
+					return readMultipartValue(origstr, str, separator)
+				}
+
 				return str[1:i], str[i+1:], nil
 			}
 
@@ -68,6 +68,14 @@ func TestKeyValParse(t *testing.T) {
 				"key2": "",
 			},
 		},
+		{
+			"with multiple apexes",
+			`key1=":6256"/":6257",key2=v2`,
+			map[string]string{
+				"key1": `":6256"/":6257"`,
+				"key2": "v2",
+			},
+		},
 		{
 			"no val key1 nor key2",
 			`key1, key2`,
@@ -0,0 +1,223 @@
+package headers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+// MediaPropertiesRandomAccess is the random access property of a media.
+type MediaPropertiesRandomAccess int
+
+// random access properties.
+const (
+	MediaPropertiesRandomAccessRandomAccess MediaPropertiesRandomAccess = iota
+	MediaPropertiesRandomAccessBeginningOnly
+	MediaPropertiesRandomAccessNoSeeking
+)
+
+// MediaPropertiesContentModifications is the content modifications property of a media.
+type MediaPropertiesContentModifications int
+
+// content modifications properties.
+const (
+	MediaPropertiesContentModificationsImmutable MediaPropertiesContentModifications = iota
+	MediaPropertiesContentModificationsDynamic
+	MediaPropertiesContentModificationsTimeProgressing
+)
+
+// MediaProperties is a Media-Properties header (RTSP 2.0).
+type MediaProperties struct {
+	// (optional) random access property
+	RandomAccess *MediaPropertiesRandomAccess
+
+	// (optional) maximum interval between random access points, in seconds.
+	// It is used with MediaPropertiesRandomAccessRandomAccess.
+	RandomAccessInterval *float64
+
+	// (optional) content modifications property
+	ContentModifications *MediaPropertiesContentModifications
+
+	// whether the media is retained for an unlimited time
+	Unlimited bool
+
+	// (optional) time until the media is retained, in UTC format
+	TimeLimited *string
+
+	// (optional) duration of the retention of the media, in seconds
+	TimeDuration *float64
+
+	// (optional) supported scales, or ranges of scales (i.e. "0.5:1.5")
+	Scales []string
+}
+
+// splitMediaProperties splits properties separated by commas,
+// excluding commas inside quotes.
+func splitMediaProperties(v string) []string {
+	var ret []string
+	inQuotes := false
+	start := 0
+
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '"':
+			inQuotes = !inQuotes
+
+		case ',':
+			if !inQuotes {
+				ret = append(ret, v[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(ret, v[start:])
+}
+
+// Read decodes a Media-Properties header.
+func (h *MediaProperties) Read(v base.HeaderValue) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value not provided")
+	}
+
+	if len(v) > 1 {
+		return fmt.Errorf("value provided multiple times (%v)", v)
+	}
+
+	for _, prop := range splitMediaProperties(v[0]) {
+		prop = strings.TrimSpace(prop)
+		if prop == "" {
+			continue
+		}
+
+		var key, val string
+		if i := strings.IndexByte(prop, '='); i >= 0 {
+			key, val = strings.TrimSpace(prop[:i]), strings.TrimSpace(prop[i+1:])
+		} else {
+			key = prop
+		}
+
+		switch key {
+		case "Random-Access":
+			v := MediaPropertiesRandomAccessRandomAccess
+			h.RandomAccess = &v
+
+			if val != "" {
+				tmp, err := strconv.ParseFloat(val, 64)
+				if err != nil {
+					return err
+				}
+				h.RandomAccessInterval = &tmp
+			}
+
+		case "Beginning-Only":
+			v := MediaPropertiesRandomAccessBeginningOnly
+			h.RandomAccess = &v
+
+		case "No-Seeking":
+			v := MediaPropertiesRandomAccessNoSeeking
+			h.RandomAccess = &v
+
+		case "Immutable":
+			v := MediaPropertiesContentModificationsImmutable
+			h.ContentModifications = &v
+
+		case "Dynamic":
+			v := MediaPropertiesContentModificationsDynamic
+			h.ContentModifications = &v
+
+		case "Time-Progressing":
+			v := MediaPropertiesContentModificationsTimeProgressing
+			h.ContentModifications = &v
+
+		case "Unlimited":
+			h.Unlimited = true
+
+		case "Time-Limited":
+			if val == "" {
+				return fmt.Errorf("invalid property (%v)", prop)
+			}
+			h.TimeLimited = &val
+
+		case "Time-Duration":
+			tmp, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return err
+			}
+			h.TimeDuration = &tmp
+
+		case "Scales":
+			val = strings.TrimPrefix(val, "\"")
+			val = strings.TrimSuffix(val, "\"")
+
+			for _, scale := range strings.Split(val, ",") {
+				scale = strings.TrimSpace(scale)
+				if scale == "" {
+					return fmt.Errorf("invalid property (%v)", prop)
+				}
+				h.Scales = append(h.Scales, scale)
+			}
+
+		default:
+			// ignore non-standard properties
+		}
+	}
+
+	return nil
+}
+
+// Write encodes a Media-Properties header.
+func (h MediaProperties) Write() base.HeaderValue {
+	var rets []string
+
+	if h.RandomAccess != nil {
+		switch *h.RandomAccess {
+		case MediaPropertiesRandomAccessRandomAccess:
+			if h.RandomAccessInterval != nil {
+				rets = append(rets, "Random-Access="+
+					strconv.FormatFloat(*h.RandomAccessInterval, 'f', -1, 64))
+			} else {
+				rets = append(rets, "Random-Access")
+			}
+
+		case MediaPropertiesRandomAccessBeginningOnly:
+			rets = append(rets, "Beginning-Only")
+
+		default:
+			rets = append(rets, "No-Seeking")
+		}
+	}
+
+	if h.ContentModifications != nil {
+		switch *h.ContentModifications {
+		case MediaPropertiesContentModificationsImmutable:
+			rets = append(rets, "Immutable")
+
+		case MediaPropertiesContentModificationsDynamic:
+			rets = append(rets, "Dynamic")
+
+		default:
+			rets = append(rets, "Time-Progressing")
+		}
+	}
+
+	if h.Unlimited {
+		rets = append(rets, "Unlimited")
+	}
+
+	if h.TimeLimited != nil {
+		rets = append(rets, "Time-Limited="+*h.TimeLimited)
+	}
+
+	if h.TimeDuration != nil {
+		rets = append(rets, "Time-Duration="+strconv.FormatFloat(*h.TimeDuration, 'f', -1, 64))
+	}
+
+	if h.Scales != nil {
+		rets = append(rets, "Scales=\""+strings.Join(h.Scales, ", ")+"\"")
+	}
+
+	return base.HeaderValue{strings.Join(rets, ", ")}
+}
@@ -0,0 +1,136 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+var casesMediaProperties = []struct {
+	name string
+	vin  base.HeaderValue
+	vout base.HeaderValue
+	h    MediaProperties
+}{
+	{
+		"live",
+		base.HeaderValue{`No-Seeking, Time-Progressing, Time-Duration=0.0`},
+		base.HeaderValue{`No-Seeking, Time-Progressing, Time-Duration=0`},
+		MediaProperties{
+			RandomAccess: func() *MediaPropertiesRandomAccess {
+				v := MediaPropertiesRandomAccessNoSeeking
+				return &v
+			}(),
+			ContentModifications: func() *MediaPropertiesContentModifications {
+				v := MediaPropertiesContentModificationsTimeProgressing
+				return &v
+			}(),
+			TimeDuration: func() *float64 {
+				v := float64(0)
+				return &v
+			}(),
+		},
+	},
+	{
+		"on demand",
+		base.HeaderValue{`Random-Access=2.5, Unlimited, Immutable, Scales="-20, -10, -4, 0.5:1.5, 4, 8, 10, 15, 20"`},
+		base.HeaderValue{`Random-Access=2.5, Immutable, Unlimited, Scales="-20, -10, -4, 0.5:1.5, 4, 8, 10, 15, 20"`},
+		MediaProperties{
+			RandomAccess: func() *MediaPropertiesRandomAccess {
+				v := MediaPropertiesRandomAccessRandomAccess
+				return &v
+			}(),
+			RandomAccessInterval: func() *float64 {
+				v := 2.5
+				return &v
+			}(),
+			ContentModifications: func() *MediaPropertiesContentModifications {
+				v := MediaPropertiesContentModificationsImmutable
+				return &v
+			}(),
+			Unlimited: true,
+			Scales:    []string{"-20", "-10", "-4", "0.5:1.5", "4", "8", "10", "15", "20"},
+		},
+	},
+	{
+		"time limited",
+		base.HeaderValue{`Beginning-Only, Dynamic, Time-Limited=20081128T165900.000Z`},
+		base.HeaderValue{`Beginning-Only, Dynamic, Time-Limited=20081128T165900.000Z`},
+		MediaProperties{
+			RandomAccess: func() *MediaPropertiesRandomAccess {
+				v := MediaPropertiesRandomAccessBeginningOnly
+				return &v
+			}(),
+			ContentModifications: func() *MediaPropertiesContentModifications {
+				v := MediaPropertiesContentModificationsDynamic
+				return &v
+			}(),
+			TimeLimited: func() *string {
+				v := "20081128T165900.000Z"
+				return &v
+			}(),
+		},
+	},
+}
+
+func TestMediaPropertiesRead(t *testing.T) {
+	for _, ca := range casesMediaProperties {
+		t.Run(ca.name, func(t *testing.T) {
+			var h MediaProperties
+			err := h.Read(ca.vin)
+			require.NoError(t, err)
+			require.Equal(t, ca.h, h)
+		})
+	}
+}
+
+func TestMediaPropertiesReadErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		hv   base.HeaderValue
+		err  string
+	}{
+		{
+			"empty",
+			base.HeaderValue{},
+			"value not provided",
+		},
+		{
+			"2 values",
+			base.HeaderValue{"Immutable", "Dynamic"},
+			"value provided multiple times ([Immutable Dynamic])",
+		},
+		{
+			"invalid random access",
+			base.HeaderValue{"Random-Access=aa"},
+			"strconv.ParseFloat: parsing \"aa\": invalid syntax",
+		},
+		{
+			"invalid time duration",
+			base.HeaderValue{"Time-Duration=aa"},
+			"strconv.ParseFloat: parsing \"aa\": invalid syntax",
+		},
+		{
+			"empty time limited",
+			base.HeaderValue{"Time-Limited"},
+			"invalid property (Time-Limited)",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var h MediaProperties
+			err := h.Read(ca.hv)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestMediaPropertiesWrite(t *testing.T) {
+	for _, ca := range casesMediaProperties {
+		t.Run(ca.name, func(t *testing.T) {
+			req := ca.h.Write()
+			require.Equal(t, ca.vout, req)
+		})
+	}
+}
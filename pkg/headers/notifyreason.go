@@ -0,0 +1,43 @@
+package headers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+// NotifyReason is a Notify-Reason header (RTSP 2.0).
+// It contains the reason of a PLAY_NOTIFY request.
+type NotifyReason string
+
+// notify reasons.
+const (
+	NotifyReasonEndOfStream           NotifyReason = "end-of-stream"
+	NotifyReasonMediaPropertiesUpdate NotifyReason = "media-properties-update"
+	NotifyReasonScaleChange           NotifyReason = "scale-change"
+)
+
+// Read decodes a Notify-Reason header.
+func (h *NotifyReason) Read(v base.HeaderValue) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value not provided")
+	}
+
+	if len(v) > 1 {
+		return fmt.Errorf("value provided multiple times (%v)", v)
+	}
+
+	v0 := strings.TrimSpace(v[0])
+	if v0 == "" {
+		return fmt.Errorf("invalid value (%v)", v[0])
+	}
+
+	*h = NotifyReason(v0)
+	return nil
+}
+
+// Write encodes a Notify-Reason header.
+func (h NotifyReason) Write() base.HeaderValue {
+	return base.HeaderValue{string(h)}
+}
@@ -0,0 +1,50 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+func TestNotifyReasonRead(t *testing.T) {
+	var h NotifyReason
+	err := h.Read(base.HeaderValue{"end-of-stream"})
+	require.NoError(t, err)
+	require.Equal(t, NotifyReasonEndOfStream, h)
+}
+
+func TestNotifyReasonReadErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		hv   base.HeaderValue
+		err  string
+	}{
+		{
+			"empty",
+			base.HeaderValue{},
+			"value not provided",
+		},
+		{
+			"2 values",
+			base.HeaderValue{"end-of-stream", "scale-change"},
+			"value provided multiple times ([end-of-stream scale-change])",
+		},
+		{
+			"empty reason",
+			base.HeaderValue{" "},
+			"invalid value ( )",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var h NotifyReason
+			err := h.Read(ca.hv)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestNotifyReasonWrite(t *testing.T) {
+	require.Equal(t, base.HeaderValue{"scale-change"}, NotifyReasonScaleChange.Write())
+}
@@ -0,0 +1,40 @@
+package headers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+// PipelinedRequests is a Pipelined-Requests header (RTSP 2.0).
+// It allows to send requests that depend on a session that is not established yet.
+type PipelinedRequests uint32
+
+// Read decodes a Pipelined-Requests header.
+func (h *PipelinedRequests) Read(v base.HeaderValue) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value not provided")
+	}
+
+	if len(v) > 1 {
+		return fmt.Errorf("value provided multiple times (%v)", v)
+	}
+
+	if len(v[0]) > 8 {
+		return fmt.Errorf("invalid value (%v)", v[0])
+	}
+
+	tmp, err := strconv.ParseUint(v[0], 16, 32)
+	if err != nil {
+		return err
+	}
+
+	*h = PipelinedRequests(tmp)
+	return nil
+}
+
+// Write encodes a Pipelined-Requests header.
+func (h PipelinedRequests) Write() base.HeaderValue {
+	return base.HeaderValue{strconv.FormatUint(uint64(h), 16)}
+}
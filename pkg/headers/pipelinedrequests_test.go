@@ -0,0 +1,55 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+func TestPipelinedRequestsRead(t *testing.T) {
+	var h PipelinedRequests
+	err := h.Read(base.HeaderValue{"7c9"})
+	require.NoError(t, err)
+	require.Equal(t, PipelinedRequests(0x7c9), h)
+}
+
+func TestPipelinedRequestsReadErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		hv   base.HeaderValue
+		err  string
+	}{
+		{
+			"empty",
+			base.HeaderValue{},
+			"value not provided",
+		},
+		{
+			"2 values",
+			base.HeaderValue{"1", "2"},
+			"value provided multiple times ([1 2])",
+		},
+		{
+			"too long",
+			base.HeaderValue{"123456789"},
+			"invalid value (123456789)",
+		},
+		{
+			"invalid value",
+			base.HeaderValue{"zz"},
+			"strconv.ParseUint: parsing \"zz\": invalid syntax",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var h PipelinedRequests
+			err := h.Read(ca.hv)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestPipelinedRequestsWrite(t *testing.T) {
+	require.Equal(t, base.HeaderValue{"7c9"}, PipelinedRequests(0x7c9).Write())
+}
@@ -220,7 +220,8 @@ func (t *RangeUTCTime) read(s string) error {
 }
 
 func (t RangeUTCTime) write() string {
-	return time.Time(t).Format("20060102T150405Z")
+	// fractions of seconds are written only when present
+	return time.Time(t).UTC().Format("20060102T150405.999999999Z")
 }
 
 // RangeUTC is a range expressed in UTC unit.
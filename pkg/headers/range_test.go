@@ -105,6 +105,16 @@ var casesRange = []struct {
 			},
 		},
 	},
+	{
+		"clock with fractions",
+		base.HeaderValue{`clock=19961108T143720.25Z-`},
+		base.HeaderValue{`clock=19961108T143720.25Z-`},
+		Range{
+			Value: &RangeUTC{
+				Start: RangeUTCTime(time.Date(1996, 11, 8, 14, 37, 20, 250000000, time.UTC)),
+			},
+		},
+	},
 	{
 		"time",
 		base.HeaderValue{`clock=19960213T143205Z-;time=19970123T143720Z`},
@@ -0,0 +1,41 @@
+package headers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+// Scale is a Scale header.
+// It is the ratio between the playback rate and the normal rate.
+// Negative values indicate reverse playback.
+type Scale float64
+
+// Read decodes a Scale header.
+func (h *Scale) Read(v base.HeaderValue) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value not provided")
+	}
+
+	if len(v) > 1 {
+		return fmt.Errorf("value provided multiple times (%v)", v)
+	}
+
+	tmp, err := strconv.ParseFloat(v[0], 64)
+	if err != nil {
+		return err
+	}
+
+	if tmp == 0 {
+		return fmt.Errorf("invalid value (%v)", v[0])
+	}
+
+	*h = Scale(tmp)
+	return nil
+}
+
+// Write encodes a Scale header.
+func (h Scale) Write() base.HeaderValue {
+	return base.HeaderValue{strconv.FormatFloat(float64(h), 'f', -1, 64)}
+}
@@ -0,0 +1,90 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+var casesScale = []struct {
+	name string
+	vin  base.HeaderValue
+	vout base.HeaderValue
+	h    Scale
+}{
+	{
+		"integer",
+		base.HeaderValue{`2`},
+		base.HeaderValue{`2`},
+		Scale(2),
+	},
+	{
+		"decimal",
+		base.HeaderValue{`0.5`},
+		base.HeaderValue{`0.5`},
+		Scale(0.5),
+	},
+	{
+		"reverse",
+		base.HeaderValue{`-1.0`},
+		base.HeaderValue{`-1`},
+		Scale(-1),
+	},
+}
+
+func TestScaleRead(t *testing.T) {
+	for _, ca := range casesScale {
+		t.Run(ca.name, func(t *testing.T) {
+			var h Scale
+			err := h.Read(ca.vin)
+			require.NoError(t, err)
+			require.Equal(t, ca.h, h)
+		})
+	}
+}
+
+func TestScaleReadErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		hv   base.HeaderValue
+		err  string
+	}{
+		{
+			"empty",
+			base.HeaderValue{},
+			"value not provided",
+		},
+		{
+			"2 values",
+			base.HeaderValue{"1", "2"},
+			"value provided multiple times ([1 2])",
+		},
+		{
+			"invalid value",
+			base.HeaderValue{"aaa"},
+			"strconv.ParseFloat: parsing \"aaa\": invalid syntax",
+		},
+		{
+			"zero",
+			base.HeaderValue{"0"},
+			"invalid value (0)",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var h Scale
+			err := h.Read(ca.hv)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestScaleWrite(t *testing.T) {
+	for _, ca := range casesScale {
+		t.Run(ca.name, func(t *testing.T) {
+			req := ca.h.Write()
+			require.Equal(t, ca.vout, req)
+		})
+	}
+}
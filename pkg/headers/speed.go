@@ -0,0 +1,41 @@
+package headers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+// Speed is a Speed header.
+// It is the ratio between the delivery rate and the normal rate,
+// and does not change the playback rate.
+type Speed float64
+
+// Read decodes a Speed header.
+func (h *Speed) Read(v base.HeaderValue) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value not provided")
+	}
+
+	if len(v) > 1 {
+		return fmt.Errorf("value provided multiple times (%v)", v)
+	}
+
+	tmp, err := strconv.ParseFloat(v[0], 64)
+	if err != nil {
+		return err
+	}
+
+	if tmp <= 0 {
+		return fmt.Errorf("invalid value (%v)", v[0])
+	}
+
+	*h = Speed(tmp)
+	return nil
+}
+
+// Write encodes a Speed header.
+func (h Speed) Write() base.HeaderValue {
+	return base.HeaderValue{strconv.FormatFloat(float64(h), 'f', -1, 64)}
+}
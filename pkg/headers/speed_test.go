@@ -0,0 +1,84 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+var casesSpeed = []struct {
+	name string
+	vin  base.HeaderValue
+	vout base.HeaderValue
+	h    Speed
+}{
+	{
+		"integer",
+		base.HeaderValue{`4`},
+		base.HeaderValue{`4`},
+		Speed(4),
+	},
+	{
+		"decimal",
+		base.HeaderValue{`2.5`},
+		base.HeaderValue{`2.5`},
+		Speed(2.5),
+	},
+}
+
+func TestSpeedRead(t *testing.T) {
+	for _, ca := range casesSpeed {
+		t.Run(ca.name, func(t *testing.T) {
+			var h Speed
+			err := h.Read(ca.vin)
+			require.NoError(t, err)
+			require.Equal(t, ca.h, h)
+		})
+	}
+}
+
+func TestSpeedReadErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		hv   base.HeaderValue
+		err  string
+	}{
+		{
+			"empty",
+			base.HeaderValue{},
+			"value not provided",
+		},
+		{
+			"2 values",
+			base.HeaderValue{"1", "2"},
+			"value provided multiple times ([1 2])",
+		},
+		{
+			"invalid value",
+			base.HeaderValue{"aaa"},
+			"strconv.ParseFloat: parsing \"aaa\": invalid syntax",
+		},
+		{
+			"negative",
+			base.HeaderValue{"-1"},
+			"invalid value (-1)",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var h Speed
+			err := h.Read(ca.hv)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestSpeedWrite(t *testing.T) {
+	for _, ca := range casesSpeed {
+		t.Run(ca.name, func(t *testing.T) {
+			req := ca.h.Write()
+			require.Equal(t, ca.vout, req)
+		})
+	}
+}
@@ -20,6 +20,37 @@ const (
 	TransportProtocolTCP
 )
 
+// TransportProfile is a RTP profile.
+type TransportProfile int
+
+// RTP profiles.
+const (
+	TransportProfileAVP TransportProfile = iota
+	TransportProfileSAVP
+	TransportProfileAVPF
+	TransportProfileSAVPF
+)
+
+// String implements fmt.Stringer.
+func (p TransportProfile) String() string {
+	switch p {
+	case TransportProfileSAVP:
+		return "SAVP"
+
+	case TransportProfileAVPF:
+		return "AVPF"
+
+	case TransportProfileSAVPF:
+		return "SAVPF"
+	}
+	return "AVP"
+}
+
+// IsSecure returns whether the profile is a secure (SRTP) profile.
+func (p TransportProfile) IsSecure() bool {
+	return p == TransportProfileSAVP || p == TransportProfileSAVPF
+}
+
 // TransportDelivery is a delivery method.
 type TransportDelivery int
 
@@ -45,6 +76,9 @@ type Transport struct {
 	// protocol of the stream
 	Protocol TransportProtocol
 
+	// RTP profile of the stream
+	Profile TransportProfile
+
 	// (optional) delivery method of the stream
 	Delivery *TransportDelivery
 
@@ -69,9 +103,20 @@ type Transport struct {
 	// (optional) server ports
 	ServerPorts *[2]int
 
+	// (optional) destination addresses of RTP and RTCP packets (RTSP 2.0).
+	// IP can be nil.
+	DestAddrs *[2]*net.UDPAddr
+
+	// (optional) source addresses of RTP and RTCP packets (RTSP 2.0).
+	// IP can be nil.
+	SrcAddrs *[2]*net.UDPAddr
+
 	// (optional) SSRC of the packets of the stream
 	SSRC *uint32
 
+	// whether RTP and RTCP packets are multiplexed on the same port (RFC 5761).
+	RTCPMux bool
+
 	// (optional) mode
 	Mode *TransportMode
 }
@@ -104,6 +149,90 @@ func parsePorts(val string) (*[2]int, error) {
 	return &[2]int{0, 0}, fmt.Errorf("invalid ports (%v)", val)
 }
 
+func parseAddr(val string) (*net.UDPAddr, error) {
+	val = strings.TrimPrefix(val, "\"")
+	val = strings.TrimSuffix(val, "\"")
+
+	host, port, err := net.SplitHostPort(val)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address (%v)", val)
+	}
+
+	tmp, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address (%v)", val)
+	}
+
+	addr := &net.UDPAddr{
+		Port: int(tmp),
+	}
+
+	// host names are not resolved, since addresses are provided by the counterpart
+	if host != "" {
+		addr.IP = net.ParseIP(host)
+		if addr.IP == nil {
+			return nil, fmt.Errorf("invalid address (%v)", val)
+		}
+	}
+
+	return addr, nil
+}
+
+func parseAddrs(val string) (*[2]*net.UDPAddr, error) {
+	parts := strings.Split(val, "/")
+
+	switch len(parts) {
+	case 1:
+		addr1, err := parseAddr(parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		addr2 := &net.UDPAddr{
+			IP:   addr1.IP,
+			Port: addr1.Port + 1,
+		}
+
+		return &[2]*net.UDPAddr{addr1, addr2}, nil
+
+	case 2:
+		addr1, err := parseAddr(parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		addr2, err := parseAddr(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		return &[2]*net.UDPAddr{addr1, addr2}, nil
+	}
+
+	return nil, fmt.Errorf("invalid addresses (%v)", val)
+}
+
+func writeAddr(addr *net.UDPAddr) string {
+	if addr.IP == nil {
+		return "\":" + strconv.FormatInt(int64(addr.Port), 10) + "\""
+	}
+	return "\"" + addr.String() + "\""
+}
+
+func parseProfile(v string) TransportProfile {
+	switch strings.Split(v, "/")[1] {
+	case "SAVP":
+		return TransportProfileSAVP
+
+	case "AVPF":
+		return TransportProfileAVPF
+
+	case "SAVPF":
+		return TransportProfileSAVPF
+	}
+	return TransportProfileAVP
+}
+
 // Read decodes a Transport header.
 func (h *Transport) Read(v base.HeaderValue) error {
 	if len(v) == 0 {
@@ -127,12 +256,15 @@ func (h *Transport) Read(v base.HeaderValue) error {
 		v := rv
 
 		switch k {
-		case "RTP/AVP", "RTP/AVP/UDP":
+		case "RTP/AVP", "RTP/AVP/UDP", "RTP/SAVP", "RTP/SAVP/UDP",
+			"RTP/AVPF", "RTP/AVPF/UDP", "RTP/SAVPF", "RTP/SAVPF/UDP":
 			h.Protocol = TransportProtocolUDP
+			h.Profile = parseProfile(k)
 			protocolFound = true
 
-		case "RTP/AVP/TCP":
+		case "RTP/AVP/TCP", "RTP/SAVP/TCP", "RTP/AVPF/TCP", "RTP/SAVPF/TCP":
 			h.Protocol = TransportProtocolTCP
+			h.Profile = parseProfile(k)
 			protocolFound = true
 
 		case "unicast":
@@ -204,6 +336,20 @@ func (h *Transport) Read(v base.HeaderValue) error {
 			}
 			h.ServerPorts = ports
 
+		case "dest_addr":
+			addrs, err := parseAddrs(v)
+			if err != nil {
+				return err
+			}
+			h.DestAddrs = addrs
+
+		case "src_addr":
+			addrs, err := parseAddrs(v)
+			if err != nil {
+				return err
+			}
+			h.SrcAddrs = addrs
+
 		case "ssrc":
 			v = strings.TrimLeft(v, " ")
 
@@ -226,6 +372,9 @@ func (h *Transport) Read(v base.HeaderValue) error {
 			v := binary.BigEndian.Uint32(ssrc[:])
 			h.SSRC = &v
 
+		case "RTCP-mux", "rtcp-mux":
+			h.RTCPMux = true
+
 		case "mode":
 			str := strings.ToLower(v)
 			str = strings.TrimPrefix(str, "\"")
@@ -263,9 +412,9 @@ func (h Transport) Write() base.HeaderValue {
 	var rets []string
 
 	if h.Protocol == TransportProtocolUDP {
-		rets = append(rets, "RTP/AVP")
+		rets = append(rets, "RTP/"+h.Profile.String())
 	} else {
-		rets = append(rets, "RTP/AVP/TCP")
+		rets = append(rets, "RTP/"+h.Profile.String()+"/TCP")
 	}
 
 	if h.Delivery != nil {
@@ -308,6 +457,18 @@ func (h Transport) Write() base.HeaderValue {
 			"-"+strconv.FormatInt(int64(h.ServerPorts[1]), 10))
 	}
 
+	if h.DestAddrs != nil {
+		rets = append(rets, "dest_addr="+writeAddr(h.DestAddrs[0])+"/"+writeAddr(h.DestAddrs[1]))
+	}
+
+	if h.SrcAddrs != nil {
+		rets = append(rets, "src_addr="+writeAddr(h.SrcAddrs[0])+"/"+writeAddr(h.SrcAddrs[1]))
+	}
+
+	if h.RTCPMux {
+		rets = append(rets, "RTCP-mux")
+	}
+
 	if h.SSRC != nil {
 		tmp := make([]byte, 4)
 		binary.BigEndian.PutUint32(tmp, *h.SSRC)
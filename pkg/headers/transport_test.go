@@ -67,6 +67,75 @@ var casesTransport = []struct {
 			Ports: &[2]int{7000, 7001},
 		},
 	},
+	{
+		"udp unicast play request rtsp 2.0",
+		base.HeaderValue{`RTP/AVP/UDP;unicast;dest_addr=":6256"/":6257";mode="PLAY"`},
+		base.HeaderValue{`RTP/AVP;unicast;dest_addr=":6256"/":6257";mode=play`},
+		Transport{
+			Protocol: TransportProtocolUDP,
+			Delivery: func() *TransportDelivery {
+				v := TransportDeliveryUnicast
+				return &v
+			}(),
+			DestAddrs: &[2]*net.UDPAddr{{Port: 6256}, {Port: 6257}},
+			Mode: func() *TransportMode {
+				v := TransportModePlay
+				return &v
+			}(),
+		},
+	},
+	{
+		"udp unicast play response rtsp 2.0",
+		base.HeaderValue{`RTP/AVP/UDP;unicast;dest_addr="192.0.2.5:6256"/"192.0.2.5:6257";src_addr="192.0.2.224:6256"/"192.0.2.224:6257"`},
+		base.HeaderValue{`RTP/AVP;unicast;dest_addr="192.0.2.5:6256"/"192.0.2.5:6257";src_addr="192.0.2.224:6256"/"192.0.2.224:6257"`},
+		Transport{
+			Protocol: TransportProtocolUDP,
+			Delivery: func() *TransportDelivery {
+				v := TransportDeliveryUnicast
+				return &v
+			}(),
+			DestAddrs: &[2]*net.UDPAddr{
+				{IP: net.ParseIP("192.0.2.5"), Port: 6256},
+				{IP: net.ParseIP("192.0.2.5"), Port: 6257},
+			},
+			SrcAddrs: &[2]*net.UDPAddr{
+				{IP: net.ParseIP("192.0.2.224"), Port: 6256},
+				{IP: net.ParseIP("192.0.2.224"), Port: 6257},
+			},
+		},
+	},
+	{
+		"udp unicast play request savp",
+		base.HeaderValue{`RTP/SAVP;unicast;client_port=3456-3457;mode="PLAY"`},
+		base.HeaderValue{`RTP/SAVP;unicast;client_port=3456-3457;mode=play`},
+		Transport{
+			Protocol: TransportProtocolUDP,
+			Profile:  TransportProfileSAVP,
+			Delivery: func() *TransportDelivery {
+				v := TransportDeliveryUnicast
+				return &v
+			}(),
+			ClientPorts: &[2]int{3456, 3457},
+			Mode: func() *TransportMode {
+				v := TransportModePlay
+				return &v
+			}(),
+		},
+	},
+	{
+		"tcp play request savpf",
+		base.HeaderValue{`RTP/SAVPF/TCP;unicast;interleaved=0-1`},
+		base.HeaderValue{`RTP/SAVPF/TCP;unicast;interleaved=0-1`},
+		Transport{
+			Protocol: TransportProtocolTCP,
+			Profile:  TransportProfileSAVPF,
+			Delivery: func() *TransportDelivery {
+				v := TransportDeliveryUnicast
+				return &v
+			}(),
+			InterleavedIDs: &[2]int{0, 1},
+		},
+	},
 	{
 		"tcp play request / response",
 		base.HeaderValue{`RTP/AVP/TCP;interleaved=0-1`},
@@ -216,6 +285,25 @@ var casesTransport = []struct {
 			ServerPorts: &[2]int{3046, 3047},
 		},
 	},
+	{
+		"udp unicast play with rtcp-mux",
+		base.HeaderValue{`RTP/AVP;unicast;client_port=14186-14186;server_port=8052-8052;RTCP-mux;mode=play`},
+		base.HeaderValue{`RTP/AVP;unicast;client_port=14186-14186;server_port=8052-8052;RTCP-mux;mode=play`},
+		Transport{
+			Protocol: TransportProtocolUDP,
+			Delivery: func() *TransportDelivery {
+				v := TransportDeliveryUnicast
+				return &v
+			}(),
+			Mode: func() *TransportMode {
+				v := TransportModePlay
+				return &v
+			}(),
+			ClientPorts: &[2]int{14186, 14186},
+			ServerPorts: &[2]int{8052, 8052},
+			RTCPMux:     true,
+		},
+	},
 }
 
 func TestTransportRead(t *testing.T) {
@@ -315,6 +403,16 @@ func TestTransportReadErrors(t *testing.T) {
 			base.HeaderValue{`RTP/AVP;unicast;mode=aa`},
 			"invalid transport mode: 'aa'",
 		},
+		{
+			"invalid dest_addr",
+			base.HeaderValue{`RTP/AVP;unicast;dest_addr="aa:6256"/"aa:6257"`},
+			"invalid address (aa:6256)",
+		},
+		{
+			"invalid src_addr",
+			base.HeaderValue{`RTP/AVP;unicast;src_addr="localhost:6256"`},
+			"invalid address (localhost:6256)",
+		},
 	} {
 		t.Run(ca.name, func(t *testing.T) {
 			var h Transport
@@ -188,3 +188,45 @@ type ErrClientRTPInfoInvalid struct {
 func (e ErrClientRTPInfoInvalid) Error() string {
 	return fmt.Sprintf("invalid RTP-Info: %v", e.Err)
 }
+
+// ErrClientRedirectFailed is an error that can be returned by a client.
+type ErrClientRedirectFailed struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e ErrClientRedirectFailed) Error() string {
+	return fmt.Sprintf("redirect failed, resumed previous session: %v", e.Err)
+}
+
+// ErrClientUnexpectedFrame is an error that can be returned by a client.
+type ErrClientUnexpectedFrame struct{}
+
+// Error implements the error interface.
+func (e ErrClientUnexpectedFrame) Error() string {
+	return "received unexpected interleaved frame"
+}
+
+// ErrClientResponseTimeout is an error that can be returned by a client.
+type ErrClientResponseTimeout struct{}
+
+// Error implements the error interface.
+func (e ErrClientResponseTimeout) Error() string {
+	return "timed out while waiting for a response"
+}
+
+// ErrClientNoRTSPSession is an error that can be returned by a client.
+type ErrClientNoRTSPSession struct{}
+
+// Error implements the error interface.
+func (e ErrClientNoRTSPSession) Error() string {
+	return "the stream is read without a RTSP session"
+}
+
+// ErrClientSDPMixedTransports is an error that can be returned by a client.
+type ErrClientSDPMixedTransports struct{}
+
+// Error implements the error interface.
+func (e ErrClientSDPMixedTransports) Error() string {
+	return "reading multicast and unicast tracks together is not supported"
+}
@@ -116,6 +116,26 @@ func (e ErrServerTransportHeaderInvalid) Error() string {
 	return fmt.Sprintf("invalid transport header: %v", e.Err)
 }
 
+// ErrServerScaleHeaderInvalid is an error that can be returned by a server.
+type ErrServerScaleHeaderInvalid struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e ErrServerScaleHeaderInvalid) Error() string {
+	return fmt.Sprintf("invalid scale header: %v", e.Err)
+}
+
+// ErrServerSpeedHeaderInvalid is an error that can be returned by a server.
+type ErrServerSpeedHeaderInvalid struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e ErrServerSpeedHeaderInvalid) Error() string {
+	return fmt.Sprintf("invalid speed header: %v", e.Err)
+}
+
 // ErrServerTrackAlreadySetup is an error that can be returned by a server.
 type ErrServerTrackAlreadySetup struct {
 	TrackID int
@@ -256,6 +276,14 @@ func (e ErrServerUDPPortsAlreadyInUse) Error() string {
 		e.Port, e.Port+1)
 }
 
+// ErrServerNoUDPPortsAvailable is an error that can be returned by a server.
+type ErrServerNoUDPPortsAvailable struct{}
+
+// Error implements the error interface.
+func (e ErrServerNoUDPPortsAvailable) Error() string {
+	return "no UDP ports are available in the port range"
+}
+
 // ErrServerSessionNotInUse is an error that can be returned by a server.
 type ErrServerSessionNotInUse struct{}
 
@@ -263,3 +291,49 @@ type ErrServerSessionNotInUse struct{}
 func (e ErrServerSessionNotInUse) Error() string {
 	return "not in use"
 }
+
+// ErrServerSessionNoConn is an error that can be returned by a server.
+type ErrServerSessionNoConn struct{}
+
+// Error implements the error interface.
+func (e ErrServerSessionNoConn) Error() string {
+	return "session is not associated with any connection"
+}
+
+// ErrServerSessionNotRTSP2 is an error that can be returned by a server.
+type ErrServerSessionNotRTSP2 struct{}
+
+// Error implements the error interface.
+func (e ErrServerSessionNotRTSP2) Error() string {
+	return "session has not been established with RTSP 2.0"
+}
+
+// ErrServerMaxSessionsReached is an error that can be returned by a server.
+type ErrServerMaxSessionsReached struct {
+	Max int
+}
+
+// Error implements the error interface.
+func (e ErrServerMaxSessionsReached) Error() string {
+	return fmt.Sprintf("maximum number of sessions reached (%d)", e.Max)
+}
+
+// ErrServerMaxSessionsPerIPReached is an error that can be returned by a server.
+type ErrServerMaxSessionsPerIPReached struct {
+	Max int
+}
+
+// Error implements the error interface.
+func (e ErrServerMaxSessionsPerIPReached) Error() string {
+	return fmt.Sprintf("maximum number of sessions per IP reached (%d)", e.Max)
+}
+
+// ErrServerMaxReadersPerStreamReached is an error that can be returned by a server.
+type ErrServerMaxReadersPerStreamReached struct {
+	Max int
+}
+
+// Error implements the error interface.
+func (e ErrServerMaxReadersPerStreamReached) Error() string {
+	return fmt.Sprintf("maximum number of readers of the stream reached (%d)", e.Max)
+}
@@ -0,0 +1,573 @@
+// Package mikey contains a MIKEY (RFC 3830) message decoder and encoder.
+// It supports messages used to transport SRTP keys in SDP (a=key-mgmt, RFC 4567),
+// with pre-shared key data type and NULL encryption and MAC algorithms,
+// as used by ONVIF devices when the control channel is protected by TLS.
+package mikey
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/aler9/gortsplib/pkg/srtp"
+)
+
+const (
+	version = 1
+
+	// seconds between 1st January 1900 and 1st January 1970
+	ntpEpochOffset = 2208988800
+)
+
+// payload types.
+const (
+	payloadTypeLast    = 0
+	payloadTypeKEMAC   = 1
+	payloadTypeT       = 5
+	payloadTypeSP      = 10
+	payloadTypeRAND    = 11
+	payloadTypeKeyData = 20
+)
+
+// DataType is the data type of a message.
+type DataType uint8
+
+// data types.
+const (
+	DataTypePSKInit DataType = 0
+	DataTypePSKResp DataType = 1
+)
+
+// KeyDataType is the type of a key data.
+type KeyDataType uint8
+
+// key data types.
+const (
+	KeyDataTypeTGK     KeyDataType = 0
+	KeyDataTypeTGKSalt KeyDataType = 1
+	KeyDataTypeTEK     KeyDataType = 2
+	KeyDataTypeTEKSalt KeyDataType = 3
+)
+
+func (t KeyDataType) hasSalt() bool {
+	return t == KeyDataTypeTGKSalt || t == KeyDataTypeTEKSalt
+}
+
+// SRTP policy parameters.
+// https://tools.ietf.org/html/rfc3830#section-6.10.1
+const (
+	PolicyParamEncryptionAlgorithm     = 0
+	PolicyParamSessionEncKeyLength     = 1
+	PolicyParamAuthenticationAlgorithm = 2
+	PolicyParamSessionAuthKeyLength    = 3
+	PolicyParamSessionSaltKeyLength    = 4
+	PolicyParamSRTPPRF                 = 5
+	PolicyParamKeyDerivationRate       = 6
+	PolicyParamSRTPEncryption          = 7
+	PolicyParamSRTCPEncryption         = 8
+	PolicyParamSenderFECOrder          = 9
+	PolicyParamSRTPAuthentication      = 10
+	PolicyParamAuthenticationTagLength = 11
+	PolicyParamSRTPPrefixLength        = 12
+)
+
+// CryptoSession is a crypto session, that is a SRTP stream.
+type CryptoSession struct {
+	// number of the security policy of the crypto session.
+	PolicyNo uint8
+
+	// SSRC of the stream.
+	SSRC uint32
+
+	// rollover counter of the stream.
+	ROC uint32
+}
+
+// PolicyParam is a parameter of a security policy.
+type PolicyParam struct {
+	Type  uint8
+	Value []byte
+}
+
+// Policy is a SRTP security policy.
+type Policy struct {
+	// number of the security policy.
+	PolicyNo uint8
+
+	// parameters.
+	Params []PolicyParam
+}
+
+// KeyData is the key data contained in a KEMAC payload.
+type KeyData struct {
+	// type of the key data.
+	Type KeyDataType
+
+	// key.
+	Key []byte
+
+	// (optional) salt.
+	Salt []byte
+}
+
+// Message is a MIKEY message.
+type Message struct {
+	// data type.
+	DataType DataType
+
+	// whether a verification message is requested.
+	V bool
+
+	// crypto session bundle ID.
+	CSBID uint32
+
+	// crypto sessions.
+	CryptoSessions []CryptoSession
+
+	// (optional) timestamp, in NTP format.
+	Timestamp *uint64
+
+	// (optional) random value.
+	Rand []byte
+
+	// security policies.
+	Policies []Policy
+
+	// key data, transported with NULL encryption and NULL MAC.
+	KeyData []KeyData
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v>>8), byte(v))
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	return appendUint32(appendUint32(buf, uint32(v>>32)), uint32(v))
+}
+
+// Unmarshal decodes a Message.
+func (m *Message) Unmarshal(buf []byte) error {
+	// common header
+	if len(buf) < 10 {
+		return fmt.Errorf("buffer is too short")
+	}
+
+	if buf[0] != version {
+		return fmt.Errorf("unsupported version (%d)", buf[0])
+	}
+
+	m.DataType = DataType(buf[1])
+	if m.DataType != DataTypePSKInit && m.DataType != DataTypePSKResp {
+		return fmt.Errorf("unsupported data type (%d)", buf[1])
+	}
+
+	nextPayload := buf[2]
+	m.V = (buf[3] >> 7) != 0
+
+	prf := buf[3] & 0x7F
+	if prf != 0 {
+		return fmt.Errorf("unsupported PRF function (%d)", prf)
+	}
+
+	m.CSBID = binary.BigEndian.Uint32(buf[4:])
+	csCount := int(buf[8])
+
+	csIDMapType := buf[9]
+	if csIDMapType != 0 {
+		return fmt.Errorf("unsupported CS ID map type (%d)", csIDMapType)
+	}
+
+	buf = buf[10:]
+
+	if len(buf) < csCount*9 {
+		return fmt.Errorf("buffer is too short")
+	}
+
+	m.CryptoSessions = make([]CryptoSession, csCount)
+	for i := range m.CryptoSessions {
+		m.CryptoSessions[i] = CryptoSession{
+			PolicyNo: buf[0],
+			SSRC:     binary.BigEndian.Uint32(buf[1:]),
+			ROC:      binary.BigEndian.Uint32(buf[5:]),
+		}
+		buf = buf[9:]
+	}
+
+	m.Timestamp = nil
+	m.Rand = nil
+	m.Policies = nil
+	m.KeyData = nil
+
+	for nextPayload != payloadTypeLast {
+		if len(buf) < 1 {
+			return fmt.Errorf("buffer is too short")
+		}
+
+		payloadType := nextPayload
+		nextPayload = buf[0]
+
+		var n int
+		var err error
+
+		switch payloadType {
+		case payloadTypeT:
+			n, err = m.unmarshalTimestamp(buf)
+
+		case payloadTypeRAND:
+			n, err = m.unmarshalRand(buf)
+
+		case payloadTypeSP:
+			n, err = m.unmarshalPolicy(buf)
+
+		case payloadTypeKEMAC:
+			n, err = m.unmarshalKEMAC(buf)
+
+		default:
+			return fmt.Errorf("unsupported payload type (%d)", payloadType)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		buf = buf[n:]
+	}
+
+	return nil
+}
+
+func (m *Message) unmarshalTimestamp(buf []byte) (int, error) {
+	if len(buf) < 2 {
+		return 0, fmt.Errorf("buffer is too short")
+	}
+
+	// only NTP-UTC and NTP timestamps are supported
+	if buf[1] != 0 && buf[1] != 1 {
+		return 0, fmt.Errorf("unsupported timestamp type (%d)", buf[1])
+	}
+
+	if len(buf) < 10 {
+		return 0, fmt.Errorf("buffer is too short")
+	}
+
+	v := binary.BigEndian.Uint64(buf[2:])
+	m.Timestamp = &v
+
+	return 10, nil
+}
+
+func (m *Message) unmarshalRand(buf []byte) (int, error) {
+	if len(buf) < 2 {
+		return 0, fmt.Errorf("buffer is too short")
+	}
+
+	l := int(buf[1])
+	if len(buf) < 2+l {
+		return 0, fmt.Errorf("buffer is too short")
+	}
+
+	m.Rand = append([]byte(nil), buf[2:2+l]...)
+
+	return 2 + l, nil
+}
+
+func (m *Message) unmarshalPolicy(buf []byte) (int, error) {
+	if len(buf) < 5 {
+		return 0, fmt.Errorf("buffer is too short")
+	}
+
+	p := Policy{
+		PolicyNo: buf[1],
+	}
+
+	// only SRTP policies are supported
+	if buf[2] != 0 {
+		return 0, fmt.Errorf("unsupported protocol type (%d)", buf[2])
+	}
+
+	l := int(binary.BigEndian.Uint16(buf[3:]))
+	if len(buf) < 5+l {
+		return 0, fmt.Errorf("buffer is too short")
+	}
+
+	params := buf[5 : 5+l]
+
+	for len(params) > 0 {
+		if len(params) < 2 || len(params) < 2+int(params[1]) {
+			return 0, fmt.Errorf("invalid policy parameter")
+		}
+
+		p.Params = append(p.Params, PolicyParam{
+			Type:  params[0],
+			Value: append([]byte(nil), params[2:2+int(params[1])]...),
+		})
+		params = params[2+int(params[1]):]
+	}
+
+	m.Policies = append(m.Policies, p)
+
+	return 5 + l, nil
+}
+
+func (m *Message) unmarshalKEMAC(buf []byte) (int, error) {
+	if len(buf) < 4 {
+		return 0, fmt.Errorf("buffer is too short")
+	}
+
+	if buf[1] != 0 {
+		return 0, fmt.Errorf("unsupported encryption algorithm (%d)", buf[1])
+	}
+
+	l := int(binary.BigEndian.Uint16(buf[2:]))
+	if len(buf) < 4+l+1 {
+		return 0, fmt.Errorf("buffer is too short")
+	}
+
+	if buf[4+l] != 0 {
+		return 0, fmt.Errorf("unsupported MAC algorithm (%d)", buf[4+l])
+	}
+
+	sub := buf[4 : 4+l]
+	nextPayload := byte(payloadTypeKeyData)
+
+	for nextPayload != payloadTypeLast {
+		if nextPayload != payloadTypeKeyData {
+			return 0, fmt.Errorf("unsupported payload type (%d)", nextPayload)
+		}
+
+		if len(sub) < 4 {
+			return 0, fmt.Errorf("buffer is too short")
+		}
+
+		nextPayload = sub[0]
+		kd := KeyData{
+			Type: KeyDataType(sub[1] >> 4),
+		}
+
+		// key validity data is not supported
+		if (sub[1] & 0x0F) != 0 {
+			return 0, fmt.Errorf("unsupported key validity type (%d)", sub[1]&0x0F)
+		}
+
+		kl := int(binary.BigEndian.Uint16(sub[2:]))
+		sub = sub[4:]
+		if len(sub) < kl {
+			return 0, fmt.Errorf("buffer is too short")
+		}
+		kd.Key = append([]byte(nil), sub[:kl]...)
+		sub = sub[kl:]
+
+		if kd.Type.hasSalt() {
+			if len(sub) < 2 {
+				return 0, fmt.Errorf("buffer is too short")
+			}
+
+			sl := int(binary.BigEndian.Uint16(sub))
+			sub = sub[2:]
+			if len(sub) < sl {
+				return 0, fmt.Errorf("buffer is too short")
+			}
+			kd.Salt = append([]byte(nil), sub[:sl]...)
+			sub = sub[sl:]
+		}
+
+		m.KeyData = append(m.KeyData, kd)
+	}
+
+	return 4 + l + 1, nil
+}
+
+// Marshal encodes a Message.
+func (m Message) Marshal() ([]byte, error) {
+	if len(m.CryptoSessions) > 255 {
+		return nil, fmt.Errorf("too many crypto sessions")
+	}
+
+	var payloadTypes []byte
+	if m.Timestamp != nil {
+		payloadTypes = append(payloadTypes, payloadTypeT)
+	}
+	if m.Rand != nil {
+		payloadTypes = append(payloadTypes, payloadTypeRAND)
+	}
+	for range m.Policies {
+		payloadTypes = append(payloadTypes, payloadTypeSP)
+	}
+	if m.KeyData != nil {
+		payloadTypes = append(payloadTypes, payloadTypeKEMAC)
+	}
+	payloadTypes = append(payloadTypes, payloadTypeLast)
+
+	buf := []byte{version, byte(m.DataType), payloadTypes[0], 0}
+	if m.V {
+		buf[3] = 0x80
+	}
+	buf = appendUint32(buf, m.CSBID)
+	buf = append(buf, byte(len(m.CryptoSessions)), 0)
+
+	for _, cs := range m.CryptoSessions {
+		buf = append(buf, cs.PolicyNo)
+		buf = appendUint32(buf, cs.SSRC)
+		buf = appendUint32(buf, cs.ROC)
+	}
+
+	payloadTypes = payloadTypes[1:]
+
+	if m.Timestamp != nil {
+		buf = append(buf, payloadTypes[0], 0)
+		buf = appendUint64(buf, *m.Timestamp)
+		payloadTypes = payloadTypes[1:]
+	}
+
+	if m.Rand != nil {
+		if len(m.Rand) > 255 {
+			return nil, fmt.Errorf("random value is too long")
+		}
+
+		buf = append(buf, payloadTypes[0], byte(len(m.Rand)))
+		buf = append(buf, m.Rand...)
+		payloadTypes = payloadTypes[1:]
+	}
+
+	for _, p := range m.Policies {
+		var params []byte
+		for _, pp := range p.Params {
+			if len(pp.Value) > 255 {
+				return nil, fmt.Errorf("policy parameter is too long")
+			}
+			params = append(params, pp.Type, byte(len(pp.Value)))
+			params = append(params, pp.Value...)
+		}
+
+		buf = append(buf, payloadTypes[0], p.PolicyNo, 0)
+		buf = appendUint16(buf, uint16(len(params)))
+		buf = append(buf, params...)
+		payloadTypes = payloadTypes[1:]
+	}
+
+	if m.KeyData != nil {
+		var sub []byte
+		for i, kd := range m.KeyData {
+			next := byte(payloadTypeKeyData)
+			if i == (len(m.KeyData) - 1) {
+				next = payloadTypeLast
+			}
+
+			sub = append(sub, next, byte(kd.Type)<<4)
+			sub = appendUint16(sub, uint16(len(kd.Key)))
+			sub = append(sub, kd.Key...)
+
+			if kd.Type.hasSalt() {
+				sub = appendUint16(sub, uint16(len(kd.Salt)))
+				sub = append(sub, kd.Salt...)
+			}
+		}
+
+		buf = append(buf, payloadTypes[0], 0)
+		buf = appendUint16(buf, uint16(len(sub)))
+		buf = append(buf, sub...)
+		buf = append(buf, 0) // NULL MAC
+	}
+
+	return buf, nil
+}
+
+func policyParam(p Policy, typ uint8) ([]byte, bool) {
+	for _, pp := range p.Params {
+		if pp.Type == typ {
+			return pp.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SRTPKey returns the SRTP master key contained in the message.
+func (m Message) SRTPKey() (*srtp.Key, error) {
+	if len(m.KeyData) == 0 {
+		return nil, fmt.Errorf("key data not found")
+	}
+
+	kd := m.KeyData[0]
+
+	if len(kd.Key) != srtp.KeyLen {
+		return nil, fmt.Errorf("invalid key length (%d)", len(kd.Key))
+	}
+
+	if len(kd.Salt) != srtp.SaltLen {
+		return nil, fmt.Errorf("invalid salt length (%d)", len(kd.Salt))
+	}
+
+	k := &srtp.Key{
+		Profile:    srtp.ProfileAESCM128HMACSHA180,
+		MasterKey:  kd.Key,
+		MasterSalt: kd.Salt,
+	}
+
+	if len(m.Policies) != 0 {
+		if v, ok := policyParam(m.Policies[0], PolicyParamAuthenticationTagLength); ok &&
+			len(v) == 1 && v[0] == 4 {
+			k.Profile = srtp.ProfileAESCM128HMACSHA132
+		}
+	}
+
+	return k, nil
+}
+
+// NewMessage allocates a Message that contains a SRTP master key.
+// ssrcs are the SSRCs of the streams protected by the key; they can be zero
+// when they are unknown.
+func NewMessage(key *srtp.Key, ssrcs []uint32) (*Message, error) {
+	// the first 4 bytes are used as crypto session bundle ID
+	randBuf := make([]byte, 4+16)
+	_, err := rand.Read(randBuf)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ts := uint64(now.Unix()+ntpEpochOffset)<<32 | (uint64(now.Nanosecond())<<32)/1000000000
+
+	tagLen := byte(10)
+	if key.Profile == srtp.ProfileAESCM128HMACSHA132 {
+		tagLen = 4
+	}
+
+	m := &Message{
+		DataType:  DataTypePSKInit,
+		CSBID:     binary.BigEndian.Uint32(randBuf),
+		Timestamp: &ts,
+		Rand:      randBuf[4:],
+		Policies: []Policy{{
+			PolicyNo: 0,
+			Params: []PolicyParam{
+				{Type: PolicyParamEncryptionAlgorithm, Value: []byte{1}},
+				{Type: PolicyParamSessionEncKeyLength, Value: []byte{srtp.KeyLen}},
+				{Type: PolicyParamAuthenticationAlgorithm, Value: []byte{1}},
+				{Type: PolicyParamSessionAuthKeyLength, Value: []byte{20}},
+				{Type: PolicyParamSessionSaltKeyLength, Value: []byte{srtp.SaltLen}},
+				{Type: PolicyParamSRTPEncryption, Value: []byte{1}},
+				{Type: PolicyParamSRTCPEncryption, Value: []byte{1}},
+				{Type: PolicyParamSRTPAuthentication, Value: []byte{1}},
+				{Type: PolicyParamAuthenticationTagLength, Value: []byte{tagLen}},
+			},
+		}},
+		KeyData: []KeyData{{
+			Type: KeyDataTypeTGKSalt,
+			Key:  key.MasterKey,
+			Salt: key.MasterSalt,
+		}},
+	}
+
+	for _, ssrc := range ssrcs {
+		m.CryptoSessions = append(m.CryptoSessions, CryptoSession{
+			SSRC: ssrc,
+		})
+	}
+
+	return m, nil
+}
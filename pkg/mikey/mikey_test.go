@@ -0,0 +1,173 @@
+package mikey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/gortsplib/pkg/srtp"
+)
+
+var testKey = []byte{
+	0xe1, 0xf9, 0x7a, 0x0d, 0x3e, 0x01, 0x8b, 0xe0,
+	0xd6, 0x4f, 0xa3, 0x2c, 0x06, 0xde, 0x41, 0x39,
+}
+
+var testSalt = []byte{
+	0x0e, 0xc6, 0x75, 0xad, 0x49, 0x8a, 0xfe, 0xeb,
+	0xb6, 0x96, 0x0b, 0x3a, 0xab, 0xe6,
+}
+
+var cases = []struct {
+	name string
+	byts []byte
+	msg  Message
+}{
+	{
+		"key data only",
+		append(append(append([]byte{
+			0x01, 0x00, 0x01, 0x00, 0x12, 0x34, 0x56, 0x78,
+			0x01, 0x00,
+			0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x24,
+			0x00, 0x10, 0x00, 0x10,
+		}, testKey...), 0x00, 0x0e), append(testSalt, 0x00)...),
+		Message{
+			DataType: DataTypePSKInit,
+			CSBID:    0x12345678,
+			CryptoSessions: []CryptoSession{{
+				SSRC: 0xaabbccdd,
+			}},
+			KeyData: []KeyData{{
+				Type: KeyDataTypeTGKSalt,
+				Key:  testKey,
+				Salt: testSalt,
+			}},
+		},
+	},
+	{
+		"all payloads",
+		append(append(append([]byte{
+			0x01, 0x00, 0x05, 0x00, 0x12, 0x34, 0x56, 0x78,
+			0x00, 0x00,
+			0x0b, 0x00, 0xe6, 0xa1, 0x2b, 0x4c, 0x00, 0x00, 0x00, 0x00,
+			0x0a, 0x04, 0x01, 0x02, 0x03, 0x04,
+			0x01, 0x00, 0x00, 0x00, 0x03, 0x0b, 0x01, 0x04,
+			0x00, 0x00, 0x00, 0x24,
+			0x00, 0x10, 0x00, 0x10,
+		}, testKey...), 0x00, 0x0e), append(testSalt, 0x00)...),
+		Message{
+			DataType:       DataTypePSKInit,
+			CSBID:          0x12345678,
+			CryptoSessions: []CryptoSession{},
+			Timestamp: func() *uint64 {
+				v := uint64(0xe6a12b4c00000000)
+				return &v
+			}(),
+			Rand: []byte{0x01, 0x02, 0x03, 0x04},
+			Policies: []Policy{{
+				Params: []PolicyParam{{
+					Type:  PolicyParamAuthenticationTagLength,
+					Value: []byte{4},
+				}},
+			}},
+			KeyData: []KeyData{{
+				Type: KeyDataTypeTGKSalt,
+				Key:  testKey,
+				Salt: testSalt,
+			}},
+		},
+	},
+}
+
+func TestUnmarshal(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			var msg Message
+			err := msg.Unmarshal(ca.byts)
+			require.NoError(t, err)
+			require.Equal(t, ca.msg, msg)
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			byts, err := ca.msg.Marshal()
+			require.NoError(t, err)
+			require.Equal(t, ca.byts, byts)
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		byts []byte
+		err  string
+	}{
+		{
+			"empty",
+			[]byte{},
+			"buffer is too short",
+		},
+		{
+			"unsupported data type",
+			[]byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			"unsupported data type (2)",
+		},
+		{
+			"unsupported encryption algorithm",
+			[]byte{
+				0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x01, 0x00, 0x00, 0x00,
+			},
+			"unsupported encryption algorithm (1)",
+		},
+		{
+			"unsupported payload",
+			[]byte{0x01, 0x00, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			"unsupported payload type (6)",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var msg Message
+			err := msg.Unmarshal(ca.byts)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestSRTPKey(t *testing.T) {
+	key, err := cases[1].msg.SRTPKey()
+	require.NoError(t, err)
+	require.Equal(t, &srtp.Key{
+		Profile:    srtp.ProfileAESCM128HMACSHA132,
+		MasterKey:  testKey,
+		MasterSalt: testSalt,
+	}, key)
+}
+
+func TestNewMessage(t *testing.T) {
+	key := &srtp.Key{
+		Profile:    srtp.ProfileAESCM128HMACSHA180,
+		MasterKey:  testKey,
+		MasterSalt: testSalt,
+	}
+
+	msg, err := NewMessage(key, []uint32{0x12345678})
+	require.NoError(t, err)
+
+	byts, err := msg.Marshal()
+	require.NoError(t, err)
+
+	var dec Message
+	err = dec.Unmarshal(byts)
+	require.NoError(t, err)
+	require.Equal(t, *msg, dec)
+
+	key2, err := dec.SRTPKey()
+	require.NoError(t, err)
+	require.Equal(t, key, key2)
+}
@@ -0,0 +1,70 @@
+// Package parameters contains a utility to decode and encode text/parameters bodies,
+// that are used by GET_PARAMETER and SET_PARAMETER requests and responses.
+package parameters
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ContentType is the content type of text/parameters bodies.
+const ContentType = "text/parameters"
+
+// Parameters are the parameters contained in a text/parameters body.
+type Parameters map[string]string
+
+// Read decodes a text/parameters body.
+// Lines that contain only a name, as in GET_PARAMETER requests,
+// are decoded into parameters with an empty value.
+func (p *Parameters) Read(byts []byte) error {
+	*p = make(Parameters)
+
+	for _, line := range strings.Split(string(byts), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			(*p)[strings.TrimSpace(line)] = ""
+			continue
+		}
+
+		name := strings.TrimSpace(line[:i])
+		if name == "" {
+			return fmt.Errorf("invalid line: '%s'", line)
+		}
+
+		(*p)[name] = strings.TrimSpace(line[i+1:])
+	}
+
+	return nil
+}
+
+// Write encodes a text/parameters body.
+// Parameters are sorted by name.
+func (p Parameters) Write() []byte {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var ret []byte
+	for _, name := range names {
+		ret = append(ret, []byte(name+": "+p[name]+"\r\n")...)
+	}
+	return ret
+}
+
+// WriteNames encodes a text/parameters body that contains only parameter names,
+// as in GET_PARAMETER requests.
+func WriteNames(names []string) []byte {
+	var ret []byte
+	for _, name := range names {
+		ret = append(ret, []byte(name+"\r\n")...)
+	}
+	return ret
+}
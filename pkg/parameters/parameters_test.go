@@ -0,0 +1,78 @@
+package parameters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var cases = []struct {
+	name string
+	dec  []byte
+	enc  []byte
+	p    Parameters
+}{
+	{
+		"empty",
+		[]byte{},
+		nil,
+		Parameters{},
+	},
+	{
+		"values",
+		[]byte("packets_received: 10\r\njitter: 0.3838\r\n"),
+		[]byte("jitter: 0.3838\r\npackets_received: 10\r\n"),
+		Parameters{
+			"packets_received": "10",
+			"jitter":           "0.3838",
+		},
+	},
+	{
+		"values without spaces and carriage returns",
+		[]byte("bitrate:1000\nfps:30"),
+		[]byte("bitrate: 1000\r\nfps: 30\r\n"),
+		Parameters{
+			"bitrate": "1000",
+			"fps":     "30",
+		},
+	},
+	{
+		"names",
+		[]byte("packets_received\r\njitter\r\n"),
+		[]byte("jitter: \r\npackets_received: \r\n"),
+		Parameters{
+			"packets_received": "",
+			"jitter":           "",
+		},
+	},
+}
+
+func TestRead(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			var p Parameters
+			err := p.Read(ca.dec)
+			require.NoError(t, err)
+			require.Equal(t, ca.p, p)
+		})
+	}
+}
+
+func TestReadError(t *testing.T) {
+	var p Parameters
+	err := p.Read([]byte(": value\r\n"))
+	require.EqualError(t, err, "invalid line: ': value'")
+}
+
+func TestWrite(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			require.Equal(t, ca.enc, ca.p.Write())
+		})
+	}
+}
+
+func TestWriteNames(t *testing.T) {
+	require.Equal(t, []byte("packets_received\r\njitter\r\n"),
+		WriteNames([]string{"packets_received", "jitter"}))
+}
@@ -18,6 +18,16 @@ func randUint32() uint32 {
 
 var now = time.Now
 
+// seconds between 1st January 1900 and 1st January 1970
+const ntpEpochOffset = 2208988800
+
+func ntpTimeToTime(v uint64) time.Time {
+	// higher 32 bits are the integer part, lower 32 bits are the fractional part
+	integerPart := int64(v>>32) - ntpEpochOffset
+	fractionalPart := int64(((v & 0xFFFFFFFF) * 1000000000) >> 32)
+	return time.Unix(integerPart, fractionalPart)
+}
+
 // RTCPReceiver is a utility to generate RTCP receiver reports.
 type RTCPReceiver struct {
 	period          time.Duration
@@ -27,6 +37,7 @@ type RTCPReceiver struct {
 	mutex           sync.Mutex
 
 	// data from RTP packets
+	mediaSSRC            *uint32
 	firstRTPReceived     bool
 	sequenceNumberCycles uint16
 	lastSequenceNumber   *uint16
@@ -36,17 +47,25 @@ type RTCPReceiver struct {
 	totalLostSinceReport uint32
 	totalSinceReport     uint32
 	jitter               float64
+	packetsReceived      uint64
+	bytesReceived        uint64
 
 	// data from rtcp packets
-	senderSSRC           uint32
-	lastSenderReportRTP  *uint32
-	lastSenderReportTime time.Time
+	senderSSRC              uint32
+	lastSenderReportRTP     *uint32
+	lastSenderReportTime    time.Time
+	lastSenderReportNTPTime time.Time
+	lastSenderReportRTPTime uint32
+
+	// data from reports
+	lastReceiverReportTime time.Time
 
 	terminate chan struct{}
 	done      chan struct{}
 }
 
 // New allocates a RTCPReceiver.
+// When writePacketRTCP is nil, reports are not generated and only statistics are collected.
 func New(period time.Duration, receiverSSRC *uint32, clockRate int,
 	writePacketRTCP func(rtcp.Packet),
 ) *RTCPReceiver {
@@ -64,11 +83,39 @@ func New(period time.Duration, receiverSSRC *uint32, clockRate int,
 		done:            make(chan struct{}),
 	}
 
-	go rr.run()
+	if writePacketRTCP != nil {
+		go rr.run()
+	} else {
+		close(rr.done)
+	}
 
 	return rr
 }
 
+// Stats are statistics about the received stream.
+type Stats struct {
+	// number of received RTP packets.
+	PacketsReceived uint64
+
+	// size of received RTP packets, headers included.
+	BytesReceived uint64
+
+	// number of lost RTP packets.
+	PacketsLost uint64
+
+	// interarrival jitter, expressed in timestamp units.
+	Jitter float64
+
+	// sequence number of the last received RTP packet.
+	LastSequenceNumber uint16
+
+	// time of the last received RTCP sender report.
+	LastSenderReport time.Time
+
+	// time of the last sent RTCP receiver report.
+	LastReceiverReport time.Time
+}
+
 // Close closes the RTCPReceiver.
 func (rr *RTCPReceiver) Close() {
 	close(rr.terminate)
@@ -125,6 +172,7 @@ func (rr *RTCPReceiver) report(ts time.Time) rtcp.Packet {
 
 	rr.totalLostSinceReport = 0
 	rr.totalSinceReport = 0
+	rr.lastReceiverReportTime = ts
 
 	return report
 }
@@ -134,6 +182,12 @@ func (rr *RTCPReceiver) ProcessPacketRTP(ts time.Time, pkt *rtp.Packet, ptsEqual
 	rr.mutex.Lock()
 	defer rr.mutex.Unlock()
 
+	rr.packetsReceived++
+	rr.bytesReceived += uint64(pkt.MarshalSize())
+
+	ssrc := pkt.SSRC
+	rr.mediaSSRC = &ssrc
+
 	// first packet
 	if rr.lastSequenceNumber == nil {
 		rr.firstRTPReceived = true
@@ -198,14 +252,59 @@ func (rr *RTCPReceiver) ProcessPacketRTP(ts time.Time, pkt *rtp.Packet, ptsEqual
 }
 
 // ProcessPacketRTCP extracts the needed data from RTCP packets.
+// Sender reports of sources that are different from the one of RTP packets
+// (for instance retransmission or FEC sources) are ignored.
 func (rr *RTCPReceiver) ProcessPacketRTCP(ts time.Time, pkt rtcp.Packet) {
 	if sr, ok := (pkt).(*rtcp.SenderReport); ok {
 		rr.mutex.Lock()
 		defer rr.mutex.Unlock()
 
+		if rr.mediaSSRC != nil && sr.SSRC != *rr.mediaSSRC {
+			return
+		}
+
 		rr.senderSSRC = sr.SSRC
 		v := uint32(sr.NTPTime >> 16)
 		rr.lastSenderReportRTP = &v
 		rr.lastSenderReportTime = ts
+		rr.lastSenderReportNTPTime = ntpTimeToTime(sr.NTPTime)
+		rr.lastSenderReportRTPTime = sr.RTPTime
 	}
 }
+
+// PacketNTP returns the absolute (NTP) time of a RTP timestamp, by using the
+// mapping contained in the last sender report of the source of RTP packets.
+// It returns false if such a sender report has not been received yet.
+func (rr *RTCPReceiver) PacketNTP(rtpTime uint32) (time.Time, bool) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	if rr.lastSenderReportRTP == nil || rr.mediaSSRC == nil || rr.senderSSRC != *rr.mediaSSRC {
+		return time.Time{}, false
+	}
+
+	// the difference is signed in order to support timestamps preceding the sender report
+	diff := int32(rtpTime - rr.lastSenderReportRTPTime)
+	return rr.lastSenderReportNTPTime.Add(time.Duration(float64(diff) / rr.clockRate * float64(time.Second))), true
+}
+
+// Stats returns statistics about the received stream.
+func (rr *RTCPReceiver) Stats() Stats {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	s := Stats{
+		PacketsReceived:    rr.packetsReceived,
+		BytesReceived:      rr.bytesReceived,
+		PacketsLost:        uint64(rr.totalLost),
+		Jitter:             rr.jitter,
+		LastSenderReport:   rr.lastSenderReportTime,
+		LastReceiverReport: rr.lastReceiverReportTime,
+	}
+
+	if rr.lastSequenceNumber != nil {
+		s.LastSequenceNumber = *rr.lastSequenceNumber
+	}
+
+	return s
+}
@@ -72,6 +72,14 @@ func TestRTCPReceiverBase(t *testing.T) {
 	rr.ProcessPacketRTP(ts, &rtpPkt, true)
 
 	<-done
+
+	require.Equal(t, Stats{
+		PacketsReceived:    2,
+		BytesReceived:      28,
+		LastSequenceNumber: 947,
+		LastSenderReport:   time.Date(2008, 0o5, 20, 22, 15, 20, 0, time.UTC),
+		LastReceiverReport: time.Date(2008, 0o5, 20, 22, 15, 22, 0, time.UTC),
+	}, rr.Stats())
 }
 
 func TestRTCPReceiverOverflow(t *testing.T) {
@@ -418,3 +426,78 @@ func TestRTCPReceiverJitter(t *testing.T) {
 
 	<-done
 }
+
+func TestRTCPReceiverPacketNTP(t *testing.T) {
+	rr := New(500*time.Millisecond, nil, 90000, func(pkt rtcp.Packet) {})
+	defer rr.Close()
+
+	rtpPkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 946,
+			Timestamp:      0xafb45733,
+			SSRC:           0xba9da416,
+		},
+		Payload: []byte("\x00\x00"),
+	}
+	rr.ProcessPacketRTP(time.Now(), &rtpPkt, true)
+
+	_, ok := rr.PacketNTP(0xafb45733)
+	require.Equal(t, false, ok)
+
+	// sender reports of other sources are ignored
+	rr.ProcessPacketRTCP(time.Now(), &rtcp.SenderReport{
+		SSRC:    0x12345678,
+		NTPTime: 0xe363887a17ced916,
+		RTPTime: 0x11111111,
+	})
+
+	_, ok = rr.PacketNTP(0xafb45733)
+	require.Equal(t, false, ok)
+
+	rr.ProcessPacketRTCP(time.Now(), &rtcp.SenderReport{
+		SSRC:    0xba9da416,
+		NTPTime: 0xe363887a17ced916,
+		RTPTime: 0xafb45733,
+	})
+
+	rr.ProcessPacketRTCP(time.Now(), &rtcp.SenderReport{
+		SSRC:    0x12345678,
+		NTPTime: 0xe363887a17ced916,
+		RTPTime: 0x11111111,
+	})
+
+	ntp, ok := rr.PacketNTP(0xafb45733 + 90000)
+	require.Equal(t, true, ok)
+	require.Equal(t, ntpTimeToTime(0xe363887a17ced916).Add(1*time.Second), ntp)
+}
+
+func TestRTCPReceiverStatsOnly(t *testing.T) {
+	v := uint32(0x65f83afb)
+	rr := New(10*time.Millisecond, &v, 90000, nil)
+	defer rr.Close()
+
+	rtpPkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 946,
+			Timestamp:      0xafb45733,
+			SSRC:           0xba9da416,
+		},
+		Payload: []byte("\x00\x00"),
+	}
+	ts := time.Date(2008, 0o5, 20, 22, 15, 20, 0, time.UTC)
+	rr.ProcessPacketRTP(ts, &rtpPkt, true)
+
+	time.Sleep(50 * time.Millisecond)
+
+	require.Equal(t, Stats{
+		PacketsReceived:    1,
+		BytesReceived:      14,
+		LastSequenceNumber: 946,
+	}, rr.Stats())
+}
@@ -19,17 +19,24 @@ type RTCPSender struct {
 	mutex           sync.Mutex
 
 	// data from RTP packets
-	senderSSRC      *uint32
-	lastRTPTimeRTP  *uint32
-	lastRTPTimeTime time.Time
-	packetCount     uint32
-	octetCount      uint32
+	senderSSRC         *uint32
+	lastRTPTimeRTP     *uint32
+	lastRTPTimeTime    time.Time
+	packetCount        uint32
+	octetCount         uint32
+	packetsSent        uint64
+	bytesSent          uint64
+	lastSequenceNumber uint16
+
+	// data from reports
+	lastSenderReportTime time.Time
 
 	terminate chan struct{}
 	done      chan struct{}
 }
 
 // New allocates a RTCPSender.
+// When writePacketRTCP is nil, reports are not generated and only statistics are collected.
 func New(period time.Duration, clockRate int,
 	writePacketRTCP func(rtcp.Packet),
 ) *RTCPSender {
@@ -41,11 +48,30 @@ func New(period time.Duration, clockRate int,
 		done:            make(chan struct{}),
 	}
 
-	go rs.run()
+	if writePacketRTCP != nil {
+		go rs.run()
+	} else {
+		close(rs.done)
+	}
 
 	return rs
 }
 
+// Stats are statistics about the sent stream.
+type Stats struct {
+	// number of sent RTP packets.
+	PacketsSent uint64
+
+	// size of sent RTP packets, headers included.
+	BytesSent uint64
+
+	// sequence number of the last sent RTP packet.
+	LastSequenceNumber uint16
+
+	// time of the last sent RTCP sender report.
+	LastSenderReport time.Time
+}
+
 // Close closes the RTCPSender.
 func (rs *RTCPSender) Close() {
 	close(rs.terminate)
@@ -80,6 +106,8 @@ func (rs *RTCPSender) report(ts time.Time) rtcp.Packet {
 		return nil
 	}
 
+	rs.lastSenderReportTime = ts
+
 	return &rtcp.SenderReport{
 		SSRC: *rs.senderSSRC,
 		NTPTime: func() uint64 {
@@ -115,4 +143,20 @@ func (rs *RTCPSender) ProcessPacketRTP(ts time.Time, pkt *rtp.Packet, ptsEqualsD
 
 	rs.packetCount++
 	rs.octetCount += uint32(len(pkt.Payload))
+	rs.packetsSent++
+	rs.bytesSent += uint64(pkt.MarshalSize())
+	rs.lastSequenceNumber = pkt.SequenceNumber
+}
+
+// Stats returns statistics about the sent stream.
+func (rs *RTCPSender) Stats() Stats {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	return Stats{
+		PacketsSent:        rs.packetsSent,
+		BytesSent:          rs.bytesSent,
+		LastSequenceNumber: rs.lastSequenceNumber,
+		LastSenderReport:   rs.lastSenderReportTime,
+	}
 }
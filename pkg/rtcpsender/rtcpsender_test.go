@@ -70,4 +70,38 @@ func TestRTCPSender(t *testing.T) {
 	rs.ProcessPacketRTP(ts, &rtpPkt, false)
 
 	<-done
+
+	require.Equal(t, Stats{
+		PacketsSent:        3,
+		BytesSent:          42,
+		LastSequenceNumber: 948,
+		LastSenderReport:   time.Date(2008, 5, 20, 22, 16, 20, 600000000, time.UTC),
+	}, rs.Stats())
+}
+
+func TestRTCPSenderStatsOnly(t *testing.T) {
+	rs := New(10*time.Millisecond, 90000, nil)
+	defer rs.Close()
+
+	rtpPkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 946,
+			Timestamp:      1287987768,
+			SSRC:           0xba9da416,
+		},
+		Payload: []byte("\x00\x00"),
+	}
+	ts := time.Date(2008, 0o5, 20, 22, 15, 20, 0, time.UTC)
+	rs.ProcessPacketRTP(ts, &rtpPkt, true)
+
+	time.Sleep(50 * time.Millisecond)
+
+	require.Equal(t, Stats{
+		PacketsSent:        1,
+		BytesSent:          14,
+		LastSequenceNumber: 946,
+	}, rs.Stats())
 }
@@ -0,0 +1,304 @@
+package rtpfec
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// number of media packets that are kept in order to perform recovery.
+	decoderHistorySize = 512
+
+	// FEC packets that protect packets older than this are discarded.
+	decoderMaxAge = 256
+
+	// maximum number of FEC packets waiting for recovery.
+	decoderMaxPending = 32
+)
+
+type fecPacket struct {
+	base            uint16
+	sequenceNumbers []uint16
+	recovery        recoveryData
+}
+
+// Decoder is a RTP FEC decoder.
+type Decoder struct {
+	// FEC scheme.
+	Scheme Scheme
+
+	// payload type of FEC packets.
+	PayloadType uint8
+
+	ssrc               *uint32
+	lastSequenceNumber uint16
+	history            map[uint16][]byte
+	historyOrder       []uint16
+	historyPos         int
+	pending            []*fecPacket
+}
+
+// Init initializes the decoder.
+func (d *Decoder) Init() {
+	d.history = make(map[uint16][]byte)
+	d.historyOrder = make([]uint16, 0, decoderHistorySize)
+}
+
+// Decode processes a media or FEC packet.
+// It returns the media packets that have been recovered.
+func (d *Decoder) Decode(pkt *rtp.Packet) ([]*rtp.Packet, error) {
+	if pkt.PayloadType != d.PayloadType {
+		err := d.processMedia(pkt)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		fec, err := d.parseFEC(pkt)
+		if err != nil {
+			return nil, err
+		}
+
+		d.pending = append(d.pending, fec)
+		if len(d.pending) > decoderMaxPending {
+			d.pending = d.pending[1:]
+		}
+	}
+
+	return d.recover(), nil
+}
+
+func (d *Decoder) processMedia(pkt *rtp.Packet) error {
+	buf, err := pkt.Marshal()
+	if err != nil {
+		return err
+	}
+
+	d.addToHistory(pkt.SequenceNumber, buf)
+
+	if d.ssrc == nil || *d.ssrc != pkt.SSRC {
+		v := pkt.SSRC
+		d.ssrc = &v
+		d.lastSequenceNumber = pkt.SequenceNumber
+	} else if diff := pkt.SequenceNumber - d.lastSequenceNumber; diff < 0x8000 {
+		d.lastSequenceNumber = pkt.SequenceNumber
+	}
+
+	// discard FEC packets that protect packets that are too old
+	n := 0
+	for _, fec := range d.pending {
+		if (d.lastSequenceNumber - fec.base) <= decoderMaxAge {
+			d.pending[n] = fec
+			n++
+		}
+	}
+	d.pending = d.pending[:n]
+
+	return nil
+}
+
+func (d *Decoder) addToHistory(seqNum uint16, buf []byte) {
+	if _, ok := d.history[seqNum]; ok {
+		return
+	}
+
+	if len(d.historyOrder) < decoderHistorySize {
+		d.historyOrder = append(d.historyOrder, seqNum)
+	} else {
+		delete(d.history, d.historyOrder[d.historyPos])
+		d.historyOrder[d.historyPos] = seqNum
+		d.historyPos = (d.historyPos + 1) % decoderHistorySize
+	}
+
+	d.history[seqNum] = buf
+}
+
+func (d *Decoder) parseFEC(pkt *rtp.Packet) (*fecPacket, error) {
+	if d.Scheme == SchemeFlexFEC {
+		return d.parseFlexFEC(pkt)
+	}
+	return d.parseULPFEC(pkt)
+}
+
+func (d *Decoder) parseULPFEC(pkt *rtp.Packet) (*fecPacket, error) {
+	buf := pkt.Payload
+
+	if len(buf) < (ulpfecHeaderSize + 4) {
+		return nil, fmt.Errorf("payload is too short")
+	}
+
+	if (buf[0] & 0x80) != 0 {
+		return nil, fmt.Errorf("extension flag is not supported")
+	}
+
+	maskBits := ulpfecShortMaskBits
+	if (buf[0] & 0x40) != 0 {
+		maskBits = ulpfecLongMaskBits
+	}
+
+	headerSize := ulpfecHeaderSize + 2 + maskBits/8
+	if len(buf) < headerSize {
+		return nil, fmt.Errorf("payload is too short")
+	}
+
+	fec := &fecPacket{
+		base: binary.BigEndian.Uint16(buf[2:]),
+		recovery: recoveryData{
+			b0:        buf[0] & 0x3F,
+			b1:        buf[1],
+			timestamp: binary.BigEndian.Uint32(buf[4:]),
+			length:    binary.BigEndian.Uint16(buf[8:]),
+		},
+	}
+
+	protectionLength := int(binary.BigEndian.Uint16(buf[10:]))
+	if len(buf[headerSize:]) < protectionLength {
+		return nil, fmt.Errorf("payload is too short")
+	}
+	fec.recovery.payload = append([]byte(nil), buf[headerSize:headerSize+protectionLength]...)
+
+	mask := buf[12:headerSize]
+	for i := 0; i < maskBits; i++ {
+		if (mask[i/8] & (1 << (7 - i%8))) != 0 {
+			fec.sequenceNumbers = append(fec.sequenceNumbers, fec.base+uint16(i))
+		}
+	}
+
+	return fec, nil
+}
+
+func (d *Decoder) parseFlexFEC(pkt *rtp.Packet) (*fecPacket, error) {
+	buf := pkt.Payload
+
+	if len(buf) < (flexfecHeaderSize + 2) {
+		return nil, fmt.Errorf("payload is too short")
+	}
+
+	if (buf[0] & 0xC0) != 0 {
+		return nil, fmt.Errorf("retransmissions and fixed masks are not supported")
+	}
+
+	if len(pkt.CSRC) != 1 {
+		return nil, fmt.Errorf("protection of multiple SSRCs is not supported")
+	}
+
+	maskSize := 2
+	maskBits := 15
+	if (buf[flexfecHeaderSize] & 0x80) == 0 {
+		maskSize = 6
+		maskBits = 46
+
+		if len(buf) < (flexfecHeaderSize + 6) {
+			return nil, fmt.Errorf("payload is too short")
+		}
+
+		if (buf[flexfecHeaderSize+2] & 0x80) == 0 {
+			maskSize = 14
+			maskBits = flexfecMaskBits
+
+			if len(buf) < (flexfecHeaderSize + 14) {
+				return nil, fmt.Errorf("payload is too short")
+			}
+		}
+	}
+
+	fec := &fecPacket{
+		base: binary.BigEndian.Uint16(buf[8:]),
+		recovery: recoveryData{
+			b0:        buf[0] & 0x3F,
+			b1:        buf[1],
+			length:    binary.BigEndian.Uint16(buf[2:]),
+			timestamp: binary.BigEndian.Uint32(buf[4:]),
+		},
+	}
+
+	fec.recovery.payload = append([]byte(nil), buf[flexfecHeaderSize+maskSize:]...)
+
+	mask := buf[flexfecHeaderSize : flexfecHeaderSize+maskSize]
+	for i := 0; i < maskBits; i++ {
+		pos := flexfecMaskBitPosition(i)
+		if (mask[pos/8] & (1 << (7 - pos%8))) != 0 {
+			fec.sequenceNumbers = append(fec.sequenceNumbers, fec.base+uint16(i))
+		}
+	}
+
+	return fec, nil
+}
+
+func (d *Decoder) recover() []*rtp.Packet {
+	var ret []*rtp.Packet
+
+	for {
+		progress := false
+		n := 0
+
+		for _, fec := range d.pending {
+			var missing []uint16
+			for _, seqNum := range fec.sequenceNumbers {
+				if _, ok := d.history[seqNum]; !ok {
+					missing = append(missing, seqNum)
+				}
+			}
+
+			switch {
+			case len(missing) == 0:
+				// all packets have been received, discard FEC packet
+
+			case len(missing) == 1 && d.ssrc != nil:
+				pkt, buf := d.recoverPacket(fec, missing[0])
+				if pkt != nil {
+					d.addToHistory(missing[0], buf)
+					ret = append(ret, pkt)
+					progress = true
+				}
+
+			default:
+				d.pending[n] = fec
+				n++
+			}
+		}
+
+		d.pending = d.pending[:n]
+
+		if !progress {
+			return ret
+		}
+	}
+}
+
+func (d *Decoder) recoverPacket(fec *fecPacket, seqNum uint16) (*rtp.Packet, []byte) {
+	rd := recoveryData{
+		b0:        fec.recovery.b0,
+		b1:        fec.recovery.b1,
+		timestamp: fec.recovery.timestamp,
+		length:    fec.recovery.length,
+		payload:   append([]byte(nil), fec.recovery.payload...),
+	}
+
+	for _, other := range fec.sequenceNumbers {
+		if other != seqNum {
+			rd.xor(d.history[other])
+		}
+	}
+
+	if int(rd.length) > len(rd.payload) {
+		return nil, nil
+	}
+
+	buf := make([]byte, rtpHeaderSize+int(rd.length))
+	buf[0] = (rtpVersion << 6) | (rd.b0 & 0x3F)
+	buf[1] = rd.b1
+	binary.BigEndian.PutUint16(buf[2:], seqNum)
+	binary.BigEndian.PutUint32(buf[4:], rd.timestamp)
+	binary.BigEndian.PutUint32(buf[8:], *d.ssrc)
+	copy(buf[rtpHeaderSize:], rd.payload)
+
+	var pkt rtp.Packet
+	err := pkt.Unmarshal(buf)
+	if err != nil {
+		return nil, nil
+	}
+
+	return &pkt, buf
+}
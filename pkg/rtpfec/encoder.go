@@ -0,0 +1,224 @@
+package rtpfec
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/pion/rtp"
+)
+
+func randUint32() uint32 {
+	var b [4]byte
+	rand.Read(b[:])
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// Encoder is a RTP FEC encoder.
+type Encoder struct {
+	// FEC scheme.
+	Scheme Scheme
+
+	// payload type of FEC packets.
+	PayloadType uint8
+
+	// SSRC of FEC packets (optional).
+	// It defaults to a random value.
+	SSRC *uint32
+
+	// initial sequence number of FEC packets (optional).
+	// It defaults to a random value.
+	InitialSequenceNumber *uint16
+
+	// number of media packets that are protected by each FEC packet (optional).
+	// It is clamped to 48 with ULPFEC and to 109 with FlexFEC.
+	// It defaults to 10.
+	GroupSize int
+
+	sequenceNumber uint16
+	group          [][]byte
+	groupBase      uint16
+	groupMask      []bool
+	lastTimestamp  uint32
+	lastSSRC       uint32
+}
+
+// Init initializes the encoder.
+func (e *Encoder) Init() {
+	if e.SSRC == nil {
+		v := randUint32()
+		e.SSRC = &v
+	}
+	if e.InitialSequenceNumber == nil {
+		v := uint16(randUint32())
+		e.InitialSequenceNumber = &v
+	}
+	if e.GroupSize == 0 {
+		e.GroupSize = 10
+	}
+	if e.GroupSize > e.Scheme.maxMaskBits() {
+		e.GroupSize = e.Scheme.maxMaskBits()
+	}
+
+	e.sequenceNumber = *e.InitialSequenceNumber
+}
+
+// Encode adds a media packet to the current group.
+// It returns a FEC packet when the group is complete, otherwise nil.
+func (e *Encoder) Encode(pkt *rtp.Packet) (*rtp.Packet, error) {
+	buf, err := pkt.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(e.group) != 0 {
+		diff := int(pkt.SequenceNumber - e.groupBase)
+
+		// packet can't be protected by the current group: discard the group.
+		if diff >= e.Scheme.maxMaskBits() || pkt.SSRC != e.lastSSRC {
+			e.group = nil
+		} else if e.groupMask[diff] {
+			return nil, nil
+		}
+	}
+
+	if len(e.group) == 0 {
+		e.groupBase = pkt.SequenceNumber
+		e.groupMask = make([]bool, e.Scheme.maxMaskBits())
+	}
+
+	e.group = append(e.group, buf)
+	e.groupMask[pkt.SequenceNumber-e.groupBase] = true
+	e.lastTimestamp = pkt.Timestamp
+	e.lastSSRC = pkt.SSRC
+
+	if len(e.group) < e.GroupSize {
+		return nil, nil
+	}
+
+	ret := e.generate()
+	e.group = nil
+	return ret, nil
+}
+
+func (e *Encoder) generate() *rtp.Packet {
+	var rd recoveryData
+	for _, buf := range e.group {
+		rd.xor(buf)
+	}
+
+	ret := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        rtpVersion,
+			PayloadType:    e.PayloadType,
+			SequenceNumber: e.sequenceNumber,
+			Timestamp:      e.lastTimestamp,
+			SSRC:           *e.SSRC,
+		},
+	}
+	e.sequenceNumber++
+
+	if e.Scheme == SchemeFlexFEC {
+		ret.CSRC = []uint32{e.lastSSRC}
+		ret.Payload = e.generateFlexFEC(&rd)
+	} else {
+		ret.Payload = e.generateULPFEC(&rd)
+	}
+
+	return ret
+}
+
+func (e *Encoder) generateULPFEC(rd *recoveryData) []byte {
+	maskBits := ulpfecShortMaskBits
+	for i := ulpfecShortMaskBits; i < len(e.groupMask); i++ {
+		if e.groupMask[i] {
+			maskBits = ulpfecLongMaskBits
+			break
+		}
+	}
+
+	// FEC header + ULP level header + payload
+	buf := make([]byte, ulpfecHeaderSize+2+maskBits/8+len(rd.payload))
+
+	buf[0] = rd.b0 & 0x3F
+	if maskBits == ulpfecLongMaskBits {
+		buf[0] |= 0x40 // L
+	}
+	buf[1] = rd.b1
+	binary.BigEndian.PutUint16(buf[2:], e.groupBase)
+	binary.BigEndian.PutUint32(buf[4:], rd.timestamp)
+	binary.BigEndian.PutUint16(buf[8:], rd.length)
+
+	binary.BigEndian.PutUint16(buf[10:], uint16(len(rd.payload)))
+	for i := 0; i < maskBits; i++ {
+		if e.groupMask[i] {
+			buf[12+i/8] |= 1 << (7 - i%8)
+		}
+	}
+
+	copy(buf[12+maskBits/8:], rd.payload)
+
+	return buf
+}
+
+func (e *Encoder) generateFlexFEC(rd *recoveryData) []byte {
+	last := 0
+	for i, v := range e.groupMask {
+		if v {
+			last = i
+		}
+	}
+
+	var maskSize int
+	switch {
+	case last < 15:
+		maskSize = 2
+	case last < 46:
+		maskSize = 6
+	default:
+		maskSize = 14
+	}
+
+	buf := make([]byte, flexfecHeaderSize+maskSize+len(rd.payload))
+
+	buf[0] = rd.b0 & 0x3F
+	buf[1] = rd.b1
+	binary.BigEndian.PutUint16(buf[2:], rd.length)
+	binary.BigEndian.PutUint32(buf[4:], rd.timestamp)
+	binary.BigEndian.PutUint16(buf[8:], e.groupBase)
+
+	mask := buf[flexfecHeaderSize : flexfecHeaderSize+maskSize]
+
+	for i, v := range e.groupMask {
+		if v {
+			pos := flexfecMaskBitPosition(i)
+			mask[pos/8] |= 1 << (7 - pos%8)
+		}
+	}
+
+	switch maskSize {
+	case 2:
+		mask[0] |= 0x80
+	case 6:
+		mask[2] |= 0x80
+	default:
+		mask[6] |= 0x80
+	}
+
+	copy(buf[flexfecHeaderSize+maskSize:], rd.payload)
+
+	return buf
+}
+
+// flexfecMaskBitPosition returns the position, inside the FlexFEC mask,
+// of the bit that corresponds to the n-th packet of the group,
+// skipping the K bits.
+func flexfecMaskBitPosition(n int) int {
+	switch {
+	case n < 15:
+		return 1 + n
+	case n < 46:
+		return 2 + n
+	default:
+		return 3 + n
+	}
+}
@@ -0,0 +1,76 @@
+// Package rtpfec contains a RTP forward error correction (FEC) decoder and encoder.
+// Supported schemes are ULPFEC (RFC 5109) and FlexFEC (RFC 8627).
+package rtpfec
+
+import (
+	"encoding/binary"
+)
+
+const (
+	rtpVersion = 0x02
+
+	// size of the fixed part of RTP headers.
+	rtpHeaderSize = 12
+
+	ulpfecHeaderSize    = 10
+	ulpfecShortMaskBits = 16
+	ulpfecLongMaskBits  = 48
+	flexfecHeaderSize   = 10
+	flexfecMaskBits     = 109
+)
+
+// Scheme is a FEC scheme.
+type Scheme int
+
+// schemes.
+const (
+	SchemeULPFEC Scheme = iota
+	SchemeFlexFEC
+)
+
+// String implements fmt.Stringer.
+// It returns the encoding name of the scheme in SDP.
+func (s Scheme) String() string {
+	if s == SchemeFlexFEC {
+		return "flexfec"
+	}
+	return "ulpfec"
+}
+
+func (s Scheme) maxMaskBits() int {
+	if s == SchemeFlexFEC {
+		return flexfecMaskBits
+	}
+	return ulpfecLongMaskBits
+}
+
+// recoveryData contains the fields that are protected by a FEC packet,
+// XORed together.
+type recoveryData struct {
+	// P, X, CC bits
+	b0 byte
+	// M, PT bits
+	b1        byte
+	timestamp uint32
+	length    uint16
+	payload   []byte
+}
+
+// xor XORs a marshaled RTP packet with the recovery data.
+func (r *recoveryData) xor(buf []byte) {
+	r.b0 ^= buf[0]
+	r.b1 ^= buf[1]
+	r.timestamp ^= binary.BigEndian.Uint32(buf[4:8])
+	r.length ^= uint16(len(buf) - rtpHeaderSize)
+
+	body := buf[rtpHeaderSize:]
+	if len(body) > len(r.payload) {
+		tmp := make([]byte, len(body))
+		copy(tmp, r.payload)
+		r.payload = tmp
+	}
+
+	for i, b := range body {
+		r.payload[i] ^= b
+	}
+}
@@ -0,0 +1,276 @@
+package rtpfec
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func mediaPackets(count int) []*rtp.Packet {
+	ret := make([]*rtp.Packet, count)
+
+	for i := range ret {
+		payload := make([]byte, 1+i%7)
+		for j := range payload {
+			payload[j] = byte(i + j)
+		}
+
+		ret[i] = &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         (i % 3) == 0,
+				PayloadType:    96,
+				SequenceNumber: uint16(65530 + i),
+				Timestamp:      2289526357 + uint32(i/3)*3000,
+				SSRC:           0x9dbb7812,
+				CSRC:           []uint32{},
+			},
+			Payload: payload,
+		}
+	}
+
+	return ret
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, ca := range []struct {
+		name      string
+		scheme    Scheme
+		groupSize int
+		lost      int
+	}{
+		{
+			"ulpfec short mask",
+			SchemeULPFEC,
+			10,
+			4,
+		},
+		{
+			"ulpfec long mask",
+			SchemeULPFEC,
+			48,
+			47,
+		},
+		{
+			"flexfec 15 bits mask",
+			SchemeFlexFEC,
+			15,
+			0,
+		},
+		{
+			"flexfec 46 bits mask",
+			SchemeFlexFEC,
+			40,
+			30,
+		},
+		{
+			"flexfec 109 bits mask",
+			SchemeFlexFEC,
+			109,
+			108,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			e := &Encoder{
+				Scheme:      ca.scheme,
+				PayloadType: 98,
+				SSRC: func() *uint32 {
+					v := uint32(0x12345678)
+					return &v
+				}(),
+				InitialSequenceNumber: func() *uint16 {
+					v := uint16(1000)
+					return &v
+				}(),
+				GroupSize: ca.groupSize,
+			}
+			e.Init()
+
+			d := &Decoder{
+				Scheme:      ca.scheme,
+				PayloadType: 98,
+			}
+			d.Init()
+
+			pkts := mediaPackets(ca.groupSize)
+
+			for i, pkt := range pkts {
+				fec, err := e.Encode(pkt)
+				require.NoError(t, err)
+
+				if i != len(pkts)-1 {
+					require.Nil(t, fec)
+				} else {
+					require.NotNil(t, fec)
+					require.Equal(t, uint8(98), fec.PayloadType)
+					require.Equal(t, uint16(1000), fec.SequenceNumber)
+					require.Equal(t, uint32(0x12345678), fec.SSRC)
+				}
+
+				if i != ca.lost {
+					recovered, err := d.Decode(pkt)
+					require.NoError(t, err)
+					require.Equal(t, []*rtp.Packet(nil), recovered)
+				}
+
+				if fec != nil {
+					byts, err := fec.Marshal()
+					require.NoError(t, err)
+
+					var fec2 rtp.Packet
+					err = fec2.Unmarshal(byts)
+					require.NoError(t, err)
+
+					recovered, err := d.Decode(&fec2)
+					require.NoError(t, err)
+					require.Equal(t, []*rtp.Packet{pkts[ca.lost]}, recovered)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeFECBeforeMedia(t *testing.T) {
+	e := &Encoder{
+		PayloadType: 98,
+		GroupSize:   3,
+	}
+	e.Init()
+
+	d := &Decoder{
+		PayloadType: 98,
+	}
+	d.Init()
+
+	pkts := mediaPackets(3)
+
+	var fec *rtp.Packet
+	for _, pkt := range pkts {
+		var err error
+		fec, err = e.Encode(pkt)
+		require.NoError(t, err)
+	}
+
+	recovered, err := d.Decode(fec)
+	require.NoError(t, err)
+	require.Equal(t, []*rtp.Packet(nil), recovered)
+
+	recovered, err = d.Decode(pkts[0])
+	require.NoError(t, err)
+	require.Equal(t, []*rtp.Packet(nil), recovered)
+
+	recovered, err = d.Decode(pkts[2])
+	require.NoError(t, err)
+	require.Equal(t, []*rtp.Packet{pkts[1]}, recovered)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name   string
+		scheme Scheme
+		pkt    *rtp.Packet
+		err    string
+	}{
+		{
+			"ulpfec too short",
+			SchemeULPFEC,
+			&rtp.Packet{
+				Header: rtp.Header{
+					Version:     2,
+					PayloadType: 98,
+				},
+				Payload: []byte{0x01, 0x02},
+			},
+			"payload is too short",
+		},
+		{
+			"ulpfec extension",
+			SchemeULPFEC,
+			&rtp.Packet{
+				Header: rtp.Header{
+					Version:     2,
+					PayloadType: 98,
+				},
+				Payload: []byte{
+					0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+			"extension flag is not supported",
+		},
+		{
+			"ulpfec invalid protection length",
+			SchemeULPFEC,
+			&rtp.Packet{
+				Header: rtp.Header{
+					Version:     2,
+					PayloadType: 98,
+				},
+				Payload: []byte{
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x05, 0x80, 0x00,
+				},
+			},
+			"payload is too short",
+		},
+		{
+			"flexfec fixed mask",
+			SchemeFlexFEC,
+			&rtp.Packet{
+				Header: rtp.Header{
+					Version:     2,
+					PayloadType: 98,
+					CSRC:        []uint32{0x9dbb7812},
+				},
+				Payload: []byte{
+					0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x80, 0x00,
+				},
+			},
+			"retransmissions and fixed masks are not supported",
+		},
+		{
+			"flexfec multiple ssrcs",
+			SchemeFlexFEC,
+			&rtp.Packet{
+				Header: rtp.Header{
+					Version:     2,
+					PayloadType: 98,
+				},
+				Payload: []byte{
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x80, 0x00,
+				},
+			},
+			"protection of multiple SSRCs is not supported",
+		},
+		{
+			"flexfec mask too short",
+			SchemeFlexFEC,
+			&rtp.Packet{
+				Header: rtp.Header{
+					Version:     2,
+					PayloadType: 98,
+					CSRC:        []uint32{0x9dbb7812},
+				},
+				Payload: []byte{
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00,
+				},
+			},
+			"payload is too short",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			d := &Decoder{
+				Scheme:      ca.scheme,
+				PayloadType: 98,
+			}
+			d.Init()
+
+			_, err := d.Decode(ca.pkt)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
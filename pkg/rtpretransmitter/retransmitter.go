@@ -0,0 +1,76 @@
+// Package rtpretransmitter contains a utility to answer RTCP NACKs by retransmitting RTP packets.
+package rtpretransmitter
+
+import (
+	"sync"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// Retransmitter keeps a history of sent RTP packets, in order to
+// retransmit them when they are requested by RTCP generic NACKs.
+type Retransmitter struct {
+	size  int
+	mutex sync.Mutex
+
+	// data from RTP packets
+	ssrc    *uint32
+	packets []*rtp.Packet
+}
+
+// New allocates a Retransmitter.
+// size is the number of packets that are kept in the history.
+// It is raised to 1 when it is not positive.
+func New(size int) *Retransmitter {
+	if size < 1 {
+		size = 1
+	}
+
+	return &Retransmitter{
+		size:    size,
+		packets: make([]*rtp.Packet, size),
+	}
+}
+
+// ProcessPacketRTP adds a RTP packet to the history.
+func (r *Retransmitter) ProcessPacketRTP(pkt *rtp.Packet) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.ssrc == nil || *r.ssrc != pkt.SSRC {
+		v := pkt.SSRC
+		r.ssrc = &v
+	}
+
+	r.packets[int(pkt.SequenceNumber)%r.size] = pkt.Clone()
+}
+
+// ProcessPacketRTCP returns the RTP packets that are requested by a RTCP packet.
+// Packets that are not in the history anymore are skipped.
+func (r *Retransmitter) ProcessPacketRTCP(pkt rtcp.Packet) []*rtp.Packet {
+	nack, ok := pkt.(*rtcp.TransportLayerNack)
+	if !ok {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.ssrc == nil || nack.MediaSSRC != *r.ssrc {
+		return nil
+	}
+
+	var ret []*rtp.Packet
+
+	for _, pair := range nack.Nacks {
+		for _, seqNum := range pair.PacketList() {
+			entry := r.packets[int(seqNum)%r.size]
+			if entry != nil && entry.SequenceNumber == seqNum {
+				ret = append(ret, entry.Clone())
+			}
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,96 @@
+package rtpretransmitter
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetransmitter(t *testing.T) {
+	r := New(4)
+
+	for i := 0; i < 6; i++ {
+		r.ProcessPacketRTP(&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    96,
+				SequenceNumber: uint16(65534 + i),
+				SSRC:           0x38F27A2F,
+			},
+			Payload: []byte{byte(i)},
+		})
+	}
+
+	out := r.ProcessPacketRTCP(&rtcp.TransportLayerNack{
+		MediaSSRC: 0x38F27A2F,
+		Nacks:     rtcp.NackPairsFromSequenceNumbers([]uint16{65534, 0, 3}),
+	})
+	require.Equal(t, []*rtp.Packet{
+		{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    96,
+				SequenceNumber: 0,
+				SSRC:           0x38F27A2F,
+			},
+			Payload: []byte{2},
+		},
+		{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    96,
+				SequenceNumber: 3,
+				SSRC:           0x38F27A2F,
+			},
+			Payload: []byte{5},
+		},
+	}, out)
+}
+
+func TestRetransmitterIgnoreOtherPackets(t *testing.T) {
+	r := New(4)
+
+	r.ProcessPacketRTP(&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: 10,
+			SSRC:           0x38F27A2F,
+		},
+		Payload: []byte{1},
+	})
+
+	out := r.ProcessPacketRTCP(&rtcp.TransportLayerNack{
+		MediaSSRC: 0x12345678,
+		Nacks:     rtcp.NackPairsFromSequenceNumbers([]uint16{10}),
+	})
+	require.Equal(t, []*rtp.Packet(nil), out)
+
+	out = r.ProcessPacketRTCP(&rtcp.PictureLossIndication{
+		MediaSSRC: 0x38F27A2F,
+	})
+	require.Equal(t, []*rtp.Packet(nil), out)
+}
+
+func TestRetransmitterInvalidSize(t *testing.T) {
+	r := New(0)
+
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: 123,
+			SSRC:           0x38F27A2F,
+		},
+		Payload: []byte{0x01, 0x02},
+	}
+	r.ProcessPacketRTP(pkt)
+
+	ret := r.ProcessPacketRTCP(&rtcp.TransportLayerNack{
+		MediaSSRC: 0x38F27A2F,
+		Nacks:     rtcp.NackPairsFromSequenceNumbers([]uint16{123}),
+	})
+	require.Equal(t, []*rtp.Packet{pkt}, ret)
+}
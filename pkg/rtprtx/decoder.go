@@ -0,0 +1,36 @@
+package rtprtx
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pion/rtp"
+)
+
+// Decoder is a RTP retransmission decoder.
+type Decoder struct {
+	// payload type of original packets.
+	PayloadType uint8
+
+	// SSRC of original packets.
+	SSRC uint32
+}
+
+// Decode extracts the original RTP packet from a retransmission packet.
+func (d *Decoder) Decode(pkt *rtp.Packet) (*rtp.Packet, error) {
+	if len(pkt.Payload) < 2 {
+		return nil, fmt.Errorf("payload is too short")
+	}
+
+	return &rtp.Packet{
+		Header: rtp.Header{
+			Version:        rtpVersion,
+			Marker:         pkt.Marker,
+			PayloadType:    d.PayloadType,
+			SequenceNumber: binary.BigEndian.Uint16(pkt.Payload),
+			Timestamp:      pkt.Timestamp,
+			SSRC:           d.SSRC,
+		},
+		Payload: pkt.Payload[2:],
+	}, nil
+}
@@ -0,0 +1,66 @@
+package rtprtx
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/pion/rtp"
+)
+
+func randUint32() uint32 {
+	var b [4]byte
+	rand.Read(b[:])
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// Encoder is a RTP retransmission encoder.
+type Encoder struct {
+	// payload type of retransmission packets.
+	PayloadType uint8
+
+	// SSRC of retransmission packets (optional).
+	// It defaults to a random value.
+	SSRC *uint32
+
+	// initial sequence number of retransmission packets (optional).
+	// It defaults to a random value.
+	InitialSequenceNumber *uint16
+
+	sequenceNumber uint16
+}
+
+// Init initializes the encoder.
+func (e *Encoder) Init() {
+	if e.SSRC == nil {
+		v := randUint32()
+		e.SSRC = &v
+	}
+	if e.InitialSequenceNumber == nil {
+		v := uint16(randUint32())
+		e.InitialSequenceNumber = &v
+	}
+
+	e.sequenceNumber = *e.InitialSequenceNumber
+}
+
+// Encode wraps a RTP packet into a retransmission packet.
+func (e *Encoder) Encode(pkt *rtp.Packet) *rtp.Packet {
+	payload := make([]byte, 2+len(pkt.Payload))
+	binary.BigEndian.PutUint16(payload, pkt.SequenceNumber)
+	copy(payload[2:], pkt.Payload)
+
+	ret := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        rtpVersion,
+			Marker:         pkt.Marker,
+			PayloadType:    e.PayloadType,
+			SequenceNumber: e.sequenceNumber,
+			Timestamp:      pkt.Timestamp,
+			SSRC:           *e.SSRC,
+		},
+		Payload: payload,
+	}
+	e.sequenceNumber++
+
+	return ret
+}
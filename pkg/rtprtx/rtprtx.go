@@ -0,0 +1,6 @@
+// Package rtprtx contains a RTP retransmission (RFC 4588) decoder and encoder.
+package rtprtx
+
+const (
+	rtpVersion = 0x02
+)
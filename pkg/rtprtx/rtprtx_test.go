@@ -0,0 +1,75 @@
+package rtprtx
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	orig := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 17645,
+			Timestamp:      2289526357,
+			SSRC:           0x9dbb7812,
+		},
+		Payload: []byte{0x01, 0x02, 0x03, 0x04},
+	}
+
+	e := &Encoder{
+		PayloadType: 97,
+		SSRC: func() *uint32 {
+			v := uint32(0x12345678)
+			return &v
+		}(),
+		InitialSequenceNumber: func() *uint16 {
+			v := uint16(1000)
+			return &v
+		}(),
+	}
+	e.Init()
+
+	enc := e.Encode(orig)
+	require.Equal(t, &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    97,
+			SequenceNumber: 1000,
+			Timestamp:      2289526357,
+			SSRC:           0x12345678,
+		},
+		Payload: []byte{0x44, 0xed, 0x01, 0x02, 0x03, 0x04},
+	}, enc)
+
+	enc2 := e.Encode(orig)
+	require.Equal(t, uint16(1001), enc2.SequenceNumber)
+
+	d := &Decoder{
+		PayloadType: 96,
+		SSRC:        0x9dbb7812,
+	}
+
+	dec, err := d.Decode(enc)
+	require.NoError(t, err)
+	require.Equal(t, orig, dec)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	d := &Decoder{
+		PayloadType: 96,
+	}
+
+	_, err := d.Decode(&rtp.Packet{
+		Header: rtp.Header{
+			Version:     2,
+			PayloadType: 97,
+		},
+		Payload: []byte{0x01},
+	})
+	require.EqualError(t, err, "payload is too short")
+}
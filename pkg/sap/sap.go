@@ -0,0 +1,150 @@
+// Package sap contains a SAP (Session Announcement Protocol, RFC 2974) packet decoder and encoder.
+package sap
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+)
+
+const (
+	version = 1
+
+	// PayloadTypeSDP is the payload type of packets that contain a SDP.
+	PayloadTypeSDP = "application/sdp"
+)
+
+// Packet is a SAP packet.
+type Packet struct {
+	// whether the packet deletes a session, instead of announcing it.
+	Deletion bool
+
+	// hash that identifies the announcement.
+	// It must change every time the payload changes.
+	MessageIDHash uint16
+
+	// IP of the announcer.
+	OriginatingSource net.IP
+
+	// authentication data.
+	AuthenticationData []byte
+
+	// MIME type of the payload.
+	// If empty, the payload is a SDP.
+	PayloadType string
+
+	// payload.
+	Payload []byte
+}
+
+// Unmarshal decodes a packet.
+func (p *Packet) Unmarshal(buf []byte) error {
+	if len(buf) < 4 {
+		return fmt.Errorf("buffer is too short")
+	}
+
+	if v := buf[0] >> 5; v != version {
+		return fmt.Errorf("unsupported version: %d", v)
+	}
+
+	isIPv6 := (buf[0] & 0x10) != 0
+
+	if (buf[0] & 0x02) != 0 {
+		return fmt.Errorf("encrypted packets are not supported")
+	}
+
+	if (buf[0] & 0x01) != 0 {
+		return fmt.Errorf("compressed packets are not supported")
+	}
+
+	p.Deletion = (buf[0] & 0x04) != 0
+	authLen := int(buf[1]) * 4
+	p.MessageIDHash = uint16(buf[2])<<8 | uint16(buf[3])
+	buf = buf[4:]
+
+	ipLen := net.IPv4len
+	if isIPv6 {
+		ipLen = net.IPv6len
+	}
+
+	if len(buf) < (ipLen + authLen) {
+		return fmt.Errorf("buffer is too short")
+	}
+
+	p.OriginatingSource = make(net.IP, ipLen)
+	copy(p.OriginatingSource, buf[:ipLen])
+	buf = buf[ipLen:]
+
+	if authLen != 0 {
+		p.AuthenticationData = buf[:authLen]
+		buf = buf[authLen:]
+	} else {
+		p.AuthenticationData = nil
+	}
+
+	// the payload type is optional.
+	// when present, it is terminated by a null character.
+	p.PayloadType = ""
+	if !(len(buf) >= 2 && buf[0] == 'v' && buf[1] == '=') {
+		i := bytes.IndexByte(buf, 0)
+		if i < 0 {
+			return fmt.Errorf("payload type is not terminated")
+		}
+
+		p.PayloadType = string(buf[:i])
+		buf = buf[i+1:]
+	}
+
+	p.Payload = buf
+
+	return nil
+}
+
+// Marshal encodes a packet.
+func (p Packet) Marshal() ([]byte, error) {
+	ip := p.OriginatingSource.To4()
+	isIPv6 := false
+	if ip == nil {
+		ip = p.OriginatingSource.To16()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid originating source")
+		}
+		isIPv6 = true
+	}
+
+	if (len(p.AuthenticationData) % 4) != 0 {
+		return nil, fmt.Errorf("length of authentication data must be a multiple of 4")
+	}
+
+	if len(p.AuthenticationData) > 255*4 {
+		return nil, fmt.Errorf("authentication data is too long")
+	}
+
+	b0 := byte(version << 5)
+	if isIPv6 {
+		b0 |= 0x10
+	}
+	if p.Deletion {
+		b0 |= 0x04
+	}
+
+	buf := []byte{
+		b0,
+		byte(len(p.AuthenticationData) / 4),
+		byte(p.MessageIDHash >> 8),
+		byte(p.MessageIDHash),
+	}
+	buf = append(buf, ip...)
+	buf = append(buf, p.AuthenticationData...)
+
+	payloadType := p.PayloadType
+	if payloadType == "" {
+		payloadType = PayloadTypeSDP
+	}
+	buf = append(buf, []byte(payloadType)...)
+	buf = append(buf, 0)
+
+	buf = append(buf, p.Payload...)
+
+	return buf, nil
+}
@@ -0,0 +1,120 @@
+package sap
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var cases = []struct {
+	name string
+	byts []byte
+	pkt  Packet
+}{
+	{
+		"ipv4 announcement",
+		append([]byte{
+			0x20, 0x00, 0x12, 0x34,
+			0xc0, 0xa8, 0x01, 0x02,
+		}, []byte("application/sdp\x00v=0\r\n")...),
+		Packet{
+			MessageIDHash:     0x1234,
+			OriginatingSource: net.ParseIP("192.168.1.2").To4(),
+			PayloadType:       "application/sdp",
+			Payload:           []byte("v=0\r\n"),
+		},
+	},
+	{
+		"ipv6 deletion with authentication",
+		append([]byte{
+			0x34, 0x01, 0xab, 0xcd,
+			0xfd, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+			0x01, 0x02, 0x03, 0x04,
+		}, []byte("application/sdp\x00v=0\r\n")...),
+		Packet{
+			Deletion:           true,
+			MessageIDHash:      0xabcd,
+			OriginatingSource:  net.ParseIP("fd00::2"),
+			AuthenticationData: []byte{0x01, 0x02, 0x03, 0x04},
+			PayloadType:        "application/sdp",
+			Payload:            []byte("v=0\r\n"),
+		},
+	},
+}
+
+func TestUnmarshal(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			var pkt Packet
+			err := pkt.Unmarshal(ca.byts)
+			require.NoError(t, err)
+			require.Equal(t, ca.pkt, pkt)
+		})
+	}
+}
+
+func TestUnmarshalWithoutPayloadType(t *testing.T) {
+	var pkt Packet
+	err := pkt.Unmarshal(append([]byte{
+		0x20, 0x00, 0x12, 0x34,
+		0xc0, 0xa8, 0x01, 0x02,
+	}, []byte("v=0\r\n")...))
+	require.NoError(t, err)
+	require.Equal(t, Packet{
+		MessageIDHash:     0x1234,
+		OriginatingSource: net.ParseIP("192.168.1.2").To4(),
+		Payload:           []byte("v=0\r\n"),
+	}, pkt)
+}
+
+func TestMarshal(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			byts, err := ca.pkt.Marshal()
+			require.NoError(t, err)
+			require.Equal(t, ca.byts, byts)
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		byts []byte
+		err  string
+	}{
+		{
+			"too short",
+			[]byte{0x20, 0x00},
+			"buffer is too short",
+		},
+		{
+			"invalid version",
+			[]byte{0x40, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04},
+			"unsupported version: 2",
+		},
+		{
+			"encrypted",
+			[]byte{0x22, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04},
+			"encrypted packets are not supported",
+		},
+		{
+			"missing source",
+			[]byte{0x20, 0x00, 0x00, 0x00, 0x01, 0x02},
+			"buffer is too short",
+		},
+		{
+			"unterminated payload type",
+			[]byte{0x20, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 'a', 'b'},
+			"payload type is not terminated",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var pkt Packet
+			err := pkt.Unmarshal(ca.byts)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
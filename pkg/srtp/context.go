@@ -0,0 +1,378 @@
+package srtp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+// key derivation labels.
+// https://tools.ietf.org/html/rfc3711#section-4.3.2
+const (
+	labelRTPEncryption  = 0x00
+	labelRTPAuth        = 0x01
+	labelRTPSalt        = 0x02
+	labelRTCPEncryption = 0x03
+	labelRTCPAuth       = 0x04
+	labelRTCPSalt       = 0x05
+)
+
+// deriveKey derives a session key from the master key and salt,
+// with a key derivation rate equal to zero.
+func deriveKey(block cipher.Block, masterSalt []byte, label byte, n int) []byte {
+	iv := make([]byte, 16)
+	copy(iv, masterSalt)
+	iv[7] ^= label
+
+	out := make([]byte, n)
+	cipher.NewCTR(block, iv).XORKeyStream(out, out)
+	return out
+}
+
+func counterIV(salt []byte, ssrc uint32, index uint64) []byte {
+	iv := make([]byte, 16)
+	copy(iv, salt)
+
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], ssrc)
+	for i := 0; i < 4; i++ {
+		iv[4+i] ^= tmp[i]
+	}
+
+	for i := 0; i < 6; i++ {
+		iv[13-i] ^= byte(index >> (8 * i))
+	}
+
+	return iv
+}
+
+type rtpState struct {
+	initialized bool
+	roc         uint32
+	lastSeq     uint16
+}
+
+// index estimates the index of a packet.
+// https://tools.ietf.org/html/rfc3711#appendix-A
+func (s *rtpState) index(seq uint16) (uint32, uint64) {
+	if !s.initialized {
+		return s.roc, uint64(s.roc)<<16 | uint64(seq)
+	}
+
+	roc := s.roc
+
+	if s.lastSeq < 0x8000 {
+		if int(seq)-int(s.lastSeq) > 0x8000 && roc > 0 {
+			roc--
+		}
+	} else if int(s.lastSeq)-0x8000 > int(seq) {
+		roc++
+	}
+
+	return roc, uint64(roc)<<16 | uint64(seq)
+}
+
+func (s *rtpState) update(roc uint32, seq uint16) {
+	if !s.initialized || roc > s.roc || (roc == s.roc && seq > s.lastSeq) {
+		s.initialized = true
+		s.roc = roc
+		s.lastSeq = seq
+	}
+}
+
+// replayWindowSize is the number of indexes that are remembered in order to detect replayed packets.
+const replayWindowSize = 64
+
+// replayWindow detects replayed packets by their index.
+// https://tools.ietf.org/html/rfc3711#section-3.3.2
+type replayWindow struct {
+	initialized bool
+	highest     uint64
+	received    uint64 // bit i is set when index highest-i has been received
+}
+
+// check returns whether a packet with the given index can be accepted,
+// that is, it has not been received yet and it is not too old.
+func (w *replayWindow) check(index uint64) bool {
+	if !w.initialized || index > w.highest {
+		return true
+	}
+
+	diff := w.highest - index
+	if diff >= replayWindowSize {
+		return false
+	}
+
+	return (w.received & (1 << diff)) == 0
+}
+
+// update marks a packet with the given index as received.
+// It must be called after the packet has been authenticated.
+func (w *replayWindow) update(index uint64) {
+	switch {
+	case !w.initialized:
+		w.initialized = true
+		w.highest = index
+		w.received = 1
+
+	case index > w.highest:
+		diff := index - w.highest
+		if diff >= replayWindowSize {
+			w.received = 1
+		} else {
+			w.received = (w.received << diff) | 1
+		}
+		w.highest = index
+
+	default:
+		w.received |= 1 << (w.highest - index)
+	}
+}
+
+// Context encrypts and decrypts SRTP and SRTCP packets that use the same master key.
+// It can be used by multiple goroutines at once.
+type Context struct {
+	profile Profile
+
+	rtpBlock  cipher.Block
+	rtpSalt   []byte
+	rtpAuth   hash.Hash
+	rtcpBlock cipher.Block
+	rtcpSalt  []byte
+	rtcpAuth  hash.Hash
+
+	mutex       sync.Mutex
+	rtpStates   map[uint32]*rtpState
+	rtcpIndexes map[uint32]uint32
+	rtpReplay   map[uint32]*replayWindow
+	rtcpReplay  map[uint32]*replayWindow
+}
+
+// NewContext allocates a Context.
+func NewContext(key *Key) (*Context, error) {
+	err := key.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	masterBlock, err := aes.NewCipher(key.MasterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rtpBlock, err := aes.NewCipher(deriveKey(masterBlock, key.MasterSalt, labelRTPEncryption, KeyLen))
+	if err != nil {
+		return nil, err
+	}
+
+	rtcpBlock, err := aes.NewCipher(deriveKey(masterBlock, key.MasterSalt, labelRTCPEncryption, KeyLen))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Context{
+		profile:     key.Profile,
+		rtpBlock:    rtpBlock,
+		rtpSalt:     deriveKey(masterBlock, key.MasterSalt, labelRTPSalt, SaltLen),
+		rtpAuth:     hmac.New(sha1.New, deriveKey(masterBlock, key.MasterSalt, labelRTPAuth, authKeyLen)),
+		rtcpBlock:   rtcpBlock,
+		rtcpSalt:    deriveKey(masterBlock, key.MasterSalt, labelRTCPSalt, SaltLen),
+		rtcpAuth:    hmac.New(sha1.New, deriveKey(masterBlock, key.MasterSalt, labelRTCPAuth, authKeyLen)),
+		rtpStates:   make(map[uint32]*rtpState),
+		rtcpIndexes: make(map[uint32]uint32),
+		rtpReplay:   make(map[uint32]*replayWindow),
+		rtcpReplay:  make(map[uint32]*replayWindow),
+	}, nil
+}
+
+func (c *Context) rtpState(ssrc uint32) *rtpState {
+	s, ok := c.rtpStates[ssrc]
+	if !ok {
+		s = &rtpState{}
+		c.rtpStates[ssrc] = s
+	}
+	return s
+}
+
+func replayWindowOf(windows map[uint32]*replayWindow, ssrc uint32) *replayWindow {
+	w, ok := windows[ssrc]
+	if !ok {
+		w = &replayWindow{}
+		windows[ssrc] = w
+	}
+	return w
+}
+
+func (c *Context) rtpAuthTag(byts []byte, roc uint32) []byte {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], roc)
+
+	c.rtpAuth.Reset()
+	c.rtpAuth.Write(byts)
+	c.rtpAuth.Write(tmp[:])
+	return c.rtpAuth.Sum(nil)[:c.profile.authTagLen()]
+}
+
+func (c *Context) rtcpAuthTag(byts []byte) []byte {
+	c.rtcpAuth.Reset()
+	c.rtcpAuth.Write(byts)
+	return c.rtcpAuth.Sum(nil)[:srtcpAuthTagLen]
+}
+
+// ROC returns the rollover counter of the RTP packet with the given SSRC and sequence number,
+// estimated from the packets that have been processed.
+func (c *Context) ROC(ssrc uint32, seq uint16) uint32 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	roc, _ := c.rtpState(ssrc).index(seq)
+	return roc
+}
+
+// SetROC sets the rollover counter of the next RTP packet with the given SSRC.
+// It is used to join a stream that is already in progress, or that has been restarted
+// from another position. Replay protection of the stream is reset.
+func (c *Context) SetROC(ssrc uint32, roc uint32) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.rtpStates[ssrc] = &rtpState{roc: roc}
+	delete(c.rtpReplay, ssrc)
+}
+
+// EncryptRTP encrypts a RTP packet.
+func (c *Context) EncryptRTP(byts []byte) ([]byte, error) {
+	var h rtp.Header
+	n, err := h.Unmarshal(byts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	s := c.rtpState(h.SSRC)
+	roc, index := s.index(h.SequenceNumber)
+	s.update(roc, h.SequenceNumber)
+
+	out := make([]byte, len(byts), len(byts)+c.profile.authTagLen())
+	copy(out, byts[:n])
+
+	cipher.NewCTR(c.rtpBlock, counterIV(c.rtpSalt, h.SSRC, index)).XORKeyStream(out[n:], byts[n:])
+
+	return append(out, c.rtpAuthTag(out, roc)...), nil
+}
+
+// DecryptRTP decrypts a SRTP packet.
+func (c *Context) DecryptRTP(byts []byte) ([]byte, error) {
+	authTagLen := c.profile.authTagLen()
+
+	if len(byts) < authTagLen {
+		return nil, fmt.Errorf("packet is too short")
+	}
+
+	var h rtp.Header
+	n, err := h.Unmarshal(byts[:len(byts)-authTagLen])
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	s := c.rtpState(h.SSRC)
+	roc, index := s.index(h.SequenceNumber)
+
+	w := replayWindowOf(c.rtpReplay, h.SSRC)
+	if !w.check(index) {
+		return nil, fmt.Errorf("replayed packet")
+	}
+
+	authenticated := byts[:len(byts)-authTagLen]
+	if subtle.ConstantTimeCompare(c.rtpAuthTag(authenticated, roc), byts[len(byts)-authTagLen:]) != 1 {
+		return nil, fmt.Errorf("authentication failed")
+	}
+
+	s.update(roc, h.SequenceNumber)
+	w.update(index)
+
+	out := make([]byte, len(authenticated))
+	copy(out, authenticated[:n])
+
+	cipher.NewCTR(c.rtpBlock, counterIV(c.rtpSalt, h.SSRC, index)).XORKeyStream(out[n:], authenticated[n:])
+
+	return out, nil
+}
+
+// EncryptRTCP encrypts a RTCP packet, or a compound RTCP packet.
+func (c *Context) EncryptRTCP(byts []byte) ([]byte, error) {
+	if len(byts) < rtcpHeaderSize {
+		return nil, fmt.Errorf("packet is too short")
+	}
+
+	ssrc := binary.BigEndian.Uint32(byts[4:])
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	index := c.rtcpIndexes[ssrc]
+	c.rtcpIndexes[ssrc] = (index + 1) & 0x7FFFFFFF
+
+	out := make([]byte, len(byts)+srtcpIndexLen, len(byts)+srtcpIndexLen+srtcpAuthTagLen)
+	copy(out, byts[:rtcpHeaderSize])
+
+	cipher.NewCTR(c.rtcpBlock, counterIV(c.rtcpSalt, ssrc, uint64(index))).
+		XORKeyStream(out[rtcpHeaderSize:], byts[rtcpHeaderSize:])
+
+	// E flag and SRTCP index
+	binary.BigEndian.PutUint32(out[len(byts):], index|0x80000000)
+
+	return append(out, c.rtcpAuthTag(out)...), nil
+}
+
+// DecryptRTCP decrypts a SRTCP packet.
+func (c *Context) DecryptRTCP(byts []byte) ([]byte, error) {
+	if len(byts) < (rtcpHeaderSize + srtcpIndexLen + srtcpAuthTagLen) {
+		return nil, fmt.Errorf("packet is too short")
+	}
+
+	authenticated := byts[:len(byts)-srtcpAuthTagLen]
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if subtle.ConstantTimeCompare(c.rtcpAuthTag(authenticated), byts[len(byts)-srtcpAuthTagLen:]) != 1 {
+		return nil, fmt.Errorf("authentication failed")
+	}
+
+	ssrc := binary.BigEndian.Uint32(byts[4:])
+	tmp := binary.BigEndian.Uint32(authenticated[len(authenticated)-srtcpIndexLen:])
+	encrypted := (tmp & 0x80000000) != 0
+	index := tmp & 0x7FFFFFFF
+
+	w := replayWindowOf(c.rtcpReplay, ssrc)
+	if !w.check(uint64(index)) {
+		return nil, fmt.Errorf("replayed packet")
+	}
+	w.update(uint64(index))
+
+	payload := authenticated[:len(authenticated)-srtcpIndexLen]
+
+	out := make([]byte, len(payload))
+	copy(out, payload)
+
+	if encrypted {
+		cipher.NewCTR(c.rtcpBlock, counterIV(c.rtcpSalt, ssrc, uint64(index))).
+			XORKeyStream(out[rtcpHeaderSize:], payload[rtcpHeaderSize:])
+	}
+
+	return out, nil
+}
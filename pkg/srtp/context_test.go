@@ -0,0 +1,226 @@
+package srtp
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustDecodeHex(s string) []byte {
+	byts, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return byts
+}
+
+var testKey = &Key{
+	Profile:    ProfileAESCM128HMACSHA180,
+	MasterKey:  mustDecodeHex("e1f97a0d3e018be0d64fa32c06de4139"),
+	MasterSalt: mustDecodeHex("0ec675ad498afeebb6960b3aabe6"),
+}
+
+// https://tools.ietf.org/html/rfc3711#appendix-B.3
+func TestDeriveKey(t *testing.T) {
+	block, err := aes.NewCipher(testKey.MasterKey)
+	require.NoError(t, err)
+
+	require.Equal(t, mustDecodeHex("c61e7a93744f39ee10734afe3ff7a087"),
+		deriveKey(block, testKey.MasterSalt, labelRTPEncryption, KeyLen))
+	require.Equal(t, mustDecodeHex("30cbbc08863d8c85d49db34a9ae1"),
+		deriveKey(block, testKey.MasterSalt, labelRTPSalt, SaltLen))
+	require.Equal(t, mustDecodeHex("cebe321f6ff7716b6fd4ab49af256a156d38baa4"),
+		deriveKey(block, testKey.MasterSalt, labelRTPAuth, authKeyLen))
+}
+
+func TestEncryptRTP(t *testing.T) {
+	c, err := NewContext(testKey)
+	require.NoError(t, err)
+
+	enc, err := c.EncryptRTP(mustDecodeHex("800f1234decafbadcafebabe" +
+		"abababababababababababababababab"))
+	require.NoError(t, err)
+	require.Equal(t, mustDecodeHex("800f1234decafbadcafebabe"+
+		"4e55dc4ce79978d88ca4d215949d2402"+
+		"b78d6acc99ea179b8dbb"), enc)
+}
+
+func TestRTPRoundTrip(t *testing.T) {
+	for _, profile := range []Profile{
+		ProfileAESCM128HMACSHA180,
+		ProfileAESCM128HMACSHA132,
+	} {
+		t.Run(profile.String(), func(t *testing.T) {
+			key := *testKey
+			key.Profile = profile
+
+			enc, err := NewContext(&key)
+			require.NoError(t, err)
+
+			dec, err := NewContext(&key)
+			require.NoError(t, err)
+
+			// sequence numbers wrap around
+			for _, seq := range []uint16{0xFFFE, 0xFFFF, 0x0000, 0x0001} {
+				pkt := []byte{
+					0x80, 0x60, byte(seq >> 8), byte(seq),
+					0x00, 0x00, 0x00, 0x01,
+					0x12, 0x34, 0x56, 0x78,
+					0x01, 0x02, 0x03, 0x04,
+				}
+
+				encrypted, err := enc.EncryptRTP(pkt)
+				require.NoError(t, err)
+				require.Equal(t, len(pkt)+profile.authTagLen(), len(encrypted))
+				require.NotEqual(t, pkt[12:], encrypted[12:len(pkt)])
+
+				decrypted, err := dec.DecryptRTP(encrypted)
+				require.NoError(t, err)
+				require.Equal(t, pkt, decrypted)
+			}
+
+			require.Equal(t, uint32(1), dec.rtpStates[0x12345678].roc)
+		})
+	}
+}
+
+func TestRTPROC(t *testing.T) {
+	enc, err := NewContext(testKey)
+	require.NoError(t, err)
+
+	dec, err := NewContext(testKey)
+	require.NoError(t, err)
+
+	encryptRTP := func(seq uint16) []byte {
+		byts, err := enc.EncryptRTP([]byte{
+			0x80, 0x60, byte(seq >> 8), byte(seq),
+			0x00, 0x00, 0x00, 0x01,
+			0x12, 0x34, 0x56, 0x78,
+			0x01, 0x02, 0x03, 0x04,
+		})
+		require.NoError(t, err)
+		return byts
+	}
+
+	encryptRTP(0xFFFE)
+	encryptRTP(0xFFFF)
+	encryptRTP(0x0000)
+	pkt := encryptRTP(0x0001)
+
+	require.Equal(t, uint32(1), enc.ROC(0x12345678, 0x0002))
+	require.Equal(t, uint32(0), enc.ROC(0x12345678, 0xFFFF))
+
+	// without the rollover counter, the packet can't be decrypted
+	_, err = dec.DecryptRTP(pkt)
+	require.EqualError(t, err, "authentication failed")
+
+	dec.SetROC(0x12345678, enc.ROC(0x12345678, 0x0001))
+
+	decrypted, err := dec.DecryptRTP(pkt)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, decrypted[12:])
+
+	// replay protection is reset
+	_, err = dec.DecryptRTP(pkt)
+	require.EqualError(t, err, "replayed packet")
+
+	dec.SetROC(0x12345678, 1)
+
+	_, err = dec.DecryptRTP(pkt)
+	require.NoError(t, err)
+}
+
+func TestRTCPRoundTrip(t *testing.T) {
+	enc, err := NewContext(testKey)
+	require.NoError(t, err)
+
+	dec, err := NewContext(testKey)
+	require.NoError(t, err)
+
+	pkt := []byte{
+		0x81, 0xc9, 0x00, 0x07, 0x12, 0x34, 0x56, 0x78,
+		0x87, 0x65, 0x43, 0x21, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	for i := 0; i < 2; i++ {
+		encrypted, err := enc.EncryptRTCP(pkt)
+		require.NoError(t, err)
+		require.Equal(t, len(pkt)+srtcpIndexLen+srtcpAuthTagLen, len(encrypted))
+		require.Equal(t, []byte{0x80, 0x00, 0x00, byte(i)}, encrypted[len(pkt):len(pkt)+srtcpIndexLen])
+
+		decrypted, err := dec.DecryptRTCP(encrypted)
+		require.NoError(t, err)
+		require.Equal(t, pkt, decrypted)
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	c, err := NewContext(testKey)
+	require.NoError(t, err)
+
+	enc, err := c.EncryptRTP(mustDecodeHex("800f1234decafbadcafebabe" +
+		"abababababababababababababababab"))
+	require.NoError(t, err)
+	enc[len(enc)-1] ^= 0xFF
+
+	_, err = c.DecryptRTP(enc)
+	require.EqualError(t, err, "authentication failed")
+
+	_, err = c.DecryptRTCP([]byte{0x01, 0x02})
+	require.EqualError(t, err, "packet is too short")
+}
+
+func TestDecryptReplay(t *testing.T) {
+	enc, err := NewContext(testKey)
+	require.NoError(t, err)
+
+	dec, err := NewContext(testKey)
+	require.NoError(t, err)
+
+	encryptRTP := func(seq uint16) []byte {
+		byts, err := enc.EncryptRTP([]byte{
+			0x80, 0x60, byte(seq >> 8), byte(seq),
+			0x00, 0x00, 0x00, 0x01,
+			0x12, 0x34, 0x56, 0x78,
+			0x01, 0x02, 0x03, 0x04,
+		})
+		require.NoError(t, err)
+		return byts
+	}
+
+	pkt1 := encryptRTP(100)
+	pkt2 := encryptRTP(101)
+	pkt3 := encryptRTP(200)
+
+	_, err = dec.DecryptRTP(pkt2)
+	require.NoError(t, err)
+
+	// reordered packets are accepted
+	_, err = dec.DecryptRTP(pkt1)
+	require.NoError(t, err)
+
+	_, err = dec.DecryptRTP(pkt1)
+	require.EqualError(t, err, "replayed packet")
+
+	_, err = dec.DecryptRTP(pkt3)
+	require.NoError(t, err)
+
+	// packets older than the window are rejected
+	_, err = dec.DecryptRTP(pkt2)
+	require.EqualError(t, err, "replayed packet")
+
+	rtcpPkt, err := enc.EncryptRTCP([]byte{
+		0x81, 0xc9, 0x00, 0x01, 0x12, 0x34, 0x56, 0x78,
+	})
+	require.NoError(t, err)
+
+	_, err = dec.DecryptRTCP(rtcpPkt)
+	require.NoError(t, err)
+
+	_, err = dec.DecryptRTCP(rtcpPkt)
+	require.EqualError(t, err, "replayed packet")
+}
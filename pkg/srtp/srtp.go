@@ -0,0 +1,170 @@
+// Package srtp contains a SRTP and SRTCP (RFC 3711) encryptor and decryptor,
+// and utilities to exchange keys with SDP Security Descriptions (RFC 4568).
+package srtp
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// KeyLen is the length of master keys.
+	KeyLen = 16
+
+	// SaltLen is the length of master salts.
+	SaltLen = 14
+
+	authKeyLen      = 20
+	srtcpAuthTagLen = 10
+	srtcpIndexLen   = 4
+	rtcpHeaderSize  = 8
+)
+
+// Profile is a SRTP protection profile (crypto suite).
+type Profile int
+
+// profiles.
+const (
+	ProfileAESCM128HMACSHA180 Profile = iota
+	ProfileAESCM128HMACSHA132
+)
+
+// String implements fmt.Stringer.
+// It returns the name of the crypto suite in SDP.
+func (p Profile) String() string {
+	if p == ProfileAESCM128HMACSHA132 {
+		return "AES_CM_128_HMAC_SHA1_32"
+	}
+	return "AES_CM_128_HMAC_SHA1_80"
+}
+
+func (p Profile) authTagLen() int {
+	if p == ProfileAESCM128HMACSHA132 {
+		return 4
+	}
+	return 10
+}
+
+// KeyExchange is a method to exchange SRTP keys.
+type KeyExchange int
+
+// key exchange methods.
+const (
+	// SDP Security Descriptions (a=crypto).
+	KeyExchangeSDES KeyExchange = iota
+
+	// MIKEY (a=key-mgmt).
+	KeyExchangeMIKEY
+)
+
+// ErrUnsupportedSDES is returned by Key.UnmarshalSDES when a crypto attribute
+// contains a crypto suite or a key method that is not supported.
+type ErrUnsupportedSDES struct {
+	// "crypto suite" or "key method".
+	Param string
+	Value string
+}
+
+// Error implements the error interface.
+func (e ErrUnsupportedSDES) Error() string {
+	return fmt.Sprintf("unsupported %s (%v)", e.Param, e.Value)
+}
+
+// Key is a SRTP master key.
+type Key struct {
+	// protection profile.
+	Profile Profile
+
+	// master key.
+	MasterKey []byte
+
+	// master salt.
+	MasterSalt []byte
+}
+
+// NewKey generates a random master key.
+func NewKey(profile Profile) (*Key, error) {
+	buf := make([]byte, KeyLen+SaltLen)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		Profile:    profile,
+		MasterKey:  buf[:KeyLen],
+		MasterSalt: buf[KeyLen:],
+	}, nil
+}
+
+// Validate checks that the key has a valid length.
+func (k Key) Validate() error {
+	if len(k.MasterKey) != KeyLen {
+		return fmt.Errorf("invalid master key length (%d)", len(k.MasterKey))
+	}
+
+	if len(k.MasterSalt) != SaltLen {
+		return fmt.Errorf("invalid master salt length (%d)", len(k.MasterSalt))
+	}
+
+	return nil
+}
+
+// UnmarshalSDES decodes a key from the value of a SDP crypto attribute (RFC 4568),
+// like "1 AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz".
+func (k *Key) UnmarshalSDES(v string) error {
+	parts := strings.Fields(v)
+	if len(parts) < 3 {
+		return fmt.Errorf("invalid crypto attribute (%v)", v)
+	}
+
+	_, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid crypto attribute tag (%v)", parts[0])
+	}
+
+	switch parts[1] {
+	case "AES_CM_128_HMAC_SHA1_80":
+		k.Profile = ProfileAESCM128HMACSHA180
+
+	case "AES_CM_128_HMAC_SHA1_32":
+		k.Profile = ProfileAESCM128HMACSHA132
+
+	default:
+		return ErrUnsupportedSDES{Param: "crypto suite", Value: parts[1]}
+	}
+
+	// only the first key is used.
+	params := strings.SplitN(parts[2], ";", 2)[0]
+
+	if !strings.HasPrefix(params, "inline:") {
+		return ErrUnsupportedSDES{Param: "key method", Value: params}
+	}
+
+	// lifetime and MKI are ignored.
+	keySalt := strings.SplitN(params[len("inline:"):], "|", 2)[0]
+
+	byts, err := base64.StdEncoding.DecodeString(keySalt)
+	if err != nil {
+		return fmt.Errorf("invalid key (%v)", keySalt)
+	}
+
+	if len(byts) != (KeyLen + SaltLen) {
+		return fmt.Errorf("invalid key length (%d)", len(byts))
+	}
+
+	k.MasterKey = byts[:KeyLen]
+	k.MasterSalt = byts[KeyLen:]
+
+	return nil
+}
+
+// MarshalSDES encodes a key into the value of a SDP crypto attribute (RFC 4568).
+func (k Key) MarshalSDES(tag int) string {
+	byts := append(append([]byte(nil), k.MasterKey...), k.MasterSalt...)
+	return strconv.FormatInt(int64(tag), 10) + " " + k.Profile.String() +
+		" inline:" + base64.StdEncoding.EncodeToString(byts)
+}
@@ -0,0 +1,60 @@
+package srtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSDESUnmarshal(t *testing.T) {
+	var k Key
+	err := k.UnmarshalSDES("1 AES_CM_128_HMAC_SHA1_32 inline:4fl6DT4Bi+DWT6MsBt5BOQ7Gda1Jiv7rtpYLOqvm|2^20|1:4")
+	require.NoError(t, err)
+	require.Equal(t, Key{
+		Profile:    ProfileAESCM128HMACSHA132,
+		MasterKey:  testKey.MasterKey,
+		MasterSalt: testKey.MasterSalt,
+	}, k)
+}
+
+func TestSDESMarshal(t *testing.T) {
+	require.Equal(t, "1 AES_CM_128_HMAC_SHA1_80 inline:4fl6DT4Bi+DWT6MsBt5BOQ7Gda1Jiv7rtpYLOqvm",
+		testKey.MarshalSDES(1))
+}
+
+func TestSDESUnmarshalErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		v    string
+		err  string
+	}{
+		{
+			"missing fields",
+			"1 AES_CM_128_HMAC_SHA1_80",
+			"invalid crypto attribute (1 AES_CM_128_HMAC_SHA1_80)",
+		},
+		{
+			"unsupported suite",
+			"1 F8_128_HMAC_SHA1_80 inline:4fl6DT4Bi+DWT6MsBt5BOQ7Gda1Jiv7rtpYLOqvm",
+			"unsupported crypto suite (F8_128_HMAC_SHA1_80)",
+		},
+		{
+			"invalid key length",
+			"1 AES_CM_128_HMAC_SHA1_80 inline:AQID",
+			"invalid key length (3)",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var k Key
+			err := k.UnmarshalSDES(ca.v)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	k, err := NewKey(ProfileAESCM128HMACSHA180)
+	require.NoError(t, err)
+	require.Equal(t, KeyLen, len(k.MasterKey))
+	require.Equal(t, SaltLen, len(k.MasterSalt))
+}
@@ -0,0 +1,10 @@
+package gortsplib
+
+// isRTCPPacket checks whether a packet received on a port where RTP and RTCP
+// are multiplexed is a RTCP packet.
+// RTCP packet types 192-223 don't overlap with RTP payload types
+// when the marker bit is taken into account.
+// https://tools.ietf.org/html/rfc5761#section-4
+func isRTCPPacket(payload []byte) bool {
+	return len(payload) >= 2 && payload[1] >= 192 && payload[1] <= 223
+}
@@ -0,0 +1,94 @@
+package gortsplib
+
+import (
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+
+	"github.com/aler9/gortsplib/pkg/rtprtx"
+)
+
+const (
+	// maximum number of packets that are requested with a single NACK.
+	rtxMaxMissingPackets = 64
+)
+
+// rtxReceiver detects lost RTP packets, requests them with RTCP NACKs
+// and extracts the original packets from retransmission packets.
+type rtxReceiver struct {
+	localSSRC       uint32
+	rtxPayloadType  uint8
+	writePacketRTCP func(rtcp.Packet)
+
+	decoder            *rtprtx.Decoder
+	originalReceived   bool
+	lastSequenceNumber uint16
+}
+
+func newRTXReceiver(
+	localSSRC uint32,
+	rtxPayloadType uint8,
+	originalPayloadType uint8,
+	writePacketRTCP func(rtcp.Packet),
+) *rtxReceiver {
+	return &rtxReceiver{
+		localSSRC:       localSSRC,
+		rtxPayloadType:  rtxPayloadType,
+		writePacketRTCP: writePacketRTCP,
+		decoder: &rtprtx.Decoder{
+			PayloadType: originalPayloadType,
+		},
+	}
+}
+
+// processPacketRTP processes an incoming RTP packet.
+// It returns the packet that must be routed to the user, or nil.
+func (r *rtxReceiver) processPacketRTP(pkt *rtp.Packet) *rtp.Packet {
+	if pkt.PayloadType == r.rtxPayloadType {
+		if !r.originalReceived {
+			return nil
+		}
+
+		out, err := r.decoder.Decode(pkt)
+		if err != nil {
+			return nil
+		}
+		return out
+	}
+
+	// packets of other formats are not associated with retransmissions
+	if pkt.PayloadType != r.decoder.PayloadType {
+		return pkt
+	}
+
+	if !r.originalReceived {
+		r.originalReceived = true
+		r.decoder.SSRC = pkt.SSRC
+		r.lastSequenceNumber = pkt.SequenceNumber
+		return pkt
+	}
+
+	r.decoder.SSRC = pkt.SSRC
+
+	diff := pkt.SequenceNumber - r.lastSequenceNumber
+
+	// duplicate or reordered packet
+	if diff == 0 || diff >= 0x8000 {
+		return pkt
+	}
+
+	if diff > 1 && diff <= (rtxMaxMissingPackets+1) {
+		missing := make([]uint16, diff-1)
+		for i := range missing {
+			missing[i] = r.lastSequenceNumber + 1 + uint16(i)
+		}
+
+		r.writePacketRTCP(&rtcp.TransportLayerNack{
+			SenderSSRC: r.localSSRC,
+			MediaSSRC:  pkt.SSRC,
+			Nacks:      rtcp.NackPairsFromSequenceNumbers(missing),
+		})
+	}
+
+	r.lastSequenceNumber = pkt.SequenceNumber
+	return pkt
+}
@@ -0,0 +1,308 @@
+package gortsplib
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aler9/gortsplib/pkg/sap"
+)
+
+// SAPSession is a session announced with the Session Announcement Protocol.
+type SAPSession struct {
+	// IP of the announcer.
+	Origin net.IP
+
+	// name of the session.
+	Name string
+
+	// SDP of the session.
+	SDP []byte
+
+	// tracks of the session.
+	Tracks Tracks
+
+	messageIDHash uint16
+	lastSeen      time.Time
+}
+
+// SAPListener discovers sessions announced with the Session Announcement Protocol (RFC 2974).
+// Discovered sessions can be read with Client.StartReadingSAP().
+type SAPListener struct {
+	//
+	// callbacks
+	//
+	// called when a session is announced for the first time, or when its SDP changes.
+	OnSessionAnnounce func(*SAPSession)
+	// called when a session is deleted by its announcer or is not announced anymore.
+	OnSessionDelete func(*SAPSession)
+
+	//
+	// parameters
+	//
+	// address to listen on.
+	// It defaults to 224.2.127.254:9875.
+	Address string
+	// a session is deleted when it is not announced for this period.
+	// It defaults to 1 hour.
+	SessionTimeout time.Duration
+	// maximum number of sessions.
+	// When a new session is announced and the limit is reached,
+	// the session that has not been announced for the longest time is deleted.
+	// It defaults to 1024.
+	MaxSessions int
+
+	//
+	// system functions
+	//
+	// function used to initialize the UDP listener.
+	// It defaults to net.ListenPacket.
+	ListenPacket func(network, address string) (net.PacketConn, error)
+
+	//
+	// private
+	//
+
+	checkPeriod time.Duration
+
+	pc        *net.UDPConn
+	ctx       context.Context
+	ctxCancel func()
+	mutex     sync.RWMutex
+	sessions  map[string]*SAPSession // key is the origin line of the SDP
+
+	// in
+	packets chan *sap.Packet
+
+	// out
+	done chan struct{}
+}
+
+// Start starts the listener.
+func (l *SAPListener) Start() error {
+	// callbacks
+	if l.OnSessionAnnounce == nil {
+		l.OnSessionAnnounce = func(*SAPSession) {}
+	}
+	if l.OnSessionDelete == nil {
+		l.OnSessionDelete = func(*SAPSession) {}
+	}
+
+	// parameters
+	if l.Address == "" {
+		l.Address = sapAddress
+	}
+	if l.SessionTimeout == 0 {
+		l.SessionTimeout = 1 * time.Hour
+	}
+	if l.MaxSessions == 0 {
+		l.MaxSessions = 1024
+	}
+
+	// system functions
+	if l.ListenPacket == nil {
+		l.ListenPacket = net.ListenPacket
+	}
+
+	// private
+	if l.checkPeriod == 0 {
+		l.checkPeriod = 1 * time.Second
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", l.Address)
+	if err != nil {
+		return err
+	}
+
+	l.pc, err = listenMulticast(l.ListenPacket, addr.IP, addr.Port, nil, multicastTTL, true)
+	if err != nil {
+		return err
+	}
+
+	l.ctx, l.ctxCancel = context.WithCancel(context.Background())
+	l.sessions = make(map[string]*SAPSession)
+	l.packets = make(chan *sap.Packet)
+	l.done = make(chan struct{})
+
+	go l.run()
+
+	return nil
+}
+
+// Close closes the listener and waits for its routines to return.
+func (l *SAPListener) Close() error {
+	l.ctxCancel()
+	<-l.done
+	return nil
+}
+
+// Sessions returns the sessions that are currently announced.
+func (l *SAPListener) Sessions() []*SAPSession {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	ret := make([]*SAPSession, 0, len(l.sessions))
+	for _, sx := range l.sessions {
+		ret = append(ret, sx)
+	}
+	return ret
+}
+
+func (l *SAPListener) run() {
+	defer close(l.done)
+
+	readerDone := make(chan struct{})
+	go l.runReader(readerDone)
+
+	t := time.NewTicker(l.checkPeriod)
+	defer t.Stop()
+
+outer:
+	for {
+		select {
+		case pkt := <-l.packets:
+			l.handlePacket(pkt)
+
+		case now := <-t.C:
+			l.removeExpired(now)
+
+		case <-l.ctx.Done():
+			break outer
+		}
+	}
+
+	l.pc.Close()
+	<-readerDone
+}
+
+func (l *SAPListener) runReader(readerDone chan struct{}) {
+	defer close(readerDone)
+
+	for {
+		buf := make([]byte, maxPacketSize)
+		n, _, err := l.pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		var pkt sap.Packet
+		err = pkt.Unmarshal(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		if pkt.PayloadType != "" && pkt.PayloadType != sap.PayloadTypeSDP {
+			continue
+		}
+
+		select {
+		case l.packets <- &pkt:
+		case <-l.ctx.Done():
+			return
+		}
+	}
+}
+
+// sapSessionKey returns the key of a session, that is its origin line.
+func sapSessionKey(origin net.IP, byts []byte) (string, string, Tracks, bool) {
+	tracks, sd, err := ReadTracks(byts, false)
+	if err != nil {
+		return "", "", nil, false
+	}
+
+	return origin.String() + " " + sd.Origin.Username + " " +
+			strconv.FormatUint(sd.Origin.SessionID, 10) + " " + sd.Origin.UnicastAddress,
+		string(sd.SessionName), tracks, true
+}
+
+func (l *SAPListener) handlePacket(pkt *sap.Packet) {
+	if pkt.Deletion {
+		l.mutex.Lock()
+		var deleted []*SAPSession
+		for key, sx := range l.sessions {
+			if sx.Origin.Equal(pkt.OriginatingSource) && sx.messageIDHash == pkt.MessageIDHash {
+				delete(l.sessions, key)
+				deleted = append(deleted, sx)
+			}
+		}
+		l.mutex.Unlock()
+
+		for _, sx := range deleted {
+			l.OnSessionDelete(sx)
+		}
+		return
+	}
+
+	key, name, tracks, ok := sapSessionKey(pkt.OriginatingSource, pkt.Payload)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+
+	l.mutex.Lock()
+	sx, ok := l.sessions[key]
+	if ok && sx.messageIDHash == pkt.MessageIDHash {
+		sx.lastSeen = now
+		l.mutex.Unlock()
+		return
+	}
+
+	var deleted *SAPSession
+	if !ok && len(l.sessions) >= l.MaxSessions {
+		deleted = l.removeOldest()
+	}
+
+	sx = &SAPSession{
+		Origin:        pkt.OriginatingSource,
+		Name:          name,
+		SDP:           pkt.Payload,
+		Tracks:        tracks,
+		messageIDHash: pkt.MessageIDHash,
+		lastSeen:      now,
+	}
+	l.sessions[key] = sx
+	l.mutex.Unlock()
+
+	if deleted != nil {
+		l.OnSessionDelete(deleted)
+	}
+
+	l.OnSessionAnnounce(sx)
+}
+
+// removeOldest removes the session that has not been announced for the longest time.
+// It must be called with the mutex locked.
+func (l *SAPListener) removeOldest() *SAPSession {
+	var oldestKey string
+	var oldest *SAPSession
+	for key, sx := range l.sessions {
+		if oldest == nil || sx.lastSeen.Before(oldest.lastSeen) {
+			oldestKey = key
+			oldest = sx
+		}
+	}
+
+	if oldest != nil {
+		delete(l.sessions, oldestKey)
+	}
+	return oldest
+}
+
+func (l *SAPListener) removeExpired(now time.Time) {
+	l.mutex.Lock()
+	var deleted []*SAPSession
+	for key, sx := range l.sessions {
+		if now.Sub(sx.lastSeen) >= l.SessionTimeout {
+			delete(l.sessions, key)
+			deleted = append(deleted, sx)
+		}
+	}
+	l.mutex.Unlock()
+
+	for _, sx := range deleted {
+		l.OnSessionDelete(sx)
+	}
+}
@@ -1,6 +1,7 @@
 package gortsplib
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
 	"crypto/tls"
@@ -11,7 +12,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aler9/gortsplib/pkg/auth"
 	"github.com/aler9/gortsplib/pkg/base"
+	"github.com/aler9/gortsplib/pkg/headers"
 	"github.com/aler9/gortsplib/pkg/liberrors"
 )
 
@@ -59,8 +62,30 @@ type sessionRequestReq struct {
 	res    chan sessionRequestRes
 }
 
+type sessionWriteRequestReq struct {
+	req *base.Request
+	res chan error
+}
+
+type streamMulticastIPRes struct {
+	ip       net.IP
+	rtpPort  int
+	rtcpPort int
+}
+
 type streamMulticastIPReq struct {
-	res chan net.IP
+	res chan streamMulticastIPRes
+}
+
+type udpListenerPairRes struct {
+	rtpl  *serverUDPListener
+	rtcpl *serverUDPListener
+	err   error
+}
+
+type udpListenerPairReq struct {
+	rtcpMux bool
+	res     chan udpListenerPairRes
 }
 
 // Server is a RTSP server.
@@ -91,7 +116,26 @@ type Server struct {
 	// a port to send and receive RTCP packets with the UDP transport.
 	// If UDPRTPAddress and UDPRTCPAddress are filled, the server can support the UDP transport.
 	UDPRTCPAddress string
+	// a range of ports to send and receive RTP and RTCP packets with the UDP transport.
+	// If filled, a dedicated pair of ports is allocated from the range for each setupped track,
+	// and released when the session is closed. This allows to distinguish clients that
+	// use the same IP and ports, and to set per-session firewall rules.
+	// Ports are opened on the host of RTSPAddress.
+	// It can't be used together with UDPRTPAddress and UDPRTCPAddress.
+	UDPPortRange [2]int
+	// enable symmetric RTP with the UDP transport.
+	// The server learns the real address of each client from the first RTP / RTCP
+	// packets it sends, and sends packets back to that address.
+	// This allows clients behind NAT to use the UDP transport.
+	UDPSymmetricRTP bool
+	// period after PLAY / RECORD in which the address of clients
+	// is learned when UDPSymmetricRTP is enabled.
+	// It defaults to 10 seconds.
+	UDPLatchingPeriod time.Duration
 	// a range of multicast IPs to use with the UDP-multicast transport.
+	// It can be either an IPv4 range (i.e. 224.1.0.0/16) or an IPv6 range (i.e. ff15::/64).
+	// If the range is a source-specific multicast range (232.0.0.0/8 or ff3x::/32),
+	// the server IP is advertised to readers, that join the group with a source filter.
 	// If MulticastIPRange, MulticastRTPPort, MulticastRTCPPort are filled, the server
 	// can support the UDP-multicast transport.
 	MulticastIPRange string
@@ -103,6 +147,21 @@ type Server struct {
 	// If MulticastIPRange, MulticastRTPPort, MulticastRTCPPort are filled, the server
 	// can support the UDP-multicast transport.
 	MulticastRTCPPort int
+	// a range of ports to send RTP and RTCP packets with the UDP-multicast transport.
+	// When set, each multicast stream receives its own pair of consecutive ports,
+	// taken sequentially from the range, instead of MulticastRTPPort and MulticastRTCPPort.
+	// It can't be used together with MulticastRTPPort and MulticastRTCPPort.
+	MulticastPortRange [2]int
+	// TTL of multicast packets.
+	// It defaults to 16.
+	MulticastTTL int
+	// address where multicast streams are announced with ServerStream.AnnounceSAP().
+	// It defaults to 224.2.127.254:9875.
+	SAPAddress string
+	// average period between SAP announcements.
+	// The actual period is randomized by up to one third, as required by RFC 2974.
+	// It defaults to 300 seconds, that is the minimum allowed by RFC 2974.
+	SAPAnnouncePeriod time.Duration
 	// read buffer count.
 	// If greater than 1, allows to pass buffers to routines different than the one
 	// that is reading frames.
@@ -114,6 +173,52 @@ type Server struct {
 	// It allows to queue packets before sending them.
 	// It defaults to 256.
 	WriteBufferCount int
+	// number of RTP packets of each ServerStream that are kept in order to be
+	// retransmitted when they are requested by readers with a NACK.
+	// It is used only with UDP readers and tracks that have a RTX payload type.
+	// It defaults to 512.
+	RTXBufferCount int
+	// number of RTP packets of each ServerStream that are protected
+	// by a forward error correction packet.
+	// It is used only with UDP and UDP-multicast readers and tracks that have a FEC payload type.
+	// It defaults to 10.
+	FECGroupSize int
+
+	//
+	// limits
+	//
+	// maximum number of connections.
+	// Additional connections receive a 503 Service Unavailable response and are closed.
+	// It defaults to 0 (unlimited).
+	MaxConnections int
+	// maximum number of sessions.
+	// Requests that would create additional sessions receive a 503 Service Unavailable response.
+	// It defaults to 0 (unlimited).
+	MaxSessions int
+	// maximum number of sessions created by a single IP.
+	// Requests that would create additional sessions receive a 453 Not Enough Bandwidth response.
+	// It defaults to 0 (unlimited).
+	MaxSessionsPerIP int
+	// maximum number of readers of each ServerStream.
+	// SETUP requests that would add additional readers receive a 453 Not Enough Bandwidth response.
+	// It defaults to 0 (unlimited).
+	MaxReadersPerStream int
+
+	//
+	// authentication
+	//
+	// a store of credentials.
+	// If filled, DESCRIBE, ANNOUNCE and SETUP requests are authenticated
+	// with it before calling the handlers.
+	AuthCredentialStore auth.CredentialStore
+	// authentication methods offered to clients when AuthCredentialStore is filled.
+	// It defaults to Basic and Digest.
+	AuthMethods []headers.AuthMethod
+	// a function that validates Bearer tokens.
+	// If filled, DESCRIBE, ANNOUNCE and SETUP requests can be authenticated
+	// with a Bearer token, that is passed to the function together with the requested path.
+	// It must return the user associated with the token, or an error if the token is not valid.
+	AuthBearerValidator func(token string, path string) (string, error)
 
 	//
 	// system functions
@@ -129,29 +234,33 @@ type Server struct {
 	// private
 	//
 
-	udpReceiverReportPeriod time.Duration
-	udpSenderReportPeriod   time.Duration
-	sessionTimeout          time.Duration
-	checkStreamPeriod       time.Duration
-
-	ctx                context.Context
-	ctxCancel          func()
-	wg                 sync.WaitGroup
-	multicastNet       *net.IPNet
-	multicastNextIP    net.IP
-	tcpListener        net.Listener
-	udpRTPListener     *serverUDPListener
-	udpRTCPListener    *serverUDPListener
-	udpRTPPacketBuffer *rtpPacketMultiBuffer
-	sessions           map[string]*ServerSession
-	conns              map[*ServerConn]struct{}
-	closeError         error
+	receiverReportPeriod time.Duration
+	senderReportPeriod   time.Duration
+	sessionTimeout       time.Duration
+	checkStreamPeriod    time.Duration
+
+	ctx               context.Context
+	ctxCancel         func()
+	authValidator     *auth.Validator
+	wg                sync.WaitGroup
+	multicastNet      *net.IPNet
+	multicastNextIP   net.IP
+	multicastNextPort int
+	udpNextPort       int
+	tcpListener       net.Listener
+	udpRTPListener    *serverUDPListener
+	udpRTCPListener   *serverUDPListener
+	sessions          map[string]*ServerSession
+	conns             map[*ServerConn]struct{}
+	rejectSlots       chan struct{}
+	closeError        error
 
 	// in
 	connClose         chan *ServerConn
 	sessionRequest    chan sessionRequestReq
 	sessionClose      chan *ServerSession
 	streamMulticastIP chan streamMulticastIPReq
+	udpListenerPair   chan udpListenerPairReq
 }
 
 // Start starts the server.
@@ -169,6 +278,29 @@ func (s *Server) Start() error {
 	if s.WriteBufferCount == 0 {
 		s.WriteBufferCount = 256
 	}
+	if s.RTXBufferCount == 0 {
+		s.RTXBufferCount = 512
+	}
+	if s.FECGroupSize == 0 {
+		s.FECGroupSize = 10
+	}
+	if s.UDPLatchingPeriod == 0 {
+		s.UDPLatchingPeriod = 10 * time.Second
+	}
+	if s.MulticastTTL == 0 {
+		s.MulticastTTL = multicastTTL
+	}
+	if s.SAPAddress == "" {
+		s.SAPAddress = sapAddress
+	}
+	if s.SAPAnnouncePeriod == 0 {
+		s.SAPAnnouncePeriod = 300 * time.Second
+	}
+
+	// authentication
+	if s.AuthCredentialStore != nil {
+		s.authValidator = auth.NewValidatorWithStore(s.AuthCredentialStore, s.AuthMethods)
+	}
 
 	// system functions
 	if s.Listen == nil {
@@ -179,11 +311,11 @@ func (s *Server) Start() error {
 	}
 
 	// private
-	if s.udpReceiverReportPeriod == 0 {
-		s.udpReceiverReportPeriod = 10 * time.Second
+	if s.receiverReportPeriod == 0 {
+		s.receiverReportPeriod = 10 * time.Second
 	}
-	if s.udpSenderReportPeriod == 0 {
-		s.udpSenderReportPeriod = 10 * time.Second
+	if s.senderReportPeriod == 0 {
+		s.senderReportPeriod = 10 * time.Second
 	}
 	if s.sessionTimeout == 0 {
 		s.sessionTimeout = 1 * 60 * time.Second
@@ -192,7 +324,7 @@ func (s *Server) Start() error {
 		s.checkStreamPeriod = 1 * time.Second
 	}
 
-	if s.TLSConfig != nil && s.UDPRTPAddress != "" {
+	if s.TLSConfig != nil && (s.UDPRTPAddress != "" || s.udpPortRangeEnabled()) {
 		return fmt.Errorf("TLS can't be used with UDP")
 	}
 
@@ -238,41 +370,89 @@ func (s *Server) Start() error {
 			s.udpRTPListener.close()
 			return err
 		}
-
-		s.udpRTPPacketBuffer = newRTPPacketMultiBuffer(uint64(s.ReadBufferCount))
 	}
 
-	if s.MulticastIPRange != "" && (s.MulticastRTPPort == 0 || s.MulticastRTCPPort == 0) ||
-		(s.MulticastRTPPort != 0 && (s.MulticastRTCPPort == 0 || s.MulticastIPRange == "")) ||
-		s.MulticastRTCPPort != 0 && (s.MulticastRTPPort == 0 || s.MulticastIPRange == "") {
-		if s.udpRTPListener != nil {
+	if s.udpPortRangeEnabled() {
+		if s.UDPRTPAddress != "" {
 			s.udpRTPListener.close()
-		}
-		if s.udpRTCPListener != nil {
 			s.udpRTCPListener.close()
+			return fmt.Errorf("UDPPortRange can't be used together with UDPRTPAddress and UDPRTCPAddress")
+		}
+
+		s.udpNextPort = s.udpPortRangeFirst()
+
+		if s.UDPPortRange[0] <= 0 || s.UDPPortRange[1] > 65535 ||
+			(s.udpNextPort+1) > s.UDPPortRange[1] {
+			return fmt.Errorf("invalid UDP port range")
 		}
-		return fmt.Errorf("MulticastIPRange, MulticastRTPPort and MulticastRTCPPort must be used together")
 	}
 
-	if s.MulticastIPRange != "" {
-		if (s.MulticastRTPPort % 2) != 0 {
+	if s.multicastPortRangeEnabled() {
+		if s.MulticastRTPPort != 0 || s.MulticastRTCPPort != 0 {
 			if s.udpRTPListener != nil {
 				s.udpRTPListener.close()
 			}
 			if s.udpRTCPListener != nil {
 				s.udpRTCPListener.close()
 			}
-			return fmt.Errorf("RTP port must be even")
+			return fmt.Errorf("MulticastPortRange can't be used together with MulticastRTPPort and MulticastRTCPPort")
 		}
 
-		if s.MulticastRTCPPort != (s.MulticastRTPPort + 1) {
+		if s.MulticastIPRange == "" {
 			if s.udpRTPListener != nil {
 				s.udpRTPListener.close()
 			}
 			if s.udpRTCPListener != nil {
 				s.udpRTCPListener.close()
 			}
-			return fmt.Errorf("RTP and RTCP ports must be consecutive")
+			return fmt.Errorf("MulticastIPRange and MulticastPortRange must be used together")
+		}
+
+		s.multicastNextPort = s.multicastPortRangeFirst()
+
+		if s.MulticastPortRange[0] <= 0 || s.MulticastPortRange[1] > 65535 ||
+			(s.multicastNextPort+1) > s.MulticastPortRange[1] {
+			if s.udpRTPListener != nil {
+				s.udpRTPListener.close()
+			}
+			if s.udpRTCPListener != nil {
+				s.udpRTCPListener.close()
+			}
+			return fmt.Errorf("invalid MulticastPortRange")
+		}
+	} else if s.MulticastIPRange != "" && (s.MulticastRTPPort == 0 || s.MulticastRTCPPort == 0) ||
+		(s.MulticastRTPPort != 0 && (s.MulticastRTCPPort == 0 || s.MulticastIPRange == "")) ||
+		s.MulticastRTCPPort != 0 && (s.MulticastRTPPort == 0 || s.MulticastIPRange == "") {
+		if s.udpRTPListener != nil {
+			s.udpRTPListener.close()
+		}
+		if s.udpRTCPListener != nil {
+			s.udpRTCPListener.close()
+		}
+		return fmt.Errorf("MulticastIPRange, MulticastRTPPort and MulticastRTCPPort must be used together")
+	}
+
+	if s.MulticastIPRange != "" {
+		if !s.multicastPortRangeEnabled() {
+			if (s.MulticastRTPPort % 2) != 0 {
+				if s.udpRTPListener != nil {
+					s.udpRTPListener.close()
+				}
+				if s.udpRTCPListener != nil {
+					s.udpRTCPListener.close()
+				}
+				return fmt.Errorf("RTP port must be even")
+			}
+
+			if s.MulticastRTCPPort != (s.MulticastRTPPort + 1) {
+				if s.udpRTPListener != nil {
+					s.udpRTPListener.close()
+				}
+				if s.udpRTCPListener != nil {
+					s.udpRTCPListener.close()
+				}
+				return fmt.Errorf("RTP and RTCP ports must be consecutive")
+			}
 		}
 
 		var err error
@@ -287,8 +467,17 @@ func (s *Server) Start() error {
 			return err
 		}
 
+		if !s.multicastNet.IP.IsMulticast() {
+			if s.udpRTPListener != nil {
+				s.udpRTPListener.close()
+			}
+			if s.udpRTCPListener != nil {
+				s.udpRTCPListener.close()
+			}
+			return fmt.Errorf("MulticastIPRange is not a multicast range")
+		}
+
 		s.multicastNextIP = s.multicastNet.IP
-		s.udpRTPPacketBuffer = newRTPPacketMultiBuffer(uint64(s.ReadBufferCount))
 	}
 
 	var err error
@@ -305,6 +494,15 @@ func (s *Server) Start() error {
 
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 
+	s.sessions = make(map[string]*ServerSession)
+	s.conns = make(map[*ServerConn]struct{})
+	s.rejectSlots = make(chan struct{}, serverMaxPendingRejections)
+	s.connClose = make(chan *ServerConn)
+	s.sessionRequest = make(chan sessionRequestReq)
+	s.sessionClose = make(chan *ServerSession)
+	s.streamMulticastIP = make(chan streamMulticastIPReq)
+	s.udpListenerPair = make(chan udpListenerPairReq)
+
 	s.wg.Add(1)
 	go s.run()
 
@@ -328,13 +526,6 @@ func (s *Server) Wait() error {
 func (s *Server) run() {
 	defer s.wg.Done()
 
-	s.sessions = make(map[string]*ServerSession)
-	s.conns = make(map[*ServerConn]struct{})
-	s.connClose = make(chan *ServerConn)
-	s.sessionRequest = make(chan sessionRequestReq)
-	s.sessionClose = make(chan *ServerSession)
-	s.streamMulticastIP = make(chan streamMulticastIPReq)
-
 	s.wg.Add(1)
 	connNew := make(chan net.Conn)
 	acceptErr := make(chan error)
@@ -368,6 +559,18 @@ func (s *Server) run() {
 				return err
 
 			case nconn := <-connNew:
+				if s.MaxConnections > 0 && len(s.conns) >= s.MaxConnections &&
+					!s.limitExceeded(ServerLimitConnections, nconn.RemoteAddr()) {
+					select {
+					case s.rejectSlots <- struct{}{}:
+						s.wg.Add(1)
+						go s.rejectConn(nconn)
+					default:
+						nconn.Close()
+					}
+					continue
+				}
+
 				sc := newServerConn(s, nconn)
 				s.conns[sc] = struct{}{}
 
@@ -403,6 +606,14 @@ func (s *Server) run() {
 						continue
 					}
 
+					if res, err := s.checkSessionLimits(req.sc); res != nil {
+						req.res <- sessionRequestRes{
+							res: res,
+							err: err,
+						}
+						continue
+					}
+
 					secretID, err := newSessionSecretID(s.sessions)
 					if err != nil {
 						req.res <- sessionRequestRes{
@@ -437,13 +648,17 @@ func (s *Server) run() {
 				ss.Close()
 
 			case req := <-s.streamMulticastIP:
-				ip32 := binary.BigEndian.Uint32(s.multicastNextIP)
-				mask := binary.BigEndian.Uint32(s.multicastNet.Mask)
-				ip32 = (ip32 & mask) | ((ip32 + 1) & ^mask)
-				ip := make(net.IP, 4)
-				binary.BigEndian.PutUint32(ip, ip32)
-				s.multicastNextIP = ip
-				req.res <- ip
+				s.multicastNextIP = multicastNextIP(s.multicastNet, s.multicastNextIP)
+				rtpPort, rtcpPort := s.allocateMulticastPorts()
+				req.res <- streamMulticastIPRes{
+					ip:       s.multicastNextIP,
+					rtpPort:  rtpPort,
+					rtcpPort: rtcpPort,
+				}
+
+			case req := <-s.udpListenerPair:
+				rtpl, rtcpl, err := s.allocateUDPListenerPair(req.rtcpMux)
+				req.res <- udpListenerPairRes{rtpl: rtpl, rtcpl: rtcpl, err: err}
 
 			case <-s.ctx.Done():
 				return liberrors.ErrServerTerminated{}
@@ -464,6 +679,169 @@ func (s *Server) run() {
 	s.tcpListener.Close()
 }
 
+// limitExceeded checks whether the handler accepts something that exceeds a limit.
+func (s *Server) limitExceeded(limit ServerLimit, remoteAddr net.Addr) bool {
+	if h, ok := s.Handler.(ServerHandlerOnLimitExceeded); ok {
+		return h.OnLimitExceeded(&ServerHandlerOnLimitExceededCtx{
+			Limit:      limit,
+			RemoteAddr: remoteAddr,
+		})
+	}
+	return false
+}
+
+// rejectConn replies to the first request of a connection that exceeds
+// MaxConnections, then closes the connection.
+// The number of connections that are being rejected is limited,
+// since each waits for a request up to ReadTimeout.
+func (s *Server) rejectConn(nconn net.Conn) {
+	defer s.wg.Done()
+	defer func() { <-s.rejectSlots }()
+
+	conn := func() net.Conn {
+		if s.TLSConfig != nil {
+			return tls.Server(nconn, s.TLSConfig)
+		}
+		return nconn
+	}()
+	defer conn.Close()
+
+	// close the connection when the server is closed
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(s.ReadTimeout))
+	var req base.Request
+	err := req.Read(bufio.NewReader(conn))
+	if err != nil {
+		return
+	}
+
+	byts, _ := base.Response{
+		StatusCode: base.StatusServiceUnavailable,
+		Header: base.Header{
+			"CSeq": req.Header["CSeq"],
+		},
+		Version: req.Version,
+	}.Write()
+
+	conn.SetWriteDeadline(time.Now().Add(s.WriteTimeout))
+	conn.Write(byts)
+}
+
+func (s *Server) udpPortRangeEnabled() bool {
+	return s.UDPPortRange != [2]int{}
+}
+
+// udpPortRangeFirst returns the first even port of UDPPortRange.
+func (s *Server) udpPortRangeFirst() int {
+	return s.UDPPortRange[0] + (s.UDPPortRange[0] % 2)
+}
+
+// allocateUDPListenerPair opens a pair of listeners on the first free pair
+// of consecutive ports of UDPPortRange, starting from the one after the last allocated pair.
+// When RTP and RTCP are multiplexed, only the RTP listener is opened.
+func (s *Server) allocateUDPListenerPair(rtcpMux bool) (*serverUDPListener, *serverUDPListener, error) {
+	host, _, err := net.SplitHostPort(s.RTSPAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	first := s.udpPortRangeFirst()
+	count := (s.UDPPortRange[1] - first + 1) / 2
+
+	for i := 0; i < count; i++ {
+		rtpPort := s.udpNextPort
+
+		s.udpNextPort += 2
+		if (s.udpNextPort + 1) > s.UDPPortRange[1] {
+			s.udpNextPort = first
+		}
+
+		rtpl, err := newServerUDPListener(s, false,
+			net.JoinHostPort(host, strconv.FormatInt(int64(rtpPort), 10)), true)
+		if err != nil {
+			continue
+		}
+
+		if rtcpMux {
+			return rtpl, nil, nil
+		}
+
+		rtcpl, err := newServerUDPListener(s, false,
+			net.JoinHostPort(host, strconv.FormatInt(int64(rtpPort+1), 10)), false)
+		if err != nil {
+			rtpl.close()
+			continue
+		}
+
+		return rtpl, rtcpl, nil
+	}
+
+	return nil, nil, liberrors.ErrServerNoUDPPortsAvailable{}
+}
+
+func (s *Server) multicastPortRangeEnabled() bool {
+	return s.MulticastPortRange != [2]int{}
+}
+
+// multicastPortRangeFirst returns the first even port of MulticastPortRange.
+func (s *Server) multicastPortRangeFirst() int {
+	return s.MulticastPortRange[0] + (s.MulticastPortRange[0] % 2)
+}
+
+// allocateMulticastPorts returns the RTP and RTCP ports of a new multicast stream.
+// With MulticastPortRange, ports are taken sequentially from the range.
+func (s *Server) allocateMulticastPorts() (int, int) {
+	if !s.multicastPortRangeEnabled() {
+		return s.MulticastRTPPort, s.MulticastRTCPPort
+	}
+
+	rtpPort := s.multicastNextPort
+
+	s.multicastNextPort += 2
+	if (s.multicastNextPort + 1) > s.MulticastPortRange[1] {
+		s.multicastNextPort = s.multicastPortRangeFirst()
+	}
+
+	return rtpPort, rtpPort + 1
+}
+
+// checkSessionLimits checks whether a connection can create a new session.
+func (s *Server) checkSessionLimits(sc *ServerConn) (*base.Response, error) {
+	if s.MaxSessions > 0 && len(s.sessions) >= s.MaxSessions &&
+		!s.limitExceeded(ServerLimitSessions, sc.NetConn().RemoteAddr()) {
+		return &base.Response{
+			StatusCode: base.StatusServiceUnavailable,
+		}, liberrors.ErrServerMaxSessionsReached{Max: s.MaxSessions}
+	}
+
+	if s.MaxSessionsPerIP > 0 {
+		count := 0
+		for _, ss := range s.sessions {
+			if ss.author.ip().Equal(sc.ip()) && ss.author.zone() == sc.zone() {
+				count++
+			}
+		}
+
+		if count >= s.MaxSessionsPerIP &&
+			!s.limitExceeded(ServerLimitSessionsPerIP, sc.NetConn().RemoteAddr()) {
+			return &base.Response{
+				StatusCode: base.StatusNotEnoughBandwidth,
+			}, liberrors.ErrServerMaxSessionsPerIPReached{Max: s.MaxSessionsPerIP}
+		}
+	}
+
+	return nil, nil
+}
+
 // StartAndWait starts the server and waits until a fatal error.
 func (s *Server) StartAndWait() error {
 	err := s.Start()
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"crypto/tls"
 	"net"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -917,6 +919,8 @@ func TestServerPublishErrorInvalidProtocol(t *testing.T) {
 }
 
 func TestServerPublishRTCPReport(t *testing.T) {
+	var session *ServerSession
+
 	s := &Server{
 		Handler: &testServerHandler{
 			onAnnounce: func(ctx *ServerHandlerOnAnnounceCtx) (*base.Response, error) {
@@ -930,15 +934,16 @@ func TestServerPublishRTCPReport(t *testing.T) {
 				}, nil, nil
 			},
 			onRecord: func(ctx *ServerHandlerOnRecordCtx) (*base.Response, error) {
+				session = ctx.Session
 				return &base.Response{
 					StatusCode: base.StatusOK,
 				}, nil
 			},
 		},
-		udpReceiverReportPeriod: 1 * time.Second,
-		UDPRTPAddress:           "127.0.0.1:8000",
-		UDPRTCPAddress:          "127.0.0.1:8001",
-		RTSPAddress:             "localhost:8554",
+		receiverReportPeriod: 1 * time.Second,
+		UDPRTPAddress:        "127.0.0.1:8000",
+		UDPRTCPAddress:       "127.0.0.1:8001",
+		RTSPAddress:          "localhost:8554",
 	}
 
 	err := s.Start()
@@ -1071,6 +1076,320 @@ func TestServerPublishRTCPReport(t *testing.T) {
 		},
 		ProfileExtensions: []uint8{},
 	}, rr)
+
+	stats := session.Stats()
+	require.Equal(t, uint64(1), stats[0].PacketsReceived)
+	require.Equal(t, uint64(16), stats[0].BytesReceived)
+	require.Equal(t, uint16(534), stats[0].LastSequenceNumber)
+	require.NotEqual(t, time.Time{}, stats[0].LastSenderReport)
+	require.NotEqual(t, time.Time{}, stats[0].LastReceiverReport)
+}
+
+func TestServerPublishRTX(t *testing.T) {
+	packetRecv := make(chan *rtp.Packet, 10)
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onAnnounce: func(ctx *ServerHandlerOnAnnounceCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil, nil
+			},
+			onRecord: func(ctx *ServerHandlerOnRecordCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onPacketRTP: func(ctx *ServerHandlerOnPacketRTPCtx) {
+				packetRecv <- ctx.Packet.Clone()
+			},
+		},
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+		RTSPAddress:    "localhost:8554",
+	}
+
+	err := s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	track := NewTrackPCMU()
+	track.SetRTXPayloadType(97)
+
+	tracks := Tracks{track}
+	tracks.setControls()
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Announce,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":         base.HeaderValue{"1"},
+			"Content-Type": base.HeaderValue{"application/sdp"},
+		},
+		Body: tracks.Write(false),
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	l1, err := net.ListenPacket("udp", "localhost:34556")
+	require.NoError(t, err)
+	defer l1.Close()
+
+	l2, err := net.ListenPacket("udp", "localhost:34557")
+	require.NoError(t, err)
+	defer l2.Close()
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"2"},
+			"Transport": headers.Transport{
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModeRecord
+					return &v
+				}(),
+				Protocol:    headers.TransportProtocolUDP,
+				ClientPorts: &[2]int{34556, 34557},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	var th headers.Transport
+	err = th.Read(res.Header["Transport"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Record,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"3"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// skip firewall opening
+	buf := make([]byte, 2048)
+	_, _, err = l2.ReadFrom(buf)
+	require.NoError(t, err)
+
+	for _, seq := range []uint16{10, 13} {
+		byts, _ := (&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    0,
+				SequenceNumber: seq,
+				Timestamp:      54352,
+				SSRC:           753621,
+			},
+			Payload: []byte{0x01, 0x02, 0x03, 0x04},
+		}).Marshal()
+		_, err = l1.WriteTo(byts, &net.UDPAddr{
+			IP:   net.ParseIP("127.0.0.1"),
+			Port: th.ServerPorts[0],
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, seq, (<-packetRecv).SequenceNumber)
+	}
+
+	buf = make([]byte, 2048)
+	n, _, err := l2.ReadFrom(buf)
+	require.NoError(t, err)
+	pkts, err := rtcp.Unmarshal(buf[:n])
+	require.NoError(t, err)
+	nack, ok := pkts[0].(*rtcp.TransportLayerNack)
+	require.True(t, ok)
+	require.Equal(t, uint32(753621), nack.MediaSSRC)
+	require.Equal(t, []rtcp.NackPair{{PacketID: 11, LostPackets: 0b1}}, nack.Nacks)
+
+	byts, _ := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    97,
+			SequenceNumber: 500,
+			Timestamp:      54352,
+			SSRC:           1234,
+		},
+		Payload: []byte{0x00, 0x0b, 0x05, 0x06},
+	}).Marshal()
+	_, err = l1.WriteTo(byts, &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: th.ServerPorts[0],
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    0,
+			SequenceNumber: 11,
+			Timestamp:      54352,
+			SSRC:           753621,
+		},
+		Payload: []byte{0x05, 0x06},
+	}, <-packetRecv)
+}
+
+func TestServerPublishNTP(t *testing.T) {
+	packetRecv := make(chan *ServerHandlerOnPacketRTPCtx, 2)
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onAnnounce: func(ctx *ServerHandlerOnAnnounceCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil, nil
+			},
+			onRecord: func(ctx *ServerHandlerOnRecordCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onPacketRTP: func(ctx *ServerHandlerOnPacketRTPCtx) {
+				packetRecv <- ctx
+			},
+		},
+		RTSPAddress: "localhost:8554",
+	}
+
+	err := s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	tracks := Tracks{NewTrackPCMU()}
+	tracks.setControls()
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Announce,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":         base.HeaderValue{"1"},
+			"Content-Type": base.HeaderValue{"application/sdp"},
+		},
+		Body: tracks.Write(false),
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"2"},
+			"Transport": headers.Transport{
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModeRecord
+					return &v
+				}(),
+				Protocol:       headers.TransportProtocolTCP,
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Record,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"3"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	for _, pkt := range []interface {
+		Marshal() ([]byte, error)
+	}{
+		&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    0,
+				SequenceNumber: 946,
+				Timestamp:      54352,
+				SSRC:           753621,
+			},
+			Payload: []byte{0x01, 0x02, 0x03, 0x04},
+		},
+		&rtcp.SenderReport{
+			SSRC:    753621,
+			NTPTime: 0xe398e48080000000, // 2021-01-01 00:00:00.5 UTC
+			RTPTime: 54352,
+		},
+		&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    0,
+				SequenceNumber: 947,
+				Timestamp:      54352 - 2000,
+				SSRC:           753621,
+			},
+			Payload: []byte{0x01, 0x02, 0x03, 0x04},
+		},
+	} {
+		channel := 0
+		if _, ok := pkt.(*rtcp.SenderReport); ok {
+			channel = 1
+		}
+
+		byts, _ := pkt.Marshal()
+		byts, _ = base.InterleavedFrame{
+			Channel: channel,
+			Payload: byts,
+		}.Write()
+		_, err = conn.Write(byts)
+		require.NoError(t, err)
+	}
+
+	ctx := <-packetRecv
+	require.Equal(t, false, ctx.NTPSynchronized)
+
+	ctx = <-packetRecv
+	require.Equal(t, true, ctx.NTPSynchronized)
+	require.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 250000000, time.UTC), ctx.NTP.UTC())
 }
 
 func TestServerPublishTimeout(t *testing.T) {
@@ -1446,3 +1765,248 @@ func TestServerPublishUDPChangeConn(t *testing.T) {
 		require.Equal(t, base.StatusOK, res.StatusCode)
 	}()
 }
+
+func TestServerPublishSymmetricRTP(t *testing.T) {
+	packetRecv := make(chan struct{})
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onAnnounce: func(ctx *ServerHandlerOnAnnounceCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil, nil
+			},
+			onRecord: func(ctx *ServerHandlerOnRecordCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onPacketRTP: func(ctx *ServerHandlerOnPacketRTPCtx) {
+				require.Equal(t, 1, ctx.TrackID)
+				require.Equal(t, &testRTPPacket, ctx.Packet)
+				close(packetRecv)
+			},
+			onPacketRTCP: func(ctx *ServerHandlerOnPacketRTCPCtx) {
+				require.Equal(t, 1, ctx.TrackID)
+				ctx.Session.WritePacketRTCP(1, &testRTCPPacket)
+			},
+		},
+		RTSPAddress:     "localhost:8554",
+		UDPRTPAddress:   "127.0.0.1:8000",
+		UDPRTCPAddress:  "127.0.0.1:8001",
+		UDPSymmetricRTP: true,
+	}
+
+	err := s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	track1, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	track2, err := NewTrackH264(97, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	tracks := Tracks{track1, track2}
+	tracks.setControls()
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Announce,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":         base.HeaderValue{"1"},
+			"Content-Type": base.HeaderValue{"application/sdp"},
+		},
+		Body: tracks.Write(false),
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	var th headers.Transport
+
+	for i := 0; i < 2; i++ {
+		inTH := &headers.Transport{
+			Protocol: headers.TransportProtocolUDP,
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Mode: func() *headers.TransportMode {
+				v := headers.TransportModeRecord
+				return &v
+			}(),
+			ClientPorts: &[2]int{35466 + i*2, 35467 + i*2},
+		}
+
+		h := base.Header{
+			"CSeq":      base.HeaderValue{strconv.FormatInt(int64(2+i), 10)},
+			"Transport": inTH.Write(),
+		}
+		if i != 0 {
+			h["Session"] = base.HeaderValue{sx.Session}
+		}
+
+		res, err = writeReqReadRes(conn, br, base.Request{
+			Method: base.Setup,
+			URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=" + strconv.FormatInt(int64(i), 10)),
+			Header: h,
+		})
+		require.NoError(t, err)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+
+		err = sx.Read(res.Header["Session"])
+		require.NoError(t, err)
+
+		err = th.Read(res.Header["Transport"])
+		require.NoError(t, err)
+	}
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Record,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"4"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// simulate a NAT that rewrites source ports
+	l1, err := net.ListenPacket("udp", "localhost:35476")
+	require.NoError(t, err)
+	defer l1.Close()
+
+	l2, err := net.ListenPacket("udp", "localhost:35477")
+	require.NoError(t, err)
+	defer l2.Close()
+
+	// client -> server
+	l1.WriteTo(testRTPPacketMarshaled, &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: th.ServerPorts[0],
+	})
+
+	<-packetRecv
+
+	byts, _ := (&rtcp.SenderReport{
+		SSRC:        testRTPPacket.SSRC,
+		NTPTime:     0xe44ac5cc00000000,
+		RTPTime:     54352,
+		PacketCount: 1,
+		OctetCount:  4,
+	}).Marshal()
+
+	l2.WriteTo(byts, &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: th.ServerPorts[1],
+	})
+
+	// server -> client, on the learned address
+	buf := make([]byte, 2048)
+	n, _, err := l2.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Equal(t, testRTCPPacketMarshaled, buf[:n])
+}
+
+func TestServerPublishUDPPortRange(t *testing.T) {
+	var mutex sync.Mutex
+	received := make(map[int]int)
+	done := make(chan struct{})
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onAnnounce: func(ctx *ServerHandlerOnAnnounceCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil, nil
+			},
+			onRecord: func(ctx *ServerHandlerOnRecordCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onPacketRTP: func(ctx *ServerHandlerOnPacketRTPCtx) {
+				require.Equal(t, []byte{0x01, 0x02, 0x03, byte(ctx.TrackID)}, ctx.Packet.Payload)
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				received[ctx.TrackID]++
+				if received[ctx.TrackID] == 100 && received[1-ctx.TrackID] >= 100 {
+					close(done)
+				}
+			},
+		},
+		RTSPAddress:  "localhost:8554",
+		UDPPortRange: [2]int{35000, 35009},
+	}
+
+	err := s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportUDP
+			return &v
+		}(),
+	}
+
+	track1, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	track2, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	err = c.StartPublishing("rtsp://localhost:8554/teststream",
+		Tracks{track1, track2})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// packets of the two tracks are received by two listeners in parallel
+	var wg sync.WaitGroup
+	for trackID := 0; trackID < 2; trackID++ {
+		wg.Add(1)
+		go func(trackID int) {
+			defer wg.Done()
+
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				c.WritePacketRTP(trackID, &rtp.Packet{
+					Header: rtp.Header{
+						Version:        2,
+						PayloadType:    96,
+						SequenceNumber: uint16(i),
+						SSRC:           0x38F27A2F + uint32(trackID),
+					},
+					Payload: []byte{0x01, 0x02, 0x03, byte(trackID)},
+				}, true)
+				time.Sleep(time.Millisecond)
+			}
+		}(trackID)
+	}
+
+	<-done
+	wg.Wait()
+}
@@ -3,7 +3,10 @@ package gortsplib
 import (
 	"bufio"
 	"crypto/tls"
+	"io"
 	"net"
+	"os"
+	"path/filepath"
 	"strconv"
 	"sync/atomic"
 	"testing"
@@ -16,7 +19,12 @@ import (
 	"golang.org/x/net/ipv4"
 
 	"github.com/aler9/gortsplib/pkg/base"
+	"github.com/aler9/gortsplib/pkg/h264"
 	"github.com/aler9/gortsplib/pkg/headers"
+	"github.com/aler9/gortsplib/pkg/liberrors"
+	"github.com/aler9/gortsplib/pkg/rtpfec"
+	"github.com/aler9/gortsplib/pkg/sap"
+	"github.com/aler9/gortsplib/pkg/srtp"
 	"github.com/aler9/gortsplib/pkg/url"
 )
 
@@ -634,6 +642,9 @@ func TestServerReadRTCPReport(t *testing.T) {
 	stream := NewServerStream(Tracks{track})
 	defer stream.Close()
 
+	var session *ServerSession
+	rrReceived := make(chan struct{})
+
 	s := &Server{
 		Handler: &testServerHandler{
 			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
@@ -642,15 +653,21 @@ func TestServerReadRTCPReport(t *testing.T) {
 				}, stream, nil
 			},
 			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				session = ctx.Session
 				return &base.Response{
 					StatusCode: base.StatusOK,
 				}, nil
 			},
+			onReaderRTCP: func(ctx *ServerHandlerOnReaderPacketRTCPCtx) {
+				if _, ok := ctx.Packet.(*rtcp.ReceiverReport); ok {
+					close(rrReceived)
+				}
+			},
 		},
-		udpSenderReportPeriod: 1 * time.Second,
-		RTSPAddress:           "localhost:8554",
-		UDPRTPAddress:         "127.0.0.1:8000",
-		UDPRTCPAddress:        "127.0.0.1:8001",
+		senderReportPeriod: 1 * time.Second,
+		RTSPAddress:        "localhost:8554",
+		UDPRTPAddress:      "127.0.0.1:8000",
+		UDPRTCPAddress:     "127.0.0.1:8001",
 	}
 
 	err = s.Start()
@@ -725,6 +742,31 @@ func TestServerReadRTCPReport(t *testing.T) {
 		OctetCount:  8,
 	}, packets[0])
 
+	byts, _ := (&rtcp.ReceiverReport{
+		SSRC: 1234,
+		Reports: []rtcp.ReceptionReport{{
+			SSRC:             0x38F27A2F,
+			TotalLost:        2,
+			LastSenderReport: uint32(packets[0].(*rtcp.SenderReport).NTPTime >> 16),
+		}},
+	}).Marshal()
+	_, err = l2.WriteTo(byts, &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: 8001,
+	})
+	require.NoError(t, err)
+
+	<-rrReceived
+
+	stats := session.Stats()
+	require.Equal(t, uint64(2), stats[0].PacketsSent)
+	require.Equal(t, uint64(2*testRTPPacket.MarshalSize()), stats[0].BytesSent)
+	require.Equal(t, testRTPPacket.SequenceNumber, stats[0].LastSequenceNumber)
+	require.Equal(t, uint64(2), stats[0].PacketsLost)
+	require.Less(t, stats[0].RTT, 1*time.Second)
+	require.NotEqual(t, time.Time{}, stats[0].LastSenderReport)
+	require.NotEqual(t, time.Time{}, stats[0].LastReceiverReport)
+
 	res, err = writeReqReadRes(conn, br, base.Request{
 		Method: base.Teardown,
 		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
@@ -737,106 +779,162 @@ func TestServerReadRTCPReport(t *testing.T) {
 	require.Equal(t, base.StatusOK, res.StatusCode)
 }
 
-func TestServerReadVLCMulticast(t *testing.T) {
-	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
-	require.NoError(t, err)
+func TestServerReadReaderPacketRTCP(t *testing.T) {
+	for _, transport := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(transport, func(t *testing.T) {
+			packetRecv := make(chan struct{})
 
-	stream := NewServerStream(Tracks{track})
-	defer stream.Close()
+			track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+			require.NoError(t, err)
 
-	listenIP := multicastCapableIP(t)
+			stream := NewServerStream(Tracks{track})
+			defer stream.Close()
 
-	s := &Server{
-		Handler: &testServerHandler{
-			onDescribe: func(ctx *ServerHandlerOnDescribeCtx) (*base.Response, *ServerStream, error) {
-				return &base.Response{
-					StatusCode: base.StatusOK,
-				}, stream, nil
-			},
-		},
-		RTSPAddress:       listenIP + ":8554",
-		MulticastIPRange:  "224.1.0.0/16",
-		MulticastRTPPort:  8000,
-		MulticastRTCPPort: 8001,
-	}
+			pli := &rtcp.PictureLossIndication{
+				SenderSSRC: 0x12345678,
+				MediaSSRC:  0x38F27A2F,
+			}
 
-	err = s.Start()
-	require.NoError(t, err)
-	defer s.Close()
+			s := &Server{
+				Handler: &testServerHandler{
+					onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream, nil
+					},
+					onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					onReaderRTCP: func(ctx *ServerHandlerOnReaderPacketRTCPCtx) {
+						require.Equal(t, stream, ctx.Stream)
+						require.Equal(t, 0, ctx.TrackID)
+						require.Equal(t, pli, ctx.Packet)
+						close(packetRecv)
+					},
+				},
+				RTSPAddress: "localhost:8554",
+			}
 
-	conn, err := net.Dial("tcp", listenIP+":8554")
-	require.NoError(t, err)
-	br := bufio.NewReader(conn)
-	defer conn.Close()
+			if transport == "udp" {
+				s.UDPRTPAddress = "127.0.0.1:8000"
+				s.UDPRTCPAddress = "127.0.0.1:8001"
+			}
 
-	res, err := writeReqReadRes(conn, br, base.Request{
-		Method: base.Describe,
-		URL:    mustParseURL("rtsp://" + listenIP + ":8554/teststream?vlcmulticast"),
-		Header: base.Header{
-			"CSeq": base.HeaderValue{"1"},
-		},
-	})
-	require.NoError(t, err)
-	require.Equal(t, base.StatusOK, res.StatusCode)
+			err = s.Start()
+			require.NoError(t, err)
+			defer s.Close()
 
-	var desc psdp.SessionDescription
-	err = desc.Unmarshal(res.Body)
-	require.NoError(t, err)
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			br := bufio.NewReader(conn)
 
-	require.Equal(t, "224.1.0.0", desc.ConnectionInformation.Address.Address)
-}
+			inTH := &headers.Transport{
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+			}
 
-func TestServerReadTCPResponseBeforeFrames(t *testing.T) {
-	writerDone := make(chan struct{})
-	writerTerminate := make(chan struct{})
+			if transport == "udp" {
+				inTH.Protocol = headers.TransportProtocolUDP
+				inTH.ClientPorts = &[2]int{35466, 35467}
+			} else {
+				inTH.Protocol = headers.TransportProtocolTCP
+				inTH.InterleavedIDs = &[2]int{0, 1}
+			}
 
-	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
-	require.NoError(t, err)
+			res, err := writeReqReadRes(conn, br, base.Request{
+				Method: base.Setup,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+				Header: base.Header{
+					"CSeq":      base.HeaderValue{"1"},
+					"Transport": inTH.Write(),
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			var th headers.Transport
+			err = th.Read(res.Header["Transport"])
+			require.NoError(t, err)
+
+			var sx headers.Session
+			err = sx.Read(res.Header["Session"])
+			require.NoError(t, err)
+
+			res, err = writeReqReadRes(conn, br, base.Request{
+				Method: base.Play,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq":    base.HeaderValue{"2"},
+					"Session": base.HeaderValue{sx.Session},
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			byts, _ := pli.Marshal()
+
+			if transport == "udp" {
+				l2, err := net.ListenPacket("udp", "localhost:35467")
+				require.NoError(t, err)
+				defer l2.Close()
+
+				_, err = l2.WriteTo(byts, &net.UDPAddr{
+					IP:   net.ParseIP("127.0.0.1"),
+					Port: th.ServerPorts[1],
+				})
+				require.NoError(t, err)
+			} else {
+				byts, _ = base.InterleavedFrame{
+					Channel: 1,
+					Payload: byts,
+				}.Write()
+				_, err = conn.Write(byts)
+				require.NoError(t, err)
+			}
+
+			<-packetRecv
+		})
+	}
+}
+
+func TestServerReadRTX(t *testing.T) {
+	track := NewTrackPCMU()
+	track.SetRTXPayloadType(97)
 
 	stream := NewServerStream(Tracks{track})
 	defer stream.Close()
 
 	s := &Server{
-		RTSPAddress: "localhost:8554",
 		Handler: &testServerHandler{
-			onConnClose: func(ctx *ServerHandlerOnConnCloseCtx) {
-				close(writerTerminate)
-				<-writerDone
-			},
 			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
 				return &base.Response{
 					StatusCode: base.StatusOK,
 				}, stream, nil
 			},
 			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
-				go func() {
-					defer close(writerDone)
-
-					stream.WritePacketRTP(0, &testRTPPacket, true)
-
-					t := time.NewTicker(50 * time.Millisecond)
-					defer t.Stop()
-
-					for {
-						select {
-						case <-t.C:
-							stream.WritePacketRTP(0, &testRTPPacket, true)
-						case <-writerTerminate:
-							return
-						}
-					}
-				}()
-
-				time.Sleep(50 * time.Millisecond)
-
 				return &base.Response{
 					StatusCode: base.StatusOK,
 				}, nil
 			},
 		},
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+		RTSPAddress:    "localhost:8554",
 	}
 
-	err = s.Start()
+	err := s.Start()
 	require.NoError(t, err)
 	defer s.Close()
 
@@ -851,26 +949,34 @@ func TestServerReadTCPResponseBeforeFrames(t *testing.T) {
 		Header: base.Header{
 			"CSeq": base.HeaderValue{"1"},
 			"Transport": headers.Transport{
-				Protocol: headers.TransportProtocolTCP,
+				Protocol: headers.TransportProtocolUDP,
 				Delivery: func() *headers.TransportDelivery {
 					v := headers.TransportDeliveryUnicast
 					return &v
 				}(),
-				Mode: func() *headers.TransportMode {
-					v := headers.TransportModePlay
-					return &v
-				}(),
-				InterleavedIDs: &[2]int{0, 1},
+				ClientPorts: &[2]int{35466, 35467},
 			}.Write(),
 		},
 	})
 	require.NoError(t, err)
 	require.Equal(t, base.StatusOK, res.StatusCode)
 
+	var th headers.Transport
+	err = th.Read(res.Header["Transport"])
+	require.NoError(t, err)
+
 	var sx headers.Session
 	err = sx.Read(res.Header["Session"])
 	require.NoError(t, err)
 
+	l1, err := net.ListenPacket("udp", "localhost:35466")
+	require.NoError(t, err)
+	defer l1.Close()
+
+	l2, err := net.ListenPacket("udp", "localhost:35467")
+	require.NoError(t, err)
+	defer l2.Close()
+
 	res, err = writeReqReadRes(conn, br, base.Request{
 		Method: base.Play,
 		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
@@ -882,14 +988,49 @@ func TestServerReadTCPResponseBeforeFrames(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, base.StatusOK, res.StatusCode)
 
-	var fr base.InterleavedFrame
-	err = fr.Read(2048, br)
+	for i := 0; i < 3; i++ {
+		stream.WritePacketRTP(0, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    0,
+				SequenceNumber: uint16(10 + i),
+				Timestamp:      54352,
+				SSRC:           753621,
+			},
+			Payload: []byte{byte(i)},
+		}, true)
+
+		buf := make([]byte, 2048)
+		_, _, err = l1.ReadFrom(buf)
+		require.NoError(t, err)
+	}
+
+	byts, _ := (&rtcp.TransportLayerNack{
+		SenderSSRC: 0x12345678,
+		MediaSSRC:  753621,
+		Nacks:      rtcp.NackPairsFromSequenceNumbers([]uint16{11}),
+	}).Marshal()
+	_, err = l2.WriteTo(byts, &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: th.ServerPorts[1],
+	})
 	require.NoError(t, err)
-}
 
-func TestServerReadPlayPlay(t *testing.T) {
-	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	buf := make([]byte, 2048)
+	n, _, err := l1.ReadFrom(buf)
+	require.NoError(t, err)
+
+	var pkt rtp.Packet
+	err = pkt.Unmarshal(buf[:n])
 	require.NoError(t, err)
+	require.Equal(t, uint8(97), pkt.PayloadType)
+	require.Equal(t, uint32(54352), pkt.Timestamp)
+	require.Equal(t, []byte{0x00, 0x0b, 0x01}, pkt.Payload)
+}
+
+func TestServerReadFEC(t *testing.T) {
+	track := NewTrackPCMU()
+	track.SetFEC(98, rtpfec.SchemeULPFEC)
 
 	stream := NewServerStream(Tracks{track})
 	defer stream.Close()
@@ -907,12 +1048,13 @@ func TestServerReadPlayPlay(t *testing.T) {
 				}, nil
 			},
 		},
+		FECGroupSize:   3,
 		UDPRTPAddress:  "127.0.0.1:8000",
 		UDPRTCPAddress: "127.0.0.1:8001",
 		RTSPAddress:    "localhost:8554",
 	}
 
-	err = s.Start()
+	err := s.Start()
 	require.NoError(t, err)
 	defer s.Close()
 
@@ -932,11 +1074,7 @@ func TestServerReadPlayPlay(t *testing.T) {
 					v := headers.TransportDeliveryUnicast
 					return &v
 				}(),
-				Mode: func() *headers.TransportMode {
-					v := headers.TransportModePlay
-					return &v
-				}(),
-				ClientPorts: &[2]int{30450, 30451},
+				ClientPorts: &[2]int{35466, 35467},
 			}.Write(),
 		},
 	})
@@ -947,82 +1085,103 @@ func TestServerReadPlayPlay(t *testing.T) {
 	err = sx.Read(res.Header["Session"])
 	require.NoError(t, err)
 
-	res, err = writeReqReadRes(conn, br, base.Request{
-		Method: base.Play,
-		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
-		Header: base.Header{
-			"CSeq":    base.HeaderValue{"2"},
-			"Session": base.HeaderValue{sx.Session},
-		},
-	})
+	l1, err := net.ListenPacket("udp", "localhost:35466")
 	require.NoError(t, err)
-	require.Equal(t, base.StatusOK, res.StatusCode)
+	defer l1.Close()
 
 	res, err = writeReqReadRes(conn, br, base.Request{
 		Method: base.Play,
 		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
 		Header: base.Header{
-			"CSeq":    base.HeaderValue{"3"},
+			"CSeq":    base.HeaderValue{"2"},
 			"Session": base.HeaderValue{sx.Session},
 		},
 	})
 	require.NoError(t, err)
 	require.Equal(t, base.StatusOK, res.StatusCode)
-}
 
-func TestServerReadPlayPausePlay(t *testing.T) {
-	writerStarted := false
-	writerDone := make(chan struct{})
-	writerTerminate := make(chan struct{})
+	d := &rtpfec.Decoder{
+		PayloadType: 98,
+	}
+	d.Init()
+
+	for i := 0; i < 3; i++ {
+		stream.WritePacketRTP(0, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    0,
+				SequenceNumber: uint16(10 + i),
+				Timestamp:      54352,
+				SSRC:           753621,
+			},
+			Payload: []byte{byte(i), 0x01, 0x02},
+		}, true)
 
-	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
-	require.NoError(t, err)
+		buf := make([]byte, 2048)
+		n, _, err := l1.ReadFrom(buf)
+		require.NoError(t, err)
 
-	stream := NewServerStream(Tracks{track})
-	defer stream.Close()
+		var pkt rtp.Packet
+		err = pkt.Unmarshal(buf[:n])
+		require.NoError(t, err)
+		require.Equal(t, uint8(0), pkt.PayloadType)
 
-	s := &Server{
-		Handler: &testServerHandler{
-			onConnClose: func(ctx *ServerHandlerOnConnCloseCtx) {
-				close(writerTerminate)
-				<-writerDone
-			},
+		// simulate the loss of the second packet
+		if i != 1 {
+			_, err = d.Decode(&pkt)
+			require.NoError(t, err)
+		}
+	}
+
+	buf := make([]byte, 2048)
+	n, _, err := l1.ReadFrom(buf)
+	require.NoError(t, err)
+
+	var pkt rtp.Packet
+	err = pkt.Unmarshal(buf[:n])
+	require.NoError(t, err)
+	require.Equal(t, uint8(98), pkt.PayloadType)
+
+	recovered, err := d.Decode(&pkt)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(recovered))
+	require.Equal(t, uint16(11), recovered[0].SequenceNumber)
+	require.Equal(t, []byte{0x01, 0x01, 0x02}, recovered[0].Payload)
+}
+
+func TestServerReadSRTP(t *testing.T) {
+	key, err := srtp.NewKey(srtp.ProfileAESCM128HMACSHA180)
+	require.NoError(t, err)
+
+	track := NewTrackPCMU()
+	err = track.SetSRTP(key, srtp.KeyExchangeSDES)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	rtcpReceived := make(chan struct{})
+
+	s := &Server{
+		Handler: &testServerHandler{
 			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
 				return &base.Response{
 					StatusCode: base.StatusOK,
 				}, stream, nil
 			},
 			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
-				if !writerStarted {
-					writerStarted = true
-					go func() {
-						defer close(writerDone)
-
-						t := time.NewTicker(50 * time.Millisecond)
-						defer t.Stop()
-
-						for {
-							select {
-							case <-t.C:
-								stream.WritePacketRTP(0, &testRTPPacket, true)
-							case <-writerTerminate:
-								return
-							}
-						}
-					}()
-				}
-
 				return &base.Response{
 					StatusCode: base.StatusOK,
 				}, nil
 			},
-			onPause: func(ctx *ServerHandlerOnPauseCtx) (*base.Response, error) {
-				return &base.Response{
-					StatusCode: base.StatusOK,
-				}, nil
+			onPacketRTCP: func(ctx *ServerHandlerOnPacketRTCPCtx) {
+				require.Equal(t, &testRTCPPacket, ctx.Packet)
+				close(rtcpReceived)
 			},
 		},
-		RTSPAddress: "localhost:8554",
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+		RTSPAddress:    "localhost:8554",
 	}
 
 	err = s.Start()
@@ -1034,71 +1193,106 @@ func TestServerReadPlayPausePlay(t *testing.T) {
 	defer conn.Close()
 	br := bufio.NewReader(conn)
 
+	inTH := headers.Transport{
+		Protocol: headers.TransportProtocolUDP,
+		Delivery: func() *headers.TransportDelivery {
+			v := headers.TransportDeliveryUnicast
+			return &v
+		}(),
+		ClientPorts: &[2]int{35466, 35467},
+	}
+
 	res, err := writeReqReadRes(conn, br, base.Request{
 		Method: base.Setup,
 		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
 		Header: base.Header{
-			"CSeq": base.HeaderValue{"1"},
-			"Transport": headers.Transport{
-				Protocol: headers.TransportProtocolTCP,
-				Delivery: func() *headers.TransportDelivery {
-					v := headers.TransportDeliveryUnicast
-					return &v
-				}(),
-				Mode: func() *headers.TransportMode {
-					v := headers.TransportModePlay
-					return &v
-				}(),
-				InterleavedIDs: &[2]int{0, 1},
-			}.Write(),
+			"CSeq":      base.HeaderValue{"1"},
+			"Transport": inTH.Write(),
 		},
 	})
 	require.NoError(t, err)
-	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, base.StatusUnsupportedTransport, res.StatusCode)
 
-	var sx headers.Session
-	err = sx.Read(res.Header["Session"])
-	require.NoError(t, err)
+	inTH.Profile = headers.TransportProfileSAVP
 
 	res, err = writeReqReadRes(conn, br, base.Request{
-		Method: base.Play,
-		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
 		Header: base.Header{
-			"CSeq":    base.HeaderValue{"2"},
-			"Session": base.HeaderValue{sx.Session},
+			"CSeq":      base.HeaderValue{"2"},
+			"Transport": inTH.Write(),
 		},
 	})
 	require.NoError(t, err)
 	require.Equal(t, base.StatusOK, res.StatusCode)
 
-	res, err = writeReqReadRes(conn, br, base.Request{
-		Method: base.Pause,
-		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
-		Header: base.Header{
-			"CSeq":    base.HeaderValue{"2"},
-			"Session": base.HeaderValue{sx.Session},
-		},
-	})
+	var th headers.Transport
+	err = th.Read(res.Header["Transport"])
+	require.NoError(t, err)
+	require.Equal(t, headers.TransportProfileSAVP, th.Profile)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
 	require.NoError(t, err)
-	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	l1, err := net.ListenPacket("udp", "localhost:35466")
+	require.NoError(t, err)
+	defer l1.Close()
+
+	l2, err := net.ListenPacket("udp", "localhost:35467")
+	require.NoError(t, err)
+	defer l2.Close()
 
 	res, err = writeReqReadRes(conn, br, base.Request{
 		Method: base.Play,
 		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
 		Header: base.Header{
-			"CSeq":    base.HeaderValue{"2"},
+			"CSeq":    base.HeaderValue{"3"},
 			"Session": base.HeaderValue{sx.Session},
 		},
 	})
 	require.NoError(t, err)
 	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	ctx, err := srtp.NewContext(key)
+	require.NoError(t, err)
+
+	stream.WritePacketRTP(0, &testRTPPacket, true)
+
+	buf := make([]byte, 2048)
+	n, _, err := l1.ReadFrom(buf)
+	require.NoError(t, err)
+
+	var pkt rtp.Packet
+	err = pkt.Unmarshal(buf[:n])
+	require.NoError(t, err)
+	require.NotEqual(t, testRTPPacket.Payload, pkt.Payload)
+
+	byts, err := ctx.DecryptRTP(buf[:n])
+	require.NoError(t, err)
+
+	err = pkt.Unmarshal(byts)
+	require.NoError(t, err)
+	require.Equal(t, testRTPPacket, pkt)
+
+	byts, err = ctx.EncryptRTCP(testRTCPPacketMarshaled)
+	require.NoError(t, err)
+
+	_, err = l2.WriteTo(byts, &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: th.ServerPorts[1],
+	})
+	require.NoError(t, err)
+
+	<-rtcpReceived
 }
 
-func TestServerReadPlayPausePause(t *testing.T) {
-	writerDone := make(chan struct{})
-	writerTerminate := make(chan struct{})
+func TestServerReadSRTPRolloverCounter(t *testing.T) {
+	key, err := srtp.NewKey(srtp.ProfileAESCM128HMACSHA180)
+	require.NoError(t, err)
 
-	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	track := NewTrackPCMU()
+	err = track.SetSRTP(key, srtp.KeyExchangeSDES)
 	require.NoError(t, err)
 
 	stream := NewServerStream(Tracks{track})
@@ -1106,9 +1300,10 @@ func TestServerReadPlayPausePause(t *testing.T) {
 
 	s := &Server{
 		Handler: &testServerHandler{
-			onConnClose: func(ctx *ServerHandlerOnConnCloseCtx) {
-				close(writerTerminate)
-				<-writerDone
+			onDescribe: func(ctx *ServerHandlerOnDescribeCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
 			},
 			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
 				return &base.Response{
@@ -1116,162 +1311,450 @@ func TestServerReadPlayPausePause(t *testing.T) {
 				}, stream, nil
 			},
 			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
-				go func() {
-					defer close(writerDone)
-
-					t := time.NewTicker(50 * time.Millisecond)
-					defer t.Stop()
-
-					for {
-						select {
-						case <-t.C:
-							stream.WritePacketRTP(0, &testRTPPacket, true)
-						case <-writerTerminate:
-							return
-						}
-					}
-				}()
-
 				return &base.Response{
 					StatusCode: base.StatusOK,
 				}, nil
 			},
-			onPause: func(ctx *ServerHandlerOnPauseCtx) (*base.Response, error) {
+		},
+		RTSPAddress: "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	writePacket := func(seqNum uint16) {
+		stream.WritePacketRTP(0, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    0,
+				SequenceNumber: seqNum,
+				Timestamp:      uint32(seqNum) * 160,
+				SSRC:           96342362,
+			},
+			Payload: []byte{0x01, 0x02, 0x03, 0x04},
+		}, true)
+	}
+
+	// sequence numbers wrap around before the reader joins the stream
+	for _, seqNum := range []uint16{0xFFFE, 0xFFFF, 0x0000} {
+		writePacket(seqNum)
+	}
+
+	received := make(chan *rtp.Packet)
+
+	c := &Client{
+		Transport: func() *Transport {
+			v := TransportTCP
+			return &v
+		}(),
+		OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
+			received <- ctx.Packet
+		},
+	}
+
+	err = c.StartReading("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer c.Close()
+
+	writePacket(0x0001)
+
+	pkt := <-received
+	require.Equal(t, uint16(0x0001), pkt.SequenceNumber)
+	require.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, pkt.Payload)
+}
+
+func TestServerReadVLCMulticast(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	listenIP := multicastCapableIP(t)
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onDescribe: func(ctx *ServerHandlerOnDescribeCtx) (*base.Response, *ServerStream, error) {
 				return &base.Response{
 					StatusCode: base.StatusOK,
-				}, nil
+				}, stream, nil
 			},
 		},
-		RTSPAddress: "localhost:8554",
+		RTSPAddress:       listenIP + ":8554",
+		MulticastIPRange:  "224.1.0.0/16",
+		MulticastRTPPort:  8000,
+		MulticastRTCPPort: 8001,
 	}
 
 	err = s.Start()
 	require.NoError(t, err)
 	defer s.Close()
 
-	conn, err := net.Dial("tcp", "localhost:8554")
+	conn, err := net.Dial("tcp", listenIP+":8554")
 	require.NoError(t, err)
-	defer conn.Close()
 	br := bufio.NewReader(conn)
+	defer conn.Close()
 
 	res, err := writeReqReadRes(conn, br, base.Request{
-		Method: base.Setup,
-		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Method: base.Describe,
+		URL:    mustParseURL("rtsp://" + listenIP + ":8554/teststream?vlcmulticast"),
 		Header: base.Header{
 			"CSeq": base.HeaderValue{"1"},
-			"Transport": headers.Transport{
-				Protocol: headers.TransportProtocolTCP,
-				Delivery: func() *headers.TransportDelivery {
-					v := headers.TransportDeliveryUnicast
-					return &v
-				}(),
-				Mode: func() *headers.TransportMode {
-					v := headers.TransportModePlay
-					return &v
-				}(),
-				InterleavedIDs: &[2]int{0, 1},
-			}.Write(),
 		},
 	})
 	require.NoError(t, err)
 	require.Equal(t, base.StatusOK, res.StatusCode)
 
-	var sx headers.Session
-	err = sx.Read(res.Header["Session"])
+	var desc psdp.SessionDescription
+	err = desc.Unmarshal(res.Body)
 	require.NoError(t, err)
 
-	res, err = writeReqReadRes(conn, br, base.Request{
-		Method: base.Play,
-		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
-		Header: base.Header{
-			"CSeq":    base.HeaderValue{"2"},
-			"Session": base.HeaderValue{sx.Session},
-		},
-	})
-	require.NoError(t, err)
-	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, "224.1.0.0", desc.ConnectionInformation.Address.Address)
+}
 
-	byts, _ := base.Request{
-		Method: base.Pause,
-		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
-		Header: base.Header{
-			"CSeq":    base.HeaderValue{"2"},
-			"Session": base.HeaderValue{sx.Session},
+func TestServerReadMulticastAllocation(t *testing.T) {
+	for _, ca := range []struct {
+		name         string
+		ipRange      string
+		destinations [2]string
+		ssm          bool
+		anyAddress   bool
+	}{
+		{
+			"ipv4 ssm",
+			"232.1.0.0/16",
+			[2]string{"232.1.0.1", "232.1.0.2"},
+			true,
+			false,
 		},
-	}.Write()
-	_, err = conn.Write(byts)
-	require.NoError(t, err)
+		{
+			"ipv4 ssm any address",
+			"232.1.0.0/16",
+			[2]string{"232.1.0.1", "232.1.0.2"},
+			true,
+			true,
+		},
+		{
+			"ipv6",
+			"ff15::/64",
+			[2]string{"ff15::1", "ff15::2"},
+			false,
+			false,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			track1, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+			require.NoError(t, err)
 
-	res, err = readResIgnoreFrames(br)
-	require.NoError(t, err)
-	require.Equal(t, base.StatusOK, res.StatusCode)
+			track2, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+			require.NoError(t, err)
 
-	byts, _ = base.Request{
-		Method: base.Pause,
+			stream := NewServerStream(Tracks{track1, track2})
+			defer stream.Close()
+
+			listenIP := multicastCapableIP(t)
+
+			// the source of packets is the IP of the interface used to reach the group
+			sourceIP := listenIP
+			rtspAddress := listenIP + ":8554"
+			if ca.anyAddress {
+				conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.ParseIP(ca.destinations[0]), Port: 35000})
+				require.NoError(t, err)
+				sourceIP = conn.LocalAddr().(*net.UDPAddr).IP.String()
+				conn.Close()
+				rtspAddress = ":8554"
+			}
+
+			s := &Server{
+				Handler: &testServerHandler{
+					onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream, nil
+					},
+				},
+				RTSPAddress:        rtspAddress,
+				MulticastIPRange:   ca.ipRange,
+				MulticastPortRange: [2]int{35000, 35010},
+				MulticastTTL:       8,
+			}
+
+			err = s.Start()
+			require.NoError(t, err)
+			defer s.Close()
+
+			conn, err := net.Dial("tcp", listenIP+":8554")
+			require.NoError(t, err)
+			br := bufio.NewReader(conn)
+			defer conn.Close()
+
+			var sx headers.Session
+
+			for i := 0; i < 2; i++ {
+				h := base.Header{
+					"CSeq": base.HeaderValue{strconv.FormatInt(int64(i+1), 10)},
+					"Transport": headers.Transport{
+						Delivery: func() *headers.TransportDelivery {
+							v := headers.TransportDeliveryMulticast
+							return &v
+						}(),
+						Mode: func() *headers.TransportMode {
+							v := headers.TransportModePlay
+							return &v
+						}(),
+						Protocol: headers.TransportProtocolUDP,
+					}.Write(),
+				}
+				if i > 0 {
+					h["Session"] = base.HeaderValue{sx.Session}
+				}
+
+				res, err := writeReqReadRes(conn, br, base.Request{
+					Method: base.Setup,
+					URL:    mustParseURL("rtsp://" + listenIP + ":8554/teststream/trackID=" + strconv.FormatInt(int64(i), 10)),
+					Header: h,
+				})
+				require.NoError(t, err)
+				require.Equal(t, base.StatusOK, res.StatusCode)
+
+				err = sx.Read(res.Header["Session"])
+				require.NoError(t, err)
+
+				var th headers.Transport
+				err = th.Read(res.Header["Transport"])
+				require.NoError(t, err)
+				require.Equal(t, ca.destinations[i], th.Destination.String())
+				require.Equal(t, &[2]int{35000 + i*2, 35001 + i*2}, th.Ports)
+				require.Equal(t, uint(8), *th.TTL)
+
+				if ca.ssm {
+					require.Equal(t, sourceIP, th.Source.String())
+				} else {
+					require.Equal(t, (*net.IP)(nil), th.Source)
+				}
+			}
+		})
+	}
+}
+
+func TestServerReadSAPAnnounce(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+	track.SetRTCPMux(true)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	listenIP := multicastCapableIP(t)
+
+	s := &Server{
+		Handler:            &testServerHandler{},
+		RTSPAddress:        listenIP + ":8554",
+		MulticastIPRange:   "224.1.0.0/16",
+		MulticastPortRange: [2]int{35000, 35010},
+		SAPAddress:         "224.2.127.254:9875",
+		SAPAnnouncePeriod:  100 * time.Millisecond,
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	l, err := listenMulticast(net.ListenPacket, net.ParseIP("224.2.127.254"), 9875, nil, multicastTTL, true)
+	require.NoError(t, err)
+	defer l.Close()
+
+	key, err := srtp.NewKey(srtp.ProfileAESCM128HMACSHA180)
+	require.NoError(t, err)
+
+	srtpTrack := NewTrackPCMU()
+	err = srtpTrack.SetSRTP(key, srtp.KeyExchangeSDES)
+	require.NoError(t, err)
+
+	srtpStream := NewServerStream(Tracks{srtpTrack})
+	defer srtpStream.Close()
+
+	err = srtpStream.AnnounceSAP(s, "test session")
+	require.EqualError(t, err, "tracks protected with SRTP can't be announced")
+
+	err = stream.AnnounceSAP(s, "test session")
+	require.NoError(t, err)
+
+	err = stream.AnnounceSAP(s, "test session")
+	require.Error(t, err)
+
+	readPacket := func() *sap.Packet {
+		l.SetReadDeadline(time.Now().Add(2 * time.Second))
+		buf := make([]byte, 2048)
+		n, _, err := l.ReadFrom(buf)
+		require.NoError(t, err)
+
+		var pkt sap.Packet
+		err = pkt.Unmarshal(buf[:n])
+		require.NoError(t, err)
+		return &pkt
+	}
+
+	pkt := readPacket()
+	require.Equal(t, false, pkt.Deletion)
+	require.Equal(t, sap.PayloadTypeSDP, pkt.PayloadType)
+	require.Equal(t, listenIP, pkt.OriginatingSource.String())
+	require.Contains(t, string(pkt.Payload), "s=test session\r\n")
+	require.Contains(t, string(pkt.Payload), "m=video 35000 RTP/AVP 96\r\n")
+	require.Contains(t, string(pkt.Payload), "c=IN IP4 224.1.0.1/16\r\n")
+	require.NotContains(t, string(pkt.Payload), "a=rtcp-mux")
+
+	pkt2 := readPacket()
+	require.Equal(t, false, pkt2.Deletion)
+	require.Equal(t, pkt.MessageIDHash, pkt2.MessageIDHash)
+
+	stream.Close()
+
+	for {
+		pkt2 = readPacket()
+		if pkt2.Deletion {
+			break
+		}
+	}
+	require.Equal(t, pkt.MessageIDHash, pkt2.MessageIDHash)
+}
+
+func TestServerReadTCPResponseBeforeFrames(t *testing.T) {
+	writerDone := make(chan struct{})
+	writerTerminate := make(chan struct{})
+
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	s := &Server{
+		RTSPAddress: "localhost:8554",
+		Handler: &testServerHandler{
+			onConnClose: func(ctx *ServerHandlerOnConnCloseCtx) {
+				close(writerTerminate)
+				<-writerDone
+			},
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				go func() {
+					defer close(writerDone)
+
+					stream.WritePacketRTP(0, &testRTPPacket, true)
+
+					t := time.NewTicker(50 * time.Millisecond)
+					defer t.Stop()
+
+					for {
+						select {
+						case <-t.C:
+							stream.WritePacketRTP(0, &testRTPPacket, true)
+						case <-writerTerminate:
+							return
+						}
+					}
+				}()
+
+				time.Sleep(50 * time.Millisecond)
+
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: headers.TransportProtocolTCP,
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
 		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
 		Header: base.Header{
 			"CSeq":    base.HeaderValue{"2"},
 			"Session": base.HeaderValue{sx.Session},
 		},
-	}.Write()
-	_, err = conn.Write(byts)
+	})
 	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
 
-	res, err = readResIgnoreFrames(br)
+	var fr base.InterleavedFrame
+	err = fr.Read(2048, br)
 	require.NoError(t, err)
-	require.Equal(t, base.StatusOK, res.StatusCode)
 }
 
-func TestServerReadTimeout(t *testing.T) {
+func TestServerReadRedirect(t *testing.T) {
 	for _, transport := range []string{
 		"udp",
-		"multicast",
-		// there's no timeout when reading with TCP
+		"tcp",
 	} {
 		t.Run(transport, func(t *testing.T) {
-			sessionClosed := make(chan struct{})
-
 			track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
 			require.NoError(t, err)
 
 			stream := NewServerStream(Tracks{track})
 			defer stream.Close()
 
+			sessionPlaying := make(chan *ServerSession, 1)
+
 			s := &Server{
 				Handler: &testServerHandler{
-					onSessionClose: func(ctx *ServerHandlerOnSessionCloseCtx) {
-						close(sessionClosed)
-					},
-					onAnnounce: func(ctx *ServerHandlerOnAnnounceCtx) (*base.Response, error) {
-						return &base.Response{
-							StatusCode: base.StatusOK,
-						}, nil
-					},
 					onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
 						return &base.Response{
 							StatusCode: base.StatusOK,
 						}, stream, nil
 					},
 					onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+						sessionPlaying <- ctx.Session
 						return &base.Response{
 							StatusCode: base.StatusOK,
 						}, nil
 					},
 				},
-				ReadTimeout:    1 * time.Second,
-				sessionTimeout: 1 * time.Second,
-				RTSPAddress:    "localhost:8554",
+				RTSPAddress: "localhost:8554",
 			}
 
-			switch transport {
-			case "udp":
+			if transport == "udp" {
 				s.UDPRTPAddress = "127.0.0.1:8000"
 				s.UDPRTCPAddress = "127.0.0.1:8001"
-
-			case "multicast":
-				s.MulticastIPRange = "224.1.0.0/16"
-				s.MulticastRTPPort = 8000
-				s.MulticastRTCPPort = 8001
 			}
 
 			err = s.Start()
@@ -1284,23 +1767,22 @@ func TestServerReadTimeout(t *testing.T) {
 			br := bufio.NewReader(conn)
 
 			inTH := &headers.Transport{
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
 				Mode: func() *headers.TransportMode {
 					v := headers.TransportModePlay
 					return &v
 				}(),
 			}
 
-			switch transport {
-			case "udp":
-				v := headers.TransportDeliveryUnicast
-				inTH.Delivery = &v
+			if transport == "udp" {
 				inTH.Protocol = headers.TransportProtocolUDP
 				inTH.ClientPorts = &[2]int{35466, 35467}
-
-			case "multicast":
-				v := headers.TransportDeliveryMulticast
-				inTH.Delivery = &v
-				inTH.Protocol = headers.TransportProtocolUDP
+			} else {
+				inTH.Protocol = headers.TransportProtocolTCP
+				inTH.InterleavedIDs = &[2]int{0, 1}
 			}
 
 			res, err := writeReqReadRes(conn, br, base.Request{
@@ -1329,123 +1811,48 @@ func TestServerReadTimeout(t *testing.T) {
 			require.NoError(t, err)
 			require.Equal(t, base.StatusOK, res.StatusCode)
 
-			<-sessionClosed
-		})
-	}
-}
+			session := <-sessionPlaying
 
-func TestServerReadWithoutTeardown(t *testing.T) {
-	for _, transport := range []string{
-		"udp",
-		"tcp",
-	} {
-		t.Run(transport, func(t *testing.T) {
-			connClosed := make(chan struct{})
-			sessionClosed := make(chan struct{})
+			err = session.Redirect(mustParseURL("rtsp://otherserver:8554/teststream"), &headers.Range{
+				Value: &headers.RangeNPT{
+					Start: headers.RangeNPTTime(5 * time.Second),
+				},
+			})
+			require.NoError(t, err)
 
-			track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+			req, err := readRequestIgnoreFrames(br)
+			require.NoError(t, err)
+			require.Equal(t, base.Redirect, req.Method)
+			require.Equal(t, mustParseURL("rtsp://localhost:8554/teststream"), req.URL)
+			require.Equal(t, base.HeaderValue{sx.Session}, req.Header["Session"])
+			require.Equal(t, base.HeaderValue{"rtsp://otherserver:8554/teststream"}, req.Header["Location"])
+			require.Equal(t, base.HeaderValue{"npt=5-"}, req.Header["Range"])
+
+			// the response is discarded by the server
+			byts, _ := base.Response{
+				StatusCode: base.StatusOK,
+				Header: base.Header{
+					"CSeq": req.Header["CSeq"],
+				},
+			}.Write()
+			_, err = conn.Write(byts)
 			require.NoError(t, err)
 
-			stream := NewServerStream(Tracks{track})
-			defer stream.Close()
+			res, err = writeReqReadRes(conn, br, base.Request{
+				Method: base.Teardown,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq":    base.HeaderValue{"3"},
+					"Session": base.HeaderValue{sx.Session},
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+		})
+	}
+}
 
-			s := &Server{
-				Handler: &testServerHandler{
-					onConnClose: func(ctx *ServerHandlerOnConnCloseCtx) {
-						close(connClosed)
-					},
-					onSessionClose: func(ctx *ServerHandlerOnSessionCloseCtx) {
-						close(sessionClosed)
-					},
-					onAnnounce: func(ctx *ServerHandlerOnAnnounceCtx) (*base.Response, error) {
-						return &base.Response{
-							StatusCode: base.StatusOK,
-						}, nil
-					},
-					onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
-						return &base.Response{
-							StatusCode: base.StatusOK,
-						}, stream, nil
-					},
-					onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
-						return &base.Response{
-							StatusCode: base.StatusOK,
-						}, nil
-					},
-				},
-				ReadTimeout:    1 * time.Second,
-				sessionTimeout: 1 * time.Second,
-				RTSPAddress:    "localhost:8554",
-			}
-
-			if transport == "udp" {
-				s.UDPRTPAddress = "127.0.0.1:8000"
-				s.UDPRTCPAddress = "127.0.0.1:8001"
-			}
-
-			err = s.Start()
-			require.NoError(t, err)
-			defer s.Close()
-
-			conn, err := net.Dial("tcp", "localhost:8554")
-			require.NoError(t, err)
-			defer conn.Close()
-			br := bufio.NewReader(conn)
-
-			inTH := &headers.Transport{
-				Delivery: func() *headers.TransportDelivery {
-					v := headers.TransportDeliveryUnicast
-					return &v
-				}(),
-				Mode: func() *headers.TransportMode {
-					v := headers.TransportModePlay
-					return &v
-				}(),
-			}
-
-			if transport == "udp" {
-				inTH.Protocol = headers.TransportProtocolUDP
-				inTH.ClientPorts = &[2]int{35466, 35467}
-			} else {
-				inTH.Protocol = headers.TransportProtocolTCP
-				inTH.InterleavedIDs = &[2]int{0, 1}
-			}
-
-			res, err := writeReqReadRes(conn, br, base.Request{
-				Method: base.Setup,
-				URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
-				Header: base.Header{
-					"CSeq":      base.HeaderValue{"1"},
-					"Transport": inTH.Write(),
-				},
-			})
-			require.NoError(t, err)
-			require.Equal(t, base.StatusOK, res.StatusCode)
-
-			var sx headers.Session
-			err = sx.Read(res.Header["Session"])
-			require.NoError(t, err)
-
-			res, err = writeReqReadRes(conn, br, base.Request{
-				Method: base.Play,
-				URL:    mustParseURL("rtsp://localhost:8554/teststream"),
-				Header: base.Header{
-					"CSeq":    base.HeaderValue{"2"},
-					"Session": base.HeaderValue{sx.Session},
-				},
-			})
-			require.NoError(t, err)
-			require.Equal(t, base.StatusOK, res.StatusCode)
-
-			conn.Close()
-
-			<-sessionClosed
-			<-connClosed
-		})
-	}
-}
-
-func TestServerReadUDPChangeConn(t *testing.T) {
+func TestServerReadPlayPlay(t *testing.T) {
 	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
 	require.NoError(t, err)
 
@@ -1464,11 +1871,6 @@ func TestServerReadUDPChangeConn(t *testing.T) {
 					StatusCode: base.StatusOK,
 				}, nil
 			},
-			onGetParameter: func(ctx *ServerHandlerOnGetParameterCtx) (*base.Response, error) {
-				return &base.Response{
-					StatusCode: base.StatusOK,
-				}, nil
-			},
 		},
 		UDPRTPAddress:  "127.0.0.1:8000",
 		UDPRTCPAddress: "127.0.0.1:8001",
@@ -1479,83 +1881,65 @@ func TestServerReadUDPChangeConn(t *testing.T) {
 	require.NoError(t, err)
 	defer s.Close()
 
-	sxID := ""
-
-	func() {
-		conn, err := net.Dial("tcp", "localhost:8554")
-		require.NoError(t, err)
-		defer conn.Close()
-		br := bufio.NewReader(conn)
-
-		inTH := &headers.Transport{
-			Delivery: func() *headers.TransportDelivery {
-				v := headers.TransportDeliveryUnicast
-				return &v
-			}(),
-			Mode: func() *headers.TransportMode {
-				v := headers.TransportModePlay
-				return &v
-			}(),
-			Protocol:    headers.TransportProtocolUDP,
-			ClientPorts: &[2]int{35466, 35467},
-		}
-
-		res, err := writeReqReadRes(conn, br, base.Request{
-			Method: base.Setup,
-			URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
-			Header: base.Header{
-				"CSeq":      base.HeaderValue{"1"},
-				"Transport": inTH.Write(),
-			},
-		})
-		require.NoError(t, err)
-		require.Equal(t, base.StatusOK, res.StatusCode)
-
-		var sx headers.Session
-		err = sx.Read(res.Header["Session"])
-		require.NoError(t, err)
-
-		res, err = writeReqReadRes(conn, br, base.Request{
-			Method: base.Play,
-			URL:    mustParseURL("rtsp://localhost:8554/teststream"),
-			Header: base.Header{
-				"CSeq":    base.HeaderValue{"2"},
-				"Session": base.HeaderValue{sx.Session},
-			},
-		})
-		require.NoError(t, err)
-		require.Equal(t, base.StatusOK, res.StatusCode)
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
 
-		sxID = sx.Session
-	}()
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: headers.TransportProtocolUDP,
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				ClientPorts: &[2]int{30450, 30451},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
 
-	func() {
-		conn, err := net.Dial("tcp", "localhost:8554")
-		require.NoError(t, err)
-		defer conn.Close()
-		br := bufio.NewReader(conn)
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
 
-		res, err := writeReqReadRes(conn, br, base.Request{
-			Method: base.GetParameter,
-			URL:    mustParseURL("rtsp://localhost:8554/teststream/"),
-			Header: base.Header{
-				"CSeq":    base.HeaderValue{"1"},
-				"Session": base.HeaderValue{sxID},
-			},
-		})
-		require.NoError(t, err)
-		require.Equal(t, base.StatusOK, res.StatusCode)
-	}()
-}
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
 
-func TestServerReadPartialTracks(t *testing.T) {
-	track1, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"3"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
 	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+}
 
-	track2, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+func TestServerReadPlayScaleSpeed(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
 	require.NoError(t, err)
 
-	stream := NewServerStream(Tracks{track1, track2})
+	stream := NewServerStream(Tracks{track})
 	defer stream.Close()
 
 	s := &Server{
@@ -1566,14 +1950,16 @@ func TestServerReadPartialTracks(t *testing.T) {
 				}, stream, nil
 			},
 			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
-				go func() {
-					time.Sleep(1 * time.Second)
-					stream.WritePacketRTP(0, &testRTPPacket, true)
-					stream.WritePacketRTP(1, &testRTPPacket, true)
-				}()
+				require.Equal(t, headers.Scale(-1), *ctx.Scale)
+				require.Equal(t, headers.Speed(4), *ctx.Speed)
 
+				// grant reverse playback, but at normal delivery rate
 				return &base.Response{
 					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Scale": ctx.Scale.Write(),
+						"Speed": headers.Speed(1).Write(),
+					},
 				}, nil
 			},
 		},
@@ -1589,61 +1975,2003 @@ func TestServerReadPartialTracks(t *testing.T) {
 	defer conn.Close()
 	br := bufio.NewReader(conn)
 
-	inTH := &headers.Transport{
-		Delivery: func() *headers.TransportDelivery {
-			v := headers.TransportDeliveryUnicast
-			return &v
-		}(),
-		Mode: func() *headers.TransportMode {
-			v := headers.TransportModePlay
-			return &v
-		}(),
-		Protocol:       headers.TransportProtocolTCP,
-		InterleavedIDs: &[2]int{4, 5},
-	}
-
 	res, err := writeReqReadRes(conn, br, base.Request{
 		Method: base.Setup,
-		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=1"),
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
 		Header: base.Header{
-			"CSeq":      base.HeaderValue{"1"},
-			"Transport": inTH.Write(),
-		},
-	})
-	require.NoError(t, err)
-	require.Equal(t, base.StatusOK, res.StatusCode)
-
-	var sx headers.Session
-	err = sx.Read(res.Header["Session"])
-	require.NoError(t, err)
-
-	res, err = writeReqReadRes(conn, br, base.Request{
-		Method: base.Play,
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: headers.TransportProtocolTCP,
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+			"Scale":   base.HeaderValue{"-1"},
+			"Speed":   base.HeaderValue{"4"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, base.HeaderValue{"-1"}, res.Header["Scale"])
+	require.Equal(t, base.HeaderValue{"1"}, res.Header["Speed"])
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"3"},
+			"Session": base.HeaderValue{sx.Session},
+			"Scale":   base.HeaderValue{"0"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusBadRequest, res.StatusCode)
+}
+
+func TestServerReadRTSP2(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	var ss *ServerSession
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				ss = ctx.Session
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+		RTSPAddress:    "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq":               base.HeaderValue{"1"},
+			"Pipelined-Requests": base.HeaderValue{"7f"},
+			"Transport": headers.Transport{
+				Protocol: headers.TransportProtocolUDP,
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				DestAddrs: &[2]*net.UDPAddr{{Port: 35466}, {Port: 35467}},
+			}.Write(),
+		},
+		Version: base.Version20,
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, base.Version20, res.Version)
+	require.Equal(t, base.HeaderValue{"7f"}, res.Header["Pipelined-Requests"])
+	require.Equal(t, base.HeaderValue{"npt"}, res.Header["Accept-Ranges"])
+	require.Equal(t, base.HeaderValue{"No-Seeking, Time-Progressing, Unlimited"}, res.Header["Media-Properties"])
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	var th headers.Transport
+	err = th.Read(res.Header["Transport"])
+	require.NoError(t, err)
+	require.Nil(t, th.ClientPorts)
+	require.Nil(t, th.ServerPorts)
+	require.Equal(t, &[2]*net.UDPAddr{
+		{IP: net.ParseIP("127.0.0.1"), Port: 35466},
+		{IP: net.ParseIP("127.0.0.1"), Port: 35467},
+	}, th.DestAddrs)
+	require.Equal(t, &[2]*net.UDPAddr{
+		{IP: net.ParseIP("127.0.0.1"), Port: 8000},
+		{IP: net.ParseIP("127.0.0.1"), Port: 8001},
+	}, th.SrcAddrs)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+		Version: base.Version20,
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, base.Version20, res.Version)
+
+	notifyErr := make(chan error)
+	go func() {
+		notifyErr <- ss.PlayNotify(headers.NotifyReasonEndOfStream, base.Header{
+			"Range": base.HeaderValue{"npt=-10"},
+		})
+	}()
+
+	req, err := readRequest(br)
+	require.NoError(t, err)
+	require.Equal(t, base.PlayNotify, req.Method)
+	require.Equal(t, base.Version20, req.Version)
+	require.Equal(t, base.HeaderValue{"end-of-stream"}, req.Header["Notify-Reason"])
+	require.Equal(t, base.HeaderValue{"npt=-10"}, req.Header["Range"])
+	require.Equal(t, base.HeaderValue{sx.Session}, req.Header["Session"])
+	require.NoError(t, <-notifyErr)
+
+	byts, _ := base.Response{
+		StatusCode: base.StatusOK,
+		Header: base.Header{
+			"CSeq": req.Header["CSeq"],
+		},
+		Version: base.Version20,
+	}.Write()
+	_, err = conn.Write(byts)
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Teardown,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"3"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+		Version: base.Version20,
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+}
+
+func TestServerReadPlayNotifyRTSP1(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	var ss *ServerSession
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				ss = ctx.Session
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+		},
+		RTSPAddress: "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: headers.TransportProtocolTCP,
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, base.Version10, res.Version)
+
+	err = ss.PlayNotify(headers.NotifyReasonEndOfStream, nil)
+	require.Equal(t, liberrors.ErrServerSessionNotRTSP2{}, err)
+}
+
+func TestServerReadPlayPausePlay(t *testing.T) {
+	writerStarted := false
+	writerDone := make(chan struct{})
+	writerTerminate := make(chan struct{})
+
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onConnClose: func(ctx *ServerHandlerOnConnCloseCtx) {
+				close(writerTerminate)
+				<-writerDone
+			},
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				if !writerStarted {
+					writerStarted = true
+					go func() {
+						defer close(writerDone)
+
+						t := time.NewTicker(50 * time.Millisecond)
+						defer t.Stop()
+
+						for {
+							select {
+							case <-t.C:
+								stream.WritePacketRTP(0, &testRTPPacket, true)
+							case <-writerTerminate:
+								return
+							}
+						}
+					}()
+				}
+
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onPause: func(ctx *ServerHandlerOnPauseCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		RTSPAddress: "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: headers.TransportProtocolTCP,
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Pause,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+}
+
+func TestServerReadPlayPausePause(t *testing.T) {
+	writerDone := make(chan struct{})
+	writerTerminate := make(chan struct{})
+
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onConnClose: func(ctx *ServerHandlerOnConnCloseCtx) {
+				close(writerTerminate)
+				<-writerDone
+			},
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				go func() {
+					defer close(writerDone)
+
+					t := time.NewTicker(50 * time.Millisecond)
+					defer t.Stop()
+
+					for {
+						select {
+						case <-t.C:
+							stream.WritePacketRTP(0, &testRTPPacket, true)
+						case <-writerTerminate:
+							return
+						}
+					}
+				}()
+
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onPause: func(ctx *ServerHandlerOnPauseCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		RTSPAddress: "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: headers.TransportProtocolTCP,
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	byts, _ := base.Request{
+		Method: base.Pause,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	}.Write()
+	_, err = conn.Write(byts)
+	require.NoError(t, err)
+
+	res, err = readResIgnoreFrames(br)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	byts, _ = base.Request{
+		Method: base.Pause,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	}.Write()
+	_, err = conn.Write(byts)
+	require.NoError(t, err)
+
+	res, err = readResIgnoreFrames(br)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+}
+
+func TestServerReadTimeout(t *testing.T) {
+	for _, transport := range []string{
+		"udp",
+		"multicast",
+		// there's no timeout when reading with TCP
+	} {
+		t.Run(transport, func(t *testing.T) {
+			sessionClosed := make(chan struct{})
+
+			track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+			require.NoError(t, err)
+
+			stream := NewServerStream(Tracks{track})
+			defer stream.Close()
+
+			s := &Server{
+				Handler: &testServerHandler{
+					onSessionClose: func(ctx *ServerHandlerOnSessionCloseCtx) {
+						close(sessionClosed)
+					},
+					onAnnounce: func(ctx *ServerHandlerOnAnnounceCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream, nil
+					},
+					onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				},
+				ReadTimeout:    1 * time.Second,
+				sessionTimeout: 1 * time.Second,
+				RTSPAddress:    "localhost:8554",
+			}
+
+			switch transport {
+			case "udp":
+				s.UDPRTPAddress = "127.0.0.1:8000"
+				s.UDPRTCPAddress = "127.0.0.1:8001"
+
+			case "multicast":
+				s.MulticastIPRange = "224.1.0.0/16"
+				s.MulticastRTPPort = 8000
+				s.MulticastRTCPPort = 8001
+			}
+
+			err = s.Start()
+			require.NoError(t, err)
+			defer s.Close()
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			br := bufio.NewReader(conn)
+
+			inTH := &headers.Transport{
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+			}
+
+			switch transport {
+			case "udp":
+				v := headers.TransportDeliveryUnicast
+				inTH.Delivery = &v
+				inTH.Protocol = headers.TransportProtocolUDP
+				inTH.ClientPorts = &[2]int{35466, 35467}
+
+			case "multicast":
+				v := headers.TransportDeliveryMulticast
+				inTH.Delivery = &v
+				inTH.Protocol = headers.TransportProtocolUDP
+			}
+
+			res, err := writeReqReadRes(conn, br, base.Request{
+				Method: base.Setup,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+				Header: base.Header{
+					"CSeq":      base.HeaderValue{"1"},
+					"Transport": inTH.Write(),
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			var sx headers.Session
+			err = sx.Read(res.Header["Session"])
+			require.NoError(t, err)
+
+			res, err = writeReqReadRes(conn, br, base.Request{
+				Method: base.Play,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq":    base.HeaderValue{"2"},
+					"Session": base.HeaderValue{sx.Session},
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			<-sessionClosed
+		})
+	}
+}
+
+func TestServerReadWithoutTeardown(t *testing.T) {
+	for _, transport := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(transport, func(t *testing.T) {
+			connClosed := make(chan struct{})
+			sessionClosed := make(chan struct{})
+
+			track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+			require.NoError(t, err)
+
+			stream := NewServerStream(Tracks{track})
+			defer stream.Close()
+
+			s := &Server{
+				Handler: &testServerHandler{
+					onConnClose: func(ctx *ServerHandlerOnConnCloseCtx) {
+						close(connClosed)
+					},
+					onSessionClose: func(ctx *ServerHandlerOnSessionCloseCtx) {
+						close(sessionClosed)
+					},
+					onAnnounce: func(ctx *ServerHandlerOnAnnounceCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream, nil
+					},
+					onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				},
+				ReadTimeout:    1 * time.Second,
+				sessionTimeout: 1 * time.Second,
+				RTSPAddress:    "localhost:8554",
+			}
+
+			if transport == "udp" {
+				s.UDPRTPAddress = "127.0.0.1:8000"
+				s.UDPRTCPAddress = "127.0.0.1:8001"
+			}
+
+			err = s.Start()
+			require.NoError(t, err)
+			defer s.Close()
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			br := bufio.NewReader(conn)
+
+			inTH := &headers.Transport{
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+			}
+
+			if transport == "udp" {
+				inTH.Protocol = headers.TransportProtocolUDP
+				inTH.ClientPorts = &[2]int{35466, 35467}
+			} else {
+				inTH.Protocol = headers.TransportProtocolTCP
+				inTH.InterleavedIDs = &[2]int{0, 1}
+			}
+
+			res, err := writeReqReadRes(conn, br, base.Request{
+				Method: base.Setup,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+				Header: base.Header{
+					"CSeq":      base.HeaderValue{"1"},
+					"Transport": inTH.Write(),
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			var sx headers.Session
+			err = sx.Read(res.Header["Session"])
+			require.NoError(t, err)
+
+			res, err = writeReqReadRes(conn, br, base.Request{
+				Method: base.Play,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq":    base.HeaderValue{"2"},
+					"Session": base.HeaderValue{sx.Session},
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			conn.Close()
+
+			<-sessionClosed
+			<-connClosed
+		})
+	}
+}
+
+func TestServerReadUDPChangeConn(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onGetParameter: func(ctx *ServerHandlerOnGetParameterCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+		RTSPAddress:    "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	sxID := ""
+
+	func() {
+		conn, err := net.Dial("tcp", "localhost:8554")
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		inTH := &headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Mode: func() *headers.TransportMode {
+				v := headers.TransportModePlay
+				return &v
+			}(),
+			Protocol:    headers.TransportProtocolUDP,
+			ClientPorts: &[2]int{35466, 35467},
+		}
+
+		res, err := writeReqReadRes(conn, br, base.Request{
+			Method: base.Setup,
+			URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+			Header: base.Header{
+				"CSeq":      base.HeaderValue{"1"},
+				"Transport": inTH.Write(),
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+
+		var sx headers.Session
+		err = sx.Read(res.Header["Session"])
+		require.NoError(t, err)
+
+		res, err = writeReqReadRes(conn, br, base.Request{
+			Method: base.Play,
+			URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+			Header: base.Header{
+				"CSeq":    base.HeaderValue{"2"},
+				"Session": base.HeaderValue{sx.Session},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+
+		sxID = sx.Session
+	}()
+
+	func() {
+		conn, err := net.Dial("tcp", "localhost:8554")
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		res, err := writeReqReadRes(conn, br, base.Request{
+			Method: base.GetParameter,
+			URL:    mustParseURL("rtsp://localhost:8554/teststream/"),
+			Header: base.Header{
+				"CSeq":    base.HeaderValue{"1"},
+				"Session": base.HeaderValue{sxID},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+	}()
+}
+
+func TestServerReadPartialTracks(t *testing.T) {
+	track1, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	track2, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track1, track2})
+	defer stream.Close()
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				go func() {
+					time.Sleep(1 * time.Second)
+					stream.WritePacketRTP(0, &testRTPPacket, true)
+					stream.WritePacketRTP(1, &testRTPPacket, true)
+				}()
+
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		RTSPAddress: "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	inTH := &headers.Transport{
+		Delivery: func() *headers.TransportDelivery {
+			v := headers.TransportDeliveryUnicast
+			return &v
+		}(),
+		Mode: func() *headers.TransportMode {
+			v := headers.TransportModePlay
+			return &v
+		}(),
+		Protocol:       headers.TransportProtocolTCP,
+		InterleavedIDs: &[2]int{4, 5},
+	}
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=1"),
+		Header: base.Header{
+			"CSeq":      base.HeaderValue{"1"},
+			"Transport": inTH.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var f base.InterleavedFrame
+	err = f.Read(2048, br)
+	require.NoError(t, err)
+	require.Equal(t, 4, f.Channel)
+	require.Equal(t, testRTPPacketMarshaled, f.Payload)
+}
+
+func TestServerReadAdditionalInfos(t *testing.T) {
+	getInfos := func() (*headers.RTPInfo, []*uint32) {
+		conn, err := net.Dial("tcp", "localhost:8554")
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		ssrcs := make([]*uint32, 2)
+
+		inTH := &headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Mode: func() *headers.TransportMode {
+				v := headers.TransportModePlay
+				return &v
+			}(),
+			Protocol:       headers.TransportProtocolTCP,
+			InterleavedIDs: &[2]int{0, 1},
+		}
+
+		res, err := writeReqReadRes(conn, br, base.Request{
+			Method: base.Setup,
+			URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+			Header: base.Header{
+				"CSeq":      base.HeaderValue{"1"},
+				"Transport": inTH.Write(),
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+
+		var th headers.Transport
+		err = th.Read(res.Header["Transport"])
+		require.NoError(t, err)
+		ssrcs[0] = th.SSRC
+
+		inTH = &headers.Transport{
+			Delivery: func() *headers.TransportDelivery {
+				v := headers.TransportDeliveryUnicast
+				return &v
+			}(),
+			Mode: func() *headers.TransportMode {
+				v := headers.TransportModePlay
+				return &v
+			}(),
+			Protocol:       headers.TransportProtocolTCP,
+			InterleavedIDs: &[2]int{2, 3},
+		}
+
+		var sx headers.Session
+		err = sx.Read(res.Header["Session"])
+		require.NoError(t, err)
+
+		res, err = writeReqReadRes(conn, br, base.Request{
+			Method: base.Setup,
+			URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=1"),
+			Header: base.Header{
+				"CSeq":      base.HeaderValue{"2"},
+				"Transport": inTH.Write(),
+				"Session":   base.HeaderValue{sx.Session},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+
+		th = headers.Transport{}
+		err = th.Read(res.Header["Transport"])
+		require.NoError(t, err)
+		ssrcs[1] = th.SSRC
+
+		res, err = writeReqReadRes(conn, br, base.Request{
+			Method: base.Play,
+			URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+			Header: base.Header{
+				"CSeq":    base.HeaderValue{"3"},
+				"Session": base.HeaderValue{sx.Session},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+
+		var ri headers.RTPInfo
+		err = ri.Read(res.Header["RTP-Info"])
+		require.NoError(t, err)
+
+		return &ri, ssrcs
+	}
+
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track, track})
+	defer stream.Close()
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		RTSPAddress: "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	stream.WritePacketRTP(0, &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: 556,
+			Timestamp:      984512368,
+			SSRC:           96342362,
+		},
+		Payload: []byte{0x01, 0x02, 0x03, 0x04},
+	}, true)
+
+	rtpInfo, ssrcs := getInfos()
+	require.Equal(t, &headers.RTPInfo{
+		&headers.RTPInfoEntry{
+			URL: (&url.URL{
+				Scheme: "rtsp",
+				Host:   "localhost:8554",
+				Path:   "/teststream/trackID=0",
+			}).String(),
+			SequenceNumber: func() *uint16 {
+				v := uint16(557)
+				return &v
+			}(),
+			Timestamp: (*rtpInfo)[0].Timestamp,
+		},
+	}, rtpInfo)
+	require.Equal(t, []*uint32{
+		func() *uint32 {
+			v := uint32(96342362)
+			return &v
+		}(),
+		nil,
+	}, ssrcs)
+
+	stream.WritePacketRTP(1, &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: 87,
+			Timestamp:      756436454,
+			SSRC:           536474323,
+		},
+		Payload: []byte{0x01, 0x02, 0x03, 0x04},
+	}, true)
+
+	rtpInfo, ssrcs = getInfos()
+	require.Equal(t, &headers.RTPInfo{
+		&headers.RTPInfoEntry{
+			URL: (&url.URL{
+				Scheme: "rtsp",
+				Host:   "localhost:8554",
+				Path:   "/teststream/trackID=0",
+			}).String(),
+			SequenceNumber: func() *uint16 {
+				v := uint16(557)
+				return &v
+			}(),
+			Timestamp: (*rtpInfo)[0].Timestamp,
+		},
+		&headers.RTPInfoEntry{
+			URL: (&url.URL{
+				Scheme: "rtsp",
+				Host:   "localhost:8554",
+				Path:   "/teststream/trackID=1",
+			}).String(),
+			SequenceNumber: func() *uint16 {
+				v := uint16(88)
+				return &v
+			}(),
+			Timestamp: (*rtpInfo)[1].Timestamp,
+		},
+	}, rtpInfo)
+	require.Equal(t, []*uint32{
+		func() *uint32 {
+			v := uint32(96342362)
+			return &v
+		}(),
+		func() *uint32 {
+			v := uint32(536474323)
+			return &v
+		}(),
+	}, ssrcs)
+}
+
+func TestServerReadGOPCache(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	stream.EnableGOPCache(100)
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		RTSPAddress: "localhost:8554",
+		// the GOP cache is bigger than the write buffer
+		WriteBufferCount: 2,
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	writePacket := func(seqNum uint16, ts uint32, ptsEqualsDTS bool) {
+		stream.WritePacketRTP(0, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    96,
+				SequenceNumber: seqNum,
+				Timestamp:      ts,
+				SSRC:           96342362,
+			},
+			Payload: []byte{0x01, 0x02, 0x03, 0x04},
+		}, ptsEqualsDTS)
+	}
+
+	// non-key frame
+	writePacket(1, 100, false)
+
+	// key frame split into two packets
+	writePacket(2, 200, false)
+	writePacket(3, 200, true)
+
+	// non-key frames
+	writePacket(4, 300, false)
+	writePacket(5, 400, false)
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				Protocol:       headers.TransportProtocolTCP,
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// RTP-Info refers to the first packet of the GOP cache
+	var ri headers.RTPInfo
+	err = ri.Read(res.Header["RTP-Info"])
+	require.NoError(t, err)
+	require.Equal(t, uint16(2), *ri[0].SequenceNumber)
+	require.Equal(t, uint32(200), *ri[0].Timestamp)
+
+	writePacket(6, 500, false)
+
+	for _, expected := range []struct {
+		seqNum uint16
+		ts     uint32
+	}{
+		{2, 200},
+		{3, 200},
+		{4, 300},
+		{5, 400},
+		{6, 500},
+	} {
+		var f base.InterleavedFrame
+		err = f.Read(2048, br)
+		require.NoError(t, err)
+		require.Equal(t, 0, f.Channel)
+
+		var pkt rtp.Packet
+		err = pkt.Unmarshal(f.Payload)
+		require.NoError(t, err)
+		require.Equal(t, expected.seqNum, pkt.SequenceNumber)
+		require.Equal(t, expected.ts, pkt.Timestamp)
+	}
+}
+
+func TestServerReadTimeShift(t *testing.T) {
+	for _, ca := range []string{
+		"memory",
+		"disk",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+			require.NoError(t, err)
+
+			stream := NewServerStream(Tracks{track})
+			defer stream.Close()
+
+			buf := &ServerTimeShiftBuffer{
+				SegmentDuration: 100 * time.Millisecond,
+			}
+			if ca == "disk" {
+				buf.Directory = t.TempDir()
+			}
+
+			err = buf.Start(stream)
+			require.NoError(t, err)
+			defer buf.Close()
+
+			s := &Server{
+				Handler: &testServerHandler{
+					onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream, nil
+					},
+					onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+						if ctx.Range != nil {
+							_, err := buf.Play(ctx.Session, ctx.Range, ctx.Speed)
+							if err != nil {
+								return &base.Response{
+									StatusCode: base.StatusBadRequest,
+								}, err
+							}
+						}
+
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				},
+				RTSPAddress: "localhost:8554",
+			}
+
+			err = s.Start()
+			require.NoError(t, err)
+			defer s.Close()
+
+			writePacket := func(i int) {
+				stream.WritePacketRTP(0, &rtp.Packet{
+					Header: rtp.Header{
+						Version:        2,
+						PayloadType:    96,
+						SequenceNumber: uint16(i + 1),
+						Timestamp:      uint32(i * 4500),
+						SSRC:           96342362,
+					},
+					Payload: []byte{0x01, 0x02, 0x03, 0x04},
+				}, (i%5) == 0)
+			}
+
+			// a key frame every 5 packets, a packet every 50ms
+			for i := 0; i < 10; i++ {
+				writePacket(i)
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			start, end, ok := buf.Bounds()
+			require.Equal(t, true, ok)
+			require.Greater(t, int64(end.Sub(start)), int64(400*time.Millisecond))
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			br := bufio.NewReader(conn)
+
+			res, err := writeReqReadRes(conn, br, base.Request{
+				Method: base.Setup,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+				Header: base.Header{
+					"CSeq": base.HeaderValue{"1"},
+					"Transport": headers.Transport{
+						Delivery: func() *headers.TransportDelivery {
+							v := headers.TransportDeliveryUnicast
+							return &v
+						}(),
+						Mode: func() *headers.TransportMode {
+							v := headers.TransportModePlay
+							return &v
+						}(),
+						Protocol:       headers.TransportProtocolTCP,
+						InterleavedIDs: &[2]int{0, 1},
+					}.Write(),
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			var sx headers.Session
+			err = sx.Read(res.Header["Session"])
+			require.NoError(t, err)
+
+			// request the 8th packet, that follows the key frame in the 6th packet
+			res, err = writeReqReadRes(conn, br, base.Request{
+				Method: base.Play,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq":    base.HeaderValue{"2"},
+					"Session": base.HeaderValue{sx.Session},
+					"Range": headers.Range{
+						Value: &headers.RangeUTC{
+							Start: headers.RangeUTCTime(start.Add(370 * time.Millisecond)),
+						},
+					}.Write(),
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			var ri headers.RTPInfo
+			err = ri.Read(res.Header["RTP-Info"])
+			require.NoError(t, err)
+			require.Equal(t, uint16(6), *ri[0].SequenceNumber)
+			require.Equal(t, uint32(5*4500), *ri[0].Timestamp)
+
+			readSeqNum := func() uint16 {
+				var f base.InterleavedFrame
+				err := f.Read(2048, br)
+				require.NoError(t, err)
+				require.Equal(t, 0, f.Channel)
+
+				var pkt rtp.Packet
+				err = pkt.Unmarshal(f.Payload)
+				require.NoError(t, err)
+				return pkt.SequenceNumber
+			}
+
+			for i := 5; i < 10; i++ {
+				require.Equal(t, uint16(i+1), readSeqNum())
+			}
+
+			// after the end of the buffer, the live stream is received
+			// without losses and duplicates.
+			for i := 10; i < 13; i++ {
+				writePacket(i)
+				require.Equal(t, uint16(i+1), readSeqNum())
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			err = buf.Close()
+			require.NoError(t, err)
+
+			if ca == "disk" {
+				files, err := os.ReadDir(buf.Directory)
+				require.NoError(t, err)
+				require.Equal(t, 0, len(files))
+			}
+		})
+	}
+}
+
+func TestServerReadTimeShiftLiveEdge(t *testing.T) {
+	for _, ca := range []string{
+		"npt zero",
+		"npt now",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+			require.NoError(t, err)
+
+			stream := NewServerStream(Tracks{track})
+			defer stream.Close()
+
+			buf := &ServerTimeShiftBuffer{}
+
+			err = buf.Start(stream)
+			require.NoError(t, err)
+			defer buf.Close()
+
+			s := &Server{
+				Handler: &testServerHandler{
+					onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream, nil
+					},
+					onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+						if ca == "npt now" {
+							require.Nil(t, ctx.Range)
+						}
+
+						_, err := buf.Play(ctx.Session, ctx.Range, ctx.Speed)
+						if err != nil {
+							return &base.Response{
+								StatusCode: base.StatusBadRequest,
+							}, err
+						}
+
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				},
+				RTSPAddress: "localhost:8554",
+			}
+
+			err = s.Start()
+			require.NoError(t, err)
+			defer s.Close()
+
+			for i := 0; i < 5; i++ {
+				stream.WritePacketRTP(0, &rtp.Packet{
+					Header: rtp.Header{
+						Version:        2,
+						PayloadType:    96,
+						SequenceNumber: uint16(i + 1),
+						Timestamp:      uint32(i * 4500),
+						SSRC:           96342362,
+					},
+					Payload: []byte{0x01, 0x02, 0x03, 0x04},
+				}, true)
+			}
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			br := bufio.NewReader(conn)
+
+			res, err := writeReqReadRes(conn, br, base.Request{
+				Method: base.Setup,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+				Header: base.Header{
+					"CSeq": base.HeaderValue{"1"},
+					"Transport": headers.Transport{
+						Delivery: func() *headers.TransportDelivery {
+							v := headers.TransportDeliveryUnicast
+							return &v
+						}(),
+						Mode: func() *headers.TransportMode {
+							v := headers.TransportModePlay
+							return &v
+						}(),
+						Protocol:       headers.TransportProtocolTCP,
+						InterleavedIDs: &[2]int{0, 1},
+					}.Write(),
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			var sx headers.Session
+			err = sx.Read(res.Header["Session"])
+			require.NoError(t, err)
+
+			res, err = writeReqReadRes(conn, br, base.Request{
+				Method: base.Play,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq":    base.HeaderValue{"2"},
+					"Session": base.HeaderValue{sx.Session},
+					"Range": func() base.HeaderValue {
+						if ca == "npt zero" {
+							return base.HeaderValue{"npt=0-"}
+						}
+						return base.HeaderValue{"npt=now-"}
+					}(),
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			// the reader starts from the live stream, not from the beginning of the buffer
+			var ri headers.RTPInfo
+			err = ri.Read(res.Header["RTP-Info"])
+			require.NoError(t, err)
+			require.Equal(t, uint16(6), *ri[0].SequenceNumber)
+		})
+	}
+}
+
+func TestServerReadTimeShiftStorageError(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	errorReceived := make(chan error, 1)
+
+	buf := &ServerTimeShiftBuffer{
+		Directory: filepath.Join(t.TempDir(), "buffer"),
+		OnError: func(err error) {
+			select {
+			case errorReceived <- err:
+			default:
+			}
+		},
+	}
+
+	err = buf.Start(stream)
+	require.NoError(t, err)
+	defer buf.Close()
+
+	// segments can't be created anymore
+	err = os.RemoveAll(buf.Directory)
+	require.NoError(t, err)
+
+	stream.WritePacketRTP(0, &testRTPPacket, true)
+
+	select {
+	case err := <-errorReceived:
+		require.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Errorf("OnError not called")
+	}
+
+	_, _, ok := buf.Bounds()
+	require.Equal(t, false, ok)
+}
+
+type testServerMediaSource struct {
+	count int
+	pos   int
+}
+
+func (s *testServerMediaSource) Duration() time.Duration {
+	return time.Duration(s.count) * 40 * time.Millisecond
+}
+
+func (s *testServerMediaSource) Seek(pos time.Duration) (time.Duration, error) {
+	// a key frame every 3 access units
+	s.pos = int(pos/(40*time.Millisecond)) / 3 * 3
+	return time.Duration(s.pos) * 40 * time.Millisecond, nil
+}
+
+func (s *testServerMediaSource) ReadAccessUnit() (*ServerMediaAccessUnit, error) {
+	if s.pos >= s.count {
+		return nil, io.EOF
+	}
+
+	typ := byte(h264.NALUTypeNonIDR)
+	if (s.pos % 3) == 0 {
+		typ = byte(h264.NALUTypeIDR)
+	}
+
+	au := &ServerMediaAccessUnit{
+		PTS:   time.Duration(s.pos) * 40 * time.Millisecond,
+		Units: [][]byte{{typ, byte(s.pos)}},
+	}
+	s.pos++
+	return au, nil
+}
+
+func TestServerReadMediaPlayerTimestamp(t *testing.T) {
+	pt := &serverMediaPlayerTrack{
+		clockRate:        90000,
+		initialTimestamp: 1000,
+	}
+
+	require.Equal(t, uint32(1000+45000), pt.timestamp(500*time.Millisecond))
+
+	// the timestamp wraps around after about 13 hours
+	require.Equal(t, uint32((1000+uint64(14*3600)*90000)%(1<<32)), pt.timestamp(14*time.Hour))
+}
+
+func TestServerReadMediaPlayer(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	ended := make(chan struct{})
+
+	player := &ServerMediaPlayer{
+		Tracks: Tracks{track},
+		Source: &testServerMediaSource{count: 10},
+		OnEnd: func() {
+			close(ended)
+		},
+	}
+	err = player.Start()
+	require.NoError(t, err)
+	defer player.Close()
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, player.Stream(), nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return player.OnPlay(ctx)
+			},
+			onPause: func(ctx *ServerHandlerOnPauseCtx) (*base.Response, error) {
+				return player.OnPause(ctx)
+			},
+		},
+		RTSPAddress: "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				Protocol:       headers.TransportProtocolTCP,
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	readPacket := func() *rtp.Packet {
+		var f base.InterleavedFrame
+		err := f.Read(2048, br)
+		require.NoError(t, err)
+		require.Equal(t, 0, f.Channel)
+
+		var pkt rtp.Packet
+		err = pkt.Unmarshal(f.Payload)
+		require.NoError(t, err)
+		return &pkt
+	}
+
+	// the position is moved to the key frame that precedes the requested one
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
 		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
 		Header: base.Header{
 			"CSeq":    base.HeaderValue{"2"},
 			"Session": base.HeaderValue{sx.Session},
+			"Range":   base.HeaderValue{"npt=0.2-"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, base.HeaderValue{"npt=0.12-0.4"}, res.Header["Range"])
+
+	var ri headers.RTPInfo
+	err = ri.Read(res.Header["RTP-Info"])
+	require.NoError(t, err)
+	require.Equal(t, "rtsp://localhost:8554/teststream/trackID=0", ri[0].URL)
+
+	firstPkt := readPacket()
+	require.Equal(t, []byte{byte(h264.NALUTypeIDR), 3}, firstPkt.Payload)
+	require.Equal(t, *ri[0].SequenceNumber, firstPkt.SequenceNumber)
+	require.Equal(t, *ri[0].Timestamp, firstPkt.Timestamp)
+
+	pkt := readPacket()
+	require.Equal(t, []byte{byte(h264.NALUTypeNonIDR), 4}, pkt.Payload)
+	require.Equal(t, *ri[0].Timestamp+40*90, pkt.Timestamp)
+
+	byts, _ := base.Request{
+		Method: base.Pause,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"4"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	}.Write()
+	_, err = conn.Write(byts)
+	require.NoError(t, err)
+
+	res, err = readResIgnoreFrames(br)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// reading resumes from the position in which it was paused
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"5"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var ri2 headers.RTPInfo
+	err = ri2.Read(res.Header["RTP-Info"])
+	require.NoError(t, err)
+
+	// no access units are lost or duplicated
+	pkt = readPacket()
+	require.Equal(t, *ri2[0].SequenceNumber, pkt.SequenceNumber)
+	require.Equal(t, *ri2[0].Timestamp, pkt.Timestamp)
+	require.Equal(t, firstPkt.SequenceNumber+uint16(pkt.Payload[1]-3), pkt.SequenceNumber)
+	require.Equal(t, firstPkt.Timestamp+uint32(pkt.Payload[1]-3)*40*90, pkt.Timestamp)
+
+	for i := int(pkt.Payload[1]) + 1; i < 10; i++ {
+		pkt = readPacket()
+		require.Equal(t, byte(i), pkt.Payload[1])
+	}
+
+	// at the end of the media, a RTCP BYE is sent
+	var f base.InterleavedFrame
+	err = f.Read(2048, br)
+	require.NoError(t, err)
+	require.Equal(t, 1, f.Channel)
+
+	pkts, err := rtcp.Unmarshal(f.Payload)
+	require.NoError(t, err)
+	_, ok := pkts[0].(*rtcp.Goodbye)
+	require.Equal(t, true, ok)
+
+	<-ended
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"6"},
+			"Session": base.HeaderValue{sx.Session},
+			"Range":   base.HeaderValue{"npt=5-"},
 		},
 	})
 	require.NoError(t, err)
-	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, base.StatusInvalidRange, res.StatusCode)
+}
+
+func TestServerRelay(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	upstreamSessionOpen := make(chan *ServerSession, 10)
+	upstreamSessionClose := make(chan struct{}, 10)
+	upstreamPLI := make(chan struct{}, 10)
+
+	upstream := &Server{
+		Handler: &testServerHandler{
+			onSessionOpen: func(ctx *ServerHandlerOnSessionOpenCtx) {
+				upstreamSessionOpen <- ctx.Session
+			},
+			onSessionClose: func(ctx *ServerHandlerOnSessionCloseCtx) {
+				upstreamSessionClose <- struct{}{}
+			},
+			onDescribe: func(ctx *ServerHandlerOnDescribeCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onReaderRTCP: func(ctx *ServerHandlerOnReaderPacketRTCPCtx) {
+				if _, ok := ctx.Packet.(*rtcp.PictureLossIndication); ok {
+					upstreamPLI <- struct{}{}
+				}
+			},
+		},
+		RTSPAddress: "localhost:8555",
+	}
+
+	err = upstream.Start()
+	require.NoError(t, err)
+	defer upstream.Close()
+
+	writerTerminate := make(chan struct{})
+	writerDone := make(chan struct{})
+	defer func() {
+		close(writerTerminate)
+		<-writerDone
+	}()
+
+	go func() {
+		defer close(writerDone)
+
+		t := time.NewTicker(20 * time.Millisecond)
+		defer t.Stop()
+
+		for i := 0; ; i++ {
+			select {
+			case <-t.C:
+				stream.WritePacketRTP(0, &rtp.Packet{
+					Header: rtp.Header{
+						Version:        2,
+						PayloadType:    96,
+						SequenceNumber: uint16(i),
+						Timestamp:      uint32(i * 1800),
+						SSRC:           96342362,
+					},
+					Payload: []byte{0x05, 0x02, 0x03, 0x04},
+				}, true)
+
+			case <-writerTerminate:
+				return
+			}
+		}
+	}()
+
+	relay := &ServerRelay{
+		Paths: map[string]string{
+			"relayed": "rtsp://localhost:8555/upstream",
+		},
+		NewClient: func() *Client {
+			return &Client{
+				Transport: func() *Transport {
+					v := TransportTCP
+					return &v
+				}(),
+			}
+		},
+		IdleTimeout:     300 * time.Millisecond,
+		ReconnectPeriod: 100 * time.Millisecond,
+	}
+	err = relay.Start()
+	require.NoError(t, err)
+	defer relay.Close()
+
+	s := &Server{
+		Handler:     relay,
+		RTSPAddress: "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
 
-	var f base.InterleavedFrame
-	err = f.Read(2048, br)
+	startReader := func() (*Client, chan struct{}) {
+		received := make(chan struct{}, 1)
+
+		c := &Client{
+			Transport: func() *Transport {
+				v := TransportTCP
+				return &v
+			}(),
+			OnPacketRTP: func(ctx *ClientOnPacketRTPCtx) {
+				select {
+				case received <- struct{}{}:
+				default:
+				}
+			},
+		}
+
+		err := c.StartReading("rtsp://localhost:8554/relayed")
+		require.NoError(t, err)
+
+		return c, received
+	}
+
+	c1, received1 := startReader()
+	defer c1.Close()
+	<-received1
+
+	c2, received2 := startReader()
+	defer c2.Close()
+	<-received2
+
+	// readers share the same upstream connection
+	upstreamSession := <-upstreamSessionOpen
+	select {
+	case <-upstreamSessionOpen:
+		t.Errorf("unexpected upstream session")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// keyframe requests are forwarded upstream
+	err = c1.WritePacketRTCPPLI(0)
 	require.NoError(t, err)
-	require.Equal(t, 4, f.Channel)
-	require.Equal(t, testRTPPacketMarshaled, f.Payload)
+	<-upstreamPLI
+
+	// the upstream connection is restored without interrupting readers
+	upstreamSession.Close()
+	<-upstreamSessionClose
+	<-upstreamSessionOpen
+
+	for _, received := range []chan struct{}{received1, received2} {
+		select {
+		case <-received:
+		default:
+		}
+		<-received
+	}
+
+	// the upstream connection is closed when there are no readers
+	c1.Close()
+	c2.Close()
+
+	select {
+	case <-upstreamSessionClose:
+	case <-time.After(2 * time.Second):
+		t.Errorf("upstream session has not been closed")
+	}
 }
 
-func TestServerReadAdditionalInfos(t *testing.T) {
-	getInfos := func() (*headers.RTPInfo, []*uint32) {
+func TestServerReadErrorUDPSamePorts(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+		RTSPAddress:    "localhost:8554",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	func() {
 		conn, err := net.Dial("tcp", "localhost:8554")
 		require.NoError(t, err)
 		defer conn.Close()
 		br := bufio.NewReader(conn)
 
-		ssrcs := make([]*uint32, 2)
-
 		inTH := &headers.Transport{
 			Delivery: func() *headers.TransportDelivery {
 				v := headers.TransportDeliveryUnicast
@@ -1653,8 +3981,8 @@ func TestServerReadAdditionalInfos(t *testing.T) {
 				v := headers.TransportModePlay
 				return &v
 			}(),
-			Protocol:       headers.TransportProtocolTCP,
-			InterleavedIDs: &[2]int{0, 1},
+			Protocol:    headers.TransportProtocolUDP,
+			ClientPorts: &[2]int{35466, 35467},
 		}
 
 		res, err := writeReqReadRes(conn, br, base.Request{
@@ -1668,12 +3996,29 @@ func TestServerReadAdditionalInfos(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, base.StatusOK, res.StatusCode)
 
-		var th headers.Transport
-		err = th.Read(res.Header["Transport"])
+		var sx headers.Session
+		err = sx.Read(res.Header["Session"])
 		require.NoError(t, err)
-		ssrcs[0] = th.SSRC
 
-		inTH = &headers.Transport{
+		res, err = writeReqReadRes(conn, br, base.Request{
+			Method: base.Play,
+			URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+			Header: base.Header{
+				"CSeq":    base.HeaderValue{"2"},
+				"Session": base.HeaderValue{sx.Session},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+	}()
+
+	func() {
+		conn, err := net.Dial("tcp", "localhost:8554")
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		inTH := &headers.Transport{
 			Delivery: func() *headers.TransportDelivery {
 				v := headers.TransportDeliveryUnicast
 				return &v
@@ -1682,53 +4027,127 @@ func TestServerReadAdditionalInfos(t *testing.T) {
 				v := headers.TransportModePlay
 				return &v
 			}(),
-			Protocol:       headers.TransportProtocolTCP,
-			InterleavedIDs: &[2]int{2, 3},
+			Protocol:    headers.TransportProtocolUDP,
+			ClientPorts: &[2]int{35466, 35467},
 		}
 
-		var sx headers.Session
-		err = sx.Read(res.Header["Session"])
-		require.NoError(t, err)
-
-		res, err = writeReqReadRes(conn, br, base.Request{
+		res, err := writeReqReadRes(conn, br, base.Request{
 			Method: base.Setup,
-			URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=1"),
+			URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
 			Header: base.Header{
-				"CSeq":      base.HeaderValue{"2"},
+				"CSeq":      base.HeaderValue{"1"},
 				"Transport": inTH.Write(),
-				"Session":   base.HeaderValue{sx.Session},
 			},
 		})
 		require.NoError(t, err)
-		require.Equal(t, base.StatusOK, res.StatusCode)
+		require.Equal(t, base.StatusBadRequest, res.StatusCode)
+	}()
+}
 
-		th = headers.Transport{}
-		err = th.Read(res.Header["Transport"])
-		require.NoError(t, err)
-		ssrcs[1] = th.SSRC
+func TestServerReadSymmetricRTP(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
 
-		res, err = writeReqReadRes(conn, br, base.Request{
-			Method: base.Play,
-			URL:    mustParseURL("rtsp://localhost:8554/teststream"),
-			Header: base.Header{
-				"CSeq":    base.HeaderValue{"3"},
-				"Session": base.HeaderValue{sx.Session},
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
 			},
+		},
+		RTSPAddress:     "localhost:8554",
+		UDPRTPAddress:   "127.0.0.1:8000",
+		UDPRTCPAddress:  "127.0.0.1:8001",
+		UDPSymmetricRTP: true,
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: headers.TransportProtocolUDP,
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				ClientPorts: &[2]int{35466, 35467},
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	var th headers.Transport
+	err = th.Read(res.Header["Transport"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// simulate a NAT that rewrites source ports
+	l1, err := net.ListenPacket("udp", "localhost:35476")
+	require.NoError(t, err)
+	defer l1.Close()
+
+	// open the NAT mapping
+	l1.WriteTo([]byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		&net.UDPAddr{
+			IP:   net.ParseIP("127.0.0.1"),
+			Port: th.ServerPorts[0],
 		})
-		require.NoError(t, err)
-		require.Equal(t, base.StatusOK, res.StatusCode)
 
-		var ri headers.RTPInfo
-		err = ri.Read(res.Header["RTP-Info"])
-		require.NoError(t, err)
+	time.Sleep(500 * time.Millisecond)
 
-		return &ri, ssrcs
-	}
+	stream.WritePacketRTP(0, &testRTPPacket, true)
+
+	// server -> client, on the learned address
+	buf := make([]byte, 2048)
+	n, _, err := l1.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Equal(t, testRTPPacketMarshaled, buf[:n])
+}
 
+func TestServerReadSymmetricRTPOtherIP(t *testing.T) {
 	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
 	require.NoError(t, err)
 
-	stream := NewServerStream(Tracks{track, track})
+	stream := NewServerStream(Tracks{track})
 	defer stream.Close()
 
 	s := &Server{
@@ -1744,98 +4163,93 @@ func TestServerReadAdditionalInfos(t *testing.T) {
 				}, nil
 			},
 		},
-		RTSPAddress: "localhost:8554",
+		RTSPAddress:     "localhost:8554",
+		UDPRTPAddress:   "127.0.0.1:8000",
+		UDPRTCPAddress:  "127.0.0.1:8001",
+		UDPSymmetricRTP: true,
 	}
 
 	err = s.Start()
 	require.NoError(t, err)
 	defer s.Close()
 
-	stream.WritePacketRTP(0, &rtp.Packet{
-		Header: rtp.Header{
-			Version:        2,
-			PayloadType:    96,
-			SequenceNumber: 556,
-			Timestamp:      984512368,
-			SSRC:           96342362,
-		},
-		Payload: []byte{0x01, 0x02, 0x03, 0x04},
-	}, true)
-
-	rtpInfo, ssrcs := getInfos()
-	require.Equal(t, &headers.RTPInfo{
-		&headers.RTPInfoEntry{
-			URL: (&url.URL{
-				Scheme: "rtsp",
-				Host:   "localhost:8554",
-				Path:   "/teststream/trackID=0",
-			}).String(),
-			SequenceNumber: func() *uint16 {
-				v := uint16(557)
-				return &v
-			}(),
-			Timestamp: (*rtpInfo)[0].Timestamp,
-		},
-	}, rtpInfo)
-	require.Equal(t, []*uint32{
-		func() *uint32 {
-			v := uint32(96342362)
-			return &v
-		}(),
-		nil,
-	}, ssrcs)
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
 
-	stream.WritePacketRTP(1, &rtp.Packet{
-		Header: rtp.Header{
-			Version:        2,
-			PayloadType:    96,
-			SequenceNumber: 87,
-			Timestamp:      756436454,
-			SSRC:           536474323,
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: headers.TransportProtocolUDP,
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				ClientPorts: &[2]int{35466, 35467},
+			}.Write(),
 		},
-		Payload: []byte{0x01, 0x02, 0x03, 0x04},
-	}, true)
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
 
-	rtpInfo, ssrcs = getInfos()
-	require.Equal(t, &headers.RTPInfo{
-		&headers.RTPInfoEntry{
-			URL: (&url.URL{
-				Scheme: "rtsp",
-				Host:   "localhost:8554",
-				Path:   "/teststream/trackID=0",
-			}).String(),
-			SequenceNumber: func() *uint16 {
-				v := uint16(557)
-				return &v
-			}(),
-			Timestamp: (*rtpInfo)[0].Timestamp,
-		},
-		&headers.RTPInfoEntry{
-			URL: (&url.URL{
-				Scheme: "rtsp",
-				Host:   "localhost:8554",
-				Path:   "/teststream/trackID=1",
-			}).String(),
-			SequenceNumber: func() *uint16 {
-				v := uint16(88)
-				return &v
-			}(),
-			Timestamp: (*rtpInfo)[1].Timestamp,
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	var th headers.Transport
+	err = th.Read(res.Header["Transport"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{sx.Session},
 		},
-	}, rtpInfo)
-	require.Equal(t, []*uint32{
-		func() *uint32 {
-			v := uint32(96342362)
-			return &v
-		}(),
-		func() *uint32 {
-			v := uint32(536474323)
-			return &v
-		}(),
-	}, ssrcs)
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// the SSRC of the stream is public
+	stream.WritePacketRTP(0, &testRTPPacket, true)
+
+	// a receiver report that contains the SSRC of the stream,
+	// sent from another IP, must not latch the session
+	l1, err := net.ListenPacket("udp", "127.0.0.2:35487")
+	require.NoError(t, err)
+	defer l1.Close()
+
+	byts, _ := (&rtcp.ReceiverReport{
+		SSRC: 0x65f83afb,
+		Reports: []rtcp.ReceptionReport{{
+			SSRC: testRTPPacket.SSRC,
+		}},
+	}).Marshal()
+	l1.WriteTo(byts, &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: th.ServerPorts[1],
+	})
+
+	time.Sleep(500 * time.Millisecond)
+
+	stream.WritePacketRTCP(0, &rtcp.SenderReport{SSRC: testRTPPacket.SSRC})
+
+	l1.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	buf := make([]byte, 2048)
+	_, _, err = l1.ReadFrom(buf)
+	require.Error(t, err)
 }
 
-func TestServerReadErrorUDPSamePorts(t *testing.T) {
+func TestServerReadUDPPortRange(t *testing.T) {
 	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
 	require.NoError(t, err)
 
@@ -1855,45 +4269,62 @@ func TestServerReadErrorUDPSamePorts(t *testing.T) {
 				}, nil
 			},
 		},
-		UDPRTPAddress:  "127.0.0.1:8000",
-		UDPRTCPAddress: "127.0.0.1:8001",
-		RTSPAddress:    "localhost:8554",
+		RTSPAddress:  "localhost:8554",
+		UDPPortRange: [2]int{35000, 35003},
 	}
 
 	err = s.Start()
 	require.NoError(t, err)
 	defer s.Close()
 
-	func() {
-		conn, err := net.Dial("tcp", "localhost:8554")
-		require.NoError(t, err)
-		defer conn.Close()
-		br := bufio.NewReader(conn)
-
-		inTH := &headers.Transport{
-			Delivery: func() *headers.TransportDelivery {
-				v := headers.TransportDeliveryUnicast
-				return &v
-			}(),
-			Mode: func() *headers.TransportMode {
-				v := headers.TransportModePlay
-				return &v
-			}(),
-			Protocol:    headers.TransportProtocolUDP,
-			ClientPorts: &[2]int{35466, 35467},
-		}
+	l1, err := net.ListenPacket("udp", "localhost:35466")
+	require.NoError(t, err)
+	defer l1.Close()
 
+	setup := func(conn net.Conn, br *bufio.Reader) *base.Response {
 		res, err := writeReqReadRes(conn, br, base.Request{
 			Method: base.Setup,
 			URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
 			Header: base.Header{
-				"CSeq":      base.HeaderValue{"1"},
-				"Transport": inTH.Write(),
+				"CSeq": base.HeaderValue{"1"},
+				"Transport": headers.Transport{
+					Protocol: headers.TransportProtocolUDP,
+					Delivery: func() *headers.TransportDelivery {
+						v := headers.TransportDeliveryUnicast
+						return &v
+					}(),
+					Mode: func() *headers.TransportMode {
+						v := headers.TransportModePlay
+						return &v
+					}(),
+					ClientPorts: &[2]int{35466, 35467},
+				}.Write(),
 			},
 		})
 		require.NoError(t, err)
+		return res
+	}
+
+	serverPorts := make(map[int]struct{})
+
+	// two readers with the same IP and ports
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", "localhost:8554")
+		require.NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+
+		res := setup(conn, br)
 		require.Equal(t, base.StatusOK, res.StatusCode)
 
+		var th headers.Transport
+		err = th.Read(res.Header["Transport"])
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, th.ServerPorts[0], 35000)
+		require.LessOrEqual(t, th.ServerPorts[1], 35003)
+		require.Equal(t, th.ServerPorts[0]+1, th.ServerPorts[1])
+		serverPorts[th.ServerPorts[0]] = struct{}{}
+
 		var sx headers.Session
 		err = sx.Read(res.Header["Session"])
 		require.NoError(t, err)
@@ -1908,36 +4339,162 @@ func TestServerReadErrorUDPSamePorts(t *testing.T) {
 		})
 		require.NoError(t, err)
 		require.Equal(t, base.StatusOK, res.StatusCode)
-	}()
+	}
 
-	func() {
-		conn, err := net.Dial("tcp", "localhost:8554")
+	require.Equal(t, 2, len(serverPorts))
+
+	// the port range is exhausted
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	res := setup(conn, bufio.NewReader(conn))
+	require.Equal(t, base.StatusServiceUnavailable, res.StatusCode)
+
+	stream.WritePacketRTP(0, &testRTPPacket, true)
+
+	// each reader receives the packet from its own port
+	sourcePorts := make(map[int]struct{})
+
+	for i := 0; i < 2; i++ {
+		buf := make([]byte, 2048)
+		n, addr, err := l1.ReadFrom(buf)
 		require.NoError(t, err)
-		defer conn.Close()
-		br := bufio.NewReader(conn)
+		require.Equal(t, testRTPPacketMarshaled, buf[:n])
+		sourcePorts[addr.(*net.UDPAddr).Port] = struct{}{}
+	}
 
-		inTH := &headers.Transport{
-			Delivery: func() *headers.TransportDelivery {
-				v := headers.TransportDeliveryUnicast
-				return &v
-			}(),
-			Mode: func() *headers.TransportMode {
-				v := headers.TransportModePlay
-				return &v
-			}(),
-			Protocol:    headers.TransportProtocolUDP,
-			ClientPorts: &[2]int{35466, 35467},
-		}
+	require.Equal(t, serverPorts, sourcePorts)
+}
 
-		res, err := writeReqReadRes(conn, br, base.Request{
-			Method: base.Setup,
-			URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
-			Header: base.Header{
-				"CSeq":      base.HeaderValue{"1"},
-				"Transport": inTH.Write(),
+func TestServerReadRTCPMux(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+	track.SetRTCPMux(true)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	rtcpRecv := make(chan struct{})
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onDescribe: func(ctx *ServerHandlerOnDescribeCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
 			},
-		})
+			onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			onPacketRTCP: func(ctx *ServerHandlerOnPacketRTCPCtx) {
+				require.Equal(t, &testRTCPPacket, ctx.Packet)
+				close(rtcpRecv)
+			},
+		},
+		RTSPAddress:    "localhost:8554",
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Describe,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	tracks, _, err := ReadTracks(res.Body, false)
+	require.NoError(t, err)
+	require.Equal(t, true, tracks[0].RTCPMux())
+
+	l1, err := net.ListenPacket("udp", "localhost:35466")
+	require.NoError(t, err)
+	defer l1.Close()
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Setup,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"2"},
+			"Transport": headers.Transport{
+				Protocol: headers.TransportProtocolUDP,
+				Delivery: func() *headers.TransportDelivery {
+					v := headers.TransportDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				ClientPorts: &[2]int{35466, 35467},
+				RTCPMux:     true,
+			}.Write(),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var th headers.Transport
+	err = th.Read(res.Header["Transport"])
+	require.NoError(t, err)
+	require.Equal(t, true, th.RTCPMux)
+	require.Equal(t, &[2]int{35466, 35466}, th.ClientPorts)
+	require.Equal(t, &[2]int{8000, 8000}, th.ServerPorts)
+
+	var sx headers.Session
+	err = sx.Read(res.Header["Session"])
+	require.NoError(t, err)
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Play,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"3"},
+			"Session": base.HeaderValue{sx.Session},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// client -> server, RTCP on the RTP port
+	l1.WriteTo(testRTCPPacketMarshaled, &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: th.ServerPorts[0],
+	})
+
+	<-rtcpRecv
+
+	stream.WritePacketRTP(0, &testRTPPacket, true)
+
+	// server -> client, RTP and RTCP on the same port
+	for {
+		buf := make([]byte, 2048)
+		n, _, err := l1.ReadFrom(buf)
 		require.NoError(t, err)
-		require.Equal(t, base.StatusBadRequest, res.StatusCode)
-	}()
+
+		if !isRTCPPacket(buf[:n]) {
+			require.Equal(t, testRTPPacketMarshaled, buf[:n])
+			break
+		}
+	}
 }
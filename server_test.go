@@ -3,8 +3,10 @@ package gortsplib
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -93,6 +95,8 @@ type testServerHandler struct {
 	onConnClose    func(*ServerHandlerOnConnCloseCtx)
 	onSessionOpen  func(*ServerHandlerOnSessionOpenCtx)
 	onSessionClose func(*ServerHandlerOnSessionCloseCtx)
+	onAuthorize    func(*ServerHandlerOnAuthorizeCtx) error
+	onLimit        func(*ServerHandlerOnLimitExceededCtx) bool
 	onDescribe     func(*ServerHandlerOnDescribeCtx) (*base.Response, *ServerStream, error)
 	onAnnounce     func(*ServerHandlerOnAnnounceCtx) (*base.Response, error)
 	onSetup        func(*ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error)
@@ -101,6 +105,7 @@ type testServerHandler struct {
 	onPause        func(*ServerHandlerOnPauseCtx) (*base.Response, error)
 	onPacketRTP    func(*ServerHandlerOnPacketRTPCtx)
 	onPacketRTCP   func(*ServerHandlerOnPacketRTCPCtx)
+	onReaderRTCP   func(*ServerHandlerOnReaderPacketRTCPCtx)
 	onSetParameter func(*ServerHandlerOnSetParameterCtx) (*base.Response, error)
 	onGetParameter func(*ServerHandlerOnGetParameterCtx) (*base.Response, error)
 }
@@ -129,6 +134,20 @@ func (sh *testServerHandler) OnSessionClose(ctx *ServerHandlerOnSessionCloseCtx)
 	}
 }
 
+func (sh *testServerHandler) OnLimitExceeded(ctx *ServerHandlerOnLimitExceededCtx) bool {
+	if sh.onLimit != nil {
+		return sh.onLimit(ctx)
+	}
+	return false
+}
+
+func (sh *testServerHandler) OnAuthorize(ctx *ServerHandlerOnAuthorizeCtx) error {
+	if sh.onAuthorize != nil {
+		return sh.onAuthorize(ctx)
+	}
+	return nil
+}
+
 func (sh *testServerHandler) OnDescribe(ctx *ServerHandlerOnDescribeCtx) (*base.Response, *ServerStream, error) {
 	if sh.onDescribe != nil {
 		return sh.onDescribe(ctx)
@@ -183,6 +202,12 @@ func (sh *testServerHandler) OnPacketRTCP(ctx *ServerHandlerOnPacketRTCPCtx) {
 	}
 }
 
+func (sh *testServerHandler) OnReaderPacketRTCP(ctx *ServerHandlerOnReaderPacketRTCPCtx) {
+	if sh.onReaderRTCP != nil {
+		sh.onReaderRTCP(ctx)
+	}
+}
+
 func (sh *testServerHandler) OnSetParameter(ctx *ServerHandlerOnSetParameterCtx) (*base.Response, error) {
 	if sh.onSetParameter != nil {
 		return sh.onSetParameter(ctx)
@@ -975,3 +1000,298 @@ func TestServerAuth(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, base.StatusOK, res.StatusCode)
 }
+
+type testCredentialStore struct{}
+
+func (testCredentialStore) Lookup(user string, realm string, path string, method base.Method) (*auth.Credentials, error) {
+	switch user {
+	case "reader", "publisher":
+		return &auth.Credentials{Pass: "testpass"}, nil
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func TestServerAuthCredentialStore(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onAuthorize: func(ctx *ServerHandlerOnAuthorizeCtx) error {
+				require.Equal(t, "teststream", ctx.Path)
+				if ctx.Action == ServerAuthActionPublish && ctx.User != "publisher" {
+					return fmt.Errorf("user can't publish")
+				}
+				return nil
+			},
+			onDescribe: func(ctx *ServerHandlerOnDescribeCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onAnnounce: func(ctx *ServerHandlerOnAnnounceCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		RTSPAddress:         "localhost:8554",
+		AuthCredentialStore: testCredentialStore{},
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, ca := range []struct {
+		name   string
+		method base.Method
+		user   string
+		status base.StatusCode
+	}{
+		{"describe wrong user", base.Describe, "other", base.StatusUnauthorized},
+		{"describe reader", base.Describe, "reader", base.StatusOK},
+		{"announce reader", base.Announce, "reader", base.StatusForbidden},
+		{"announce publisher", base.Announce, "publisher", base.StatusOK},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			br := bufio.NewReader(conn)
+
+			req := base.Request{
+				Method: ca.method,
+				URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq": base.HeaderValue{"1"},
+				},
+			}
+
+			if ca.method == base.Announce {
+				req.Header["Content-Type"] = base.HeaderValue{"application/sdp"}
+				req.Body = Tracks{track}.Write(false)
+			}
+
+			res, err := writeReqReadRes(conn, br, req)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusUnauthorized, res.StatusCode)
+
+			sender, err := auth.NewSender(res.Header["WWW-Authenticate"], ca.user, "testpass")
+			require.NoError(t, err)
+
+			req.Header["CSeq"] = base.HeaderValue{"2"}
+			sender.AddAuthorization(&req)
+
+			res, err = writeReqReadRes(conn, br, req)
+			require.NoError(t, err)
+			require.Equal(t, ca.status, res.StatusCode)
+		})
+	}
+}
+
+func TestServerAuthBearer(t *testing.T) {
+	track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	s := &Server{
+		Handler: &testServerHandler{
+			onAuthorize: func(ctx *ServerHandlerOnAuthorizeCtx) error {
+				require.Equal(t, "myuser", ctx.User)
+				return nil
+			},
+			onDescribe: func(ctx *ServerHandlerOnDescribeCtx) (*base.Response, *ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+		},
+		RTSPAddress: "localhost:8554",
+		AuthBearerValidator: func(token string, path string) (string, error) {
+			require.Equal(t, "teststream", path)
+			if token != "validtoken" {
+				return "", fmt.Errorf("invalid token")
+			}
+			return "myuser", nil
+		},
+	}
+
+	err = s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	res, err := writeReqReadRes(conn, br, base.Request{
+		Method: base.Describe,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusUnauthorized, res.StatusCode)
+	require.Equal(t, base.HeaderValue{`Bearer realm="IPCAM"`}, res.Header["WWW-Authenticate"])
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Describe,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":          base.HeaderValue{"2"},
+			"Authorization": base.HeaderValue{"Bearer wrongtoken"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusUnauthorized, res.StatusCode)
+	require.Equal(t, base.HeaderValue{`Bearer realm="IPCAM", error="invalid_token"`}, res.Header["WWW-Authenticate"])
+
+	res, err = writeReqReadRes(conn, br, base.Request{
+		Method: base.Describe,
+		URL:    mustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":          base.HeaderValue{"3"},
+			"Authorization": base.HeaderValue{"Bearer validtoken"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+}
+
+func TestServerLimits(t *testing.T) {
+	for _, ca := range []string{
+		"connections",
+		"sessions",
+		"sessions per ip",
+		"readers per stream",
+		"override",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			track, err := NewTrackH264(96, []byte{0x01, 0x02, 0x03, 0x04}, []byte{0x01, 0x02, 0x03, 0x04}, nil)
+			require.NoError(t, err)
+
+			stream := NewServerStream(Tracks{track})
+			defer stream.Close()
+
+			s := &Server{
+				Handler: &testServerHandler{
+					onSetup: func(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream, nil
+					},
+					onLimit: func(ctx *ServerHandlerOnLimitExceededCtx) bool {
+						return ca == "override" && ctx.Limit == ServerLimitSessions
+					},
+				},
+				RTSPAddress: "localhost:8554",
+			}
+
+			switch ca {
+			case "connections":
+				s.MaxConnections = 1
+
+			case "sessions", "override":
+				s.MaxSessions = 1
+
+			case "sessions per ip":
+				s.MaxSessionsPerIP = 1
+
+			case "readers per stream":
+				s.MaxReadersPerStream = 1
+			}
+
+			err = s.Start()
+			require.NoError(t, err)
+			defer s.Close()
+
+			setup := func(conn net.Conn, br *bufio.Reader) *base.Response {
+				res, err := writeReqReadRes(conn, br, base.Request{
+					Method: base.Setup,
+					URL:    mustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+					Header: base.Header{
+						"CSeq": base.HeaderValue{"1"},
+						"Transport": headers.Transport{
+							Protocol: headers.TransportProtocolTCP,
+							Delivery: func() *headers.TransportDelivery {
+								v := headers.TransportDeliveryUnicast
+								return &v
+							}(),
+							Mode: func() *headers.TransportMode {
+								v := headers.TransportModePlay
+								return &v
+							}(),
+							InterleavedIDs: &[2]int{0, 1},
+						}.Write(),
+					},
+				})
+				require.NoError(t, err)
+				return res
+			}
+
+			conn1, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn1.Close()
+
+			res := setup(conn1, bufio.NewReader(conn1))
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			conn2, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn2.Close()
+
+			res = setup(conn2, bufio.NewReader(conn2))
+
+			switch ca {
+			case "connections", "sessions":
+				require.Equal(t, base.StatusServiceUnavailable, res.StatusCode)
+
+			case "sessions per ip", "readers per stream":
+				require.Equal(t, base.StatusNotEnoughBandwidth, res.StatusCode)
+
+			case "override":
+				require.Equal(t, base.StatusOK, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestServerLimitsPendingRejections(t *testing.T) {
+	s := &Server{
+		Handler:        &testServerHandler{},
+		RTSPAddress:    "localhost:8554",
+		MaxConnections: 1,
+	}
+
+	err := s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn1, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn1.Close()
+
+	// rejected connections that don't send any request
+	for i := 0; i < serverMaxPendingRejections; i++ {
+		conn, err := net.Dial("tcp", "localhost:8554")
+		require.NoError(t, err)
+		defer conn.Close()
+	}
+
+	// further connections are closed without waiting for a request
+	conn2, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn2.Close()
+
+	conn2.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn2.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/pion/rtcp"
 
 	"github.com/aler9/gortsplib/pkg/base"
+	"github.com/aler9/gortsplib/pkg/headers"
 	"github.com/aler9/gortsplib/pkg/liberrors"
 	"github.com/aler9/gortsplib/pkg/url"
 )
@@ -190,7 +191,7 @@ func (sc *ServerConn) readFuncStandard(readRequest chan readReq) error {
 	var req base.Request
 
 	for {
-		err := req.Read(sc.br)
+		err := req.ReadIgnoreResponses(sc.br)
 		if err != nil {
 			return err
 		}
@@ -233,14 +234,8 @@ func (sc *ServerConn) readFuncTCP(readRequest chan readReq) error {
 					return err
 				}
 
-				if h, ok := sc.s.Handler.(ServerHandlerOnPacketRTCP); ok {
-					for _, pkt := range packets {
-						h.OnPacketRTCP(&ServerHandlerOnPacketRTCPCtx{
-							Session: sc.session,
-							TrackID: trackID,
-							Packet:  pkt,
-						})
-					}
+				for _, pkt := range packets {
+					sc.session.onPacketRTCP(trackID, pkt)
 				}
 			}
 
@@ -262,15 +257,22 @@ func (sc *ServerConn) readFuncTCP(readRequest chan readReq) error {
 					return err
 				}
 
+				sc.session.setuppedTracks[trackID].rtcpReceiver.ProcessPacketRTP(time.Now(), pkt,
+					len(out) != 0 && out[0].PTSEqualsDTS)
+
 				if h, ok := sc.s.Handler.(ServerHandlerOnPacketRTP); ok {
+					ntp, ntpSynchronized := sc.session.setuppedTracks[trackID].rtcpReceiver.PacketNTP(pkt.Timestamp)
+
 					for _, entry := range out {
 						h.OnPacketRTP(&ServerHandlerOnPacketRTPCtx{
-							Session:      sc.session,
-							TrackID:      trackID,
-							Packet:       entry.Packet,
-							PTSEqualsDTS: entry.PTSEqualsDTS,
-							H264NALUs:    entry.H264NALUs,
-							H264PTS:      entry.H264PTS,
+							Session:         sc.session,
+							TrackID:         trackID,
+							Packet:          entry.Packet,
+							PTSEqualsDTS:    entry.PTSEqualsDTS,
+							H264NALUs:       entry.H264NALUs,
+							H264PTS:         entry.H264PTS,
+							NTP:             ntp,
+							NTPSynchronized: ntpSynchronized,
 						})
 					}
 				}
@@ -285,7 +287,10 @@ func (sc *ServerConn) readFuncTCP(readRequest chan readReq) error {
 					return err
 				}
 
+				now := time.Now()
+
 				for _, pkt := range packets {
+					sc.session.setuppedTracks[trackID].rtcpReceiver.ProcessPacketRTCP(now, pkt)
 					sc.session.onPacketRTCP(trackID, pkt)
 				}
 			}
@@ -295,6 +300,7 @@ func (sc *ServerConn) readFuncTCP(readRequest chan readReq) error {
 	}
 
 	var req base.Request
+	var res base.Response
 	var frame base.InterleavedFrame
 
 	for {
@@ -302,7 +308,8 @@ func (sc *ServerConn) readFuncTCP(readRequest chan readReq) error {
 			sc.conn.SetReadDeadline(time.Now().Add(sc.s.ReadTimeout))
 		}
 
-		what, err := base.ReadInterleavedFrameOrRequest(&frame, tcpMaxFramePayloadSize, &req, sc.br)
+		what, err := base.ReadInterleavedFrameOrRequestOrResponse(&frame, tcpMaxFramePayloadSize,
+			&req, &res, sc.br)
 		if err != nil {
 			return err
 		}
@@ -318,7 +325,15 @@ func (sc *ServerConn) readFuncTCP(readRequest chan readReq) error {
 
 			// forward frame only if it has been set up
 			if trackID, ok := sc.session.tcpTracksByChannel[channel]; ok {
-				err := processFunc(trackID, isRTP, frame.Payload)
+				payload := frame.Payload
+				if ctx := sc.session.setuppedTracks[trackID].srtpReadContext; ctx != nil {
+					payload, err = srtpDecrypt(ctx, isRTP, payload)
+					if err != nil {
+						continue
+					}
+				}
+
+				err := processFunc(trackID, isRTP, payload)
 				if err != nil {
 					return err
 				}
@@ -336,6 +351,9 @@ func (sc *ServerConn) readFuncTCP(readRequest chan readReq) error {
 			case <-sc.ctx.Done():
 				return liberrors.ErrServerTerminated{}
 			}
+
+		case *base.Response:
+			// responses to requests sent by the server (REDIRECT) are discarded
 		}
 	}
 }
@@ -400,6 +418,10 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 
 			path, query := url.PathSplitQuery(pathAndQuery)
 
+			if res := sc.authenticate(req, path, query, ServerAuthActionRead); res != nil {
+				return res, nil
+			}
+
 			res, stream, err := h.OnDescribe(&ServerHandlerOnDescribeCtx{
 				Conn:    sc,
 				Request: req,
@@ -520,6 +542,109 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 	}, liberrors.ErrServerUnhandledRequest{Request: req}
 }
 
+func (sc *ServerConn) authenticateUser(req *base.Request, path string) (string, error) {
+	var auth headers.Authorization
+	err := auth.Read(req.Header["Authorization"])
+	if err != nil {
+		return "", err
+	}
+
+	switch auth.Method {
+	case headers.AuthBearer:
+		if sc.s.AuthBearerValidator == nil {
+			return "", fmt.Errorf("unsupported authentication method")
+		}
+
+		return sc.s.AuthBearerValidator(auth.BearerToken, path)
+
+	default:
+		if sc.s.authValidator == nil {
+			return "", fmt.Errorf("unsupported authentication method")
+		}
+
+		err := sc.s.authValidator.ValidateRequestPath(req, path)
+		if err != nil {
+			return "", err
+		}
+
+		if auth.Method == headers.AuthBasic {
+			return auth.BasicUser, nil
+		}
+		return *auth.DigestValues.Username, nil
+	}
+}
+
+func (sc *ServerConn) authenticateHeader(req *base.Request, err error) base.HeaderValue {
+	var ret base.HeaderValue
+
+	if sc.s.authValidator != nil {
+		ret = append(ret, sc.s.authValidator.HeaderForError(err)...)
+	}
+
+	if sc.s.AuthBearerValidator != nil {
+		h := headers.Authenticate{
+			Method: headers.AuthBearer,
+			Realm: func() *string {
+				v := "IPCAM"
+				return &v
+			}(),
+		}
+
+		// notify the client that the provided token was rejected
+		var auth headers.Authorization
+		if auth.Read(req.Header["Authorization"]) == nil && auth.Method == headers.AuthBearer {
+			v := "invalid_token"
+			h.Error = &v
+		}
+
+		ret = append(ret, h.Write()...)
+	}
+
+	return ret
+}
+
+// authenticate authenticates and authorizes a request before it is routed to the handler.
+// It returns a response if the request must be rejected.
+func (sc *ServerConn) authenticate(
+	req *base.Request,
+	path string,
+	query string,
+	action ServerAuthAction,
+) *base.Response {
+	user := ""
+
+	if sc.s.authValidator != nil || sc.s.AuthBearerValidator != nil {
+		var err error
+		user, err = sc.authenticateUser(req, path)
+		if err != nil {
+			return &base.Response{
+				StatusCode: base.StatusUnauthorized,
+				Header: base.Header{
+					"WWW-Authenticate": sc.authenticateHeader(req, err),
+				},
+			}
+		}
+	}
+
+	if h, ok := sc.s.Handler.(ServerHandlerOnAuthorize); ok {
+		err := h.OnAuthorize(&ServerHandlerOnAuthorizeCtx{
+			Conn:    sc,
+			Request: req,
+			Path:    path,
+			Query:   query,
+			Action:  action,
+			User:    user,
+		})
+		if err != nil {
+			return &base.Response{
+				StatusCode: base.StatusForbidden,
+			}
+		}
+	}
+
+	return nil
+}
+
 func (sc *ServerConn) handleRequestOuter(req *base.Request) error {
 	if h, ok := sc.s.Handler.(ServerHandlerOnRequest); ok {
 		h.OnRequest(sc, req)
@@ -539,6 +664,14 @@ func (sc *ServerConn) handleRequestOuter(req *base.Request) error {
 	// add server
 	res.Header["Server"] = base.HeaderValue{"gortsplib"}
 
+	// reply with the same protocol version of the request
+	res.Version = req.Version
+
+	// RTSP 2.0: add pipelined requests
+	if v, ok := req.Header["Pipelined-Requests"]; ok && req.Version == base.Version20 {
+		res.Header["Pipelined-Requests"] = v
+	}
+
 	if h, ok := sc.s.Handler.(ServerHandlerOnResponse); ok {
 		h.OnResponse(sc, res)
 	}
@@ -0,0 +1,87 @@
+package gortsplib
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+type serverGOPCacheEntry struct {
+	sequenceNumber uint16
+	timestamp      uint32
+	ssrc           uint32
+	roc            uint32
+	byts           []byte
+}
+
+// serverGOPCache stores the RTP packets of a track since the last key frame,
+// in order to send them to new readers, that can start decoding immediately.
+type serverGOPCache struct {
+	maxPackets int
+
+	mutex   sync.Mutex
+	started bool // a key frame has been received
+	entries []serverGOPCacheEntry
+}
+
+func newServerGOPCache(maxPackets int) *serverGOPCache {
+	return &serverGOPCache{
+		maxPackets: maxPackets,
+	}
+}
+
+// removeUntil removes the entries that precede the first one with the given timestamp.
+func (c *serverGOPCache) removeUntil(timestamp uint32) {
+	for i, e := range c.entries {
+		if e.timestamp == timestamp {
+			c.entries = c.entries[i:]
+			return
+		}
+	}
+	c.entries = nil
+}
+
+func (c *serverGOPCache) processPacket(pkt *rtp.Packet, byts []byte, roc uint32, ptsEqualsDTS bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = append(c.entries, serverGOPCacheEntry{
+		sequenceNumber: pkt.SequenceNumber,
+		timestamp:      pkt.Timestamp,
+		ssrc:           pkt.SSRC,
+		roc:            roc,
+		byts:           byts,
+	})
+
+	switch {
+	// ptsEqualsDTS is true in the last packet of a key frame,
+	// while the key frame begins with the first packet with the same timestamp.
+	case ptsEqualsDTS:
+		c.removeUntil(pkt.Timestamp)
+		c.started = true
+
+	// before the first key frame, keep the packets of the current frame only,
+	// since they may belong to a key frame.
+	case !c.started:
+		c.removeUntil(pkt.Timestamp)
+	}
+
+	// key frames are too rare: stop caching until the next one.
+	if len(c.entries) > c.maxPackets {
+		c.entries = nil
+		c.started = false
+	}
+}
+
+// cached returns the packets since the last key frame.
+func (c *serverGOPCache) cached() []serverGOPCacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.started {
+		return nil
+	}
+
+	// entries are never modified in place, therefore they can be read without locks.
+	return c.entries
+}
@@ -1,12 +1,14 @@
 package gortsplib
 
 import (
+	"net"
 	"time"
 
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 
 	"github.com/aler9/gortsplib/pkg/base"
+	"github.com/aler9/gortsplib/pkg/headers"
 )
 
 // ServerHandler is the interface implemented by all the server handlers.
@@ -33,6 +35,40 @@ type ServerHandlerOnConnClose interface {
 	OnConnClose(*ServerHandlerOnConnCloseCtx)
 }
 
+// ServerLimit is a limit of the server.
+type ServerLimit int
+
+// limits.
+const (
+	// Server.MaxConnections.
+	ServerLimitConnections ServerLimit = iota
+
+	// Server.MaxSessions.
+	ServerLimitSessions
+
+	// Server.MaxSessionsPerIP.
+	ServerLimitSessionsPerIP
+
+	// Server.MaxReadersPerStream.
+	ServerLimitReadersPerStream
+)
+
+// ServerHandlerOnLimitExceededCtx is the context of an exceeded limit.
+type ServerHandlerOnLimitExceededCtx struct {
+	Limit ServerLimit
+
+	// address of the client that exceeded the limit.
+	RemoteAddr net.Addr
+}
+
+// ServerHandlerOnLimitExceeded can be implemented by a ServerHandler.
+type ServerHandlerOnLimitExceeded interface {
+	// called when a connection, a session or a reader would exceed a limit.
+	// it must return true to accept it anyway, false to reject it.
+	// it must not block, since it is called by the server routines.
+	OnLimitExceeded(*ServerHandlerOnLimitExceededCtx) bool
+}
+
 // ServerHandlerOnSessionOpenCtx is the context of a session opening.
 type ServerHandlerOnSessionOpenCtx struct {
 	Session *ServerSession
@@ -65,6 +101,40 @@ type ServerHandlerOnResponse interface {
 	OnResponse(*ServerConn, *base.Response)
 }
 
+// ServerAuthAction is an action that requires an authorization.
+type ServerAuthAction int
+
+// actions.
+const (
+	// read a stream (DESCRIBE, SETUP in play mode).
+	ServerAuthActionRead ServerAuthAction = iota
+
+	// publish a stream (ANNOUNCE, SETUP in record mode).
+	ServerAuthActionPublish
+)
+
+// ServerHandlerOnAuthorizeCtx is the context of an authorization decision.
+type ServerHandlerOnAuthorizeCtx struct {
+	Conn    *ServerConn
+	Request *base.Request
+	Path    string
+	Query   string
+	Action  ServerAuthAction
+
+	// user authenticated with Server.AuthCredentialStore or Server.AuthBearerValidator.
+	// It is empty if both are not filled.
+	User string
+}
+
+// ServerHandlerOnAuthorize can be implemented by a ServerHandler.
+type ServerHandlerOnAuthorize interface {
+	// called before OnDescribe, OnAnnounce and OnSetup, after the request
+	// has been authenticated.
+	// it must return nil to allow the action, otherwise the request
+	// is rejected with code 403.
+	OnAuthorize(*ServerHandlerOnAuthorizeCtx) error
+}
+
 // ServerHandlerOnDescribeCtx is the context of a DESCRIBE request.
 type ServerHandlerOnDescribeCtx struct {
 	Conn    *ServerConn
@@ -112,6 +182,8 @@ type ServerHandlerOnSetup interface {
 	// the stream is needed to
 	// - add the session the the stream's readers
 	// - send the stream SSRC to the session
+	// with RTSP 2.0, the response can contain the Accept-Ranges and Media-Properties
+	// headers; if they are not set, they are filled with the ones of a live stream.
 	OnSetup(*ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error)
 }
 
@@ -122,6 +194,16 @@ type ServerHandlerOnPlayCtx struct {
 	Request *base.Request
 	Path    string
 	Query   string
+
+	// requested playback rate, nil if not provided by the client.
+	// The granted rate can be confirmed by setting the Scale header of the response.
+	Scale *headers.Scale
+	// requested delivery rate, nil if not provided by the client.
+	// The granted rate can be confirmed by setting the Speed header of the response.
+	Speed *headers.Speed
+	// requested range, nil if not provided by the client or if it can't be decoded.
+	// It can be used to read from a time-shift buffer or to seek.
+	Range *headers.Range
 }
 
 // ServerHandlerOnPlay can be implemented by a ServerHandler.
@@ -192,6 +274,11 @@ type ServerHandlerOnPacketRTPCtx struct {
 	PTSEqualsDTS bool
 	H264NALUs    [][]byte
 	H264PTS      time.Duration
+
+	// absolute time of the packet, computed with the latest RTCP sender report.
+	NTP time.Time
+	// whether NTP is filled, that happens after the first RTCP sender report.
+	NTPSynchronized bool
 }
 
 // ServerHandlerOnPacketRTP can be implemented by a ServerHandler.
@@ -210,3 +297,19 @@ type ServerHandlerOnPacketRTCPCtx struct {
 type ServerHandlerOnPacketRTCP interface {
 	OnPacketRTCP(*ServerHandlerOnPacketRTCPCtx)
 }
+
+// ServerHandlerOnReaderPacketRTCPCtx is the context of a RTCP packet sent by a reader.
+type ServerHandlerOnReaderPacketRTCPCtx struct {
+	Session *ServerSession
+	Stream  *ServerStream
+	TrackID int
+	Packet  rtcp.Packet
+}
+
+// ServerHandlerOnReaderPacketRTCP can be implemented by a ServerHandler.
+// It allows to receive RTCP packets sent by the readers of a ServerStream,
+// like receiver reports and keyframe requests (PLI, FIR) or NACKs,
+// in order to forward them to the publisher of the stream.
+type ServerHandlerOnReaderPacketRTCP interface {
+	OnReaderPacketRTCP(*ServerHandlerOnReaderPacketRTCPCtx)
+}
@@ -0,0 +1,437 @@
+package gortsplib
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+
+	"github.com/aler9/gortsplib/pkg/base"
+	"github.com/aler9/gortsplib/pkg/h264"
+	"github.com/aler9/gortsplib/pkg/headers"
+	"github.com/aler9/gortsplib/pkg/rtcpsender"
+	"github.com/aler9/gortsplib/pkg/rtpaac"
+	"github.com/aler9/gortsplib/pkg/rtph264"
+	"github.com/aler9/gortsplib/pkg/url"
+)
+
+// ServerMediaAccessUnit is an access unit read from a ServerMediaSource.
+type ServerMediaAccessUnit struct {
+	// ID of the track.
+	TrackID int
+
+	// presentation timestamp, relative to the beginning of the media.
+	PTS time.Duration
+
+	// content of the access unit:
+	// - NALUs with H264 tracks
+	// - AUs with AAC tracks
+	// - payloads of RTP packets with other tracks
+	Units [][]byte
+}
+
+// ServerMediaSource is a source of media that can be served on demand.
+type ServerMediaSource interface {
+	// Duration returns the duration of the media.
+	Duration() time.Duration
+
+	// Seek moves the reading position to the key frame that precedes the given position,
+	// and returns the position of the key frame.
+	Seek(time.Duration) (time.Duration, error)
+
+	// ReadAccessUnit returns the next access unit, in order of PTS.
+	// It returns an error (usually io.EOF) when the end of the media has been reached.
+	ReadAccessUnit() (*ServerMediaAccessUnit, error)
+}
+
+type serverMediaPlayerTrack struct {
+	clockRate          int
+	initialTimestamp   uint32
+	nextSequenceNumber uint16
+	ssrc               uint32
+	encode             func([][]byte, time.Duration) ([]*rtp.Packet, error)
+	isH264             bool
+	rtcpSender         *rtcpsender.RTCPSender
+}
+
+func newServerMediaPlayerTrack(track Track) (*serverMediaPlayerTrack, error) {
+	pt := &serverMediaPlayerTrack{
+		clockRate:          track.ClockRate(),
+		initialTimestamp:   randUint32(),
+		nextSequenceNumber: uint16(randUint32()),
+		ssrc:               randUint32(),
+	}
+
+	switch tt := track.(type) {
+	case *TrackH264:
+		enc := &rtph264.Encoder{
+			PayloadType:           tt.payloadType,
+			SSRC:                  &pt.ssrc,
+			InitialSequenceNumber: &pt.nextSequenceNumber,
+			InitialTimestamp:      &pt.initialTimestamp,
+		}
+		enc.Init()
+		pt.encode = enc.Encode
+		pt.isH264 = true
+
+	case *TrackAAC:
+		enc := &rtpaac.Encoder{
+			PayloadType:           tt.payloadType,
+			SSRC:                  &pt.ssrc,
+			InitialSequenceNumber: &pt.nextSequenceNumber,
+			InitialTimestamp:      &pt.initialTimestamp,
+			SampleRate:            tt.ClockRate(),
+			SizeLength:            tt.SizeLength(),
+			IndexLength:           tt.IndexLength(),
+			IndexDeltaLength:      tt.IndexDeltaLength(),
+		}
+		enc.Init()
+		pt.encode = enc.Encode
+
+	default:
+		formats := track.MediaDescription().MediaName.Formats
+		if len(formats) == 0 {
+			return nil, fmt.Errorf("unable to find the payload type of the track")
+		}
+
+		tmp, err := strconv.ParseUint(formats[0], 10, 8)
+		if err != nil {
+			return nil, err
+		}
+		payloadType := uint8(tmp)
+
+		sequenceNumber := pt.nextSequenceNumber
+
+		// each unit is the payload of a packet
+		pt.encode = func(units [][]byte, pts time.Duration) ([]*rtp.Packet, error) {
+			ret := make([]*rtp.Packet, len(units))
+			for i, unit := range units {
+				ret[i] = &rtp.Packet{
+					Header: rtp.Header{
+						Version:        2,
+						PayloadType:    payloadType,
+						SequenceNumber: sequenceNumber,
+						Timestamp:      pt.timestamp(pts),
+						SSRC:           pt.ssrc,
+						Marker:         i == len(units)-1,
+					},
+					Payload: unit,
+				}
+				sequenceNumber++
+			}
+			return ret, nil
+		}
+	}
+
+	return pt, nil
+}
+
+// timestamp converts a PTS into a RTP timestamp.
+// The conversion is performed with integers, since the timestamp wraps around
+// and converting a float that doesn't fit into an uint32 is undefined.
+func (pt *serverMediaPlayerTrack) timestamp(pts time.Duration) uint32 {
+	secs := uint64(pts / time.Second)
+	dec := uint64(pts % time.Second)
+	cr := uint64(pt.clockRate)
+	return pt.initialTimestamp + uint32(secs*cr+dec*cr/uint64(time.Second))
+}
+
+// ServerMediaPlayer serves a ServerMediaSource to a session (video on demand).
+// It handles pacing, seeking with the Range header, pausing and resuming,
+// and the end of the media, and fills the Range and RTP-Info headers of PLAY responses.
+//
+// A ServerMediaPlayer must be allocated for each session:
+// its stream must be returned by OnSetup(), and its OnPlay() and OnPause() methods
+// must be called by the handler's ones. It must be closed when the session is closed.
+type ServerMediaPlayer struct {
+	//
+	// parameters
+	//
+	// tracks of the media.
+	Tracks Tracks
+	// source of the media.
+	Source ServerMediaSource
+
+	//
+	// callbacks
+	//
+	// called when the end of the media is reached.
+	OnEnd func()
+
+	stream   *ServerStream
+	ss       *ServerSession
+	pTracks  []*serverMediaPlayerTrack
+	started  bool
+	position time.Duration
+	pending  *ServerMediaAccessUnit
+
+	// in
+	terminate chan struct{}
+
+	// out
+	done chan struct{}
+}
+
+// Start initializes the player.
+func (p *ServerMediaPlayer) Start() error {
+	if p.OnEnd == nil {
+		p.OnEnd = func() {}
+	}
+
+	p.pTracks = make([]*serverMediaPlayerTrack, len(p.Tracks))
+	for trackID, track := range p.Tracks {
+		var err error
+		p.pTracks[trackID], err = newServerMediaPlayerTrack(track)
+		if err != nil {
+			return err
+		}
+	}
+
+	p.stream = NewServerStream(p.Tracks)
+
+	return nil
+}
+
+// Close stops the player.
+func (p *ServerMediaPlayer) Close() error {
+	p.stop()
+
+	for _, pt := range p.pTracks {
+		if pt.rtcpSender != nil {
+			pt.rtcpSender.Close()
+		}
+	}
+
+	p.stream.Close()
+	return nil
+}
+
+// Stream returns the stream of the player, that must be returned by OnDescribe() and OnSetup().
+func (p *ServerMediaPlayer) Stream() *ServerStream {
+	return p.stream
+}
+
+// OnPlay handles a PLAY request.
+// The reading position can be set with a Range header in NPT units,
+// otherwise reading resumes from the position in which it was paused.
+func (p *ServerMediaPlayer) OnPlay(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+	if p.ss == nil {
+		p.ss = ctx.Session
+
+		for trackID, pt := range p.pTracks {
+			cTrackID := trackID
+			pt.rtcpSender = rtcpsender.New(p.ss.s.senderReportPeriod, pt.clockRate,
+				func(pkt rtcp.Packet) {
+					p.ss.WritePacketRTCP(cTrackID, pkt)
+				})
+		}
+	} else if p.ss != ctx.Session {
+		return &base.Response{
+			StatusCode: base.StatusBadRequest,
+		}, fmt.Errorf("player is used by another session")
+	}
+
+	duration := p.Source.Duration()
+	pos := p.position
+	seek := !p.started
+
+	if ctx.Range != nil {
+		npt, ok := ctx.Range.Value.(*headers.RangeNPT)
+		if !ok {
+			return &base.Response{
+				StatusCode: base.StatusInvalidRange,
+			}, fmt.Errorf("unsupported range unit")
+		}
+
+		pos = time.Duration(npt.Start)
+		if pos > duration {
+			return &base.Response{
+				StatusCode: base.StatusInvalidRange,
+			}, fmt.Errorf("range start (%v) is after the end of the media (%v)", pos, duration)
+		}
+
+		seek = true
+	}
+
+	p.stop()
+
+	if seek {
+		var err error
+		pos, err = p.Source.Seek(pos)
+		if err != nil {
+			return &base.Response{
+				StatusCode: base.StatusInvalidRange,
+			}, err
+		}
+		p.pending = nil
+	}
+
+	p.started = true
+	p.position = pos
+
+	speed := float64(1)
+	if ctx.Speed != nil {
+		speed = float64(*ctx.Speed)
+	}
+
+	res := &base.Response{
+		StatusCode: base.StatusOK,
+		Header: base.Header{
+			"Range": headers.Range{
+				Value: &headers.RangeNPT{
+					Start: headers.RangeNPTTime(pos),
+					End: func() *headers.RangeNPTTime {
+						v := headers.RangeNPTTime(duration)
+						return &v
+					}(),
+				},
+			}.Write(),
+			"RTP-Info": p.rtpInfo(ctx.Request, pos).Write(),
+		},
+	}
+
+	if ctx.Speed != nil {
+		res.Header["Speed"] = ctx.Speed.Write()
+	}
+
+	p.terminate = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run(speed)
+
+	return res, nil
+}
+
+// OnPause handles a PAUSE request.
+func (p *ServerMediaPlayer) OnPause(ctx *ServerHandlerOnPauseCtx) (*base.Response, error) {
+	p.stop()
+
+	// resume from the access unit that has not been sent yet
+	if p.pending != nil {
+		p.position = p.pending.PTS
+	}
+
+	return &base.Response{
+		StatusCode: base.StatusOK,
+	}, nil
+}
+
+func (p *ServerMediaPlayer) rtpInfo(req *base.Request, pos time.Duration) headers.RTPInfo {
+	var ri headers.RTPInfo
+
+	for trackID, pt := range p.pTracks {
+		if _, ok := p.ss.setuppedTracks[trackID]; !ok {
+			continue
+		}
+
+		u := &url.URL{
+			Scheme: req.URL.Scheme,
+			User:   req.URL.User,
+			Host:   req.URL.Host,
+			Path:   "/" + *p.ss.setuppedPath + "/trackID=" + strconv.FormatInt(int64(trackID), 10),
+		}
+
+		seqNum := pt.nextSequenceNumber
+		ts := pt.timestamp(pos)
+
+		ri = append(ri, &headers.RTPInfoEntry{
+			URL:            u.String(),
+			SequenceNumber: &seqNum,
+			Timestamp:      &ts,
+		})
+	}
+
+	return ri
+}
+
+func (p *ServerMediaPlayer) stop() {
+	if p.terminate == nil {
+		return
+	}
+
+	close(p.terminate)
+	<-p.done
+	p.terminate = nil
+}
+
+func (p *ServerMediaPlayer) run(speed float64) {
+	defer close(p.done)
+
+	wallStart := time.Now()
+	posStart := p.position
+
+	for {
+		if p.pending == nil {
+			au, err := p.Source.ReadAccessUnit()
+			if err != nil {
+				p.end()
+				return
+			}
+
+			if au.TrackID < 0 || au.TrackID >= len(p.pTracks) {
+				continue
+			}
+
+			p.pending = au
+		}
+
+		au := p.pending
+
+		wait := time.Duration(float64(au.PTS-posStart)/speed) - time.Since(wallStart)
+		if wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-p.terminate:
+				t.Stop()
+				return
+			case <-p.ss.ctx.Done():
+				t.Stop()
+				return
+			}
+		} else {
+			select {
+			case <-p.terminate:
+				return
+			case <-p.ss.ctx.Done():
+				return
+			default:
+			}
+		}
+
+		p.writeAccessUnit(au)
+		p.position = au.PTS
+		p.pending = nil
+	}
+}
+
+func (p *ServerMediaPlayer) writeAccessUnit(au *ServerMediaAccessUnit) {
+	pt := p.pTracks[au.TrackID]
+
+	pkts, err := pt.encode(au.Units, au.PTS)
+	if err != nil || len(pkts) == 0 {
+		return
+	}
+
+	ptsEqualsDTS := !pt.isH264 || h264.IDRPresent(au.Units)
+	now := time.Now()
+
+	for i, pkt := range pkts {
+		p.ss.WritePacketRTP(au.TrackID, pkt)
+		pt.rtcpSender.ProcessPacketRTP(now, pkt, ptsEqualsDTS && i == len(pkts)-1)
+	}
+
+	pt.nextSequenceNumber = pkts[len(pkts)-1].SequenceNumber + 1
+}
+
+// end notifies the reader that the end of the media has been reached.
+func (p *ServerMediaPlayer) end() {
+	for trackID, pt := range p.pTracks {
+		p.ss.WritePacketRTCP(trackID, &rtcp.Goodbye{
+			Sources: []uint32{pt.ssrc},
+		})
+	}
+
+	p.OnEnd()
+}
@@ -16,6 +16,7 @@ type serverMulticastHandler struct {
 	rtpl        *serverUDPListener
 	rtcpl       *serverUDPListener
 	writeBuffer *ringbuffer.RingBuffer
+	source      net.IP
 
 	writerDone chan struct{}
 }
@@ -26,10 +27,22 @@ func newServerMulticastHandler(s *Server) (*serverMulticastHandler, error) {
 		return nil, err
 	}
 
+	// with source-specific multicast, readers must know the source of packets
+	var source net.IP
+	if isSSMAddress(rtpl.ip()) {
+		source, err = multicastOriginIP(s, &net.UDPAddr{IP: rtpl.ip(), Port: rtpl.port()})
+		if err != nil {
+			rtpl.close()
+			rtcpl.close()
+			return nil, err
+		}
+	}
+
 	h := &serverMulticastHandler{
 		rtpl:        rtpl,
 		rtcpl:       rtcpl,
 		writeBuffer: ringbuffer.New(uint64(s.WriteBufferCount)),
+		source:      source,
 		writerDone:  make(chan struct{}),
 	}
 
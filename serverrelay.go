@@ -0,0 +1,441 @@
+package gortsplib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+
+	"github.com/aler9/gortsplib/pkg/base"
+	"github.com/aler9/gortsplib/pkg/url"
+)
+
+// serverRelaySource reads a stream from an upstream server and writes it into a ServerStream.
+type serverRelaySource struct {
+	r    *ServerRelay
+	path string
+	u    *url.URL
+
+	// protected by the mutex of the relay
+	stream    *ServerStream
+	client    *Client
+	readers   map[*ServerSession]struct{}
+	idleTimer *time.Timer
+
+	ctx       context.Context
+	ctxCancel func()
+
+	// out
+	ready chan struct{}
+	done  chan struct{}
+}
+
+func newServerRelaySource(r *ServerRelay, path string, u *url.URL) *serverRelaySource {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+
+	s := &serverRelaySource{
+		r:         r,
+		path:      path,
+		u:         u,
+		readers:   make(map[*ServerSession]struct{}),
+		ctx:       ctx,
+		ctxCancel: ctxCancel,
+		ready:     make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *serverRelaySource) close() {
+	s.ctxCancel()
+	<-s.done
+
+	if s.stream != nil {
+		s.stream.Close()
+	}
+}
+
+func (s *serverRelaySource) run() {
+	defer close(s.done)
+
+	for {
+		err := s.runClient()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		default:
+		}
+
+		s.r.OnSourceError(s.path, err)
+
+		t := time.NewTimer(s.r.ReconnectPeriod)
+		select {
+		case <-t.C:
+		case <-s.ctx.Done():
+			t.Stop()
+			return
+		}
+	}
+}
+
+func (s *serverRelaySource) runClient() error {
+	c := s.r.NewClient()
+
+	var stream *ServerStream
+
+	c.OnPacketRTP = func(ctx *ClientOnPacketRTPCtx) {
+		stream.WritePacketRTP(ctx.TrackID, ctx.Packet, ctx.PTSEqualsDTS)
+	}
+
+	err := c.Start(s.u.Scheme, s.u.Host)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		s.r.mutex.Lock()
+		s.client = nil
+		s.r.mutex.Unlock()
+
+		c.Close()
+	}()
+
+	tracks, baseURL, _, err := c.DescribeContext(s.ctx, s.u)
+	if err != nil {
+		return err
+	}
+
+	stream = s.setTracks(tracks)
+
+	err = c.SetupAndPlayContext(s.ctx, tracks, baseURL)
+	if err != nil {
+		return err
+	}
+
+	s.r.mutex.Lock()
+	s.client = c
+	s.r.mutex.Unlock()
+
+	select {
+	case <-s.ready:
+	default:
+		close(s.ready)
+	}
+
+	readErr := make(chan error)
+	go func() {
+		readErr <- c.Wait()
+	}()
+
+	select {
+	case err := <-readErr:
+		return err
+
+	case <-s.ctx.Done():
+		c.Close()
+		<-readErr
+		return fmt.Errorf("terminated")
+	}
+}
+
+// setTracks returns the stream in which packets of the given tracks are written.
+// After a reconnection, the existing stream is reused when tracks are unchanged,
+// in order not to interrupt readers.
+func (s *serverRelaySource) setTracks(tracks Tracks) *ServerStream {
+	s.r.mutex.Lock()
+	old := s.stream
+
+	if old != nil && serverRelayTracksEqual(old.Tracks(), tracks) {
+		s.r.mutex.Unlock()
+		return old
+	}
+
+	s.stream = NewServerStream(tracks)
+	stream := s.stream
+	s.r.mutex.Unlock()
+
+	// readers of the previous stream are disconnected
+	if old != nil {
+		old.Close()
+	}
+
+	return stream
+}
+
+func serverRelayTracksEqual(a Tracks, b Tracks) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].MediaDescription().MediaName.Media != b[i].MediaDescription().MediaName.Media ||
+			a[i].ClockRate() != b[i].ClockRate() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// waitStream waits until the upstream stream is available.
+func (s *serverRelaySource) waitStream() (*ServerStream, error) {
+	t := time.NewTimer(s.r.ReadyTimeout)
+	defer t.Stop()
+
+	select {
+	case <-s.ready:
+		s.r.mutex.Lock()
+		defer s.r.mutex.Unlock()
+		return s.stream, nil
+
+	case <-t.C:
+		return nil, fmt.Errorf("source of path '%s' is not ready", s.path)
+
+	case <-s.done:
+		return nil, fmt.Errorf("source of path '%s' has been closed", s.path)
+	}
+}
+
+// ServerRelay is a ServerHandler that serves streams read from other servers (proxy).
+// Each path is read from its upstream URL when the first reader arrives,
+// the upstream connection is shared among readers, and it is closed when there are
+// no readers for IdleTimeout. The connection is restored automatically when it fails.
+//
+// ServerRelay can be used directly as the handler of a Server, or its
+// methods can be called by the corresponding methods of another handler.
+type ServerRelay struct {
+	//
+	// parameters
+	//
+	// maps server paths to upstream URLs.
+	Paths map[string]string
+	// function that allocates the clients that read from upstream (optional).
+	// It defaults to a function that returns a Client with default settings.
+	NewClient func() *Client
+	// time to wait for the upstream stream to be available.
+	// It defaults to 10 seconds.
+	ReadyTimeout time.Duration
+	// time after which the upstream connection is closed when there are no readers.
+	// It defaults to 10 seconds.
+	IdleTimeout time.Duration
+	// period between reconnection attempts.
+	// It defaults to 2 seconds.
+	ReconnectPeriod time.Duration
+
+	//
+	// callbacks
+	//
+	// called when the upstream connection of a path fails.
+	OnSourceError func(string, error)
+
+	mutex    sync.Mutex
+	urls     map[string]*url.URL
+	sources  map[string]*serverRelaySource
+	sessions map[*ServerSession]*serverRelaySource
+	closed   bool
+}
+
+// Start initializes the relay.
+func (r *ServerRelay) Start() error {
+	// parameters
+	if r.NewClient == nil {
+		r.NewClient = func() *Client {
+			return &Client{}
+		}
+	}
+	if r.ReadyTimeout == 0 {
+		r.ReadyTimeout = 10 * time.Second
+	}
+	if r.IdleTimeout == 0 {
+		r.IdleTimeout = 10 * time.Second
+	}
+	if r.ReconnectPeriod == 0 {
+		r.ReconnectPeriod = 2 * time.Second
+	}
+
+	// callbacks
+	if r.OnSourceError == nil {
+		r.OnSourceError = func(string, error) {}
+	}
+
+	r.urls = make(map[string]*url.URL)
+	for path, rawURL := range r.Paths {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return fmt.Errorf("invalid URL of path '%s': %v", path, err)
+		}
+		r.urls[path] = u
+	}
+
+	r.sources = make(map[string]*serverRelaySource)
+	r.sessions = make(map[*ServerSession]*serverRelaySource)
+
+	return nil
+}
+
+// Close closes all upstream connections.
+func (r *ServerRelay) Close() error {
+	r.mutex.Lock()
+	r.closed = true
+	sources := r.sources
+	r.sources = make(map[string]*serverRelaySource)
+	r.sessions = make(map[*ServerSession]*serverRelaySource)
+	for _, s := range sources {
+		if s.idleTimer != nil {
+			s.idleTimer.Stop()
+		}
+	}
+	r.mutex.Unlock()
+
+	for _, s := range sources {
+		s.close()
+	}
+
+	return nil
+}
+
+// source returns the source of a path, and starts it if it is not running.
+func (r *ServerRelay) source(path string) (*serverRelaySource, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil, fmt.Errorf("terminated")
+	}
+
+	u, ok := r.urls[path]
+	if !ok {
+		return nil, fmt.Errorf("path '%s' is not configured", path)
+	}
+
+	s, ok := r.sources[path]
+	if !ok {
+		s = newServerRelaySource(r, path, u)
+		r.sources[path] = s
+
+		// a source without readers is closed after IdleTimeout
+		r.startIdleTimer(s)
+	}
+
+	return s, nil
+}
+
+func (r *ServerRelay) startIdleTimer(s *serverRelaySource) {
+	s.idleTimer = time.AfterFunc(r.IdleTimeout, func() {
+		r.mutex.Lock()
+		if len(s.readers) != 0 || r.sources[s.path] != s {
+			r.mutex.Unlock()
+			return
+		}
+		delete(r.sources, s.path)
+		r.mutex.Unlock()
+
+		s.close()
+	})
+}
+
+// OnDescribe implements ServerHandlerOnDescribe.
+func (r *ServerRelay) OnDescribe(ctx *ServerHandlerOnDescribeCtx) (*base.Response, *ServerStream, error) {
+	s, err := r.source(ctx.Path)
+	if err != nil {
+		return &base.Response{
+			StatusCode: base.StatusNotFound,
+		}, nil, err
+	}
+
+	stream, err := s.waitStream()
+	if err != nil {
+		return &base.Response{
+			StatusCode: base.StatusNotFound,
+		}, nil, err
+	}
+
+	return &base.Response{
+		StatusCode: base.StatusOK,
+	}, stream, nil
+}
+
+// OnSetup implements ServerHandlerOnSetup.
+func (r *ServerRelay) OnSetup(ctx *ServerHandlerOnSetupCtx) (*base.Response, *ServerStream, error) {
+	s, err := r.source(ctx.Path)
+	if err != nil {
+		return &base.Response{
+			StatusCode: base.StatusNotFound,
+		}, nil, err
+	}
+
+	stream, err := s.waitStream()
+	if err != nil {
+		return &base.Response{
+			StatusCode: base.StatusNotFound,
+		}, nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.sources[ctx.Path] != s {
+		return &base.Response{
+			StatusCode: base.StatusNotFound,
+		}, nil, fmt.Errorf("source of path '%s' has been closed", ctx.Path)
+	}
+
+	s.readers[ctx.Session] = struct{}{}
+	s.idleTimer.Stop()
+	r.sessions[ctx.Session] = s
+
+	return &base.Response{
+		StatusCode: base.StatusOK,
+	}, stream, nil
+}
+
+// OnPlay implements ServerHandlerOnPlay.
+func (r *ServerRelay) OnPlay(ctx *ServerHandlerOnPlayCtx) (*base.Response, error) {
+	return &base.Response{
+		StatusCode: base.StatusOK,
+	}, nil
+}
+
+// OnSessionClose implements ServerHandlerOnSessionClose.
+func (r *ServerRelay) OnSessionClose(ctx *ServerHandlerOnSessionCloseCtx) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	s, ok := r.sessions[ctx.Session]
+	if !ok {
+		return
+	}
+
+	delete(r.sessions, ctx.Session)
+	delete(s.readers, ctx.Session)
+
+	if len(s.readers) == 0 && r.sources[s.path] == s {
+		r.startIdleTimer(s)
+	}
+}
+
+// OnReaderPacketRTCP implements ServerHandlerOnReaderPacketRTCP.
+// Keyframe requests and NACKs are forwarded to the upstream server.
+func (r *ServerRelay) OnReaderPacketRTCP(ctx *ServerHandlerOnReaderPacketRTCPCtx) {
+	switch ctx.Packet.(type) {
+	case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest, *rtcp.TransportLayerNack:
+	default:
+		return
+	}
+
+	r.mutex.Lock()
+	s, ok := r.sessions[ctx.Session]
+	if !ok || s.stream != ctx.Stream || s.client == nil {
+		r.mutex.Unlock()
+		return
+	}
+	c := s.client
+	r.mutex.Unlock()
+
+	c.WritePacketRTCP(ctx.TrackID, ctx.Packet)
+}
@@ -0,0 +1,214 @@
+package gortsplib
+
+import (
+	"hash/fnv"
+	"math"
+	"net"
+	"time"
+
+	psdp "github.com/pion/sdp/v3"
+
+	"github.com/aler9/gortsplib/pkg/sap"
+)
+
+func sdpAddressType(ip net.IP) string {
+	if ip.To4() != nil {
+		return "IP4"
+	}
+	return "IP6"
+}
+
+// sapMessageIDHash computes the message identifier hash of an announcement,
+// that changes every time the announced SDP changes.
+func sapMessageIDHash(payload []byte) uint16 {
+	h := fnv.New32a()
+	h.Write(payload)
+	v := h.Sum32()
+	hash := uint16(v>>16) ^ uint16(v)
+
+	// zero means that the hash is not used
+	if hash == 0 {
+		hash = 1
+	}
+	return hash
+}
+
+func sapRemoveAttribute(attrs []psdp.Attribute, key string) []psdp.Attribute {
+	var ret []psdp.Attribute
+	for _, attr := range attrs {
+		if attr.Key != key {
+			ret = append(ret, attr)
+		}
+	}
+	return ret
+}
+
+// sapSessionDescription generates the SDP of an announced stream,
+// that contains the multicast group and ports of each track.
+func sapSessionDescription(s *Server, st *ServerStream, sessionName string, originIP net.IP) []byte {
+	sd := st.tracks.sessionDescription(false)
+
+	sessionID := uint64(time.Now().Unix()) + ntpEpochOffset
+
+	sd.SessionName = psdp.SessionName(sessionName)
+	sd.Origin = psdp.Origin{
+		Username:       "-",
+		SessionID:      sessionID,
+		SessionVersion: sessionID,
+		NetworkType:    "IN",
+		AddressType:    sdpAddressType(originIP),
+		UnicastAddress: originIP.String(),
+	}
+
+	// the connection information is provided by each media description
+	sd.ConnectionInformation = nil
+
+	for trackID, md := range sd.MediaDescriptions {
+		h := st.serverMulticastHandlers[trackID]
+		ip := h.ip()
+
+		addr := &psdp.Address{Address: ip.String()}
+		if ip.To4() != nil {
+			// TTL is mandatory with IPv4 multicast addresses
+			ttl := s.MulticastTTL
+			addr.TTL = &ttl
+		}
+
+		// RTCP packets are sent to the port that follows the RTP one
+		md.Attributes = sapRemoveAttribute(md.Attributes, "rtcp-mux")
+
+		md.MediaName.Port = psdp.RangedPort{Value: h.rtpl.port()}
+		md.ConnectionInformation = &psdp.ConnectionInformation{
+			NetworkType: "IN",
+			AddressType: sdpAddressType(ip),
+			Address:     addr,
+		}
+
+		// with source-specific multicast, receivers must know the source of packets (RFC 4570)
+		if isSSMAddress(ip) && (originIP.To4() != nil) == (ip.To4() != nil) {
+			md.Attributes = append(md.Attributes, psdp.Attribute{
+				Key: "source-filter",
+				Value: " incl IN " + sdpAddressType(ip) + " " + ip.String() +
+					" " + originIP.String(),
+			})
+		}
+	}
+
+	byts, _ := sd.Marshal()
+	return byts
+}
+
+// serverSAPAnnouncer periodically announces a stream with SAP.
+type serverSAPAnnouncer struct {
+	pc           net.PacketConn
+	addr         *net.UDPAddr
+	period       time.Duration
+	writeTimeout time.Duration
+	announcement []byte
+	deletion     []byte
+
+	terminate chan struct{}
+	done      chan struct{}
+}
+
+func newServerSAPAnnouncer(s *Server, st *ServerStream, sessionName string) (*serverSAPAnnouncer, error) {
+	addr, err := net.ResolveUDPAddr("udp", s.SAPAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	originIP, err := multicastOriginIP(s, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	sdp := sapSessionDescription(s, st, sessionName, originIP)
+	hash := sapMessageIDHash(sdp)
+
+	announcement, err := sap.Packet{
+		MessageIDHash:     hash,
+		OriginatingSource: originIP,
+		PayloadType:       sap.PayloadTypeSDP,
+		Payload:           sdp,
+	}.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	deletion, err := sap.Packet{
+		Deletion:          true,
+		MessageIDHash:     hash,
+		OriginatingSource: originIP,
+		PayloadType:       sap.PayloadTypeSDP,
+		Payload:           sdp,
+	}.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	pc, err := s.ListenPacket("udp", ":0")
+	if err != nil {
+		return nil, err
+	}
+
+	err = newMulticastPacketConn(pc, addr.IP).SetMulticastTTL(s.MulticastTTL)
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+
+	a := &serverSAPAnnouncer{
+		pc:           pc,
+		addr:         addr,
+		period:       s.SAPAnnouncePeriod,
+		writeTimeout: s.WriteTimeout,
+		announcement: announcement,
+		deletion:     deletion,
+		terminate:    make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	go a.run()
+
+	return a, nil
+}
+
+// close stops the announcements and notifies receivers that the session has been deleted.
+func (a *serverSAPAnnouncer) close() {
+	close(a.terminate)
+	<-a.done
+}
+
+func (a *serverSAPAnnouncer) write(payload []byte) {
+	a.pc.SetWriteDeadline(time.Now().Add(a.writeTimeout))
+	a.pc.WriteTo(payload, a.addr)
+}
+
+// nextInterval returns the interval before the next announcement, that is
+// randomized in order to avoid the synchronization of announcers (RFC 2974, section 3.1).
+func (a *serverSAPAnnouncer) nextInterval() time.Duration {
+	offset := float64(randUint32())/math.MaxUint32*2/3 - 1.0/3
+	return time.Duration(float64(a.period) * (1 + offset))
+}
+
+func (a *serverSAPAnnouncer) run() {
+	defer close(a.done)
+	defer a.pc.Close()
+
+	a.write(a.announcement)
+
+	t := time.NewTimer(a.nextInterval())
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			a.write(a.announcement)
+			t.Reset(a.nextInterval())
+
+		case <-a.terminate:
+			a.write(a.deletion)
+			return
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	"github.com/aler9/gortsplib/pkg/ringbuffer"
 	"github.com/aler9/gortsplib/pkg/rtcpreceiver"
 	"github.com/aler9/gortsplib/pkg/rtpcleaner"
+	"github.com/aler9/gortsplib/pkg/srtp"
 	"github.com/aler9/gortsplib/pkg/url"
 )
 
@@ -142,16 +144,78 @@ func (s ServerSessionState) String() string {
 
 // ServerSessionSetuppedTrack is a setupped track of a ServerSession.
 type ServerSessionSetuppedTrack struct {
+	// read
+	packetsSent     uint64 // read atomically
+	bytesSent       uint64 // read atomically
+	receiverReports *receiverReportStats
+
 	id               int
 	tcpChannel       int
 	udpRTPReadPort   int
 	udpRTPWriteAddr  *net.UDPAddr
 	udpRTCPReadPort  int
 	udpRTCPWriteAddr *net.UDPAddr
+	udpRTPListener   *serverUDPListener
+	udpRTCPListener  *serverUDPListener
+	udpSSRC          *uint32 // SSRC declared by the client or learned with symmetric RTP
+	rtcpMux          bool
+	udpMutex         sync.RWMutex
+	srtpReadContext  *srtp.Context // decrypts packets sent by the client
+	srtpWriteContext *srtp.Context // encrypts packets written with WritePacket*()
 
 	// publish
-	udpRTCPReceiver *rtcpreceiver.RTCPReceiver
-	cleaner         *rtpcleaner.Cleaner
+	rtcpReceiver   *rtcpreceiver.RTCPReceiver
+	udpRTXReceiver *rtxReceiver
+	cleaner        *rtpcleaner.Cleaner
+}
+
+func (sst *ServerSessionSetuppedTrack) udpWriteAddr(isRTP bool) *net.UDPAddr {
+	sst.udpMutex.RLock()
+	defer sst.udpMutex.RUnlock()
+
+	if isRTP {
+		return sst.udpRTPWriteAddr
+	}
+	return sst.udpRTCPWriteAddr
+}
+
+func (sst *ServerSessionSetuppedTrack) udpClientSSRC() (uint32, bool) {
+	sst.udpMutex.RLock()
+	defer sst.udpMutex.RUnlock()
+
+	if sst.udpSSRC == nil {
+		return 0, false
+	}
+	return *sst.udpSSRC, true
+}
+
+// udpLatch replaces the address of the client with the one learned with symmetric RTP.
+func (sst *ServerSessionSetuppedTrack) udpLatch(isRTP bool, addr *net.UDPAddr, ssrc *uint32) {
+	sst.udpMutex.Lock()
+	defer sst.udpMutex.Unlock()
+
+	if isRTP {
+		sst.udpRTPWriteAddr = addr
+	} else {
+		sst.udpRTCPWriteAddr = addr
+	}
+
+	if ssrc != nil && sst.udpSSRC == nil {
+		sst.udpSSRC = ssrc
+	}
+}
+
+func (sst *ServerSessionSetuppedTrack) udpAddClient(ss *ServerSession, isPublishing bool, withRTP bool) {
+	// with RTP/RTCP multiplexing, RTCP packets are received by the RTP listener
+	if sst.rtcpMux {
+		sst.udpRTPListener.addClient(ss.author.ip(), sst.udpRTPReadPort, ss, sst, isPublishing)
+		return
+	}
+
+	if withRTP {
+		sst.udpRTPListener.addClient(ss.author.ip(), sst.udpRTPReadPort, ss, sst, isPublishing)
+	}
+	sst.udpRTCPListener.addClient(ss.author.ip(), sst.udpRTCPReadPort, ss, sst, isPublishing)
 }
 
 // ServerSession is a server-side RTSP session.
@@ -172,20 +236,25 @@ type ServerSession struct {
 	setuppedPath        *string
 	setuppedQuery       *string
 	lastRequestTime     time.Time
+	lastRequestURL      *url.URL
+	version             base.Version
+	cseq                int
 	tcpConn             *ServerConn
 	announcedTracks     Tracks // publish
 	udpLastFrameTime    *int64 // publish
 	udpCheckStreamTimer *time.Timer
 	writerRunning       bool
 	writeBuffer         *ringbuffer.RingBuffer
+	statsMutex          sync.RWMutex
 
 	// writer channels
 	writerDone chan struct{}
 
 	// in
-	request     chan sessionRequestReq
-	connRemove  chan *ServerConn
-	startWriter chan struct{}
+	request      chan sessionRequestReq
+	connRemove   chan *ServerConn
+	startWriter  chan struct{}
+	writeRequest chan sessionWriteRequestReq
 }
 
 func newServerSession(
@@ -207,6 +276,7 @@ func newServerSession(
 		request:             make(chan sessionRequestReq),
 		connRemove:          make(chan *ServerConn),
 		startWriter:         make(chan struct{}),
+		writeRequest:        make(chan sessionWriteRequestReq),
 	}
 
 	s.wg.Add(1)
@@ -241,6 +311,96 @@ func (ss *ServerSession) AnnouncedTracks() Tracks {
 	return ss.announcedTracks
 }
 
+// Stats returns statistics about the setupped tracks, indexed by track ID.
+func (ss *ServerSession) Stats() map[int]TrackStats {
+	ss.statsMutex.RLock()
+	defer ss.statsMutex.RUnlock()
+
+	ret := make(map[int]TrackStats, len(ss.setuppedTracks))
+
+	for trackID, st := range ss.setuppedTracks {
+		var s TrackStats
+
+		if st.receiverReports != nil {
+			s.fillFromSender(ss.setuppedStream.senderStats(trackID))
+
+			// packets of multicast readers are sent by the stream
+			if *ss.setuppedTransport != TransportUDPMulticast {
+				s.PacketsSent = atomic.LoadUint64(&st.packetsSent)
+				s.BytesSent = atomic.LoadUint64(&st.bytesSent)
+			}
+
+			st.receiverReports.fill(&s)
+		} else if st.rtcpReceiver != nil {
+			s.fillFromReceiver(st.rtcpReceiver.Stats(), ss.announcedTracks[trackID].ClockRate())
+		}
+
+		ret[trackID] = s
+	}
+
+	return ret
+}
+
+// Redirect sends a REDIRECT request to the client, that asks the client
+// to tear down the session and to establish it again on location.
+// ra is optional and is sent in the Range header; clients use it
+// to resume the stream from a given position.
+// It must not be called inside OnSetup(), OnPlay(), OnRecord() and OnPause().
+func (ss *ServerSession) Redirect(location *url.URL, ra *headers.Range) error {
+	req := &base.Request{
+		Method: base.Redirect,
+		Header: base.Header{
+			"Location": base.HeaderValue{location.String()},
+		},
+	}
+
+	if ra != nil {
+		req.Header["Range"] = ra.Write()
+	}
+
+	return ss.sendRequest(req)
+}
+
+// PlayNotify sends a PLAY_NOTIFY request to the client, that notifies the client
+// about an event related to the stream, like the end of the stream (RTSP 2.0).
+// header is optional and allows to add additional headers, like Range or Media-Properties.
+// It must not be called inside OnSetup(), OnPlay(), OnRecord() and OnPause().
+func (ss *ServerSession) PlayNotify(reason headers.NotifyReason, header base.Header) error {
+	req := &base.Request{
+		Method: base.PlayNotify,
+		Header: base.Header{
+			"Notify-Reason": reason.Write(),
+		},
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	return ss.sendRequest(req)
+}
+
+func (ss *ServerSession) sendRequest(req *base.Request) error {
+	cres := make(chan error)
+	select {
+	case ss.writeRequest <- sessionWriteRequestReq{req: req, res: cres}:
+		return <-cres
+
+	case <-ss.ctx.Done():
+		return liberrors.ErrServerTerminated{}
+	}
+}
+
+func (ss *ServerSession) closeRTCPReceivers() {
+	ss.statsMutex.Lock()
+	defer ss.statsMutex.Unlock()
+
+	for _, st := range ss.setuppedTracks {
+		st.rtcpReceiver.Close()
+		st.rtcpReceiver = nil
+	}
+}
+
 func (ss *ServerSession) checkState(allowed map[ServerSessionState]struct{}) error {
 	if _, ok := allowed[ss.state]; ok {
 		return nil
@@ -274,19 +434,21 @@ func (ss *ServerSession) run() {
 		ss.setuppedStream.readerSetInactive(ss)
 
 		if *ss.setuppedTransport == TransportUDP {
-			ss.s.udpRTCPListener.removeClient(ss)
+			ss.udpRemoveClients()
 		}
 
 	case ServerSessionStateRecord:
 		if *ss.setuppedTransport == TransportUDP {
-			ss.s.udpRTPListener.removeClient(ss)
-			ss.s.udpRTCPListener.removeClient(ss)
+			ss.udpRemoveClients()
 
 			for _, at := range ss.setuppedTracks {
-				at.udpRTCPReceiver.Close()
-				at.udpRTCPReceiver = nil
+				at.udpRTXReceiver = nil
 			}
 		}
+
+		for _, at := range ss.setuppedTracks {
+			at.rtcpReceiver.Close()
+		}
 	}
 
 	if ss.setuppedStream != nil {
@@ -298,6 +460,18 @@ func (ss *ServerSession) run() {
 		<-ss.writerDone
 	}
 
+	// release ports allocated from the port range
+	if ss.s.udpPortRangeEnabled() {
+		for _, sst := range ss.setuppedTracks {
+			if sst.udpRTPListener != nil {
+				sst.udpRTPListener.close()
+			}
+			if sst.udpRTCPListener != nil {
+				sst.udpRTCPListener.close()
+			}
+		}
+	}
+
 	for sc := range ss.conns {
 		if sc == ss.tcpConn {
 			sc.Close()
@@ -325,11 +499,22 @@ func (ss *ServerSession) run() {
 	}
 }
 
+func (ss *ServerSession) udpRemoveClients() {
+	for _, sst := range ss.setuppedTracks {
+		sst.udpRTPListener.removeClient(ss)
+		if sst.udpRTCPListener != nil {
+			sst.udpRTCPListener.removeClient(ss)
+		}
+	}
+}
+
 func (ss *ServerSession) runInner() error {
 	for {
 		select {
 		case req := <-ss.request:
 			ss.lastRequestTime = time.Now()
+			ss.lastRequestURL = req.req.URL
+			ss.version = req.req.Version
 
 			if _, ok := ss.conns[req.sc]; !ok {
 				ss.conns[req.sc] = struct{}{}
@@ -395,6 +580,9 @@ func (ss *ServerSession) runInner() error {
 				return liberrors.ErrServerSessionNotInUse{}
 			}
 
+		case req := <-ss.writeRequest:
+			req.res <- ss.doWriteRequest(req.req)
+
 		case <-ss.startWriter:
 			if !ss.writerRunning && (ss.state == ServerSessionStateRecord ||
 				ss.state == ServerSessionStatePlay) &&
@@ -427,6 +615,38 @@ func (ss *ServerSession) runInner() error {
 	}
 }
 
+func (ss *ServerSession) doWriteRequest(req *base.Request) error {
+	if req.Method == base.PlayNotify && ss.version != base.Version20 {
+		return liberrors.ErrServerSessionNotRTSP2{}
+	}
+
+	// when the transport is TCP, requests must be sent through the connection that carries the stream
+	sc := ss.tcpConn
+	if sc == nil {
+		for c := range ss.conns {
+			sc = c
+			break
+		}
+	}
+
+	if sc == nil {
+		return liberrors.ErrServerSessionNoConn{}
+	}
+
+	ss.cseq++
+
+	req.URL = ss.lastRequestURL
+	req.Version = ss.version
+	req.Header["CSeq"] = base.HeaderValue{strconv.FormatInt(int64(ss.cseq), 10)}
+	req.Header["Session"] = base.HeaderValue{ss.secretID}
+
+	byts, _ := req.Write()
+
+	sc.conn.SetWriteDeadline(time.Now().Add(ss.s.WriteTimeout))
+	_, err := sc.conn.Write(byts)
+	return err
+}
+
 func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base.Response, error) {
 	if ss.tcpConn != nil && sc != ss.tcpConn {
 		return &base.Response{
@@ -487,6 +707,10 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 
 		path, query := url.PathSplitQuery(pathAndQuery)
 
+		if res := sc.authenticate(req, path, query, ServerAuthActionPublish); res != nil {
+			return res, nil
+		}
+
 		ct, ok := req.Header["Content-Type"]
 		if !ok || len(ct) != 1 {
 			return &base.Response{
@@ -574,6 +798,11 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			}, liberrors.ErrServerTransportHeaderInvalid{Err: err}
 		}
 
+		// RTSP 2.0: client ports are provided with the destination addresses
+		if inTH.ClientPorts == nil && inTH.DestAddrs != nil {
+			inTH.ClientPorts = &[2]int{inTH.DestAddrs[0].Port, inTH.DestAddrs[1].Port}
+		}
+
 		trackID, path, query, err := setupGetTrackIDPathQuery(req.URL, inTH.Mode,
 			ss.announcedTracks, ss.setuppedPath, ss.setuppedQuery, ss.setuppedBaseURL)
 		if err != nil {
@@ -603,7 +832,7 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 				}, liberrors.ErrServerTransportHeaderNoClientPorts{}
 			}
 
-			if ss.s.udpRTPListener == nil {
+			if ss.s.udpRTPListener == nil && !ss.s.udpPortRangeEnabled() {
 				return &base.Response{
 					StatusCode: base.StatusUnsupportedTransport,
 				}, nil
@@ -665,6 +894,15 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			}
 		}
 
+		action := ServerAuthActionRead
+		if ss.state == ServerSessionStatePreRecord {
+			action = ServerAuthActionPublish
+		}
+
+		if res := sc.authenticate(req, path, query, action); res != nil {
+			return res, nil
+		}
+
 		res, stream, err := ss.s.Handler.(ServerHandlerOnSetup).OnSetup(&ServerHandlerOnSetupCtx{
 			Server:    ss.s,
 			Session:   ss,
@@ -692,12 +930,81 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			return res, err
 		}
 
+		var setupTrack Track
+		if ss.state == ServerSessionStateInitial || ss.state == ServerSessionStatePrePlay {
+			setupTrack = stream.tracks[trackID]
+		} else {
+			setupTrack = ss.announcedTracks[trackID]
+		}
+
+		// tracks protected with SRTP must be setupped with a secure profile, and vice versa.
+		srtpKey, _, isSecure := setupTrack.SRTP()
+		if isSecure != inTH.Profile.IsSecure() {
+			return &base.Response{
+				StatusCode: base.StatusUnsupportedTransport,
+			}, nil
+		}
+
+		var srtpReadContext *srtp.Context
+		var srtpWriteContext *srtp.Context
+		if isSecure {
+			var err error
+			srtpReadContext, err = srtp.NewContext(srtpKey)
+			if err != nil {
+				return &base.Response{
+					StatusCode: base.StatusBadRequest,
+				}, err
+			}
+
+			// readers share the context of the stream, since they receive the same packets.
+			if ss.state == ServerSessionStateInitial || ss.state == ServerSessionStatePrePlay {
+				srtpWriteContext = stream.stTracks[trackID].srtpContext
+			} else {
+				srtpWriteContext = srtpReadContext
+			}
+		}
+
+		// RTP and RTCP are multiplexed on the same port when requested by the client.
+		// in this case, a RTCP listener is not needed.
+		rtcpMux := transport == TransportUDP && inTH.RTCPMux
+
+		var udpRTPListener, udpRTCPListener *serverUDPListener
+		if transport == TransportUDP {
+			if ss.s.udpPortRangeEnabled() {
+				var err error
+				udpRTPListener, udpRTCPListener, err = newServerUDPListenerUnicastPair(ss.s, rtcpMux)
+				if err != nil {
+					return &base.Response{
+						StatusCode: base.StatusServiceUnavailable,
+					}, err
+				}
+			} else {
+				udpRTPListener = ss.s.udpRTPListener
+				if !rtcpMux {
+					udpRTCPListener = ss.s.udpRTCPListener
+				}
+			}
+		}
+
 		if ss.state == ServerSessionStateInitial {
 			err := stream.readerAdd(ss,
 				transport,
 				inTH.ClientPorts,
 			)
 			if err != nil {
+				if ss.s.udpPortRangeEnabled() && udpRTPListener != nil {
+					udpRTPListener.close()
+					if udpRTCPListener != nil {
+						udpRTCPListener.close()
+					}
+				}
+
+				if _, ok := err.(liberrors.ErrServerMaxReadersPerStreamReached); ok {
+					return &base.Response{
+						StatusCode: base.StatusNotEnoughBandwidth,
+					}, err
+				}
+
 				return &base.Response{
 					StatusCode: base.StatusBadRequest,
 				}, err
@@ -709,7 +1016,9 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			ss.setuppedStream = stream
 		}
 
-		th := headers.Transport{}
+		th := headers.Transport{
+			Profile: inTH.Profile,
+		}
 
 		if ss.state == ServerSessionStatePrePlay {
 			ssrc := stream.ssrc(trackID)
@@ -725,13 +1034,29 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 		}
 
 		sst := &ServerSessionSetuppedTrack{
-			id: trackID,
+			id:               trackID,
+			srtpReadContext:  srtpReadContext,
+			srtpWriteContext: srtpWriteContext,
+		}
+
+		if ss.state == ServerSessionStatePrePlay {
+			sst.receiverReports = newReceiverReportStats(stream.tracks[trackID].ClockRate())
 		}
 
 		switch transport {
 		case TransportUDP:
+			sst.rtcpMux = rtcpMux
 			sst.udpRTPReadPort = inTH.ClientPorts[0]
 			sst.udpRTCPReadPort = inTH.ClientPorts[1]
+			sst.udpRTPListener = udpRTPListener
+			sst.udpRTCPListener = udpRTCPListener
+
+			serverPorts := [2]int{udpRTPListener.port(), udpRTPListener.port()}
+			if rtcpMux {
+				sst.udpRTCPReadPort = sst.udpRTPReadPort
+			} else {
+				serverPorts[1] = udpRTCPListener.port()
+			}
 
 			sst.udpRTPWriteAddr = &net.UDPAddr{
 				IP:   ss.author.ip(),
@@ -745,21 +1070,50 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 				Port: sst.udpRTCPReadPort,
 			}
 
+			if ss.state == ServerSessionStatePreRecord {
+				sst.udpSSRC = inTH.SSRC
+			}
+
 			th.Protocol = headers.TransportProtocolUDP
 			de := headers.TransportDeliveryUnicast
 			th.Delivery = &de
-			th.ClientPorts = inTH.ClientPorts
-			th.ServerPorts = &[2]int{sc.s.udpRTPListener.port(), sc.s.udpRTCPListener.port()}
+			th.RTCPMux = rtcpMux
+
+			if req.Version == base.Version20 {
+				localIP := sc.conn.LocalAddr().(*net.TCPAddr).IP
+				th.DestAddrs = &[2]*net.UDPAddr{sst.udpRTPWriteAddr, sst.udpRTCPWriteAddr}
+				th.SrcAddrs = &[2]*net.UDPAddr{
+					{IP: localIP, Port: serverPorts[0]},
+					{IP: localIP, Port: serverPorts[1]},
+				}
+			} else {
+				th.ClientPorts = &[2]int{sst.udpRTPReadPort, sst.udpRTCPReadPort}
+				th.ServerPorts = &serverPorts
+			}
 
 		case TransportUDPMulticast:
 			th.Protocol = headers.TransportProtocolUDP
 			de := headers.TransportDeliveryMulticast
 			th.Delivery = &de
-			v := uint(127)
+			v := uint(ss.s.MulticastTTL)
 			th.TTL = &v
-			d := stream.serverMulticastHandlers[trackID].ip()
-			th.Destination = &d
-			th.Ports = &[2]int{ss.s.MulticastRTPPort, ss.s.MulticastRTCPPort}
+			mh := stream.serverMulticastHandlers[trackID]
+			d := mh.ip()
+			ports := [2]int{mh.rtpl.port(), mh.rtcpl.port()}
+
+			if mh.source != nil {
+				th.Source = &mh.source
+			}
+
+			if req.Version == base.Version20 {
+				th.DestAddrs = &[2]*net.UDPAddr{
+					{IP: d, Port: ports[0]},
+					{IP: d, Port: ports[1]},
+				}
+			} else {
+				th.Destination = &d
+				th.Ports = &ports
+			}
 
 		default: // TCP
 			sst.tcpChannel = inTH.InterleavedIDs[0]
@@ -784,6 +1138,23 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 
 		res.Header["Transport"] = th.Write()
 
+		// RTSP 2.0: SETUP responses contain the media properties
+		if req.Version == base.Version20 && ss.state == ServerSessionStatePrePlay {
+			if _, ok := res.Header["Accept-Ranges"]; !ok {
+				res.Header["Accept-Ranges"] = headers.AcceptRanges{"npt"}.Write()
+			}
+
+			if _, ok := res.Header["Media-Properties"]; !ok {
+				ra := headers.MediaPropertiesRandomAccessNoSeeking
+				cm := headers.MediaPropertiesContentModificationsTimeProgressing
+				res.Header["Media-Properties"] = headers.MediaProperties{
+					RandomAccess:         &ra,
+					ContentModifications: &cm,
+					Unlimited:            true,
+				}.Write()
+			}
+		}
+
 		return res, err
 
 	case base.Play:
@@ -817,12 +1188,47 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			}, liberrors.ErrServerPathHasChanged{Prev: *ss.setuppedPath, Cur: path}
 		}
 
+		var scale *headers.Scale
+		if v, ok := req.Header["Scale"]; ok {
+			scale = new(headers.Scale)
+			err := scale.Read(v)
+			if err != nil {
+				return &base.Response{
+					StatusCode: base.StatusBadRequest,
+				}, liberrors.ErrServerScaleHeaderInvalid{Err: err}
+			}
+		}
+
+		var speed *headers.Speed
+		if v, ok := req.Header["Speed"]; ok {
+			speed = new(headers.Speed)
+			err := speed.Read(v)
+			if err != nil {
+				return &base.Response{
+					StatusCode: base.StatusBadRequest,
+				}, liberrors.ErrServerSpeedHeaderInvalid{Err: err}
+			}
+		}
+
+		// ranges that can't be decoded (i.e. npt=now-) are ignored,
+		// since the Range header is optional.
+		var ra *headers.Range
+		if v, ok := req.Header["Range"]; ok {
+			var tmp headers.Range
+			err := tmp.Read(v)
+			if err == nil {
+				ra = &tmp
+			}
+		}
+
 		// allocate writeBuffer before calling OnPlay().
 		// in this way it's possible to call ServerSession.WritePacket*()
 		// inside the callback.
+		// the GOP cache is queued into writeBuffer before the live stream,
+		// therefore writeBuffer is enlarged in order to contain it.
 		if ss.state != ServerSessionStatePlay &&
 			*ss.setuppedTransport != TransportUDPMulticast {
-			ss.writeBuffer = ringbuffer.New(uint64(ss.s.WriteBufferCount))
+			ss.writeBuffer = ringbuffer.New(uint64(ss.s.WriteBufferCount + ss.setuppedStream.gopCacheSize(ss)))
 		}
 
 		res, err := sc.s.Handler.(ServerHandlerOnPlay).OnPlay(&ServerHandlerOnPlayCtx{
@@ -831,6 +1237,9 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			Request: req,
 			Path:    path,
 			Query:   query,
+			Scale:   scale,
+			Speed:   speed,
+			Range:   ra,
 		})
 
 		if res.StatusCode != base.StatusOK {
@@ -855,8 +1264,10 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			go ss.runWriter()
 
 			for _, track := range ss.setuppedTracks {
-				// readers can send RTCP packets only
-				sc.s.udpRTCPListener.addClient(ss.author.ip(), track.udpRTCPReadPort, ss, track, false)
+				// readers can send RTCP packets only.
+				// with symmetric RTP, RTP packets sent by readers to open NAT mappings
+				// are used to learn their address.
+				track.udpAddClient(ss, false, sc.s.UDPSymmetricRTP)
 
 				// firewall opening is performed by RTCP sender reports generated by ServerStream
 			}
@@ -872,7 +1283,7 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			// runWriter() is called by ServerConn after the response has been sent
 		}
 
-		ss.setuppedStream.readerSetActive(ss)
+		rtpInfos := ss.setuppedStream.readerSetActive(ss)
 
 		var trackIDs []int
 		for trackID := range ss.setuppedTracks {
@@ -884,12 +1295,17 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 		})
 
 		var ri headers.RTPInfo
+		var km headers.KeyMgmt
 		now := time.Now()
 
 		for _, trackID := range trackIDs {
-			seqNum, ts, ok := ss.setuppedStream.rtpInfo(trackID, now)
+			// the reader receives the time-shift buffer or the GOP cache first
+			info, ok := rtpInfos[trackID]
 			if !ok {
-				continue
+				info, ok = ss.setuppedStream.rtpInfo(trackID, now)
+				if !ok {
+					continue
+				}
 			}
 
 			u := &url.URL{
@@ -901,9 +1317,22 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 
 			ri = append(ri, &headers.RTPInfoEntry{
 				URL:            u.String(),
-				SequenceNumber: &seqNum,
-				Timestamp:      &ts,
+				SequenceNumber: &info.sequenceNumber,
+				Timestamp:      &info.timestamp,
 			})
+
+			// readers that join a stream in progress need the rollover counter
+			// of the first packet in order to decrypt it.
+			if key, _, ok := ss.setuppedStream.tracks[trackID].SRTP(); ok {
+				data, err := srtpKeyMgmtData(key, info.ssrc, info.roc)
+				if err == nil {
+					km = append(km, &headers.KeyMgmtEntry{
+						Protocol: "mikey",
+						URL:      u.String(),
+						Data:     data,
+					})
+				}
+			}
 		}
 		if len(ri) > 0 {
 			if res.Header == nil {
@@ -911,6 +1340,9 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			}
 			res.Header["RTP-Info"] = ri.Write()
 		}
+		if len(km) > 0 {
+			res.Header["KeyMgmt"] = km.Write()
+		}
 
 		return res, err
 
@@ -971,10 +1403,28 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 
 		ss.state = ServerSessionStateRecord
 
+		ss.statsMutex.Lock()
 		for trackID, st := range ss.setuppedTracks {
 			_, isH264 := ss.announcedTracks[trackID].(*TrackH264)
 			st.cleaner = rtpcleaner.NewCleaner(isH264, *ss.setuppedTransport == TransportTCP)
+
+			// statistics are collected with any transport,
+			// but receiver reports are sent with UDP only.
+			var writeReport func(rtcp.Packet)
+			if *ss.setuppedTransport == TransportUDP {
+				ctrackID := trackID
+				writeReport = func(pkt rtcp.Packet) {
+					ss.WritePacketRTCP(ctrackID, pkt)
+				}
+			}
+
+			st.rtcpReceiver = rtcpreceiver.New(
+				ss.s.receiverReportPeriod,
+				nil,
+				ss.announcedTracks[trackID].ClockRate(),
+				writeReport)
 		}
+		ss.statsMutex.Unlock()
 
 		switch *ss.setuppedTransport {
 		case TransportUDP:
@@ -991,16 +1441,15 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 
 				ctrackID := trackID
 
-				st.udpRTCPReceiver = rtcpreceiver.New(
-					ss.s.udpReceiverReportPeriod,
-					nil,
-					ss.announcedTracks[trackID].ClockRate(),
-					func(pkt rtcp.Packet) {
-						ss.WritePacketRTCP(ctrackID, pkt)
-					})
+				if rtxPayloadType, ok := ss.announcedTracks[trackID].RTXPayloadType(); ok {
+					originalPayloadType, _ := trackRTXOriginalPayloadType(ss.announcedTracks[trackID])
+					st.udpRTXReceiver = newRTXReceiver(randUint32(), rtxPayloadType, originalPayloadType,
+						func(pkt rtcp.Packet) {
+							ss.WritePacketRTCP(ctrackID, pkt)
+						})
+				}
 
-				ss.s.udpRTPListener.addClient(ss.author.ip(), st.udpRTPReadPort, ss, st, true)
-				ss.s.udpRTCPListener.addClient(ss.author.ip(), st.udpRTCPReadPort, ss, st, true)
+				st.udpAddClient(ss, true, true)
 			}
 
 		default: // TCP
@@ -1066,7 +1515,7 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			case TransportUDP:
 				ss.udpCheckStreamTimer = emptyTimer()
 
-				ss.s.udpRTCPListener.removeClient(ss)
+				ss.udpRemoveClients()
 
 			case TransportUDPMulticast:
 				ss.udpCheckStreamTimer = emptyTimer()
@@ -1082,12 +1531,10 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 			case TransportUDP:
 				ss.udpCheckStreamTimer = emptyTimer()
 
-				ss.s.udpRTPListener.removeClient(ss)
-				ss.s.udpRTCPListener.removeClient(ss)
+				ss.udpRemoveClients()
 
 				for _, st := range ss.setuppedTracks {
-					st.udpRTCPReceiver.Close()
-					st.udpRTCPReceiver = nil
+					st.udpRTXReceiver = nil
 				}
 
 			default: // TCP
@@ -1096,6 +1543,8 @@ func (ss *ServerSession) handleRequest(sc *ServerConn, req *base.Request) (*base
 				ss.tcpConn = nil
 			}
 
+			ss.closeRTCPReceivers()
+
 			for _, st := range ss.setuppedTracks {
 				st.cleaner = nil
 			}
@@ -1161,9 +1610,15 @@ func (ss *ServerSession) runWriter() {
 	if *ss.setuppedTransport == TransportUDP {
 		writeFunc = func(trackID int, isRTP bool, payload []byte) {
 			if isRTP {
-				ss.s.udpRTPListener.write(payload, ss.setuppedTracks[trackID].udpRTPWriteAddr)
+				sst := ss.setuppedTracks[trackID]
+				sst.udpRTPListener.write(payload, sst.udpWriteAddr(true))
 			} else {
-				ss.s.udpRTCPListener.write(payload, ss.setuppedTracks[trackID].udpRTCPWriteAddr)
+				sst := ss.setuppedTracks[trackID]
+				if sst.rtcpMux {
+					sst.udpRTPListener.write(payload, sst.udpWriteAddr(true))
+				} else {
+					sst.udpRTCPListener.write(payload, sst.udpWriteAddr(false))
+				}
 			}
 		}
 	} else { // TCP
@@ -1215,13 +1670,37 @@ func (ss *ServerSession) onPacketRTCP(trackID int, pkt rtcp.Packet) {
 			Packet:  pkt,
 		})
 	}
+
+	if ss.setuppedStream != nil {
+		ss.setuppedTracks[trackID].receiverReports.processPacketRTCP(time.Now(), pkt)
+
+		if *ss.setuppedTransport == TransportUDP {
+			ss.setuppedStream.retransmit(ss, trackID, pkt)
+		}
+
+		if h, ok := ss.s.Handler.(ServerHandlerOnReaderPacketRTCP); ok {
+			h.OnReaderPacketRTCP(&ServerHandlerOnReaderPacketRTCPCtx{
+				Session: ss,
+				Stream:  ss.setuppedStream,
+				TrackID: trackID,
+				Packet:  pkt,
+			})
+		}
+	}
 }
 
+// writePacketRTP writes a RTP packet that has already been encrypted, if needed.
 func (ss *ServerSession) writePacketRTP(trackID int, byts []byte) {
-	if _, ok := ss.setuppedTracks[trackID]; !ok {
+	st, ok := ss.setuppedTracks[trackID]
+	if !ok {
 		return
 	}
 
+	if ss.setuppedStream != nil {
+		atomic.AddUint64(&st.packetsSent, 1)
+		atomic.AddUint64(&st.bytesSent, uint64(len(byts)))
+	}
+
 	ss.writeBuffer.Push(trackTypePayload{
 		trackID: trackID,
 		isRTP:   true,
@@ -1236,9 +1715,17 @@ func (ss *ServerSession) WritePacketRTP(trackID int, pkt *rtp.Packet) {
 		return
 	}
 
+	if st, ok := ss.setuppedTracks[trackID]; ok && st.srtpWriteContext != nil {
+		byts, err = st.srtpWriteContext.EncryptRTP(byts)
+		if err != nil {
+			return
+		}
+	}
+
 	ss.writePacketRTP(trackID, byts)
 }
 
+// writePacketRTCP writes a RTCP packet that has already been encrypted, if needed.
 func (ss *ServerSession) writePacketRTCP(trackID int, byts []byte) {
 	if _, ok := ss.setuppedTracks[trackID]; !ok {
 		return
@@ -1258,5 +1745,13 @@ func (ss *ServerSession) WritePacketRTCP(trackID int, pkt rtcp.Packet) {
 		return
 	}
 
+	if st, ok := ss.setuppedTracks[trackID]; ok && st.srtpWriteContext != nil {
+		byts, err = st.srtpWriteContext.EncryptRTCP(byts)
+		if err != nil {
+			return
+		}
+	}
+
 	ss.writePacketRTCP(trackID, byts)
 }
+
@@ -10,22 +10,46 @@ import (
 
 	"github.com/aler9/gortsplib/pkg/liberrors"
 	"github.com/aler9/gortsplib/pkg/rtcpsender"
+	"github.com/aler9/gortsplib/pkg/rtpfec"
+	"github.com/aler9/gortsplib/pkg/rtpretransmitter"
+	"github.com/aler9/gortsplib/pkg/rtprtx"
+	"github.com/aler9/gortsplib/pkg/srtp"
 )
 
+// serverStreamRTPInfo contains the sequence number and timestamp
+// of the first packet sent to a reader.
+type serverStreamRTPInfo struct {
+	sequenceNumber uint16
+	timestamp      uint32
+	ssrc           uint32
+	roc            uint32 // SRTP rollover counter
+}
+
 type serverStreamTrack struct {
 	firstPacketSent    bool
 	lastSequenceNumber uint16
 	lastSSRC           uint32
 	lastTimeRTP        uint32
 	lastTimeNTP        time.Time
-	udpRTCPSender      *rtcpsender.RTCPSender
+	rtcpSender         *rtcpsender.RTCPSender
+	rtxHistory         *rtpretransmitter.Retransmitter
+	rtxMutex           sync.Mutex
+	rtxEncoder         *rtprtx.Encoder
+	fecMutex           sync.Mutex
+	fecEncoder         *rtpfec.Encoder
+	gopCache           *serverGOPCache
+	srtpContext        *srtp.Context // shared by all readers
 }
 
 // ServerStream represents a single stream.
 // This is in charge of
 // - distributing the stream to each reader
 // - allocating multicast listeners
+// - announcing multicast groups with SAP
+// - caching the last GOP of each track for new readers
+// - feeding the time-shift buffer
 // - gathering infos about the stream to generate SSRC and RTP-Info
+// - encrypting packets of tracks protected with SRTP
 type ServerStream struct {
 	tracks Tracks
 
@@ -33,7 +57,10 @@ type ServerStream struct {
 	s                       *Server
 	readersUnicast          map[*ServerSession]struct{}
 	readers                 map[*ServerSession]struct{}
+	readersDelayed          map[*ServerSession]*serverTimeShiftReader
 	serverMulticastHandlers []*serverMulticastHandler
+	sapAnnouncer            *serverSAPAnnouncer
+	timeShiftBuffer         *ServerTimeShiftBuffer
 	stTracks                []*serverStreamTrack
 }
 
@@ -46,11 +73,19 @@ func NewServerStream(tracks Tracks) *ServerStream {
 		tracks:         tracks,
 		readersUnicast: make(map[*ServerSession]struct{}),
 		readers:        make(map[*ServerSession]struct{}),
+		readersDelayed: make(map[*ServerSession]*serverTimeShiftReader),
 	}
 
 	st.stTracks = make([]*serverStreamTrack, len(tracks))
 	for i := range st.stTracks {
-		st.stTracks[i] = &serverStreamTrack{}
+		// packets are encrypted once and sent to all readers,
+		// in order not to reuse the key stream with different packets.
+		// keys have already been validated by Track.SetSRTP().
+		srtpContext, _ := newSRTPContext(tracks[i])
+
+		st.stTracks[i] = &serverStreamTrack{
+			srtpContext: srtpContext,
+		}
 	}
 
 	return st
@@ -65,6 +100,16 @@ func (st *ServerStream) Close() error {
 		ss.Close()
 	}
 
+	for _, r := range st.readersDelayed {
+		r.stop()
+	}
+	st.readersDelayed = nil
+
+	if st.sapAnnouncer != nil {
+		st.sapAnnouncer.close()
+		st.sapAnnouncer = nil
+	}
+
 	if st.serverMulticastHandlers != nil {
 		for _, h := range st.serverMulticastHandlers {
 			h.close()
@@ -78,6 +123,76 @@ func (st *ServerStream) Close() error {
 	return nil
 }
 
+// AnnounceSAP allocates the multicast groups of the stream on s and announces them
+// with the Session Announcement Protocol (RFC 2974) on Server.SAPAddress,
+// allowing receivers to read the stream without RTSP.
+// s must support the UDP-multicast transport.
+// Tracks protected with SRTP can't be announced, since announcements are not encrypted
+// and would expose their keys.
+// The stream is announced until it is closed.
+func (st *ServerStream) AnnounceSAP(s *Server, sessionName string) error {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	if st.readers == nil {
+		return fmt.Errorf("stream is closed")
+	}
+
+	if st.sapAnnouncer != nil {
+		return fmt.Errorf("stream is already announced")
+	}
+
+	for _, track := range st.tracks {
+		if _, _, ok := track.SRTP(); ok {
+			return fmt.Errorf("tracks protected with SRTP can't be announced")
+		}
+	}
+
+	if st.s != nil && st.s != s {
+		return fmt.Errorf("stream is already used by another server")
+	}
+
+	if s.multicastNet == nil {
+		return fmt.Errorf("server is not started or does not support the UDP-multicast transport")
+	}
+
+	st.initialize(s)
+
+	err := st.allocateMulticastHandlers()
+	if err != nil {
+		return err
+	}
+
+	st.sapAnnouncer, err = newServerSAPAnnouncer(s, st, sessionName)
+	if err != nil {
+		if len(st.readers) == 0 {
+			for _, h := range st.serverMulticastHandlers {
+				h.close()
+			}
+			st.serverMulticastHandlers = nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// EnableGOPCache enables a cache that stores, for each track, the RTP packets
+// since the last key frame (a packet written with ptsEqualsDTS = true).
+// The cache is sent to readers that start playing with the UDP or TCP transport,
+// before the live stream, in order to allow them to start decoding immediately.
+// maxPackets is the maximum number of packets stored for each track;
+// the write buffer of each reader is enlarged in order to contain the caches of all tracks.
+// This must be called before writing any packet.
+func (st *ServerStream) EnableGOPCache(maxPackets int) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	for _, track := range st.stTracks {
+		track.gopCache = newServerGOPCache(maxPackets)
+	}
+}
+
 // Tracks returns the tracks of the stream.
 func (st *ServerStream) Tracks() Tracks {
 	return st.tracks
@@ -89,14 +204,14 @@ func (st *ServerStream) ssrc(trackID int) uint32 {
 	return st.stTracks[trackID].lastSSRC
 }
 
-func (st *ServerStream) rtpInfo(trackID int, now time.Time) (uint16, uint32, bool) {
+func (st *ServerStream) rtpInfo(trackID int, now time.Time) (serverStreamRTPInfo, bool) {
 	st.mutex.Lock()
 	defer st.mutex.Unlock()
 
 	track := st.stTracks[trackID]
 
 	if !track.firstPacketSent {
-		return 0, 0, false
+		return serverStreamRTPInfo{}, false
 	}
 
 	// sequence number of the first packet of the stream
@@ -110,7 +225,93 @@ func (st *ServerStream) rtpInfo(trackID int, now time.Time) (uint16, uint32, boo
 		uint64(now.Sub(track.lastTimeNTP).Seconds()*float64(cr)) -
 		uint64(cr)/10)
 
-	return seq, ts, true
+	var roc uint32
+	if track.srtpContext != nil {
+		roc = track.srtpContext.ROC(track.lastSSRC, seq)
+	}
+
+	return serverStreamRTPInfo{
+		sequenceNumber: seq,
+		timestamp:      ts,
+		ssrc:           track.lastSSRC,
+		roc:            roc,
+	}, true
+}
+
+func (st *ServerStream) senderStats(trackID int) rtcpsender.Stats {
+	st.mutex.RLock()
+	defer st.mutex.RUnlock()
+
+	track := st.stTracks[trackID]
+
+	if track.rtcpSender == nil {
+		return rtcpsender.Stats{}
+	}
+
+	return track.rtcpSender.Stats()
+}
+
+// initialize binds the stream to a server.
+func (st *ServerStream) initialize(s *Server) {
+	if st.s != nil {
+		return
+	}
+
+	st.s = s
+
+	for trackID, track := range st.stTracks {
+		cTrackID := trackID
+		track.rtcpSender = rtcpsender.New(
+			st.s.senderReportPeriod,
+			st.tracks[trackID].ClockRate(),
+			func(pkt rtcp.Packet) {
+				st.writePacketRTCPSenderReport(cTrackID, pkt)
+			},
+		)
+
+		if rtxPayloadType, ok := st.tracks[trackID].RTXPayloadType(); ok {
+			track.rtxHistory = rtpretransmitter.New(st.s.RTXBufferCount)
+			track.rtxEncoder = &rtprtx.Encoder{
+				PayloadType: rtxPayloadType,
+			}
+			track.rtxEncoder.Init()
+		}
+
+		if fecPayloadType, fecScheme, ok := st.tracks[trackID].FEC(); ok {
+			track.fecEncoder = &rtpfec.Encoder{
+				Scheme:      fecScheme,
+				PayloadType: fecPayloadType,
+				GroupSize:   st.s.FECGroupSize,
+			}
+			track.fecEncoder.Init()
+		}
+	}
+}
+
+// allocateMulticastHandlers allocates multicast listeners, if they are not allocated yet.
+func (st *ServerStream) allocateMulticastHandlers() error {
+	if st.serverMulticastHandlers != nil {
+		return nil
+	}
+
+	st.serverMulticastHandlers = make([]*serverMulticastHandler, len(st.tracks))
+
+	for i := range st.tracks {
+		h, err := newServerMulticastHandler(st.s)
+		if err != nil {
+			for _, h := range st.serverMulticastHandlers {
+				if h != nil {
+					h.close()
+				}
+			}
+			st.serverMulticastHandlers = nil
+			return err
+		}
+
+		st.serverMulticastHandlers[i] = h
+	}
+
+	return nil
 }
 
 func (st *ServerStream) readerAdd(
@@ -125,26 +326,20 @@ func (st *ServerStream) readerAdd(
 		return fmt.Errorf("stream is closed")
 	}
 
-	if st.s == nil {
-		st.s = ss.s
-
-		for trackID, track := range st.stTracks {
-			cTrackID := trackID
-			track.udpRTCPSender = rtcpsender.New(
-				st.s.udpSenderReportPeriod,
-				st.tracks[trackID].ClockRate(),
-				func(pkt rtcp.Packet) {
-					st.writePacketRTCPSenderReport(cTrackID, pkt)
-				},
-			)
-		}
+	if ss.s.MaxReadersPerStream > 0 && len(st.readers) >= ss.s.MaxReadersPerStream &&
+		!ss.s.limitExceeded(ServerLimitReadersPerStream, ss.author.NetConn().RemoteAddr()) {
+		return liberrors.ErrServerMaxReadersPerStreamReached{Max: ss.s.MaxReadersPerStream}
 	}
 
+	st.initialize(ss.s)
+
 	switch transport {
 	case TransportUDP:
 		// check if client ports are already in use by another reader.
+		// this is not needed when each session has dedicated ports.
 		for r := range st.readersUnicast {
-			if *r.setuppedTransport == TransportUDP &&
+			if !ss.s.udpPortRangeEnabled() &&
+				*r.setuppedTransport == TransportUDP &&
 				r.author.ip().Equal(ss.author.ip()) &&
 				r.author.zone() == ss.author.zone() {
 				for _, rt := range r.setuppedTracks {
@@ -156,24 +351,9 @@ func (st *ServerStream) readerAdd(
 		}
 
 	case TransportUDPMulticast:
-		// allocate multicast listeners
-		if st.serverMulticastHandlers == nil {
-			st.serverMulticastHandlers = make([]*serverMulticastHandler, len(st.tracks))
-
-			for i := range st.tracks {
-				h, err := newServerMulticastHandler(st.s)
-				if err != nil {
-					for _, h := range st.serverMulticastHandlers {
-						if h != nil {
-							h.close()
-						}
-					}
-					st.serverMulticastHandlers = nil
-					return err
-				}
-
-				st.serverMulticastHandlers[i] = h
-			}
+		err := st.allocateMulticastHandlers()
+		if err != nil {
+			return err
 		}
 	}
 
@@ -188,7 +368,14 @@ func (st *ServerStream) readerRemove(ss *ServerSession) {
 
 	delete(st.readers, ss)
 
-	if len(st.readers) == 0 && st.serverMulticastHandlers != nil {
+	if r, ok := st.readersDelayed[ss]; ok {
+		r.stop()
+		delete(st.readersDelayed, ss)
+	}
+
+	// multicast listeners of announced streams are kept
+	// until the stream is closed.
+	if len(st.readers) == 0 && st.serverMulticastHandlers != nil && st.sapAnnouncer == nil {
 		for _, l := range st.serverMulticastHandlers {
 			l.rtpl.close()
 			l.rtcpl.close()
@@ -197,22 +384,84 @@ func (st *ServerStream) readerRemove(ss *ServerSession) {
 	}
 }
 
-func (st *ServerStream) readerSetActive(ss *ServerSession) {
+// readerSetActive starts sending the stream to a reader.
+// It returns the first packet sent to the reader of each track
+// whose packets are read from the time-shift buffer or from the GOP cache.
+func (st *ServerStream) readerSetActive(ss *ServerSession) map[int]serverStreamRTPInfo {
 	st.mutex.Lock()
 	defer st.mutex.Unlock()
 
 	switch *ss.setuppedTransport {
 	case TransportUDP, TransportTCP:
+		// the reader is switched to the live stream by the time-shift buffer
+		if r, ok := st.readersDelayed[ss]; ok {
+			r.start()
+			return r.rtpInfos
+		}
+
 		st.readersUnicast[ss] = struct{}{}
+		return st.sendGOPCache(ss)
 
 	default: // UDPMulticast
 		for trackID, track := range ss.setuppedTracks {
 			st.serverMulticastHandlers[trackID].rtcpl.addClient(
 				ss.author.ip(), st.serverMulticastHandlers[trackID].rtcpl.port(), ss, track, false)
 		}
+		return nil
 	}
 }
 
+// gopCacheSize returns the maximum number of packets that are sent
+// from the GOP cache to a reader when it starts playing.
+func (st *ServerStream) gopCacheSize(ss *ServerSession) int {
+	st.mutex.RLock()
+	defer st.mutex.RUnlock()
+
+	size := 0
+	for trackID := range ss.setuppedTracks {
+		if gopCache := st.stTracks[trackID].gopCache; gopCache != nil {
+			size += gopCache.maxPackets
+		}
+	}
+	return size
+}
+
+// sendGOPCache sends the GOP cache of each track to a reader that is starting to play.
+func (st *ServerStream) sendGOPCache(ss *ServerSession) map[int]serverStreamRTPInfo {
+	caches := make(map[int][]serverGOPCacheEntry)
+
+	for trackID := range ss.setuppedTracks {
+		gopCache := st.stTracks[trackID].gopCache
+		if gopCache == nil {
+			continue
+		}
+
+		if entries := gopCache.cached(); len(entries) != 0 {
+			caches[trackID] = entries
+		}
+	}
+
+	if len(caches) == 0 {
+		return nil
+	}
+
+	ret := make(map[int]serverStreamRTPInfo)
+
+	for trackID, entries := range caches {
+		for _, e := range entries {
+			ss.writePacketRTP(trackID, e.byts)
+		}
+		ret[trackID] = serverStreamRTPInfo{
+			sequenceNumber: entries[0].sequenceNumber,
+			timestamp:      entries[0].timestamp,
+			ssrc:           entries[0].ssrc,
+			roc:            entries[0].roc,
+		}
+	}
+
+	return ret
+}
+
 func (st *ServerStream) readerSetInactive(ss *ServerSession) {
 	st.mutex.Lock()
 	defer st.mutex.Unlock()
@@ -221,6 +470,11 @@ func (st *ServerStream) readerSetInactive(ss *ServerSession) {
 	case TransportUDP, TransportTCP:
 		delete(st.readersUnicast, ss)
 
+		if r, ok := st.readersDelayed[ss]; ok {
+			r.stop()
+			delete(st.readersDelayed, ss)
+		}
+
 	default: // UDPMulticast
 		if st.serverMulticastHandlers != nil {
 			for trackID := range ss.setuppedTracks {
@@ -230,6 +484,32 @@ func (st *ServerStream) readerSetInactive(ss *ServerSession) {
 	}
 }
 
+// marshalRTP encodes a RTP packet and encrypts it if the track is protected with SRTP.
+func (st *ServerStream) marshalRTP(trackID int, pkt *rtp.Packet) ([]byte, error) {
+	byts, err := pkt.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx := st.stTracks[trackID].srtpContext; ctx != nil {
+		return ctx.EncryptRTP(byts)
+	}
+	return byts, nil
+}
+
+// marshalRTCP encodes a RTCP packet and encrypts it if the track is protected with SRTP.
+func (st *ServerStream) marshalRTCP(trackID int, pkt rtcp.Packet) ([]byte, error) {
+	byts, err := pkt.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx := st.stTracks[trackID].srtpContext; ctx != nil {
+		return ctx.EncryptRTCP(byts)
+	}
+	return byts, nil
+}
+
 // WritePacketRTP writes a RTP packet to all the readers of the stream.
 func (st *ServerStream) WritePacketRTP(trackID int, pkt *rtp.Packet, ptsEqualsDTS bool) {
 	byts := make([]byte, maxPacketSize)
@@ -239,10 +519,20 @@ func (st *ServerStream) WritePacketRTP(trackID int, pkt *rtp.Packet, ptsEqualsDT
 	}
 	byts = byts[:n]
 
+	track := st.stTracks[trackID]
+
+	var roc uint32
+	if track.srtpContext != nil {
+		byts, err = track.srtpContext.EncryptRTP(byts)
+		if err != nil {
+			return
+		}
+		roc = track.srtpContext.ROC(pkt.SSRC, pkt.SequenceNumber)
+	}
+
 	st.mutex.RLock()
 	defer st.mutex.RUnlock()
 
-	track := st.stTracks[trackID]
 	now := time.Now()
 
 	if !track.firstPacketSent ||
@@ -259,8 +549,20 @@ func (st *ServerStream) WritePacketRTP(trackID int, pkt *rtp.Packet, ptsEqualsDT
 		track.lastSSRC = pkt.Header.SSRC
 	}
 
-	if track.udpRTCPSender != nil {
-		track.udpRTCPSender.ProcessPacketRTP(now, pkt, ptsEqualsDTS)
+	if track.rtcpSender != nil {
+		track.rtcpSender.ProcessPacketRTP(now, pkt, ptsEqualsDTS)
+	}
+
+	if track.rtxHistory != nil {
+		track.rtxHistory.ProcessPacketRTP(pkt)
+	}
+
+	if track.gopCache != nil {
+		track.gopCache.processPacket(pkt, byts, roc, ptsEqualsDTS)
+	}
+
+	if st.timeShiftBuffer != nil {
+		st.timeShiftBuffer.writePacketRTP(trackID, byts, roc, ptsEqualsDTS, now)
 	}
 
 	// send unicast
@@ -272,11 +574,40 @@ func (st *ServerStream) WritePacketRTP(trackID int, pkt *rtp.Packet, ptsEqualsDT
 	if st.serverMulticastHandlers != nil {
 		st.serverMulticastHandlers[trackID].writePacketRTP(byts)
 	}
+
+	if track.fecEncoder != nil {
+		track.fecMutex.Lock()
+		fec, err := track.fecEncoder.Encode(pkt)
+		track.fecMutex.Unlock()
+
+		if err == nil && fec != nil {
+			st.writePacketFEC(trackID, fec)
+		}
+	}
+}
+
+func (st *ServerStream) writePacketFEC(trackID int, pkt *rtp.Packet) {
+	byts, err := st.marshalRTP(trackID, pkt)
+	if err != nil {
+		return
+	}
+
+	// send unicast (UDP only)
+	for r := range st.readersUnicast {
+		if *r.setuppedTransport == TransportUDP {
+			r.writePacketRTP(trackID, byts)
+		}
+	}
+
+	// send multicast
+	if st.serverMulticastHandlers != nil {
+		st.serverMulticastHandlers[trackID].writePacketRTP(byts)
+	}
 }
 
 // WritePacketRTCP writes a RTCP packet to all the readers of the stream.
 func (st *ServerStream) WritePacketRTCP(trackID int, pkt rtcp.Packet) {
-	byts, err := pkt.Marshal()
+	byts, err := st.marshalRTCP(trackID, pkt)
 	if err != nil {
 		return
 	}
@@ -296,7 +627,7 @@ func (st *ServerStream) WritePacketRTCP(trackID int, pkt rtcp.Packet) {
 }
 
 func (st *ServerStream) writePacketRTCPSenderReport(trackID int, pkt rtcp.Packet) {
-	byts, err := pkt.Marshal()
+	byts, err := st.marshalRTCP(trackID, pkt)
 	if err != nil {
 		return
 	}
@@ -316,3 +647,33 @@ func (st *ServerStream) writePacketRTCPSenderReport(trackID int, pkt rtcp.Packet
 		st.serverMulticastHandlers[trackID].writePacketRTCP(byts)
 	}
 }
+
+// retransmit sends to a reader the RTP packets requested by a RTCP NACK,
+// wrapped into retransmission packets.
+func (st *ServerStream) retransmit(ss *ServerSession, trackID int, pkt rtcp.Packet) {
+	st.mutex.RLock()
+	defer st.mutex.RUnlock()
+
+	track := st.stTracks[trackID]
+
+	if track.rtxHistory == nil {
+		return
+	}
+
+	if _, ok := st.readersUnicast[ss]; !ok {
+		return
+	}
+
+	for _, rpkt := range track.rtxHistory.ProcessPacketRTCP(pkt) {
+		track.rtxMutex.Lock()
+		rtxPkt := track.rtxEncoder.Encode(rpkt)
+		track.rtxMutex.Unlock()
+
+		byts, err := st.marshalRTP(trackID, rtxPkt)
+		if err != nil {
+			continue
+		}
+
+		ss.writePacketRTP(trackID, byts)
+	}
+}
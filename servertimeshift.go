@@ -0,0 +1,682 @@
+package gortsplib
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aler9/gortsplib/pkg/headers"
+)
+
+// maximum number of records that are read to fill RTP-Info.
+const serverTimeShiftRTPInfoMaxRecords = 10000
+
+// maximum number of packets that are waiting to be stored.
+const serverTimeShiftQueueSize = 1024
+
+func trackHasKeyFrames(track Track) bool {
+	switch track.(type) {
+	case *TrackH264, *TrackH265:
+		return true
+	}
+	return false
+}
+
+// serverTimeShiftCursor is a position inside a time-shift buffer.
+type serverTimeShiftCursor struct {
+	b      *ServerTimeShiftBuffer
+	seg    *serverTimeShiftSegment
+	offset int64
+}
+
+// next returns the record at the position of the cursor and moves the cursor
+// to the following record. It returns nil when the end of the buffer has been reached.
+func (c *serverTimeShiftCursor) next() (*serverTimeShiftRecord, error) {
+	c.b.mutex.Lock()
+	for c.offset >= c.seg.size {
+		if c.seg.next == nil {
+			c.b.mutex.Unlock()
+			return nil, nil
+		}
+
+		next := c.seg.next
+		next.refs++
+		c.b.releaseSegment(c.seg)
+		c.seg = next
+		c.offset = 0
+	}
+	seg := c.seg
+	c.b.mutex.Unlock()
+
+	rec, offset, err := seg.readRecord(c.offset)
+	if err != nil {
+		return nil, err
+	}
+
+	c.offset = offset
+	return rec, nil
+}
+
+// atEnd checks whether the end of the buffer has been reached.
+func (c *serverTimeShiftCursor) atEnd() bool {
+	c.b.mutex.Lock()
+	defer c.b.mutex.Unlock()
+	return c.offset >= c.seg.size && c.seg.next == nil
+}
+
+func (c *serverTimeShiftCursor) close() {
+	c.b.mutex.Lock()
+	defer c.b.mutex.Unlock()
+	c.b.releaseSegment(c.seg)
+}
+
+// serverTimeShiftReader sends the packets of a time-shift buffer to a session.
+type serverTimeShiftReader struct {
+	b        *ServerTimeShiftBuffer
+	ss       *ServerSession
+	cursor   *serverTimeShiftCursor
+	startNTP time.Time
+	speed    float64
+	rtpInfos map[int]serverStreamRTPInfo
+
+	// protected by the mutex of the stream
+	started    bool
+	terminated bool
+
+	terminate chan struct{}
+}
+
+// start starts sending packets. It must be called with the stream mutex locked.
+func (r *serverTimeShiftReader) start() {
+	r.started = true
+	r.b.wg.Add(1)
+	go r.run()
+}
+
+// stop stops sending packets. It must be called with the stream mutex locked.
+func (r *serverTimeShiftReader) stop() {
+	if r.terminated {
+		return
+	}
+	r.terminated = true
+
+	if r.started {
+		close(r.terminate)
+	} else {
+		r.cursor.close()
+	}
+}
+
+func (r *serverTimeShiftReader) run() {
+	defer r.b.wg.Done()
+	defer r.cursor.close()
+
+	st := r.b.stream
+	wallStart := time.Now()
+
+	for {
+		rec, err := r.cursor.next()
+		if err != nil {
+			// the segment has been removed while it was being read
+			r.catchUp(true)
+			return
+		}
+
+		if rec == nil {
+			// the notification is obtained before checking the end of the buffer,
+			// in order not to miss packets that are stored in the meanwhile.
+			written := r.b.writeNotification()
+
+			if !r.cursor.atEnd() {
+				continue
+			}
+
+			if r.catchUp(false) {
+				return
+			}
+
+			// wait for new packets
+			select {
+			case <-written:
+			case <-r.terminate:
+				return
+			}
+			continue
+		}
+
+		wait := time.Duration(float64(rec.ntp.Sub(r.startNTP))/r.speed) - time.Since(wallStart)
+		if wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-r.terminate:
+				t.Stop()
+				return
+			}
+		}
+
+		st.mutex.RLock()
+		if r.terminated {
+			st.mutex.RUnlock()
+			return
+		}
+		r.ss.writePacketRTP(rec.trackID, rec.byts)
+		st.mutex.RUnlock()
+	}
+}
+
+// catchUp switches the session to the live stream when the end of the buffer is reached,
+// or immediately if force is true.
+func (r *serverTimeShiftReader) catchUp(force bool) bool {
+	st := r.b.stream
+
+	// packets are queued into the buffer with the stream mutex locked,
+	// therefore no packet can be lost or duplicated between the buffer and the live stream.
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	if r.terminated {
+		return true
+	}
+
+	// packets can't be queued while the stream mutex is locked,
+	// therefore when the queue is empty, the end of the buffer can't move anymore.
+	if !force && (r.b.queued() != 0 || !r.cursor.atEnd()) {
+		return false
+	}
+
+	r.terminated = true
+	delete(st.readersDelayed, r.ss)
+	st.readersUnicast[r.ss] = struct{}{}
+	return true
+}
+
+// ServerTimeShiftBuffer is a time-shift (DVR) buffer.
+// It is attached to a ServerStream, stores the packets of the last period
+// in memory or on disk, and allows readers to start reading from a point in the past,
+// then to catch up with the live stream.
+type ServerTimeShiftBuffer struct {
+	// duration of the buffer.
+	// It defaults to 30 minutes.
+	Duration time.Duration
+	// packets are stored into segments of this duration,
+	// that are removed when they exit the buffer.
+	// It defaults to 10 seconds.
+	SegmentDuration time.Duration
+	// directory in which packets are stored.
+	// If empty, packets are stored in memory.
+	Directory string
+	// called when packets can't be stored.
+	// It is called by a dedicated routine.
+	OnError func(error)
+
+	stream          *ServerStream
+	keyFrameTrackID int
+	wg              sync.WaitGroup
+	queue           chan serverTimeShiftRecord
+	writerDone      chan struct{}
+
+	mutex        sync.Mutex
+	pending      int // packets that are queued or being stored
+	discarded    int // packets discarded since the queue was full
+	written      chan struct{}
+	segments     []*serverTimeShiftSegment
+	lastRAPNTP   time.Time
+	auStarted    bool
+	auTimestamp  uint32
+	auSeg        *serverTimeShiftSegment
+	auRAP        serverTimeShiftRandomAccessPoint
+	auRegistered bool
+}
+
+// Start attaches the buffer to a stream and starts storing its packets.
+func (b *ServerTimeShiftBuffer) Start(stream *ServerStream) error {
+	if b.Duration == 0 {
+		b.Duration = 30 * time.Minute
+	}
+	if b.SegmentDuration == 0 {
+		b.SegmentDuration = 10 * time.Second
+	}
+
+	if b.Directory != "" {
+		err := os.MkdirAll(b.Directory, 0o755)
+		if err != nil {
+			return err
+		}
+	}
+
+	// key frames of the first video track are used as random access points.
+	b.keyFrameTrackID = -1
+	for trackID, track := range stream.tracks {
+		if trackHasKeyFrames(track) {
+			b.keyFrameTrackID = trackID
+			break
+		}
+	}
+
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	if stream.readers == nil {
+		return fmt.Errorf("stream is closed")
+	}
+
+	if stream.timeShiftBuffer != nil {
+		return fmt.Errorf("stream already has a time-shift buffer")
+	}
+
+	b.stream = stream
+	b.queue = make(chan serverTimeShiftRecord, serverTimeShiftQueueSize)
+	b.writerDone = make(chan struct{})
+	b.written = make(chan struct{})
+	stream.timeShiftBuffer = b
+
+	go b.runWriter()
+
+	return nil
+}
+
+// Close detaches the buffer from the stream and removes stored packets.
+// Sessions that are reading from the buffer are switched to the live stream.
+func (b *ServerTimeShiftBuffer) Close() error {
+	st := b.stream
+
+	st.mutex.Lock()
+	attached := st.timeShiftBuffer == b
+	if attached {
+		st.timeShiftBuffer = nil
+	}
+	for ss, r := range st.readersDelayed {
+		if r.b != b {
+			continue
+		}
+
+		r.stop()
+		delete(st.readersDelayed, ss)
+
+		if r.started && st.readersUnicast != nil {
+			st.readersUnicast[ss] = struct{}{}
+		}
+	}
+	st.mutex.Unlock()
+
+	// packets can't be queued anymore
+	if attached {
+		close(b.queue)
+		<-b.writerDone
+	}
+
+	b.wg.Wait()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, seg := range b.segments {
+		seg.removed = true
+		if seg.refs == 0 {
+			seg.storage.remove()
+		}
+	}
+	b.segments = nil
+
+	return nil
+}
+
+// Bounds returns the time interval of the stored packets.
+func (b *ServerTimeShiftBuffer) Bounds() (time.Time, time.Time, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.segments) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return b.segments[0].startNTP, b.segments[len(b.segments)-1].endNTP, true
+}
+
+// Play makes a session read the stream from the point in time requested with ra,
+// that must be expressed in UTC units (i.e. clock=20220101T100000Z-) in order to point to the past.
+// Ranges expressed in NPT units (i.e. npt=0-, that is sent by most clients) and nil ranges
+// point to the live edge. The session starts reading from the key frame that precedes the requested
+// point, at the rate requested with speed, and switches to the live stream when
+// the end of the buffer is reached.
+// It must be called inside ServerHandler.OnPlay, with the values of ServerHandlerOnPlayCtx.
+// It returns the effective starting point.
+func (b *ServerTimeShiftBuffer) Play(ss *ServerSession, ra *headers.Range, speed *headers.Speed) (time.Time, error) {
+	st := b.stream
+
+	if ss.setuppedStream != st {
+		return time.Time{}, fmt.Errorf("session is not reading the stream of the buffer")
+	}
+
+	if *ss.setuppedTransport == TransportUDPMulticast {
+		return time.Time{}, fmt.Errorf("time-shift is not supported with the UDP-multicast transport")
+	}
+
+	cursor, startNTP, err := b.seek(ra)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// requested point is not in the past: read the live stream
+	if cursor == nil {
+		st.mutex.Lock()
+		defer st.mutex.Unlock()
+
+		if r, ok := st.readersDelayed[ss]; ok {
+			r.stop()
+			delete(st.readersDelayed, ss)
+			if r.started {
+				st.readersUnicast[ss] = struct{}{}
+			}
+		}
+
+		return time.Now(), nil
+	}
+
+	r := &serverTimeShiftReader{
+		b:         b,
+		ss:        ss,
+		cursor:    cursor,
+		startNTP:  startNTP,
+		speed:     1,
+		rtpInfos:  b.rtpInfos(cursor, ss),
+		terminate: make(chan struct{}),
+	}
+	if speed != nil {
+		r.speed = float64(*speed)
+	}
+
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	if st.readers == nil {
+		cursor.close()
+		return time.Time{}, fmt.Errorf("stream is closed")
+	}
+
+	if prev, ok := st.readersDelayed[ss]; ok {
+		prev.stop()
+	}
+	delete(st.readersUnicast, ss)
+	st.readersDelayed[ss] = r
+
+	// when the session is already playing, readerSetActive() is not called
+	if ss.state == ServerSessionStatePlay {
+		r.start()
+	}
+
+	return startNTP, nil
+}
+
+// seek returns a cursor that points to the random access point that precedes
+// the requested point, or nil if the requested point is not in the past.
+func (b *ServerTimeShiftBuffer) seek(ra *headers.Range) (*serverTimeShiftCursor, time.Time, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if ra == nil || len(b.segments) == 0 {
+		return nil, time.Time{}, nil
+	}
+
+	var target time.Time
+	switch v := ra.Value.(type) {
+	case *headers.RangeNPT:
+		// the NPT of a live stream starts from the live edge,
+		// therefore it can't point to the past.
+		return nil, time.Time{}, nil
+
+	case *headers.RangeUTC:
+		target = time.Time(v.Start)
+
+	default:
+		return nil, time.Time{}, fmt.Errorf("unsupported range unit")
+	}
+
+	if !target.Before(b.segments[len(b.segments)-1].endNTP) {
+		return nil, time.Time{}, nil
+	}
+
+	var seg *serverTimeShiftSegment
+	var rap serverTimeShiftRandomAccessPoint
+
+outer:
+	for _, s := range b.segments {
+		for _, p := range s.raps {
+			// use the first random access point when target precedes all of them
+			if seg != nil && p.ntp.After(target) {
+				break outer
+			}
+			seg = s
+			rap = p
+		}
+	}
+
+	if seg == nil {
+		return nil, time.Time{}, nil
+	}
+
+	seg.refs++
+
+	return &serverTimeShiftCursor{
+		b:      b,
+		seg:    seg,
+		offset: rap.offset,
+	}, rap.ntp, nil
+}
+
+// rtpInfos returns the sequence number and timestamp of the first packet
+// of each track that is read by a session starting from a cursor.
+func (b *ServerTimeShiftBuffer) rtpInfos(cursor *serverTimeShiftCursor, ss *ServerSession) map[int]serverStreamRTPInfo {
+	b.mutex.Lock()
+	cursor.seg.refs++
+	b.mutex.Unlock()
+
+	c := &serverTimeShiftCursor{
+		b:      b,
+		seg:    cursor.seg,
+		offset: cursor.offset,
+	}
+	defer c.close()
+
+	ret := make(map[int]serverStreamRTPInfo)
+
+	for i := 0; i < serverTimeShiftRTPInfoMaxRecords && len(ret) < len(ss.setuppedTracks); i++ {
+		rec, err := c.next()
+		if err != nil || rec == nil {
+			break
+		}
+
+		if _, ok := ss.setuppedTracks[rec.trackID]; !ok {
+			continue
+		}
+
+		if _, ok := ret[rec.trackID]; !ok {
+			ret[rec.trackID] = serverStreamRTPInfo{
+				sequenceNumber: binary.BigEndian.Uint16(rec.byts[2:]),
+				timestamp:      binary.BigEndian.Uint32(rec.byts[4:]),
+				ssrc:           binary.BigEndian.Uint32(rec.byts[8:]),
+				roc:            rec.roc,
+			}
+		}
+	}
+
+	return ret
+}
+
+// releaseSegment must be called with the buffer mutex locked.
+func (b *ServerTimeShiftBuffer) releaseSegment(seg *serverTimeShiftSegment) {
+	seg.refs--
+	if seg.refs == 0 && seg.removed {
+		seg.storage.remove()
+	}
+}
+
+func (b *ServerTimeShiftBuffer) newStorage() (serverTimeShiftStorage, error) {
+	if b.Directory != "" {
+		return newServerTimeShiftFileStorage(b.Directory)
+	}
+	return &serverTimeShiftMemoryStorage{}, nil
+}
+
+// writePacketRTP is called by ServerStream with its mutex read-locked.
+// Packets are stored by runWriter, in order not to perform I/O with the mutex locked.
+func (b *ServerTimeShiftBuffer) writePacketRTP(trackID int, byts []byte, roc uint32, ptsEqualsDTS bool, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	select {
+	case b.queue <- serverTimeShiftRecord{
+		trackID:      trackID,
+		ptsEqualsDTS: ptsEqualsDTS,
+		ntp:          now,
+		roc:          roc,
+		byts:         byts,
+	}:
+		b.pending++
+
+	default:
+		b.discarded++
+	}
+}
+
+// queued returns the number of packets that are waiting to be stored.
+func (b *ServerTimeShiftBuffer) queued() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.pending
+}
+
+// writeNotification returns a channel that is closed when the next packet is processed.
+func (b *ServerTimeShiftBuffer) writeNotification() chan struct{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.written
+}
+
+func (b *ServerTimeShiftBuffer) runWriter() {
+	defer close(b.writerDone)
+
+	for rec := range b.queue {
+		err := b.store(rec)
+
+		b.mutex.Lock()
+		b.pending--
+		close(b.written)
+		b.written = make(chan struct{})
+		discarded := b.discarded
+		b.discarded = 0
+		b.mutex.Unlock()
+
+		if b.OnError != nil {
+			if err != nil {
+				b.OnError(err)
+			}
+			if discarded != 0 {
+				b.OnError(fmt.Errorf("%d packets discarded because the queue is full", discarded))
+			}
+		}
+	}
+}
+
+// store writes a record into the last segment.
+// Segments are added and written by runWriter only,
+// therefore I/O can be performed without locking the mutex.
+func (b *ServerTimeShiftBuffer) store(rec serverTimeShiftRecord) error {
+	b.mutex.Lock()
+	var cur *serverTimeShiftSegment
+	if len(b.segments) != 0 {
+		cur = b.segments[len(b.segments)-1]
+	}
+	b.mutex.Unlock()
+
+	if cur == nil || rec.ntp.Sub(cur.startNTP) >= b.SegmentDuration {
+		storage, err := b.newStorage()
+		if err != nil {
+			return err
+		}
+
+		seg := &serverTimeShiftSegment{
+			storage:  storage,
+			startNTP: rec.ntp,
+		}
+
+		b.mutex.Lock()
+		if cur != nil {
+			cur.next = seg
+		}
+		b.segments = append(b.segments, seg)
+		b.mutex.Unlock()
+
+		cur = seg
+	}
+
+	offset := cur.size
+
+	_, err := cur.storage.Write(rec.marshal())
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	cur.size += serverTimeShiftRecordHeaderSize + int64(len(rec.byts))
+	cur.endNTP = rec.ntp
+
+	b.addRandomAccessPoint(cur, offset, rec.trackID, rec.byts, rec.ptsEqualsDTS, rec.ntp)
+	b.removeExpired(rec.ntp)
+
+	return nil
+}
+
+func (b *ServerTimeShiftBuffer) addRandomAccessPoint(
+	cur *serverTimeShiftSegment,
+	offset int64,
+	trackID int,
+	byts []byte,
+	ptsEqualsDTS bool,
+	now time.Time,
+) {
+	// without key frames, every packet is a random access point: add one every second.
+	if b.keyFrameTrackID < 0 {
+		if now.Sub(b.lastRAPNTP) >= time.Second {
+			cur.raps = append(cur.raps, serverTimeShiftRandomAccessPoint{offset: offset, ntp: now})
+			b.lastRAPNTP = now
+		}
+		return
+	}
+
+	if trackID != b.keyFrameTrackID {
+		return
+	}
+
+	// ptsEqualsDTS is true in the last packet of a key frame,
+	// while the key frame begins with the first packet with the same timestamp.
+	ts := binary.BigEndian.Uint32(byts[4:])
+	if !b.auStarted || ts != b.auTimestamp {
+		b.auStarted = true
+		b.auTimestamp = ts
+		b.auSeg = cur
+		b.auRAP = serverTimeShiftRandomAccessPoint{offset: offset, ntp: now}
+		b.auRegistered = false
+	}
+
+	if ptsEqualsDTS && !b.auRegistered {
+		b.auSeg.raps = append(b.auSeg.raps, b.auRAP)
+		b.auRegistered = true
+	}
+}
+
+func (b *ServerTimeShiftBuffer) removeExpired(now time.Time) {
+	for len(b.segments) > 1 && now.Sub(b.segments[0].endNTP) > b.Duration {
+		seg := b.segments[0]
+		seg.removed = true
+		if seg.refs == 0 {
+			seg.storage.remove()
+		}
+		b.segments = b.segments[1:]
+	}
+}
@@ -0,0 +1,133 @@
+package gortsplib
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const serverTimeShiftRecordHeaderSize = 16
+
+// serverTimeShiftRecord is a RTP packet stored into a time-shift buffer.
+type serverTimeShiftRecord struct {
+	trackID      int
+	ptsEqualsDTS bool
+	ntp          time.Time
+	roc          uint32 // SRTP rollover counter
+	byts         []byte
+}
+
+func (r serverTimeShiftRecord) marshal() []byte {
+	buf := make([]byte, serverTimeShiftRecordHeaderSize+len(r.byts))
+	buf[0] = byte(r.trackID)
+	if r.ptsEqualsDTS {
+		buf[1] = 1
+	}
+	binary.BigEndian.PutUint64(buf[2:], uint64(r.ntp.UnixNano()))
+	binary.BigEndian.PutUint16(buf[10:], uint16(len(r.byts)))
+	binary.BigEndian.PutUint32(buf[12:], r.roc)
+	copy(buf[serverTimeShiftRecordHeaderSize:], r.byts)
+	return buf
+}
+
+// serverTimeShiftStorage is where the records of a segment are stored.
+type serverTimeShiftStorage interface {
+	io.Writer
+	io.ReaderAt
+	remove() error
+}
+
+type serverTimeShiftMemoryStorage struct {
+	mutex sync.RWMutex
+	buf   []byte
+}
+
+func (s *serverTimeShiftMemoryStorage) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.buf = append(s.buf, p...)
+	return len(p), nil
+}
+
+func (s *serverTimeShiftMemoryStorage) ReadAt(p []byte, off int64) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if off >= int64(len(s.buf)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, s.buf[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (s *serverTimeShiftMemoryStorage) remove() error {
+	return nil
+}
+
+type serverTimeShiftFileStorage struct {
+	*os.File
+}
+
+func newServerTimeShiftFileStorage(dir string) (*serverTimeShiftFileStorage, error) {
+	f, err := os.CreateTemp(dir, "segment-*.bin")
+	if err != nil {
+		return nil, err
+	}
+
+	return &serverTimeShiftFileStorage{f}, nil
+}
+
+func (s *serverTimeShiftFileStorage) remove() error {
+	s.File.Close()
+	return os.Remove(s.File.Name())
+}
+
+// serverTimeShiftRandomAccessPoint is a record from which reading can start,
+// that is the first record of a key frame.
+type serverTimeShiftRandomAccessPoint struct {
+	offset int64
+	ntp    time.Time
+}
+
+// serverTimeShiftSegment is a portion of a time-shift buffer.
+// All fields are protected by the mutex of the buffer.
+type serverTimeShiftSegment struct {
+	storage  serverTimeShiftStorage
+	startNTP time.Time
+	endNTP   time.Time
+	size     int64
+	raps     []serverTimeShiftRandomAccessPoint
+	next     *serverTimeShiftSegment
+
+	refs    int // readers that are reading the segment
+	removed bool
+}
+
+func (seg *serverTimeShiftSegment) readRecord(offset int64) (*serverTimeShiftRecord, int64, error) {
+	var header [serverTimeShiftRecordHeaderSize]byte
+	_, err := seg.storage.ReadAt(header[:], offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rec := &serverTimeShiftRecord{
+		trackID:      int(header[0]),
+		ptsEqualsDTS: header[1] != 0,
+		ntp:          time.Unix(0, int64(binary.BigEndian.Uint64(header[2:]))),
+		roc:          binary.BigEndian.Uint32(header[12:]),
+		byts:         make([]byte, binary.BigEndian.Uint16(header[10:])),
+	}
+
+	_, err = seg.storage.ReadAt(rec.byts, offset+serverTimeShiftRecordHeaderSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return rec, offset + serverTimeShiftRecordHeaderSize + int64(len(rec.byts)), nil
+}
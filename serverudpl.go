@@ -1,6 +1,7 @@
 package gortsplib
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
 	"strconv"
@@ -9,13 +10,47 @@ import (
 	"time"
 
 	"github.com/pion/rtcp"
-	"golang.org/x/net/ipv4"
 )
 
 type clientData struct {
 	ss           *ServerSession
 	track        *ServerSessionSetuppedTrack
 	isPublishing bool
+
+	// symmetric RTP
+	latchDeadline time.Time // zero when the client address is known
+	payloadTypes  map[uint8]struct{}
+}
+
+// latchMatch describes how much a packet coming from an unknown address
+// is likely to belong to a client whose address has not been learned yet.
+type latchMatch int
+
+const (
+	// the packet does not belong to the client
+	latchMatchNone latchMatch = iota
+
+	// the packet comes from the IP of the RTSP connection of the client
+	latchMatchIP
+
+	// the packet contains the SSRC of the client or of the stream
+	latchMatchSSRC
+)
+
+// packetSenderSSRC returns the SSRC of the sender of a RTP or RTCP packet.
+// The SSRC is not encrypted by SRTP.
+func packetSenderSSRC(isRTP bool, payload []byte) (uint32, bool) {
+	if isRTP {
+		if len(payload) < 12 {
+			return 0, false
+		}
+		return binary.BigEndian.Uint32(payload[8:12]), true
+	}
+
+	if len(payload) < 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(payload[4:8]), true
 }
 
 type clientAddr struct {
@@ -39,31 +74,35 @@ type serverUDPListener struct {
 
 	pc           *net.UDPConn
 	listenIP     net.IP
+	multicast    bool
 	isRTP        bool
 	writeTimeout time.Duration
 	clientsMutex sync.RWMutex
 	clients      map[clientAddr]*clientData
 
+	// owned by the reader goroutine
+	rtpPacketBuffer *rtpPacketMultiBuffer
+
 	readerDone chan struct{}
 }
 
 func newServerUDPListenerMulticastPair(s *Server) (*serverUDPListener, *serverUDPListener, error) {
-	res := make(chan net.IP)
+	res := make(chan streamMulticastIPRes)
 	select {
 	case s.streamMulticastIP <- streamMulticastIPReq{res: res}:
 	case <-s.ctx.Done():
 		return nil, nil, fmt.Errorf("terminated")
 	}
-	ip := <-res
+	r := <-res
 
 	rtpl, err := newServerUDPListener(s, true,
-		ip.String()+":"+strconv.FormatInt(int64(s.MulticastRTPPort), 10), true)
+		net.JoinHostPort(r.ip.String(), strconv.FormatInt(int64(r.rtpPort), 10)), true)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	rtcpl, err := newServerUDPListener(s, true,
-		ip.String()+":"+strconv.FormatInt(int64(s.MulticastRTCPPort), 10), false)
+		net.JoinHostPort(r.ip.String(), strconv.FormatInt(int64(r.rtcpPort), 10)), false)
 	if err != nil {
 		rtpl.close()
 		return nil, nil, err
@@ -72,6 +111,18 @@ func newServerUDPListenerMulticastPair(s *Server) (*serverUDPListener, *serverUD
 	return rtpl, rtcpl, nil
 }
 
+func newServerUDPListenerUnicastPair(s *Server, rtcpMux bool) (*serverUDPListener, *serverUDPListener, error) {
+	res := make(chan udpListenerPairRes)
+	select {
+	case s.udpListenerPair <- udpListenerPairReq{rtcpMux: rtcpMux, res: res}:
+	case <-s.ctx.Done():
+		return nil, nil, fmt.Errorf("terminated")
+	}
+	r := <-res
+
+	return r.rtpl, r.rtcpl, r.err
+}
+
 func newServerUDPListener(
 	s *Server,
 	multicast bool,
@@ -86,35 +137,18 @@ func newServerUDPListener(
 			return nil, err
 		}
 
-		tmp, err := s.ListenPacket("udp", "224.0.0.0:"+port)
+		portNum, err := strconv.ParseInt(port, 10, 64)
 		if err != nil {
 			return nil, err
 		}
 
-		p := ipv4.NewPacketConn(tmp)
-
-		err = p.SetMulticastTTL(multicastTTL)
-		if err != nil {
-			return nil, err
-		}
+		listenIP = net.ParseIP(host)
 
-		intfs, err := net.Interfaces()
+		// the server only writes to the group, therefore joining it is not mandatory.
+		pc, err = listenMulticast(s.ListenPacket, listenIP, int(portNum), nil, s.MulticastTTL, false)
 		if err != nil {
 			return nil, err
 		}
-
-		listenIP = net.ParseIP(host)
-
-		for _, intf := range intfs {
-			if (intf.Flags & net.FlagMulticast) != 0 {
-				// do not check for errors.
-				// on macOS, there are interfaces with the multicast flag but
-				// without support for multicast, that makes this function fail.
-				p.JoinGroup(&intf, &net.UDPAddr{IP: listenIP})
-			}
-		}
-
-		pc = tmp.(*net.UDPConn)
 	} else {
 		tmp, err := s.ListenPacket("udp", address)
 		if err != nil {
@@ -134,12 +168,17 @@ func newServerUDPListener(
 		s:            s,
 		pc:           pc,
 		listenIP:     listenIP,
+		multicast:    multicast,
 		clients:      make(map[clientAddr]*clientData),
 		isRTP:        isRTP,
 		writeTimeout: s.WriteTimeout,
 		readerDone:   make(chan struct{}),
 	}
 
+	if isRTP {
+		u.rtpPacketBuffer = newRTPPacketMultiBuffer(uint64(s.ReadBufferCount))
+	}
+
 	go u.runReader()
 
 	return u, nil
@@ -161,13 +200,6 @@ func (u *serverUDPListener) port() int {
 func (u *serverUDPListener) runReader() {
 	defer close(u.readerDone)
 
-	var processFunc func(*clientData, []byte)
-	if u.isRTP {
-		processFunc = u.processRTP
-	} else {
-		processFunc = u.processRTCP
-	}
-
 	for {
 		buf := make([]byte, maxPacketSize)
 		n, addr, err := u.pc.ReadFromUDP(buf)
@@ -182,17 +214,52 @@ func (u *serverUDPListener) runReader() {
 			var clientAddr clientAddr
 			clientAddr.fill(addr.IP, addr.Port)
 			clientData, ok := u.clients[clientAddr]
+
+			if u.latching() && (!ok || !clientData.latchDeadline.IsZero()) {
+				// learning the client address requires write access to clients
+				u.clientsMutex.RUnlock()
+				u.latch(clientAddr, addr, buf[:n])
+				u.clientsMutex.RLock()
+
+				clientData, ok = u.clients[clientAddr]
+			}
+
 			if !ok {
 				return
 			}
 
-			processFunc(clientData, buf[:n])
+			payload := buf[:n]
+			isRTP := u.packetIsRTP(clientData, payload)
+
+			// readers send RTP packets only to open NAT mappings
+			if isRTP && !clientData.isPublishing {
+				return
+			}
+
+			if clientData.track.srtpReadContext != nil {
+				payload, err = srtpDecrypt(clientData.track.srtpReadContext, isRTP, payload)
+				if err != nil {
+					return
+				}
+			}
+
+			if isRTP {
+				u.processRTP(clientData, payload)
+			} else {
+				u.processRTCP(clientData, payload)
+			}
 		}()
 	}
 }
 
+// packetIsRTP checks whether a packet is a RTP packet, taking into account
+// clients that multiplex RTP and RTCP on the RTP port.
+func (u *serverUDPListener) packetIsRTP(cd *clientData, payload []byte) bool {
+	return u.isRTP && !(cd.track.rtcpMux && isRTCPPacket(payload))
+}
+
 func (u *serverUDPListener) processRTP(clientData *clientData, payload []byte) {
-	pkt := u.s.udpRTPPacketBuffer.next()
+	pkt := u.rtpPacketBuffer.next()
 	err := pkt.Unmarshal(payload)
 	if err != nil {
 		return
@@ -201,22 +268,33 @@ func (u *serverUDPListener) processRTP(clientData *clientData, payload []byte) {
 	now := time.Now()
 	atomic.StoreInt64(clientData.ss.udpLastFrameTime, now.Unix())
 
+	if clientData.track.udpRTXReceiver != nil {
+		pkt = clientData.track.udpRTXReceiver.processPacketRTP(pkt)
+		if pkt == nil {
+			return
+		}
+	}
+
 	out, err := clientData.track.cleaner.Clear(pkt)
 	if err != nil {
 		return
 	}
 	out0 := out[0]
 
-	clientData.track.udpRTCPReceiver.ProcessPacketRTP(now, pkt, out0.PTSEqualsDTS)
+	clientData.track.rtcpReceiver.ProcessPacketRTP(now, pkt, out0.PTSEqualsDTS)
 
 	if h, ok := clientData.ss.s.Handler.(ServerHandlerOnPacketRTP); ok {
+		ntp, ntpSynchronized := clientData.track.rtcpReceiver.PacketNTP(pkt.Timestamp)
+
 		h.OnPacketRTP(&ServerHandlerOnPacketRTPCtx{
-			Session:      clientData.ss,
-			TrackID:      clientData.track.id,
-			Packet:       out0.Packet,
-			PTSEqualsDTS: out0.PTSEqualsDTS,
-			H264NALUs:    out0.H264NALUs,
-			H264PTS:      out0.H264PTS,
+			Session:         clientData.ss,
+			TrackID:         clientData.track.id,
+			Packet:          out0.Packet,
+			PTSEqualsDTS:    out0.PTSEqualsDTS,
+			H264NALUs:       out0.H264NALUs,
+			H264PTS:         out0.H264PTS,
+			NTP:             ntp,
+			NTPSynchronized: ntpSynchronized,
 		})
 	}
 }
@@ -232,7 +310,7 @@ func (u *serverUDPListener) processRTCP(clientData *clientData, payload []byte)
 		atomic.StoreInt64(clientData.ss.udpLastFrameTime, now.Unix())
 
 		for _, pkt := range packets {
-			clientData.track.udpRTCPReceiver.ProcessPacketRTCP(now, pkt)
+			clientData.track.rtcpReceiver.ProcessPacketRTCP(now, pkt)
 		}
 	}
 
@@ -259,11 +337,26 @@ func (u *serverUDPListener) addClient(ip net.IP, port int, ss *ServerSession,
 	var addr clientAddr
 	addr.fill(ip, port)
 
-	u.clients[addr] = &clientData{
+	cd := &clientData{
 		ss:           ss,
 		track:        track,
 		isPublishing: isPublishing,
 	}
+
+	if u.latching() {
+		cd.latchDeadline = time.Now().Add(u.s.UDPLatchingPeriod)
+
+		if isPublishing && u.isRTP {
+			cd.payloadTypes = make(map[uint8]struct{})
+			for _, f := range ss.announcedTracks[track.id].MediaDescription().MediaName.Formats {
+				if tmp, err := strconv.ParseUint(f, 10, 8); err == nil {
+					cd.payloadTypes[uint8(tmp)] = struct{}{}
+				}
+			}
+		}
+	}
+
+	u.clients[addr] = cd
 }
 
 func (u *serverUDPListener) removeClient(ss *ServerSession) {
@@ -276,3 +369,110 @@ func (u *serverUDPListener) removeClient(ss *ServerSession) {
 		}
 	}
 }
+
+func (u *serverUDPListener) latching() bool {
+	return u.s.UDPSymmetricRTP && !u.multicast
+}
+
+// latch associates a packet coming from an unknown address with the client
+// that most likely sent it, and replaces the address of the client with the
+// packet address (symmetric RTP).
+// Packets that can't be associated with a single client are ignored.
+func (u *serverUDPListener) latch(ca clientAddr, addr *net.UDPAddr, payload []byte) {
+	u.clientsMutex.Lock()
+	defer u.clientsMutex.Unlock()
+
+	// the client is using the address it declared
+	if cd, ok := u.clients[ca]; ok {
+		cd.latchDeadline = time.Time{}
+		return
+	}
+
+	now := time.Now()
+	bestMatch := latchMatchNone
+	var candidates []clientAddr
+
+	for key, cd := range u.clients {
+		if cd.latchDeadline.IsZero() || now.After(cd.latchDeadline) {
+			continue
+		}
+
+		m := u.latchMatch(ca, key, cd, payload)
+		switch {
+		case m > bestMatch:
+			bestMatch = m
+			candidates = []clientAddr{key}
+
+		case m == bestMatch && m != latchMatchNone:
+			candidates = append(candidates, key)
+		}
+	}
+
+	if len(candidates) != 1 {
+		return
+	}
+
+	cd := u.clients[candidates[0]]
+	delete(u.clients, candidates[0])
+	cd.latchDeadline = time.Time{}
+	u.clients[ca] = cd
+
+	var ssrc *uint32
+	if cd.isPublishing && u.packetIsRTP(cd, payload) {
+		if v, ok := packetSenderSSRC(true, payload); ok {
+			ssrc = &v
+		}
+	}
+
+	cd.track.udpLatch(u.isRTP, &net.UDPAddr{
+		IP:   addr.IP,
+		Zone: addr.Zone,
+		Port: addr.Port,
+	}, ssrc)
+}
+
+func (u *serverUDPListener) latchMatch(ca clientAddr, key clientAddr, cd *clientData, payload []byte) latchMatch {
+	isRTP := u.packetIsRTP(cd, payload)
+
+	if cd.isPublishing {
+		if ssrc, ok := cd.track.udpClientSSRC(); ok {
+			if v, ok := packetSenderSSRC(isRTP, payload); ok && v == ssrc {
+				return latchMatchSSRC
+			}
+			return latchMatchNone
+		}
+
+		if isRTP {
+			if len(payload) < 2 {
+				return latchMatchNone
+			}
+
+			if _, ok := cd.payloadTypes[payload[1]&0x7F]; !ok {
+				return latchMatchNone
+			}
+		}
+	} else if !isRTP && cd.track.srtpReadContext == nil && ca.ip == key.ip {
+		// receiver reports of readers contain the SSRC of the stream.
+		// since the SSRC of the stream is public, it is used only to distinguish
+		// readers that share the same IP, and the IP must match anyway.
+		if ssrc := cd.ss.setuppedStream.ssrc(cd.track.id); ssrc != 0 {
+			if packets, err := rtcp.Unmarshal(payload); err == nil {
+				for _, pkt := range packets {
+					if rr, ok := pkt.(*rtcp.ReceiverReport); ok {
+						for _, r := range rr.Reports {
+							if r.SSRC == ssrc {
+								return latchMatchSSRC
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if ca.ip == key.ip {
+		return latchMatchIP
+	}
+
+	return latchMatchNone
+}
@@ -0,0 +1,41 @@
+package gortsplib
+
+import (
+	"github.com/aler9/gortsplib/pkg/mikey"
+	"github.com/aler9/gortsplib/pkg/srtp"
+)
+
+// newSRTPContext allocates a SRTP context for a track, if the track is protected with SRTP.
+func newSRTPContext(track Track) (*srtp.Context, error) {
+	key, _, ok := track.SRTP()
+	if !ok {
+		return nil, nil
+	}
+	return srtp.NewContext(key)
+}
+
+func srtpEncrypt(ctx *srtp.Context, isRTP bool, payload []byte) ([]byte, error) {
+	if isRTP {
+		return ctx.EncryptRTP(payload)
+	}
+	return ctx.EncryptRTCP(payload)
+}
+
+func srtpDecrypt(ctx *srtp.Context, isRTP bool, payload []byte) ([]byte, error) {
+	if isRTP {
+		return ctx.DecryptRTP(payload)
+	}
+	return ctx.DecryptRTCP(payload)
+}
+
+// srtpKeyMgmtData returns a MIKEY message that contains a SRTP master key
+// and the rollover counter of a stream, to be inserted into a KeyMgmt header.
+func srtpKeyMgmtData(key *srtp.Key, ssrc uint32, roc uint32) ([]byte, error) {
+	msg, err := mikey.NewMessage(key, []uint32{ssrc})
+	if err != nil {
+		return nil, err
+	}
+
+	msg.CryptoSessions[0].ROC = roc
+	return msg.Marshal()
+}
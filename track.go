@@ -1,12 +1,16 @@
 package gortsplib
 
 import (
+	"encoding/base64"
 	"fmt"
 	"strconv"
 	"strings"
 
 	psdp "github.com/pion/sdp/v3"
 
+	"github.com/aler9/gortsplib/pkg/mikey"
+	"github.com/aler9/gortsplib/pkg/rtpfec"
+	"github.com/aler9/gortsplib/pkg/srtp"
 	"github.com/aler9/gortsplib/pkg/url"
 )
 
@@ -24,11 +28,277 @@ type Track interface {
 	// MediaDescription returns the track media description in SDP format.
 	MediaDescription() *psdp.MediaDescription
 
+	// RTXPayloadType returns the payload type of RTP retransmission (RFC 4588) packets, if any.
+	RTXPayloadType() (uint8, bool)
+
+	// SetRTXPayloadType sets the payload type of RTP retransmission (RFC 4588) packets.
+	SetRTXPayloadType(uint8)
+
+	// FEC returns the payload type and scheme of forward error correction packets, if any.
+	FEC() (uint8, rtpfec.Scheme, bool)
+
+	// SetFEC sets the payload type and scheme of forward error correction packets.
+	SetFEC(uint8, rtpfec.Scheme)
+
+	// SRTP returns the SRTP master key of the track and the method used to exchange it, if any.
+	SRTP() (*srtp.Key, srtp.KeyExchange, bool)
+
+	// SetSRTP sets the SRTP master key of the track and the method used to exchange it.
+	SetSRTP(*srtp.Key, srtp.KeyExchange) error
+
+	// RTCPMux returns whether RTP and RTCP packets of the track can be multiplexed on the same port (RFC 5761).
+	RTCPMux() bool
+
+	// SetRTCPMux sets whether RTP and RTCP packets of the track can be multiplexed on the same port (RFC 5761).
+	SetRTCPMux(bool)
+
 	clone() Track
 	url(*url.URL) (*url.URL, error)
+	srtpAttribute() psdp.Attribute
+	rtxAssociatedPayloadType() (uint8, bool)
+	setRTXAssociatedPayloadType(uint8)
+}
+
+// trackExtractFormat removes the first format with one of the given encoding names
+// from a media description and returns its payload type and encoding name.
+// It is used to extract retransmission and FEC formats.
+func trackExtractFormat(md *psdp.MediaDescription, encodings ...string) (*psdp.MediaDescription, *uint8, string) {
+	for _, attr := range md.Attributes {
+		if attr.Key != "rtpmap" {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimSpace(attr.Value), " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		encoding := ""
+		for _, e := range encodings {
+			if strings.HasPrefix(strings.ToLower(parts[1]), e+"/") {
+				encoding = e
+				break
+			}
+		}
+		if encoding == "" {
+			continue
+		}
+
+		tmp, err := strconv.ParseUint(parts[0], 10, 8)
+		if err != nil {
+			continue
+		}
+
+		var formats []string
+		for _, f := range md.MediaName.Formats {
+			if f != parts[0] {
+				formats = append(formats, f)
+			}
+		}
+		if len(formats) != (len(md.MediaName.Formats) - 1) {
+			continue
+		}
+
+		var attributes []psdp.Attribute
+		for _, attr := range md.Attributes {
+			if (attr.Key == "rtpmap" || attr.Key == "fmtp") &&
+				strings.HasPrefix(strings.TrimSpace(attr.Value), parts[0]+" ") {
+				continue
+			}
+			attributes = append(attributes, attr)
+		}
+
+		ret := *md
+		ret.MediaName.Formats = formats
+		ret.Attributes = attributes
+
+		payloadType := uint8(tmp)
+		return &ret, &payloadType, encoding
+	}
+
+	return md, nil, ""
+}
+
+// trackReadRTXAssociatedPayloadType reads the payload type of original packets
+// that is associated with a retransmission payload type through the apt parameter (RFC 4588).
+func trackReadRTXAssociatedPayloadType(md *psdp.MediaDescription, rtxPayloadType uint8) (uint8, bool) {
+	prefix := strconv.FormatUint(uint64(rtxPayloadType), 10) + " "
+
+	for _, attr := range md.Attributes {
+		if attr.Key != "fmtp" || !strings.HasPrefix(strings.TrimSpace(attr.Value), prefix) {
+			continue
+		}
+
+		params := strings.TrimPrefix(strings.TrimSpace(attr.Value), prefix)
+		for _, param := range strings.Split(params, ";") {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || strings.ToLower(kv[0]) != "apt" {
+				continue
+			}
+
+			tmp, err := strconv.ParseUint(kv[1], 10, 8)
+			if err != nil {
+				return 0, false
+			}
+			return uint8(tmp), true
+		}
+	}
+
+	return 0, false
+}
+
+func trackHasFormat(md *psdp.MediaDescription, payloadType uint8) bool {
+	format := strconv.FormatUint(uint64(payloadType), 10)
+	for _, f := range md.MediaName.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// trackRTXOriginalPayloadType returns the payload type of the packets
+// that are retransmitted with the retransmission payload type of a track.
+func trackRTXOriginalPayloadType(track Track) (uint8, bool) {
+	if payloadType, ok := track.rtxAssociatedPayloadType(); ok {
+		return payloadType, true
+	}
+
+	// tracks that have not been read from a SDP have a single format,
+	// that is associated with retransmissions by Tracks.Write().
+	formats := track.MediaDescription().MediaName.Formats
+	if len(formats) == 0 {
+		return 0, false
+	}
+
+	tmp, err := strconv.ParseUint(formats[0], 10, 8)
+	if err != nil {
+		return 0, false
+	}
+	return uint8(tmp), true
+}
+
+// trackReadSRTPKey reads the SRTP master key of a media description, if any.
+// Keys can be provided with SDP Security Descriptions (a=crypto, RFC 4568)
+// or with MIKEY (a=key-mgmt, RFC 4567).
+func trackReadSRTPKey(attributes []psdp.Attribute) (*srtp.Key, srtp.KeyExchange, error) {
+	for _, attr := range attributes {
+		switch attr.Key {
+		case "crypto":
+			var key srtp.Key
+			err := key.UnmarshalSDES(attr.Value)
+			if err != nil {
+				// skip unsupported crypto suites, another attribute may contain a supported one
+				if _, ok := err.(srtp.ErrUnsupportedSDES); ok {
+					continue
+				}
+				return nil, 0, err
+			}
+			return &key, srtp.KeyExchangeSDES, nil
+
+		case "key-mgmt":
+			parts := strings.Fields(attr.Value)
+			if len(parts) != 2 || parts[0] != "mikey" {
+				continue
+			}
+
+			byts, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid MIKEY message: %s", err)
+			}
+
+			var msg mikey.Message
+			err = msg.Unmarshal(byts)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid MIKEY message: %s", err)
+			}
+
+			key, err := msg.SRTPKey()
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid MIKEY message: %s", err)
+			}
+			return key, srtp.KeyExchangeMIKEY, nil
+		}
+	}
+
+	return nil, 0, nil
+}
+
+// trackWriteSRTPKey returns the SDP attribute that contains a SRTP master key.
+func trackWriteSRTPKey(key *srtp.Key, keyExchange srtp.KeyExchange) (psdp.Attribute, error) {
+	if keyExchange == srtp.KeyExchangeMIKEY {
+		msg, err := mikey.NewMessage(key, []uint32{0})
+		if err != nil {
+			return psdp.Attribute{}, err
+		}
+
+		byts, err := msg.Marshal()
+		if err != nil {
+			return psdp.Attribute{}, err
+		}
+
+		return psdp.Attribute{
+			Key:   "key-mgmt",
+			Value: "mikey " + base64.StdEncoding.EncodeToString(byts),
+		}, nil
+	}
+
+	return psdp.Attribute{
+		Key:   "crypto",
+		Value: key.MarshalSDES(1),
+	}, nil
 }
 
 func newTrackFromMediaDescription(md *psdp.MediaDescription) (Track, error) {
+	srtpKey, srtpKeyExchange, err := trackReadSRTPKey(md.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	origMD := md
+	md, rtxPayloadType, _ := trackExtractFormat(md, "rtx")
+	md, fecPayloadType, fecEncoding := trackExtractFormat(md, "ulpfec", "flexfec")
+
+	track, err := newTrackFromMediaDescriptionCodec(md)
+	if err != nil {
+		return nil, err
+	}
+
+	if rtxPayloadType != nil {
+		// retransmission packets can be decoded only when they are associated
+		// with a format of the track.
+		if apt, ok := trackReadRTXAssociatedPayloadType(origMD, *rtxPayloadType); ok &&
+			trackHasFormat(md, apt) {
+			track.SetRTXPayloadType(*rtxPayloadType)
+			track.setRTXAssociatedPayloadType(apt)
+		}
+	}
+
+	if srtpKey != nil {
+		err := track.SetSRTP(srtpKey, srtpKeyExchange)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, attr := range md.Attributes {
+		if attr.Key == "rtcp-mux" {
+			track.SetRTCPMux(true)
+		}
+	}
+
+	if fecPayloadType != nil {
+		if fecEncoding == "flexfec" {
+			track.SetFEC(*fecPayloadType, rtpfec.SchemeFlexFEC)
+		} else {
+			track.SetFEC(*fecPayloadType, rtpfec.SchemeULPFEC)
+		}
+	}
+
+	return track, nil
+}
+
+func newTrackFromMediaDescriptionCodec(md *psdp.MediaDescription) (Track, error) {
 	control := func() string {
 		for _, attr := range md.Attributes {
 			if attr.Key == "control" {
@@ -100,7 +370,15 @@ func newTrackFromMediaDescription(md *psdp.MediaDescription) (Track, error) {
 }
 
 type trackBase struct {
-	control string
+	control         string
+	rtxPayloadType  *uint8
+	rtxAPT          *uint8
+	fecPayloadType  *uint8
+	fecScheme       rtpfec.Scheme
+	srtpKey         *srtp.Key
+	srtpKeyExchange srtp.KeyExchange
+	srtpKeyAttr     psdp.Attribute
+	rtcpMux         bool
 }
 
 // GetControl gets the track control.
@@ -113,6 +391,92 @@ func (t *trackBase) SetControl(c string) {
 	t.control = c
 }
 
+// RTXPayloadType returns the payload type of RTP retransmission (RFC 4588) packets, if any.
+func (t *trackBase) RTXPayloadType() (uint8, bool) {
+	if t.rtxPayloadType == nil {
+		return 0, false
+	}
+	return *t.rtxPayloadType, true
+}
+
+// SetRTXPayloadType sets the payload type of RTP retransmission (RFC 4588) packets.
+// The retransmission format is added to the SDP generated by Tracks.Write().
+func (t *trackBase) SetRTXPayloadType(payloadType uint8) {
+	t.rtxPayloadType = &payloadType
+}
+
+func (t *trackBase) rtxAssociatedPayloadType() (uint8, bool) {
+	if t.rtxAPT == nil {
+		return 0, false
+	}
+	return *t.rtxAPT, true
+}
+
+func (t *trackBase) setRTXAssociatedPayloadType(payloadType uint8) {
+	t.rtxAPT = &payloadType
+}
+
+// FEC returns the payload type and scheme of forward error correction packets, if any.
+func (t *trackBase) FEC() (uint8, rtpfec.Scheme, bool) {
+	if t.fecPayloadType == nil {
+		return 0, 0, false
+	}
+	return *t.fecPayloadType, t.fecScheme, true
+}
+
+// SetFEC sets the payload type and scheme of forward error correction packets.
+// The FEC format is added to the SDP generated by Tracks.Write().
+func (t *trackBase) SetFEC(payloadType uint8, scheme rtpfec.Scheme) {
+	t.fecPayloadType = &payloadType
+	t.fecScheme = scheme
+}
+
+// SRTP returns the SRTP master key of the track and the method used to exchange it, if any.
+func (t *trackBase) SRTP() (*srtp.Key, srtp.KeyExchange, bool) {
+	if t.srtpKey == nil {
+		return nil, 0, false
+	}
+	return t.srtpKey, t.srtpKeyExchange, true
+}
+
+// SetSRTP sets the SRTP master key of the track and the method used to exchange it.
+// When a key is set, the track is protected with SRTP (RTP/SAVP profile),
+// and the key is added to the SDP generated by Tracks.Write().
+// Since the key is transmitted in clear inside the SDP, it should be used
+// in conjunction with TLS (RTSPS).
+func (t *trackBase) SetSRTP(key *srtp.Key, keyExchange srtp.KeyExchange) error {
+	err := key.Validate()
+	if err != nil {
+		return err
+	}
+
+	attr, err := trackWriteSRTPKey(key, keyExchange)
+	if err != nil {
+		return err
+	}
+
+	t.srtpKey = key
+	t.srtpKeyExchange = keyExchange
+	t.srtpKeyAttr = attr
+	return nil
+}
+
+func (t *trackBase) srtpAttribute() psdp.Attribute {
+	return t.srtpKeyAttr
+}
+
+// RTCPMux returns whether RTP and RTCP packets of the track can be multiplexed on the same port (RFC 5761).
+func (t *trackBase) RTCPMux() bool {
+	return t.rtcpMux
+}
+
+// SetRTCPMux sets whether RTP and RTCP packets of the track can be multiplexed on the same port (RFC 5761).
+// When enabled, the rtcp-mux attribute is added to the SDP generated by Tracks.Write(),
+// and clients that use the UDP transport request multiplexing to the server.
+func (t *trackBase) SetRTCPMux(v bool) {
+	t.rtcpMux = v
+}
+
 func (t *trackBase) url(contentBase *url.URL) (*url.URL, error) {
 	if contentBase == nil {
 		return nil, fmt.Errorf("Content-Base header not provided")
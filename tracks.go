@@ -7,6 +7,7 @@ import (
 
 	psdp "github.com/pion/sdp/v3"
 
+	"github.com/aler9/gortsplib/pkg/rtpfec"
 	"github.com/aler9/gortsplib/pkg/sdp"
 )
 
@@ -22,6 +23,12 @@ func ReadTracks(byts []byte, skipGenericTracksWithoutClockRate bool) (Tracks, *s
 		return nil, nil, err
 	}
 
+	// MIKEY messages can be provided at session level
+	sessionSRTPKey, sessionSRTPKeyExchange, err := trackReadSRTPKey(sd.Attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var tracks Tracks //nolint:prealloc
 
 	for i, md := range sd.MediaDescriptions {
@@ -34,6 +41,13 @@ func ReadTracks(byts []byte, skipGenericTracksWithoutClockRate bool) (Tracks, *s
 			return nil, nil, fmt.Errorf("unable to parse track %d: %s", i+1, err)
 		}
 
+		if _, _, ok := t.SRTP(); !ok && sessionSRTPKey != nil {
+			err := t.SetSRTP(sessionSRTPKey, sessionSRTPKeyExchange)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to parse track %d: %s", i+1, err)
+			}
+		}
+
 		tracks = append(tracks, t)
 	}
 
@@ -60,6 +74,11 @@ func (ts Tracks) setControls() {
 
 // Write encodes tracks in the SDP format.
 func (ts Tracks) Write(multicast bool) []byte {
+	byts, _ := ts.sessionDescription(multicast).Marshal()
+	return byts
+}
+
+func (ts Tracks) sessionDescription(multicast bool) *sdp.SessionDescription {
 	address := "0.0.0.0"
 	if multicast {
 		address = "224.1.0.0"
@@ -85,9 +104,63 @@ func (ts Tracks) Write(multicast bool) []byte {
 	}
 
 	for _, track := range ts {
-		sout.MediaDescriptions = append(sout.MediaDescriptions, track.MediaDescription())
+		md := track.MediaDescription()
+
+		if len(md.MediaName.Formats) == 1 {
+			mainFormat := md.MediaName.Formats[0]
+			clockRate := strconv.FormatInt(int64(track.ClockRate()), 10)
+			md.MediaName.Formats = []string{mainFormat}
+
+			if rtxPayloadType, ok := track.RTXPayloadType(); ok {
+				rtxFormat := strconv.FormatInt(int64(rtxPayloadType), 10)
+
+				md.MediaName.Formats = append(md.MediaName.Formats, rtxFormat)
+				md.Attributes = append(md.Attributes,
+					psdp.Attribute{
+						Key:   "rtpmap",
+						Value: rtxFormat + " rtx/" + clockRate,
+					},
+					psdp.Attribute{
+						Key:   "fmtp",
+						Value: rtxFormat + " apt=" + mainFormat,
+					},
+				)
+			}
+
+			if fecPayloadType, fecScheme, ok := track.FEC(); ok {
+				fecFormat := strconv.FormatInt(int64(fecPayloadType), 10)
+
+				md.MediaName.Formats = append(md.MediaName.Formats, fecFormat)
+				md.Attributes = append(md.Attributes,
+					psdp.Attribute{
+						Key:   "rtpmap",
+						Value: fecFormat + " " + fecScheme.String() + "/" + clockRate,
+					},
+				)
+
+				// repair-window is mandatory in FlexFEC
+				if fecScheme == rtpfec.SchemeFlexFEC {
+					md.Attributes = append(md.Attributes,
+						psdp.Attribute{
+							Key:   "fmtp",
+							Value: fecFormat + " repair-window=200000",
+						},
+					)
+				}
+			}
+		}
+
+		if _, _, ok := track.SRTP(); ok {
+			md.MediaName.Protos = []string{"RTP", "SAVP"}
+			md.Attributes = append(md.Attributes, track.srtpAttribute())
+		}
+
+		if track.RTCPMux() {
+			md.Attributes = append(md.Attributes, psdp.Attribute{Key: "rtcp-mux"})
+		}
+
+		sout.MediaDescriptions = append(sout.MediaDescriptions, md)
 	}
 
-	byts, _ := sout.Marshal()
-	return byts
+	return sout
 }
@@ -4,6 +4,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/gortsplib/pkg/rtpfec"
+	"github.com/aler9/gortsplib/pkg/srtp"
 )
 
 func TestTracksReadErrors(t *testing.T) {
@@ -85,3 +88,216 @@ func TestTracksReadSkipGenericTracksWithoutClockRate(t *testing.T) {
 		},
 	}, tracks)
 }
+
+func TestTracksReadWriteRTX(t *testing.T) {
+	sdp := []byte("v=0\r\n" +
+		"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+		"s=Stream\r\n" +
+		"c=IN IP4 0.0.0.0\r\n" +
+		"t=0 0\r\n" +
+		"m=video 0 RTP/AVP 96 97\r\n" +
+		"a=rtpmap:96 H264/90000\r\n" +
+		"a=fmtp:96 packetization-mode=1; sprop-parameter-sets=Z2QAKKy0A8ARPyo=,aO4Bniw=; profile-level-id=640028\r\n" +
+		"a=rtpmap:97 rtx/90000\r\n" +
+		"a=fmtp:97 apt=96\r\n" +
+		"a=control:trackID=0\r\n")
+
+	tracks, _, err := ReadTracks(sdp, false)
+	require.NoError(t, err)
+	require.Equal(t, Tracks{
+		&TrackH264{
+			trackBase: trackBase{
+				control: "trackID=0",
+				rtxPayloadType: func() *uint8 {
+					v := uint8(97)
+					return &v
+				}(),
+				rtxAPT: func() *uint8 {
+					v := uint8(96)
+					return &v
+				}(),
+			},
+			payloadType: 96,
+			sps:         []byte{0x67, 0x64, 0x00, 0x28, 0xac, 0xb4, 0x03, 0xc0, 0x11, 0x3f, 0x2a},
+			pps:         []byte{0x68, 0xee, 0x01, 0x9e, 0x2c},
+		},
+	}, tracks)
+
+	pt, ok := tracks[0].RTXPayloadType()
+	require.True(t, ok)
+	require.Equal(t, uint8(97), pt)
+
+	byts := tracks.Write(false)
+	require.Equal(t, "v=0\r\n"+
+		"o=- 0 0 IN IP4 127.0.0.1\r\n"+
+		"s=Stream\r\n"+
+		"c=IN IP4 0.0.0.0\r\n"+
+		"t=0 0\r\n"+
+		"m=video 0 RTP/AVP 96 97\r\n"+
+		"a=rtpmap:96 H264/90000\r\n"+
+		"a=fmtp:96 packetization-mode=1; sprop-parameter-sets=Z2QAKKy0A8ARPyo=,aO4Bniw=; profile-level-id=640028\r\n"+
+		"a=control:trackID=0\r\n"+
+		"a=rtpmap:97 rtx/90000\r\n"+
+		"a=fmtp:97 apt=96\r\n", string(byts))
+}
+
+func TestTracksReadRTXWithoutAssociatedFormat(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		fmtp string
+	}{
+		{
+			"missing apt",
+			"",
+		},
+		{
+			"apt of another track",
+			"a=fmtp:97 apt=98\r\n",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			sdp := []byte("v=0\r\n" +
+				"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+				"s=Stream\r\n" +
+				"c=IN IP4 0.0.0.0\r\n" +
+				"t=0 0\r\n" +
+				"m=video 0 RTP/AVP 96 97\r\n" +
+				"a=rtpmap:96 H264/90000\r\n" +
+				"a=fmtp:96 packetization-mode=1; sprop-parameter-sets=Z2QAKKy0A8ARPyo=,aO4Bniw=; profile-level-id=640028\r\n" +
+				"a=rtpmap:97 rtx/90000\r\n" +
+				ca.fmtp +
+				"a=control:trackID=0\r\n")
+
+			tracks, _, err := ReadTracks(sdp, false)
+			require.NoError(t, err)
+
+			_, ok := tracks[0].RTXPayloadType()
+			require.Equal(t, false, ok)
+		})
+	}
+}
+
+func TestTracksReadWriteRTCPMux(t *testing.T) {
+	sdp := []byte("v=0\r\n" +
+		"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+		"s=Stream\r\n" +
+		"c=IN IP4 0.0.0.0\r\n" +
+		"t=0 0\r\n" +
+		"m=video 0 RTP/AVP 96\r\n" +
+		"a=rtpmap:96 H264/90000\r\n" +
+		"a=fmtp:96 packetization-mode=1; sprop-parameter-sets=Z2QAKKy0A8ARPyo=,aO4Bniw=; profile-level-id=640028\r\n" +
+		"a=rtcp-mux\r\n" +
+		"a=control:trackID=0\r\n")
+
+	tracks, _, err := ReadTracks(sdp, false)
+	require.NoError(t, err)
+	require.Equal(t, true, tracks[0].RTCPMux())
+
+	byts := tracks.Write(false)
+	require.Equal(t, "v=0\r\n"+
+		"o=- 0 0 IN IP4 127.0.0.1\r\n"+
+		"s=Stream\r\n"+
+		"c=IN IP4 0.0.0.0\r\n"+
+		"t=0 0\r\n"+
+		"m=video 0 RTP/AVP 96\r\n"+
+		"a=rtpmap:96 H264/90000\r\n"+
+		"a=fmtp:96 packetization-mode=1; sprop-parameter-sets=Z2QAKKy0A8ARPyo=,aO4Bniw=; profile-level-id=640028\r\n"+
+		"a=control:trackID=0\r\n"+
+		"a=rtcp-mux\r\n", string(byts))
+}
+
+func TestTracksReadWriteFEC(t *testing.T) {
+	sdp := []byte("v=0\r\n" +
+		"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+		"s=Stream\r\n" +
+		"c=IN IP4 0.0.0.0\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 0 RTP/AVP 0 97 98\r\n" +
+		"a=rtpmap:0 PCMU/8000\r\n" +
+		"a=control:trackID=0\r\n" +
+		"a=rtpmap:97 rtx/8000\r\n" +
+		"a=fmtp:97 apt=0\r\n" +
+		"a=rtpmap:98 flexfec/8000\r\n" +
+		"a=fmtp:98 repair-window=200000\r\n")
+
+	tracks, _, err := ReadTracks(sdp, false)
+	require.NoError(t, err)
+
+	rtxPayloadType, ok := tracks[0].RTXPayloadType()
+	require.True(t, ok)
+	require.Equal(t, uint8(97), rtxPayloadType)
+
+	fecPayloadType, fecScheme, ok := tracks[0].FEC()
+	require.True(t, ok)
+	require.Equal(t, uint8(98), fecPayloadType)
+	require.Equal(t, rtpfec.SchemeFlexFEC, fecScheme)
+
+	require.Equal(t, sdp, tracks.Write(false))
+
+	track := NewTrackPCMU()
+	track.SetFEC(98, rtpfec.SchemeULPFEC)
+
+	byts := Tracks{track}.Write(false)
+	require.Equal(t, "v=0\r\n"+
+		"o=- 0 0 IN IP4 127.0.0.1\r\n"+
+		"s=Stream\r\n"+
+		"c=IN IP4 0.0.0.0\r\n"+
+		"t=0 0\r\n"+
+		"m=audio 0 RTP/AVP 0 98\r\n"+
+		"a=rtpmap:0 PCMU/8000\r\n"+
+		"a=control\r\n"+
+		"a=rtpmap:98 ulpfec/8000\r\n", string(byts))
+
+	tracks, _, err = ReadTracks(byts, false)
+	require.NoError(t, err)
+
+	fecPayloadType, fecScheme, ok = tracks[0].FEC()
+	require.True(t, ok)
+	require.Equal(t, uint8(98), fecPayloadType)
+	require.Equal(t, rtpfec.SchemeULPFEC, fecScheme)
+}
+
+func TestTracksReadWriteSRTP(t *testing.T) {
+	t.Run("sdes", func(t *testing.T) {
+		sdp := []byte("v=0\r\n" +
+			"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+			"s=Stream\r\n" +
+			"c=IN IP4 0.0.0.0\r\n" +
+			"t=0 0\r\n" +
+			"m=audio 0 RTP/SAVP 0\r\n" +
+			"a=rtpmap:0 PCMU/8000\r\n" +
+			"a=control\r\n" +
+			"a=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:4fl6DT4Bi+DWT6MsBt5BOQ7Gda1Jiv7rtpYLOqvm\r\n")
+
+		tracks, _, err := ReadTracks(sdp, false)
+		require.NoError(t, err)
+
+		key, ex, ok := tracks[0].SRTP()
+		require.True(t, ok)
+		require.Equal(t, srtp.KeyExchangeSDES, ex)
+		require.Equal(t, srtp.ProfileAESCM128HMACSHA180, key.Profile)
+
+		require.Equal(t, sdp, tracks.Write(false))
+	})
+
+	t.Run("mikey", func(t *testing.T) {
+		key, err := srtp.NewKey(srtp.ProfileAESCM128HMACSHA132)
+		require.NoError(t, err)
+
+		track := NewTrackPCMU()
+		err = track.SetSRTP(key, srtp.KeyExchangeMIKEY)
+		require.NoError(t, err)
+
+		byts := Tracks{track}.Write(false)
+		require.Contains(t, string(byts), "m=audio 0 RTP/SAVP 0\r\n")
+		require.Contains(t, string(byts), "a=key-mgmt:mikey ")
+
+		tracks, _, err := ReadTracks(byts, false)
+		require.NoError(t, err)
+
+		key2, ex, ok := tracks[0].SRTP()
+		require.True(t, ok)
+		require.Equal(t, srtp.KeyExchangeMIKEY, ex)
+		require.Equal(t, key, key2)
+	})
+}
@@ -0,0 +1,134 @@
+package gortsplib
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+
+	"github.com/aler9/gortsplib/pkg/rtcpreceiver"
+	"github.com/aler9/gortsplib/pkg/rtcpsender"
+)
+
+// TrackStats are the statistics of a track.
+// Depending on the direction of the track, either received or sent
+// quantities are filled.
+type TrackStats struct {
+	// number of received RTP packets.
+	PacketsReceived uint64
+
+	// size of received RTP packets, headers included.
+	BytesReceived uint64
+
+	// number of sent RTP packets.
+	PacketsSent uint64
+
+	// size of sent RTP packets, headers included.
+	BytesSent uint64
+
+	// number of lost RTP packets.
+	// When sending, it is the value reported by the counterpart.
+	PacketsLost uint64
+
+	// interarrival jitter.
+	// When sending, it is the value reported by the counterpart.
+	Jitter time.Duration
+
+	// sequence number of the last received or sent RTP packet.
+	LastSequenceNumber uint16
+
+	// round-trip time, computed from the RTCP receiver reports
+	// of the counterpart. It is available only when sending.
+	RTT time.Duration
+
+	// time of the last received or sent RTCP sender report.
+	LastSenderReport time.Time
+
+	// time of the last received or sent RTCP receiver report.
+	LastReceiverReport time.Time
+}
+
+func timeToNTP(t time.Time) uint64 {
+	ns := t.UnixNano() + ntpEpochOffset*1000000000
+
+	// higher 32 bits are the integer part, lower 32 bits are the fractional part
+	integerPart := uint64(ns / 1000000000)
+	fractionalPart := (uint64(ns%1000000000) << 32) / 1000000000
+	return integerPart<<32 | fractionalPart
+}
+
+func jitterToDuration(jitter float64, clockRate int) time.Duration {
+	return time.Duration(jitter / float64(clockRate) * float64(time.Second))
+}
+
+func (s *TrackStats) fillFromReceiver(rs rtcpreceiver.Stats, clockRate int) {
+	s.PacketsReceived = rs.PacketsReceived
+	s.BytesReceived = rs.BytesReceived
+	s.PacketsLost = rs.PacketsLost
+	s.Jitter = jitterToDuration(rs.Jitter, clockRate)
+	s.LastSequenceNumber = rs.LastSequenceNumber
+	s.LastSenderReport = rs.LastSenderReport
+	s.LastReceiverReport = rs.LastReceiverReport
+}
+
+func (s *TrackStats) fillFromSender(ss rtcpsender.Stats) {
+	s.PacketsSent = ss.PacketsSent
+	s.BytesSent = ss.BytesSent
+	s.LastSequenceNumber = ss.LastSequenceNumber
+	s.LastSenderReport = ss.LastSenderReport
+}
+
+// receiverReportStats extracts statistics from the RTCP receiver reports
+// sent by the counterpart of a sender.
+type receiverReportStats struct {
+	clockRate int
+	mutex     sync.Mutex
+
+	packetsLost uint64
+	jitter      float64
+	rtt         time.Duration
+	lastTime    time.Time
+}
+
+func newReceiverReportStats(clockRate int) *receiverReportStats {
+	return &receiverReportStats{
+		clockRate: clockRate,
+	}
+}
+
+func (r *receiverReportStats) processPacketRTCP(ts time.Time, pkt rtcp.Packet) {
+	rr, ok := pkt.(*rtcp.ReceiverReport)
+	if !ok || len(rr.Reports) == 0 {
+		return
+	}
+
+	report := rr.Reports[0]
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.packetsLost = uint64(report.TotalLost)
+	r.jitter = float64(report.Jitter)
+	r.lastTime = ts
+
+	// round-trip time is computed by subtracting the time of the last sender report
+	// and the delay since then from the arrival time, in units of 1/65536 seconds.
+	// https://tools.ietf.org/html/rfc3550#page-40
+	if report.LastSenderReport != 0 {
+		arrival := uint32(timeToNTP(ts) >> 16)
+		rtt := arrival - report.LastSenderReport - report.Delay
+		if rtt < 0x80000000 {
+			r.rtt = time.Duration(float64(rtt) / 65536 * float64(time.Second))
+		}
+	}
+}
+
+func (r *receiverReportStats) fill(s *TrackStats) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	s.PacketsLost = r.packetsLost
+	s.Jitter = jitterToDuration(r.jitter, r.clockRate)
+	s.RTT = r.rtt
+	s.LastReceiverReport = r.lastTime
+}